@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/klaus/pkg/config"
+)
+
+// newConfigCmd creates the Cobra command for inspecting the configuration.
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Validate and print the klaus configuration",
+		Long: `Validate and print the configuration serve would run with: the YAML
+config file, environment variable overrides and the serve flags, which
+both subcommands accept.`,
+	}
+	cmd.AddCommand(newConfigValidateCmd())
+	cmd.AddCommand(newConfigPrintCmd())
+	return cmd
+}
+
+func newConfigValidateCmd() *cobra.Command {
+	var (
+		configPath string
+		flags      serveFlags
+	)
+
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration for errors",
+		Long: `Check the configuration the way serve does on start and on reload:
+unknown keys in the config file, invalid values, malformed subagent
+definitions and tool patterns, relative paths and incomplete OAuth
+settings. All errors are reported and the command exits non-zero if
+there are any.
+
+Unlike 'klaus doctor', it does not check the environment, such as
+whether the claude binary or the workspace exist.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, _, err := loadServeConfig(cmd, configPath, &flags); err != nil {
+				return err
+			}
+			_, err := fmt.Fprintln(cmd.OutOrStdout(), "config is valid")
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&configPath, "config", config.DefaultConfigPath, "Path to YAML config file")
+	flags.register(cmd)
+
+	return cmd
+}
+
+func newConfigPrintCmd() *cobra.Command {
+	var (
+		configPath string
+		flags      serveFlags
+		effective  bool
+		jsonOutput bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "print",
+		Short: "Print the configured values and where they came from",
+		Long: `Print the value of every field set by the config file, an environment
+variable or a flag, along with its source. With --effective, fields left
+at their default are printed too, giving the final merged configuration.
+Secrets are redacted.
+
+An invalid configuration is printed and the validation errors are
+returned.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, sources, err := loadServeConfig(cmd, configPath, &flags)
+			if sources == nil {
+				// The config could not be loaded at all.
+				return err
+			}
+
+			fields := config.Effective(cfg, sources)
+			if !effective {
+				set := fields[:0]
+				for _, f := range fields {
+					if f.Source.Kind != config.SourceDefault {
+						set = append(set, f)
+					}
+				}
+				fields = set
+			}
+			if jsonOutput {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				enc.SetEscapeHTML(false)
+				if encErr := enc.Encode(fields); encErr != nil {
+					return encErr
+				}
+			} else if printErr := printConfigFields(cmd.OutOrStdout(), fields); printErr != nil {
+				return printErr
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&configPath, "config", config.DefaultConfigPath, "Path to YAML config file")
+	cmd.Flags().BoolVar(&effective, "effective", false, "Also print fields left at their default")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the fields as JSON")
+	flags.register(cmd)
+
+	return cmd
+}
+
+// printConfigFields writes one line per field with its value as JSON and
+// its source.
+func printConfigFields(w io.Writer, fields []config.EffectiveField) error {
+	for _, f := range fields {
+		var value bytes.Buffer
+		enc := json.NewEncoder(&value)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(f.Value); err != nil {
+			return fmt.Errorf("%s: %w", f.Field, err)
+		}
+		if _, err := fmt.Fprintf(w, "%s = %s  # %s\n", f.Field, bytes.TrimSpace(value.Bytes()), f.Source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
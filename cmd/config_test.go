@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/klaus/pkg/config"
+)
+
+// runConfigCmd runs `klaus config` with args and returns its output.
+func runConfigCmd(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := newConfigCmd()
+	cmd.SetArgs(args)
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SilenceUsage = true
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigValidate(t *testing.T) {
+	t.Setenv("CLAUDE_MODEL", "")
+	valid := writeTestConfig(t, "claude:\n  model: sonnet\n")
+	out, err := runConfigCmd(t, "validate", "--config", valid)
+	if err != nil || !strings.Contains(out, "config is valid") {
+		t.Fatalf("expected a valid config, got %q, %v", out, err)
+	}
+
+	invalid := writeTestConfig(t, "claude:\n  modle: sonnet\n  agents: '{\"a\":'\n")
+	_, err = runConfigCmd(t, "validate", "--config", invalid)
+	if err == nil {
+		t.Fatal("expected validation to fail")
+	}
+	for _, want := range []string{"claude.modle: unknown field", "claude.agents: invalid JSON"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+
+	// Flags are validated like in serve.
+	_, err = runConfigCmd(t, "validate", "--config", valid, "--enable-oauth")
+	if err == nil || !strings.Contains(err.Error(), "oauth.baseURL is required") {
+		t.Errorf("expected an OAuth error, got %v", err)
+	}
+}
+
+func TestConfigPrint(t *testing.T) {
+	t.Setenv("CLAUDE_MODEL", "")
+	t.Setenv("CLAUDE_MAX_TURNS", "7")
+	t.Setenv("CLAUDE_EFFORT", "")
+	t.Setenv("PORT", "")
+	path := writeTestConfig(t, "claude:\n  model: sonnet\noauth:\n  google:\n    clientSecret: hunter2\n")
+
+	out, err := runConfigCmd(t, "print", "--config", path, "--port", "9090")
+	if err != nil {
+		t.Fatalf("print: %v", err)
+	}
+	for _, want := range []string{
+		`claude.model = "sonnet"  # yaml`,
+		"claude.maxTurns = 7  # env CLAUDE_MAX_TURNS",
+		`server.port = "9090"  # flag --port`,
+		`oauth.google.clientSecret = "<redacted>"  # yaml`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("secret leaked:\n%s", out)
+	}
+	if strings.Contains(out, "claude.effort") {
+		t.Errorf("expected defaults to be omitted without --effective:\n%s", out)
+	}
+
+	out, err = runConfigCmd(t, "print", "--config", path, "--effective", "--json")
+	if err != nil {
+		t.Fatalf("print --effective: %v", err)
+	}
+	var fields []config.EffectiveField
+	if err := json.Unmarshal([]byte(out), &fields); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	sources := map[string]config.Source{}
+	for _, f := range fields {
+		sources[f.Field] = f.Source
+	}
+	if sources["claude.effort"].Kind != config.SourceDefault || sources["claude.model"] != (config.Source{Kind: config.SourceYAML, Name: path}) {
+		t.Errorf("unexpected sources: %v", sources)
+	}
+}
+
+func TestConfigPrint_Invalid(t *testing.T) {
+	t.Setenv("CLAUDE_MODEL", "")
+	path := writeTestConfig(t, "claude:\n  model: sonnet\n  bogus: 1\n")
+	out, err := runConfigCmd(t, "print", "--config", path)
+	if err == nil || !strings.Contains(err.Error(), "claude.bogus: unknown field") {
+		t.Errorf("expected the validation error, got %v", err)
+	}
+	if !strings.Contains(out, `claude.model = "sonnet"`) {
+		t.Errorf("expected the config to be printed anyway:\n%s", out)
+	}
+}
+
+func TestConfigPrint_Profile(t *testing.T) {
+	t.Setenv("CLAUDE_MODEL", "")
+	t.Setenv("KLAUS_PROFILE", "")
+	path := writeTestConfig(t, "claude:\n  model: sonnet\nprofiles:\n  reviewer:\n    model: opus\n")
+
+	out, err := runConfigCmd(t, "print", "--config", path, "--profile", "reviewer")
+	if err != nil {
+		t.Fatalf("print: %v", err)
+	}
+	for _, want := range []string{
+		`claude.model = "opus"  # profile reviewer`,
+		`profile = "reviewer"  # flag --profile`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	t.Setenv("TOK", "super-secret-token")
+	path = writeTestConfig(t, `profiles:
+  reviewer:
+    mcpServers:
+      gh:
+        type: http
+        url: https://example.com/mcp
+        headers:
+          Authorization: Bearer ${TOK}
+      local:
+        command: server
+        env:
+          API_KEY: ${TOK}
+`)
+	for _, args := range [][]string{{}, {"--profile", "reviewer"}, {"--json"}} {
+		out, err := runConfigCmd(t, append([]string{"print", "--config", path}, args...)...)
+		if err != nil {
+			t.Fatalf("print %v: %v", args, err)
+		}
+		if strings.Contains(out, "super-secret-token") {
+			t.Errorf("print %v: secret of a profile leaked:\n%s", args, out)
+		}
+		if !strings.Contains(out, "redacted") || !strings.Contains(out, "https://example.com/mcp") {
+			t.Errorf("print %v: expected the profile's MCP servers with masked secrets:\n%s", args, out)
+		}
+	}
+
+	_, err = runConfigCmd(t, "validate", "--config", path, "--profile", "implementer")
+	if err == nil || !strings.Contains(err.Error(), `unknown profile "implementer"`) {
+		t.Errorf("expected an unknown profile error, got %v", err)
+	}
+}
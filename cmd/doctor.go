@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/klaus/pkg/config"
+	"github.com/giantswarm/klaus/pkg/doctor"
+)
+
+// errNotReady is returned by `klaus doctor` when a check failed, so that the
+// command exits non-zero.
+var errNotReady = errors.New("not ready: at least one check failed")
+
+// newDoctorCmd creates the Cobra command for checking the configuration.
+func newDoctorCmd() *cobra.Command {
+	var (
+		configPath string
+		jsonOutput bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the configuration and environment of klaus",
+		Long: `Validate the configuration and run the readiness checks that the server
+reports on /readyz?verbose: the claude binary and its version, the
+workspace, the MCP server config, plugin and additional directories,
+subagent definitions and credentials.
+
+Every check reports ok, warn or fail. Warnings point at problems that do
+not prevent prompts from running, such as an MCP server host that does
+not resolve. The command exits non-zero if a check failed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+			if err := cfg.Validate(); err != nil {
+				return fmt.Errorf("config validation: %w", err)
+			}
+			// A generated MCP config file only lives as long as the
+			// checks, instead of joining those of serve.
+			mcpDir, err := os.MkdirTemp("", "klaus-doctor-mcp-")
+			if err != nil {
+				return err
+			}
+			defer func() { _ = os.RemoveAll(mcpDir) }()
+			opts, err := claudeOptions(cfg, mcpDir)
+			if err != nil {
+				return err
+			}
+
+			report := doctor.Run(cmd.Context(), doctor.Checks(cfg, opts))
+			if jsonOutput {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(report); err != nil {
+					return err
+				}
+			} else {
+				printDoctorReport(cmd.OutOrStdout(), report)
+			}
+			if !report.Ready {
+				return errNotReady
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&configPath, "config", config.DefaultConfigPath, "Path to YAML config file")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the report as JSON")
+
+	return cmd
+}
+
+// printDoctorReport writes one line per check.
+func printDoctorReport(w io.Writer, report doctor.Report) {
+	for _, r := range report.Checks {
+		line := fmt.Sprintf("[%-4s] %-14s %s", r.Status, r.Name, r.Detail)
+		if r.Error != "" {
+			if r.Detail != "" {
+				line += ": "
+			}
+			line += r.Error
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/klaus/pkg/doctor"
+)
+
+func TestDoctor_FailsWithoutBinary(t *testing.T) {
+	noConfig := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("CLAUDE_WORKSPACE", t.TempDir())
+	t.Setenv("CLAUDE_BINARY", filepath.Join(t.TempDir(), "claude"))
+
+	var out bytes.Buffer
+	cmd := newDoctorCmd()
+	cmd.SetArgs([]string{"--config", noConfig, "--json"})
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SilenceUsage = true
+	if err := cmd.Execute(); !errors.Is(err, errNotReady) {
+		t.Fatalf("expected errNotReady, got %v", err)
+	}
+
+	var report doctor.Report
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	statuses := map[string]string{}
+	for _, r := range report.Checks {
+		statuses[r.Name] = r.Status
+	}
+	if statuses["claude_binary"] != doctor.StatusFail {
+		t.Errorf("expected claude_binary to fail: %+v", report.Checks)
+	}
+	if statuses["workspace"] != doctor.StatusOK {
+		t.Errorf("expected workspace to pass: %+v", report.Checks)
+	}
+}
+
+func TestPrintDoctorReport(t *testing.T) {
+	var out bytes.Buffer
+	printDoctorReport(&out, doctor.Report{Checks: []doctor.Result{
+		{Name: "workspace", Status: doctor.StatusOK, Detail: "/workspace"},
+		{Name: "mcp_config", Status: doctor.StatusWarn, Detail: "1 servers", Error: "lookup failed"},
+	}})
+	got := out.String()
+	for _, want := range []string{"[ok  ] workspace", "/workspace\n", "[warn] mcp_config", "1 servers: lookup failed"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in output:\n%s", want, got)
+		}
+	}
+}
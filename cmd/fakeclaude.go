@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/klaus/pkg/fakeclaude"
+)
+
+// newFakeClaudeCmd creates the Cobra command for the scriptable fake CLI.
+func newFakeClaudeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "fake-claude [claude flags] [-- prompt]",
+		Short: "Run a scriptable stand-in for the claude CLI",
+		Long: `Run a deterministic stand-in for the Claude Code CLI that accepts the same
+flags and emits stream-json from a scenario YAML. No API key or network
+access is needed.
+
+The scenario is read from --scenario or the ` + fakeclaude.ScenarioEnv + `
+environment variable; without one every prompt is echoed back. Point klaus
+at the fake with:
+
+  claude:
+    binary: /usr/local/bin/klaus
+    binaryArgs: ["fake-claude", "--scenario", "/etc/klaus/scenario.yaml"]`,
+		// Flags are parsed by the fake itself, as the real CLI would.
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if slices.Contains(args, "--help") || slices.Contains(args, "-h") {
+				return cmd.Help()
+			}
+			os.Exit(fakeclaude.Main(args, os.Stdin, os.Stdout, os.Stderr))
+			return nil
+		},
+	}
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"log/slog"
+	"reflect"
+
+	"github.com/giantswarm/klaus/pkg/claude"
+	"github.com/giantswarm/klaus/pkg/config"
+	"github.com/giantswarm/klaus/pkg/doctor"
+)
+
+// configSource is where serve loads its config from.
+type configSource struct {
+	// path is the config file or directory, watched for changes along
+	// with the config.d directory next to it.
+	path string
+	// load reads the config file, applies env var and flag overrides and
+	// validates the result.
+	load func() (config.Config, error)
+}
+
+// configReloader applies a changed config file or SOUL.md to the running
+// server. It is only called by config.Watcher, which serializes calls.
+type configReloader struct {
+	source    configSource
+	soulPath  string
+	process   claude.Prompter
+	readiness *doctor.Runner
+	// versions detects the CLI version again only when the binary changes.
+	versions *cliVersionDetector
+	// prober probes the MCP servers; nil when probing is disabled.
+	prober *claude.MCPProber
+
+	// current is the config in effect, and opts the options built from it.
+	current config.Config
+	opts    claude.Options
+}
+
+// watchedPaths returns the files a config reload is triggered by: the config
+// files, SOUL.md and the files cfg reads. Secret files are watched too.
+// Rotated MCP server secrets are applied like other claude settings; rotated
+// OAuth secrets are only logged and reported, as the OAuth server needs a
+// restart.
+func watchedPaths(source configSource, soulPath string, cfg config.Config) []string {
+	paths := append(config.ConfigPaths(source.path), soulPath)
+	paths = append(paths, cfg.SecretFiles()...)
+	if cfg.Claude.MCPConfigPath != "" {
+		paths = append(paths, cfg.Claude.MCPConfigPath)
+	}
+	return paths
+}
+
+// apply loads the config and hands the resulting options to the process,
+// which uses them from the next run on. The process only gets them when they
+// differ from the current ones, so that changes that do not affect the agent,
+// such as a rotated OAuth secret, do not restart a chat subprocess. A config
+// that does not load, validate or suit the CLI is rejected, keeping the
+// current one. The watcher then watches the files the new config reads.
+func (r *configReloader) apply() (config.ApplyResult, error) {
+	cfg, err := r.source.load()
+	if err != nil {
+		return config.ApplyResult{}, err
+	}
+	opts, err := serveOptions(cfg, r.soulPath, r.versions)
+	if err != nil {
+		return config.ApplyResult{}, err
+	}
+
+	r.prober.SetServers(opts.MCPServers, opts.WorkDir)
+	opts.MCPProber = r.prober
+
+	restartRequired := restartRequiredSettings(r.current, cfg)
+	if rotated := rotatedOAuthSecrets(r.current, cfg); len(rotated) > 0 {
+		// The OAuth server is created once at startup and keeps using the
+		// old secrets.
+		slog.Warn("config reload: rotated OAuth secrets are not applied until klaus restarts", "secrets", rotated)
+	}
+	if !optionsEqual(opts, r.opts) {
+		r.process.UpdateOptions(opts)
+	}
+	pruneMCPConfigs(opts)
+	if r.readiness != nil {
+		r.readiness.SetChecks(doctor.Checks(cfg, opts))
+	}
+	r.current = cfg
+	r.opts = opts
+	return config.ApplyResult{
+		RestartRequired: restartRequired,
+		Paths:           watchedPaths(r.source, r.soulPath, cfg),
+	}, nil
+}
+
+// optionsEqual reports whether a and b run the agent the same way.
+// Redactors are compared by their patterns, as every load compiles new ones.
+func optionsEqual(a, b claude.Options) bool {
+	if !a.Redactor.Equal(b.Redactor) || len(a.Profiles) != len(b.Profiles) {
+		return false
+	}
+	for name, profile := range a.Profiles {
+		other, ok := b.Profiles[name]
+		if !ok || !optionsEqual(profile, other) {
+			return false
+		}
+	}
+	a.Redactor, b.Redactor = nil, nil
+	a.Profiles, b.Profiles = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// restartRequiredSettings returns the settings that differ between old and
+// updated but are only read when klaus starts.
+func restartRequiredSettings(old, updated config.Config) []string {
+	var changed []string
+	for _, s := range []struct {
+		name     string
+		old, new any
+	}{
+		{"server.port", old.Server.Port, updated.Server.Port},
+		{"server.ownerSubject", old.Server.OwnerSubject, updated.Server.OwnerSubject},
+		{"server.configReload", old.Server.ConfigReload, updated.Server.ConfigReload},
+		{"server.mcpProbe", old.Server.MCPProbe, updated.Server.MCPProbe},
+		{"server.readinessChecks", old.Server.ReadinessChecks, updated.Server.ReadinessChecks},
+		{"oauth", withoutOAuthSecrets(old.OAuth), withoutOAuthSecrets(updated.OAuth)},
+		{"claude.mode", old.Claude.Mode, updated.Claude.Mode},
+		{"claude.resourceLimits.memoryMaxMiB", old.Claude.ResourceLimits.MemoryMaxMiB, updated.Claude.ResourceLimits.MemoryMaxMiB},
+		{"claude.resourceLimits.cpus", old.Claude.ResourceLimits.CPUs, updated.Claude.ResourceLimits.CPUs},
+	} {
+		if !reflect.DeepEqual(s.old, s.new) {
+			changed = append(changed, s.name)
+		}
+	}
+	return append(changed, rotatedOAuthSecrets(old, updated)...)
+}
+
+// rotatedOAuthSecrets returns the OAuth secrets that differ between old and
+// updated, e.g. because their secret file was rotated.
+func rotatedOAuthSecrets(old, updated config.Config) []string {
+	var rotated []string
+	for _, s := range []struct {
+		name     string
+		old, new string
+	}{
+		{"oauth.google.clientSecret", old.OAuth.Google.ClientSecret, updated.OAuth.Google.ClientSecret},
+		{"oauth.dex.clientSecret", old.OAuth.Dex.ClientSecret, updated.OAuth.Dex.ClientSecret},
+		{"oauth.security.encryptionKey", old.OAuth.Security.EncryptionKey, updated.OAuth.Security.EncryptionKey},
+		{"oauth.security.registrationToken", old.OAuth.Security.RegistrationToken, updated.OAuth.Security.RegistrationToken},
+	} {
+		if s.old != s.new {
+			rotated = append(rotated, s.name)
+		}
+	}
+	return rotated
+}
+
+// withoutOAuthSecrets returns o without the secrets, which
+// rotatedOAuthSecrets reports on their own.
+func withoutOAuthSecrets(o config.OAuthFileConfig) config.OAuthFileConfig {
+	o.Google.ClientSecret = ""
+	o.Dex.ClientSecret = ""
+	o.Security.EncryptionKey = ""
+	o.Security.RegistrationToken = ""
+	return o
+}
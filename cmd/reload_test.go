@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/klaus/pkg/claude"
+	"github.com/giantswarm/klaus/pkg/config"
+	"github.com/giantswarm/klaus/pkg/doctor"
+)
+
+func TestConfigReloader_Apply(t *testing.T) {
+	t.Setenv("CLAUDE_MODEL", "")
+	t.Setenv("CLAUDE_PERMISSION_MODE", "")
+	t.Setenv("CLAUDE_BINARY", filepath.Join(t.TempDir(), "claude"))
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load := func() (config.Config, error) {
+		cfg, err := config.Load(path)
+		if err != nil {
+			return cfg, err
+		}
+		return cfg, cfg.Validate()
+	}
+
+	writeConfig("claude:\n  model: old\n")
+	cfg, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	process := claude.NewProcess(claude.DefaultOptions())
+	r := &configReloader{
+		source:    configSource{path: path, load: load},
+		soulPath:  filepath.Join(t.TempDir(), "SOUL.md"),
+		process:   process,
+		readiness: doctor.NewRunner(nil, time.Hour),
+		versions:  &cliVersionDetector{},
+		current:   cfg,
+	}
+
+	writeConfig("claude:\n  model: new\nserver:\n  port: \"9090\"\n")
+	result, err := r.apply()
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	restartRequired := result.RestartRequired
+	if got := process.Status().Model; got != "new" {
+		t.Errorf("expected model new, got %q", got)
+	}
+	if !slices.Equal(restartRequired, []string{"server.port"}) {
+		t.Errorf("expected server.port to require a restart, got %v", restartRequired)
+	}
+
+	writeConfig("claude:\n  model: broken\n  permissionMode: bogus\n")
+	if _, err := r.apply(); err == nil {
+		t.Fatal("expected an invalid config to be rejected")
+	}
+	if got := process.Status().Model; got != "new" {
+		t.Errorf("expected the last good model to stay in effect, got %q", got)
+	}
+}
+
+func TestRestartRequiredSettings_RotatedOAuthSecret(t *testing.T) {
+	var old, updated config.Config
+	old.OAuth.Dex.ClientSecret = "old"
+	updated.OAuth.Dex.ClientSecret = "new"
+	if got := restartRequiredSettings(old, updated); !slices.Equal(got, []string{"oauth.dex.clientSecret"}) {
+		t.Errorf("expected only the rotated secret to require a restart, got %v", got)
+	}
+}
+
+func TestCLIVersionDetector_DetectsOncePerBinary(t *testing.T) {
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	binary := filepath.Join(dir, "claude")
+	script := "#!/bin/sh\necho call >> " + calls + "\necho '2.1.81 (Claude Code)'\n"
+	if err := os.WriteFile(binary, []byte(script), 0o700); err != nil { // #nosec G306 -- test script must be executable
+		t.Fatal(err)
+	}
+
+	d := &cliVersionDetector{}
+	opts := claude.Options{Binary: binary}
+	for range 2 {
+		if v, err := d.detect(opts); err != nil || v.String() != "2.1.81" {
+			t.Fatalf("detect: %v, %v", v, err)
+		}
+	}
+	opts.BinaryArgs = []string{"--verbose"}
+	if _, err := d.detect(opts); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(calls) //#nosec G304 -- test file
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "call"); n != 2 {
+		t.Errorf("expected the binary to run once per binary and args, ran %d times", n)
+	}
+}
+
+// updateCountingPrompter counts the options updates of the wrapped process.
+type updateCountingPrompter struct {
+	claude.Prompter
+	updates int
+}
+
+func (p *updateCountingPrompter) UpdateOptions(opts claude.Options) {
+	p.updates++
+	p.Prompter.UpdateOptions(opts)
+}
+
+func TestConfigReloader_ApplySkipsUnchangedOptions(t *testing.T) {
+	t.Setenv("CLAUDE_MODEL", "")
+	t.Setenv("CLAUDE_PERMISSION_MODE", "")
+	t.Setenv("CLAUDE_BINARY", filepath.Join(t.TempDir(), "claude"))
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	secretFile := filepath.Join(dir, "dex-secret")
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load := func() (config.Config, error) {
+		cfg, err := config.Load(path)
+		if err != nil {
+			return cfg, err
+		}
+		return cfg, cfg.Validate()
+	}
+
+	write(secretFile, "old")
+	write(path, "claude:\n  model: sonnet\n  redaction:\n    enabled: true\noauth:\n  dex:\n    clientSecretFile: "+secretFile+"\n")
+	cfg, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := &cliVersionDetector{}
+	opts, err := serveOptions(cfg, filepath.Join(dir, "SOUL.md"), versions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	process := &updateCountingPrompter{Prompter: claude.NewProcess(opts)}
+	r := &configReloader{
+		source:   configSource{path: path, load: load},
+		soulPath: filepath.Join(dir, "SOUL.md"),
+		process:  process,
+		versions: versions,
+		current:  cfg,
+		opts:     opts,
+	}
+
+	write(secretFile, "rotated")
+	result, err := r.apply()
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	restartRequired := result.RestartRequired
+	if !slices.Contains(result.Paths, secretFile) {
+		t.Errorf("expected the secret file to stay watched, got %v", result.Paths)
+	}
+	if !slices.Equal(restartRequired, []string{"oauth.dex.clientSecret"}) {
+		t.Errorf("expected the rotated secret to require a restart, got %v", restartRequired)
+	}
+	if process.updates != 0 {
+		t.Errorf("expected a rotated OAuth secret not to update the options, got %d updates", process.updates)
+	}
+
+	write(path, "claude:\n  model: opus\n  redaction:\n    enabled: true\noauth:\n  dex:\n    clientSecretFile: "+secretFile+"\n")
+	if _, err := r.apply(); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if process.updates != 1 || process.Status().Model != "opus" {
+		t.Errorf("expected the changed model to be applied once, got %d updates and model %q", process.updates, process.Status().Model)
+	}
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/klaus/pkg/claude"
+	"github.com/giantswarm/klaus/pkg/server"
+	"github.com/giantswarm/klaus/pkg/transcript"
+)
+
+// defaultReplayPrompt is recorded as the user prompt of a replayed run.
+const defaultReplayPrompt = "(replay)"
+
+// replayReport is the output of `klaus replay`.
+type replayReport struct {
+	Status     claude.StatusInfo            `json:"status"`
+	OpenAI     claude.OpenAIMessagesInfo    `json:"openai"`
+	Summary    transcript.TranscriptSummary `json:"summary"`
+	ResultText string                       `json:"result_text"`
+}
+
+// newReplayCmd creates the Cobra command for replaying recorded stream-json.
+func newReplayCmd() *cobra.Command {
+	var (
+		prompt string
+		serve  bool
+		port   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "replay <file.jsonl>",
+		Short: "Replay recorded claude stream-json output through klaus",
+		Long: `Replay recorded claude CLI stdout (stream-json, one message per line)
+through the same parsing, status accounting, subagent tracking, OpenAI
+conversion and transcript summary as a live run, and print the results as
+JSON. Use it to reproduce parsing bugs from production transcripts, or
+with recordings written by claude.recording.
+
+With --serve, klaus starts the normal HTTP server in agent mode instead.
+Every prompt is answered by replaying the recording, so the MCP tools
+(status, result, messages, ...) can be inspected as in production.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			recording, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("reading recording: %w", err)
+			}
+
+			// Keep replayed results out of the real result store.
+			resultDir, err := os.MkdirTemp("", "klaus-replay-")
+			if err != nil {
+				return err
+			}
+			defer func() { _ = os.RemoveAll(resultDir) }()
+
+			// Recordings written by claude.recording carry the original
+			// prompt in their header.
+			if header, ok := claude.ReadRecordingHeader(recording); ok && !cmd.Flags().Changed("prompt") && header.Prompt != "" {
+				prompt = header.Prompt
+			}
+
+			opts := claude.DefaultOptions()
+			opts.ResultDir = resultDir
+			process := claude.NewReplayProcess(opts, recording)
+
+			if serve {
+				return serveReplay(process, port)
+			}
+			report, err := runReplay(cmd.Context(), process, prompt)
+			if err != nil {
+				return err
+			}
+			return writeReplayReport(cmd.OutOrStdout(), report)
+		},
+	}
+
+	cmd.Flags().StringVar(&prompt, "prompt", defaultReplayPrompt, "Prompt recorded as the user message of the replayed run; recordings with a header default to their original prompt")
+	cmd.Flags().BoolVar(&serve, "serve", false, "Serve the replay behind the normal MCP tools instead of printing it")
+	cmd.Flags().StringVar(&port, "port", "8080", "HTTP server port for --serve")
+
+	return cmd
+}
+
+// runReplay replays the recording once and collects the resulting status,
+// OpenAI messages and transcript summary.
+func runReplay(ctx context.Context, process *claude.Process, prompt string) (replayReport, error) {
+	text, messages, err := process.RunSyncWithOptions(ctx, prompt, nil)
+	if err != nil {
+		return replayReport{}, fmt.Errorf("replaying: %w", err)
+	}
+
+	detail := process.ResultDetail()
+	detail.ResultText = text
+	detail.Messages = messages
+
+	return replayReport{
+		Status:     process.Status(),
+		OpenAI:     process.OpenAIMessages(0),
+		Summary:    transcript.Summarize(detail),
+		ResultText: text,
+	}, nil
+}
+
+func writeReplayReport(w io.Writer, report replayReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// serveReplay runs the HTTP server in agent mode backed by the replay.
+func serveReplay(process claude.Prompter, port string) error {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	serverCtx, serverCancel := context.WithCancel(context.Background())
+	defer serverCancel()
+
+	slog.Info("serving replay", "port", port)
+	return runWithoutOAuth(serverCtx, process, server.Config{Port: port, Mode: server.ModeAgent}, quit)
+}
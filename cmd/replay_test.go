@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/giantswarm/klaus/pkg/claude"
+)
+
+func TestRunReplay(t *testing.T) {
+	recording, err := os.ReadFile("../pkg/claude/testdata/stream_json_fixture.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := claude.DefaultOptions()
+	opts.ResultDir = t.TempDir()
+
+	report, err := runReplay(context.Background(), claude.NewReplayProcess(opts, recording), "hello")
+	if err != nil {
+		t.Fatalf("runReplay: %v", err)
+	}
+	if report.ResultText == "" || report.Summary.ResultText != report.ResultText {
+		t.Errorf("expected result text in report and summary, got %q / %q", report.ResultText, report.Summary.ResultText)
+	}
+	if report.Summary.ToolCalls["Write"] != 1 {
+		t.Errorf("expected summary tool calls, got %v", report.Summary.ToolCalls)
+	}
+	if len(report.OpenAI.Messages) == 0 {
+		t.Error("expected OpenAI messages")
+	}
+
+	var buf bytes.Buffer
+	if err := writeReplayReport(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	for _, key := range []string{"status", "openai", "summary", "result_text"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("report missing %q", key)
+		}
+	}
+}
@@ -44,5 +44,10 @@ func init() {
 	serveCmd = newServeCmd()
 	rootCmd.AddCommand(newVersionCmd())
 	rootCmd.AddCommand(newSelfUpdateCmd())
+	rootCmd.AddCommand(newReplayCmd())
+	rootCmd.AddCommand(newSessionCmd())
+	rootCmd.AddCommand(newDoctorCmd())
+	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newFakeClaudeCmd())
 	rootCmd.AddCommand(serveCmd)
 }
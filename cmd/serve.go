@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
@@ -21,6 +22,7 @@ import (
 
 	"github.com/giantswarm/klaus/pkg/claude"
 	"github.com/giantswarm/klaus/pkg/config"
+	"github.com/giantswarm/klaus/pkg/doctor"
 	"github.com/giantswarm/klaus/pkg/project"
 	"github.com/giantswarm/klaus/pkg/server"
 )
@@ -35,37 +37,17 @@ const (
 	// Personality files are short markdown documents; anything larger is likely
 	// a misconfiguration and could cause issues with CLI argument limits.
 	maxSOULFileSize = 64 * 1024
+
+	// readinessCacheTTL is how long /readyz reuses the result of the
+	// readiness checks.
+	readinessCacheTTL = 30 * time.Second
 )
 
 // newServeCmd creates the Cobra command for starting the klaus server.
 func newServeCmd() *cobra.Command {
 	var (
-		port       string
 		configPath string
-
-		// OAuth options (CLI flags override config file values).
-		enableOAuth                      bool
-		oauthBaseURL                     string
-		oauthProvider                    string
-		googleClientID                   string
-		googleClientSecret               string
-		dexIssuerURL                     string
-		dexClientID                      string
-		dexClientSecret                  string
-		dexConnectorID                   string
-		dexCAFile                        string
-		disableStreaming                 bool
-		registrationToken                string
-		allowPublicRegistration          bool
-		allowInsecureAuthWithoutState    bool
-		maxClientsPerIP                  int
-		oauthEncryptionKey               string
-		enableCIMD                       bool
-		cimdAllowPrivateIPs              bool
-		trustedPublicRegistrationSchemes []string
-		disableStrictSchemeMatching      bool
-		tlsCertFile                      string
-		tlsKeyFile                       string
+		flags      serveFlags
 	)
 
 	cmd := &cobra.Command{
@@ -77,7 +59,7 @@ MCP protocol endpoints for AI agent orchestration.
 The server provides:
   - /mcp    -- Streamable HTTP MCP endpoint
   - /healthz -- Liveness probe
-  - /readyz  -- Readiness probe
+  - /readyz  -- Readiness probe (?verbose reports the individual checks)
   - /status  -- JSON status endpoint
 
 With OAuth enabled (--enable-oauth), the /mcp endpoint requires OAuth 2.1
@@ -87,34 +69,25 @@ Bearer token authentication. Additional endpoints are exposed:
   - /oauth/register, /oauth/authorize, /oauth/token, /oauth/callback
 
 Configuration is loaded from a YAML file (default /etc/klaus/config.yaml)
-with environment variable overrides for backward compatibility. See
-pkg/config for the full Config struct and supported fields.`,
+and the *.yaml files of the config.d directory next to it, with
+environment variable overrides for backward compatibility. See
+pkg/config for the full Config struct and supported fields. --profile
+selects one of the named profiles of claude settings.
+
+The config files and the personality SOUL.md are watched for changes
+(server.configReload). Valid changes to the claude settings take effect
+from the next run; invalid ones are rejected and the last good config
+stays in effect.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Load structured config: YAML file -> env var overrides.
-			cfg, err := config.Load(configPath)
-			if err != nil {
-				return fmt.Errorf("loading config: %w", err)
+			// loadConfig is also used to reload the config on changes.
+			loadConfig := func() (config.Config, error) {
+				cfg, _, err := loadServeConfig(cmd, configPath, &flags)
+				return cfg, err
 			}
 
-			// CLI flags override config file + env values for OAuth settings.
-			// Only override when the flag was explicitly set by the user.
-			// This must happen BEFORE validation so that flag overrides are
-			// included in the validation pass.
-			applyOAuthFlagOverrides(cmd, &cfg,
-				enableOAuth, oauthBaseURL, oauthProvider,
-				googleClientID, googleClientSecret,
-				dexIssuerURL, dexClientID, dexClientSecret, dexConnectorID, dexCAFile,
-				disableStreaming, registrationToken,
-				allowPublicRegistration, allowInsecureAuthWithoutState,
-				maxClientsPerIP, oauthEncryptionKey,
-				enableCIMD, cimdAllowPrivateIPs,
-				trustedPublicRegistrationSchemes, disableStrictSchemeMatching,
-				tlsCertFile, tlsKeyFile,
-			)
-
-			// Validate after all overrides (YAML -> env -> flags) are applied.
-			if err := cfg.Validate(); err != nil {
-				return fmt.Errorf("config validation: %w", err)
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
 			}
 
 			// Build the OAuthConfig from the unified config struct.
@@ -155,36 +128,12 @@ pkg/config for the full Config struct and supported fields.`,
 				DisableStreaming: cfg.OAuth.DisableStreaming,
 			}
 
-			return runServe(port, cfg, enableOAuth || cfg.OAuth.Enabled, oauthConfig)
+			return runServe(flags.port, cfg, configSource{path: configPath, load: loadConfig}, cfg.OAuth.Enabled, oauthConfig)
 		},
 	}
 
 	cmd.Flags().StringVar(&configPath, "config", config.DefaultConfigPath, "Path to YAML config file")
-	cmd.Flags().StringVar(&port, "port", "", "HTTP server port (overrides config file and PORT env var, default: 8080)")
-
-	// OAuth flags (override config file values when explicitly set).
-	cmd.Flags().BoolVar(&enableOAuth, "enable-oauth", false, "Enable OAuth 2.1 authentication for the MCP endpoint")
-	cmd.Flags().StringVar(&oauthBaseURL, "oauth-base-url", "", "OAuth base URL (e.g., https://klaus.example.com)")
-	cmd.Flags().StringVar(&oauthProvider, "oauth-provider", server.OAuthProviderDex, fmt.Sprintf("OAuth provider: %s or %s", server.OAuthProviderDex, server.OAuthProviderGoogle))
-	cmd.Flags().StringVar(&googleClientID, "google-client-id", "", "Google OAuth Client ID (or GOOGLE_CLIENT_ID env)")
-	cmd.Flags().StringVar(&googleClientSecret, "google-client-secret", "", "Google OAuth Client Secret (or GOOGLE_CLIENT_SECRET env)")
-	cmd.Flags().StringVar(&dexIssuerURL, "dex-issuer-url", "", "Dex OIDC issuer URL (or DEX_ISSUER_URL env)")
-	cmd.Flags().StringVar(&dexClientID, "dex-client-id", "", "Dex OAuth Client ID (or DEX_CLIENT_ID env)")
-	cmd.Flags().StringVar(&dexClientSecret, "dex-client-secret", "", "Dex OAuth Client Secret (or DEX_CLIENT_SECRET env)")
-	cmd.Flags().StringVar(&dexConnectorID, "dex-connector-id", "", "Dex connector ID to bypass connector selection (optional)")
-	cmd.Flags().StringVar(&dexCAFile, "dex-ca-file", "", "CA certificate file for Dex TLS verification (optional)")
-	cmd.Flags().BoolVar(&disableStreaming, "disable-streaming", false, "Disable streaming for streamable-http transport")
-	cmd.Flags().StringVar(&registrationToken, "registration-token", "", "OAuth client registration access token")
-	cmd.Flags().BoolVar(&allowPublicRegistration, "allow-public-registration", false, "Allow unauthenticated OAuth client registration (NOT RECOMMENDED for production)")
-	cmd.Flags().BoolVar(&allowInsecureAuthWithoutState, "allow-insecure-auth-without-state", false, "Allow authorization requests without state parameter")
-	cmd.Flags().IntVar(&maxClientsPerIP, "max-clients-per-ip", 10, "Maximum OAuth clients per IP address")
-	cmd.Flags().StringVar(&oauthEncryptionKey, "oauth-encryption-key", "", "AES-256 encryption key for token encryption (base64, or OAUTH_ENCRYPTION_KEY env)")
-	cmd.Flags().BoolVar(&enableCIMD, "enable-cimd", true, "Enable Client ID Metadata Documents (MCP 2025-11-25)")
-	cmd.Flags().BoolVar(&cimdAllowPrivateIPs, "cimd-allow-private-ips", false, "Allow CIMD metadata URLs to resolve to private IPs")
-	cmd.Flags().StringSliceVar(&trustedPublicRegistrationSchemes, "trusted-public-registration-schemes", nil, "URI schemes allowed for unauthenticated client registration (e.g., cursor,vscode)")
-	cmd.Flags().BoolVar(&disableStrictSchemeMatching, "disable-strict-scheme-matching", false, "Allow mixed redirect URI schemes with trusted scheme registration")
-	cmd.Flags().StringVar(&tlsCertFile, "tls-cert-file", "", "TLS certificate file for HTTPS (PEM format)")
-	cmd.Flags().StringVar(&tlsKeyFile, "tls-key-file", "", "TLS private key file for HTTPS (PEM format)")
+	flags.register(cmd)
 
 	return cmd
 }
@@ -199,7 +148,7 @@ func shutdownTelemetry(name string, fn func(context.Context) error) {
 }
 
 // runServe contains the main server logic, now driven by the structured Config.
-func runServe(portFlag string, cfg config.Config, enableOAuth bool, oauthConfig server.OAuthConfig) error {
+func runServe(portFlag string, cfg config.Config, source configSource, enableOAuth bool, oauthConfig server.OAuthConfig) error {
 	var logShutdown logging.Shutdown = func(_ context.Context) error { return nil }
 	if l, shutdown, initErr := logging.Init(context.Background(),
 		logging.WithServiceName(project.Name),
@@ -222,8 +171,204 @@ func runServe(portFlag string, cfg config.Config, enableOAuth bool, oauthConfig
 	}
 	defer func() { shutdownTelemetry("tracing", traceShutdown) }()
 
-	// Build Claude options from config struct.
+	soulPath := soulFilePath()
+	versions := &cliVersionDetector{}
+	opts, err := serveOptions(cfg, soulPath, versions)
+	if err != nil {
+		return err
+	}
+	pruneMCPConfigs(opts)
+	// The MCP servers are probed in the background once the server runs.
+	prober := cfg.Server.MCPProbe.Prober()
+	prober.SetServers(opts.MCPServers, opts.WorkDir)
+	opts.MCPProber = prober
+
+	// Create the Claude process manager.
+	// Note: permissionMode and effort are already validated by cfg.Validate()
+	// using the canonical validators from the claude package.
+	var process claude.Prompter
+	if cfg.Claude.Mode == server.ModeChat {
+		slog.Info("starting in chat mode (bidirectional stream-json)")
+		process = claude.NewPersistentProcess(opts)
+	} else {
+		process = claude.NewProcess(opts)
+	}
+
+	// Owner-based access control.
+	if cfg.Server.OwnerSubject != "" {
+		slog.Info("owner-based access control enabled", "subject", cfg.Server.OwnerSubject)
+	}
+
+	// Determine listen port: flag > config > default.
+	listenPort := cfg.EffectivePort(portFlag)
+
+	// Wait for interrupt signal for graceful shutdown.
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	serverCtx, serverCancel := context.WithCancel(context.Background())
+	defer serverCancel()
+
+	if prober != nil {
+		go prober.Run(serverCtx)
+	}
+
+	mode := server.ModeAgent
+	if cfg.Claude.Mode == server.ModeChat {
+		mode = server.ModeChat
+	}
+
+	// Readiness checks are opt-in and cached so that frequent probes do not
+	// re-run the claude binary.
+	var readiness *doctor.Runner
+	if cfg.Server.ReadinessChecks {
+		readiness = doctor.NewRunner(doctor.Checks(cfg, opts), readinessCacheTTL)
+	}
+
+	srvCfg := server.Config{
+		Port:         listenPort,
+		Mode:         mode,
+		OwnerSubject: cfg.Server.OwnerSubject,
+		Readiness:    readiness,
+	}
+
+	if cfg.Server.ConfigReload.Disabled {
+		slog.Info("config hot reload disabled")
+	} else {
+		reloader := &configReloader{
+			source:    source,
+			soulPath:  soulPath,
+			process:   process,
+			readiness: readiness,
+			versions:  versions,
+			prober:    prober,
+			current:   cfg,
+			opts:      opts,
+		}
+		watcher, err := config.NewWatcher(watchedPaths(source, soulPath, cfg), cfg.Server.ConfigReload.EffectiveInterval(), reloader.apply)
+		if err != nil {
+			return fmt.Errorf("watching config: %w", err)
+		}
+		go watcher.Run(serverCtx)
+		srvCfg.ConfigReload = watcher
+	}
+
+	if enableOAuth {
+		return runWithOAuth(serverCtx, process, srvCfg, oauthConfig, quit)
+	}
+	return runWithoutOAuth(serverCtx, process, srvCfg, quit)
+}
+
+// serveOptions builds the options of the Claude process: the claude
+// section of the config, the personality and the detected CLI version,
+// whose capabilities are checked against the options. Each profile gets
+// options of its own, built the same way.
+func serveOptions(cfg config.Config, soulPath string, versions *cliVersionDetector) (claude.Options, error) {
+	opts, err := claudeOptions(cfg, claude.MCPConfigDir())
+	if err != nil {
+		return opts, err
+	}
+	opts.Profile = cfg.Profile
+
+	// Load personality SOUL.md. The KLAUS_SOUL_FILE env var allows operators
+	// to override the default path (useful for Kubernetes image volumes where
+	// SubPath is not supported).
+	soul, err := loadSOULFile(soulPath)
+	if err != nil {
+		slog.Warn("failed to load personality", "path", soulPath, "error", err)
+	} else if soul != "" {
+		slog.Info("loaded personality", "path", soulPath, "bytes", len(soul))
+	} else if os.Getenv("KLAUS_SOUL_FILE") != "" {
+		slog.Warn("KLAUS_SOUL_FILE set but file does not exist or is empty", "path", soulPath)
+	}
+	appendSOUL(&opts, soul)
+
+	profiles := make(map[string]claude.Options, len(cfg.Profiles))
+	for _, name := range cfg.ProfileNames() {
+		profileCfg := cfg
+		if profileCfg.Claude, err = cfg.ProfileClaude(name); err != nil {
+			return opts, err
+		}
+		profileOpts, err := claudeOptions(profileCfg, claude.MCPConfigDir())
+		if err != nil {
+			return opts, fmt.Errorf("profile %s: %w", name, err)
+		}
+		profileOpts.Profile = name
+		appendSOUL(&profileOpts, soul)
+		profiles[name] = profileOpts
+	}
+
+	// Detect the CLI version so that options the CLI does not know, and chat
+	// mode on a CLI without its protocol, are rejected up front instead of
+	// failing on the first prompt. Profiles cannot change the binary, so the
+	// version applies to them too.
+	if v, err := versions.detect(opts); err != nil {
+		slog.Warn("could not detect claude CLI version, assuming all capabilities", "error", err)
+	} else {
+		slog.Info("detected claude CLI", "version", v.String())
+		opts.CLIVersion = &v
+		if err := opts.CheckCLICapabilities(); err != nil {
+			return opts, fmt.Errorf("unsupported claude CLI version: %w", err)
+		}
+		if cfg.Claude.Mode == server.ModeChat {
+			if err := opts.CheckChatMode(); err != nil {
+				return opts, fmt.Errorf("unsupported claude CLI version: %w", err)
+			}
+		}
+		for _, name := range cfg.ProfileNames() {
+			profileOpts := profiles[name]
+			profileOpts.CLIVersion = &v
+			if err := profileOpts.CheckCLICapabilities(); err != nil {
+				return opts, fmt.Errorf("unsupported claude CLI version for profile %s: %w", name, err)
+			}
+			profiles[name] = profileOpts
+		}
+	}
+	if len(profiles) > 0 {
+		opts.Profiles = profiles
+	}
+	return opts, nil
+}
+
+// appendSOUL appends the personality to the system prompt of opts.
+func appendSOUL(opts *claude.Options, soul string) {
+	if soul == "" {
+		return
+	}
+	if opts.AppendSystemPrompt != "" {
+		opts.AppendSystemPrompt += "\n\n"
+	}
+	opts.AppendSystemPrompt += soul
+}
+
+// cliVersionDetector detects the version of the claude binary, and only
+// detects it again when claude.binary or claude.binaryArgs change, so that
+// config reloads do not execute the binary each time.
+type cliVersionDetector struct {
+	detected   bool
+	binary     string
+	binaryArgs []string
+	version    claude.CLIVersion
+	err        error
+}
+
+// detect returns the version of the binary of opts.
+func (d *cliVersionDetector) detect(opts claude.Options) (claude.CLIVersion, error) {
+	if d.detected && d.binary == opts.Binary && slices.Equal(d.binaryArgs, opts.BinaryArgs) {
+		return d.version, d.err
+	}
+	d.version, d.err = claude.DetectCLIVersion(context.Background(), opts)
+	d.detected, d.binary, d.binaryArgs = true, opts.Binary, slices.Clone(opts.BinaryArgs)
+	return d.version, d.err
+}
+
+// claudeOptions maps the claude section of the config onto the options of
+// the Claude process. The personality and the detected CLI version are
+// added by runServe. A generated MCP config file is written to mcpDir.
+func claudeOptions(cfg config.Config, mcpDir string) (claude.Options, error) {
 	opts := claude.DefaultOptions()
+	opts.Binary = cfg.Claude.Binary
+	opts.BinaryArgs = cfg.Claude.BinaryArgs
 
 	if cfg.Claude.Model != "" {
 		opts.Model = cfg.Claude.Model
@@ -240,8 +385,8 @@ func runServe(portFlag string, cfg config.Config, enableOAuth bool, oauthConfig
 	if cfg.Claude.PermissionMode != "" {
 		opts.PermissionMode = cfg.Claude.PermissionMode
 	}
-	if cfg.Claude.MCPConfigPath != "" {
-		opts.MCPConfigPath = cfg.Claude.MCPConfigPath
+	if err := setMCPServers(&opts, cfg.Claude, mcpDir); err != nil {
+		return claude.Options{}, err
 	}
 	if cfg.Claude.StrictMCPConfig {
 		opts.StrictMCPConfig = true
@@ -283,82 +428,35 @@ func runServe(portFlag string, cfg config.Config, enableOAuth bool, oauthConfig
 		opts.AddDirs = cfg.Claude.AddDirs
 	}
 
-	// Subagent definitions.
+	// Subagent definitions. They are checked by cfg.Validate(), which
+	// rejects malformed JSON.
 	if cfg.Claude.Agents != "" {
 		var agents map[string]claude.AgentConfig
 		if err := json.Unmarshal([]byte(cfg.Claude.Agents), &agents); err != nil {
-			slog.Warn("failed to parse claude.agents", "error", err)
-		} else {
-			opts.Agents = agents
+			return claude.Options{}, fmt.Errorf("claude.agents: %w", err)
 		}
+		opts.Agents = agents
 	}
 	if cfg.Claude.ActiveAgent != "" {
 		opts.ActiveAgent = cfg.Claude.ActiveAgent
 	}
+	opts.OverridePolicy = cfg.Claude.OverridePolicy.Policy()
+	opts.ResourceLimits = cfg.Claude.ResourceLimits.Limits()
+	opts.Recording = cfg.Claude.Recording.Options()
+	opts.AutoCompact = cfg.Claude.AutoCompact.Options()
+	redactor, err := cfg.Claude.Redaction.Redactor()
+	if err != nil {
+		return claude.Options{}, fmt.Errorf("claude.redaction: %w", err)
+	}
+	opts.Redactor = redactor
 	// Derive NoSessionPersistence from mode: agent -> true, chat -> false.
 	// DefaultOptions() already sets NoSessionPersistence=true (agent default),
 	// so only override for chat mode.
 	if cfg.Claude.Mode == server.ModeChat {
 		opts.NoSessionPersistence = false
 	}
-
-	// Load personality SOUL.md. The KLAUS_SOUL_FILE env var allows operators
-	// to override the default path (useful for Kubernetes image volumes where
-	// SubPath is not supported).
-	soulPath := soulFilePath()
-	if soul, err := loadSOULFile(soulPath); err != nil {
-		slog.Warn("failed to load personality", "path", soulPath, "error", err)
-	} else if soul != "" {
-		if opts.AppendSystemPrompt != "" {
-			opts.AppendSystemPrompt += "\n\n"
-		}
-		opts.AppendSystemPrompt += soul
-		slog.Info("loaded personality", "path", soulPath, "bytes", len(soul))
-	} else if os.Getenv("KLAUS_SOUL_FILE") != "" {
-		slog.Warn("KLAUS_SOUL_FILE set but file does not exist or is empty", "path", soulPath)
-	}
-
-	// Create the Claude process manager.
-	// Note: permissionMode and effort are already validated by cfg.Validate()
-	// using the canonical validators from the claude package.
-	var process claude.Prompter
-	if cfg.Claude.Mode == server.ModeChat {
-		slog.Info("starting in chat mode (bidirectional stream-json)")
-		process = claude.NewPersistentProcess(opts)
-	} else {
-		process = claude.NewProcess(opts)
-	}
-
-	// Owner-based access control.
-	if cfg.Server.OwnerSubject != "" {
-		slog.Info("owner-based access control enabled", "subject", cfg.Server.OwnerSubject)
-	}
-
-	// Determine listen port: flag > config > default.
-	listenPort := cfg.EffectivePort(portFlag)
-
-	// Wait for interrupt signal for graceful shutdown.
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-
-	serverCtx, serverCancel := context.WithCancel(context.Background())
-	defer serverCancel()
-
-	mode := server.ModeAgent
-	if cfg.Claude.Mode == server.ModeChat {
-		mode = server.ModeChat
-	}
-
-	srvCfg := server.Config{
-		Port:         listenPort,
-		Mode:         mode,
-		OwnerSubject: cfg.Server.OwnerSubject,
-	}
-
-	if enableOAuth {
-		return runWithOAuth(serverCtx, process, srvCfg, oauthConfig, quit)
-	}
-	return runWithoutOAuth(serverCtx, process, srvCfg, quit)
+	opts.ResumeOnStart = cfg.Claude.ResumeOnStart
+	return opts, nil
 }
 
 func runWithOAuth(serverCtx context.Context, process claude.Prompter, cfg server.Config, config server.OAuthConfig, quit chan os.Signal) error {
@@ -377,7 +475,7 @@ func runWithOAuth(serverCtx context.Context, process claude.Prompter, cfg server
 		slog.Warn("OAuth encryption key not set - tokens will be stored unencrypted")
 	}
 
-	oauthSrv, err := server.NewOAuthServer(serverCtx, process, config, cfg.OwnerSubject)
+	oauthSrv, err := server.NewOAuthServer(serverCtx, process, config, cfg.OwnerSubject, cfg.Readiness, cfg.ConfigReload)
 	if err != nil {
 		return fmt.Errorf("failed to create OAuth server: %w", err)
 	}
@@ -437,85 +535,219 @@ func runServerLifecycle(
 	return nil
 }
 
-// applyOAuthFlagOverrides applies CLI flag values to the config struct.
-// Only flags that were explicitly set by the user override config/env values.
-func applyOAuthFlagOverrides(
-	cmd *cobra.Command, cfg *config.Config,
-	enableOAuth bool, baseURL, provider string,
-	gClientID, gClientSecret string,
-	dIssuerURL, dClientID, dClientSecret, dConnectorID, dCAFile string,
-	disableStreaming bool, regToken string,
-	allowPubReg, allowInsecureAuth bool,
-	maxClients int, encKey string,
-	eCIMD, cimdPrivIPs bool,
-	trustedSchemes []string, disableStrictScheme bool,
-	tlsCert, tlsKey string,
-) {
-	if cmd.Flags().Changed("enable-oauth") {
-		cfg.OAuth.Enabled = enableOAuth
+// serveFlags are the flags of serve that override config file and env var
+// values. The config commands accept them too, so that they see the config
+// serve would run with.
+type serveFlags struct {
+	port    string
+	profile string
+
+	// OAuth options.
+	enableOAuth                      bool
+	oauthBaseURL                     string
+	oauthProvider                    string
+	googleClientID                   string
+	googleClientSecret               string
+	dexIssuerURL                     string
+	dexClientID                      string
+	dexClientSecret                  string
+	dexConnectorID                   string
+	dexCAFile                        string
+	disableStreaming                 bool
+	registrationToken                string
+	allowPublicRegistration          bool
+	allowInsecureAuthWithoutState    bool
+	maxClientsPerIP                  int
+	oauthEncryptionKey               string
+	enableCIMD                       bool
+	cimdAllowPrivateIPs              bool
+	trustedPublicRegistrationSchemes []string
+	disableStrictSchemeMatching      bool
+	tlsCertFile                      string
+	tlsKeyFile                       string
+}
+
+func (f *serveFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.port, "port", "", "HTTP server port (overrides config file and PORT env var, default: 8080)")
+	cmd.Flags().StringVar(&f.profile, "profile", "", "Claude profile to run with (overrides config file and KLAUS_PROFILE env var)")
+
+	// OAuth flags (override config file values when explicitly set).
+	cmd.Flags().BoolVar(&f.enableOAuth, "enable-oauth", false, "Enable OAuth 2.1 authentication for the MCP endpoint")
+	cmd.Flags().StringVar(&f.oauthBaseURL, "oauth-base-url", "", "OAuth base URL (e.g., https://klaus.example.com)")
+	cmd.Flags().StringVar(&f.oauthProvider, "oauth-provider", server.OAuthProviderDex, fmt.Sprintf("OAuth provider: %s or %s", server.OAuthProviderDex, server.OAuthProviderGoogle))
+	cmd.Flags().StringVar(&f.googleClientID, "google-client-id", "", "Google OAuth Client ID (or GOOGLE_CLIENT_ID env)")
+	cmd.Flags().StringVar(&f.googleClientSecret, "google-client-secret", "", "Google OAuth Client Secret (or GOOGLE_CLIENT_SECRET env)")
+	cmd.Flags().StringVar(&f.dexIssuerURL, "dex-issuer-url", "", "Dex OIDC issuer URL (or DEX_ISSUER_URL env)")
+	cmd.Flags().StringVar(&f.dexClientID, "dex-client-id", "", "Dex OAuth Client ID (or DEX_CLIENT_ID env)")
+	cmd.Flags().StringVar(&f.dexClientSecret, "dex-client-secret", "", "Dex OAuth Client Secret (or DEX_CLIENT_SECRET env)")
+	cmd.Flags().StringVar(&f.dexConnectorID, "dex-connector-id", "", "Dex connector ID to bypass connector selection (optional)")
+	cmd.Flags().StringVar(&f.dexCAFile, "dex-ca-file", "", "CA certificate file for Dex TLS verification (optional)")
+	cmd.Flags().BoolVar(&f.disableStreaming, "disable-streaming", false, "Disable streaming for streamable-http transport")
+	cmd.Flags().StringVar(&f.registrationToken, "registration-token", "", "OAuth client registration access token")
+	cmd.Flags().BoolVar(&f.allowPublicRegistration, "allow-public-registration", false, "Allow unauthenticated OAuth client registration (NOT RECOMMENDED for production)")
+	cmd.Flags().BoolVar(&f.allowInsecureAuthWithoutState, "allow-insecure-auth-without-state", false, "Allow authorization requests without state parameter")
+	cmd.Flags().IntVar(&f.maxClientsPerIP, "max-clients-per-ip", 10, "Maximum OAuth clients per IP address")
+	cmd.Flags().StringVar(&f.oauthEncryptionKey, "oauth-encryption-key", "", "AES-256 encryption key for token encryption (base64, or OAUTH_ENCRYPTION_KEY env)")
+	cmd.Flags().BoolVar(&f.enableCIMD, "enable-cimd", true, "Enable Client ID Metadata Documents (MCP 2025-11-25)")
+	cmd.Flags().BoolVar(&f.cimdAllowPrivateIPs, "cimd-allow-private-ips", false, "Allow CIMD metadata URLs to resolve to private IPs")
+	cmd.Flags().StringSliceVar(&f.trustedPublicRegistrationSchemes, "trusted-public-registration-schemes", nil, "URI schemes allowed for unauthenticated client registration (e.g., cursor,vscode)")
+	cmd.Flags().BoolVar(&f.disableStrictSchemeMatching, "disable-strict-scheme-matching", false, "Allow mixed redirect URI schemes with trusted scheme registration")
+	cmd.Flags().StringVar(&f.tlsCertFile, "tls-cert-file", "", "TLS certificate file for HTTPS (PEM format)")
+	cmd.Flags().StringVar(&f.tlsKeyFile, "tls-key-file", "", "TLS private key file for HTTPS (PEM format)")
+}
+
+// apply applies the flag values to the config struct and records them in
+// sources. Only flags that were explicitly set by the user override
+// config/env values.
+func (f *serveFlags) apply(cmd *cobra.Command, cfg *config.Config, sources config.Sources) {
+	set := func(flag, field string) bool {
+		if !cmd.Flags().Changed(flag) {
+			return false
+		}
+		sources.SetFlag(field, flag)
+		return true
+	}
+
+	if set("port", "server.port") {
+		cfg.Server.Port = f.port
+	}
+	if set("profile", "profile") {
+		cfg.Profile = f.profile
 	}
-	if cmd.Flags().Changed("oauth-base-url") {
-		cfg.OAuth.BaseURL = baseURL
+	if set("enable-oauth", "oauth.enabled") {
+		cfg.OAuth.Enabled = f.enableOAuth
 	}
-	if cmd.Flags().Changed("oauth-provider") {
-		cfg.OAuth.Provider = provider
+	if set("oauth-base-url", "oauth.baseURL") {
+		cfg.OAuth.BaseURL = f.oauthBaseURL
 	}
-	if cmd.Flags().Changed("google-client-id") {
-		cfg.OAuth.Google.ClientID = gClientID
+	if set("oauth-provider", "oauth.provider") {
+		cfg.OAuth.Provider = f.oauthProvider
 	}
-	if cmd.Flags().Changed("google-client-secret") {
-		cfg.OAuth.Google.ClientSecret = gClientSecret
+	if set("google-client-id", "oauth.google.clientID") {
+		cfg.OAuth.Google.ClientID = f.googleClientID
 	}
-	if cmd.Flags().Changed("dex-issuer-url") {
-		cfg.OAuth.Dex.IssuerURL = dIssuerURL
+	if set("google-client-secret", "oauth.google.clientSecret") {
+		cfg.OAuth.Google.ClientSecret = f.googleClientSecret
 	}
-	if cmd.Flags().Changed("dex-client-id") {
-		cfg.OAuth.Dex.ClientID = dClientID
+	if set("dex-issuer-url", "oauth.dex.issuerURL") {
+		cfg.OAuth.Dex.IssuerURL = f.dexIssuerURL
 	}
-	if cmd.Flags().Changed("dex-client-secret") {
-		cfg.OAuth.Dex.ClientSecret = dClientSecret
+	if set("dex-client-id", "oauth.dex.clientID") {
+		cfg.OAuth.Dex.ClientID = f.dexClientID
 	}
-	if cmd.Flags().Changed("dex-connector-id") {
-		cfg.OAuth.Dex.ConnectorID = dConnectorID
+	if set("dex-client-secret", "oauth.dex.clientSecret") {
+		cfg.OAuth.Dex.ClientSecret = f.dexClientSecret
 	}
-	if cmd.Flags().Changed("dex-ca-file") {
-		cfg.OAuth.Dex.CAFile = dCAFile
+	if set("dex-connector-id", "oauth.dex.connectorID") {
+		cfg.OAuth.Dex.ConnectorID = f.dexConnectorID
 	}
-	if cmd.Flags().Changed("disable-streaming") {
-		cfg.OAuth.DisableStreaming = disableStreaming
+	if set("dex-ca-file", "oauth.dex.caFile") {
+		cfg.OAuth.Dex.CAFile = f.dexCAFile
 	}
-	if cmd.Flags().Changed("registration-token") {
-		cfg.OAuth.Security.RegistrationToken = regToken
+	if set("disable-streaming", "oauth.disableStreaming") {
+		cfg.OAuth.DisableStreaming = f.disableStreaming
 	}
-	if cmd.Flags().Changed("allow-public-registration") {
-		cfg.OAuth.Security.AllowPublicRegistration = allowPubReg
+	if set("registration-token", "oauth.security.registrationToken") {
+		cfg.OAuth.Security.RegistrationToken = f.registrationToken
 	}
-	if cmd.Flags().Changed("allow-insecure-auth-without-state") {
-		cfg.OAuth.Security.AllowInsecureAuthWithoutState = allowInsecureAuth
+	if set("allow-public-registration", "oauth.security.allowPublicRegistration") {
+		cfg.OAuth.Security.AllowPublicRegistration = f.allowPublicRegistration
 	}
-	if cmd.Flags().Changed("max-clients-per-ip") {
-		cfg.OAuth.Security.MaxClientsPerIP = maxClients
+	if set("allow-insecure-auth-without-state", "oauth.security.allowInsecureAuthWithoutState") {
+		cfg.OAuth.Security.AllowInsecureAuthWithoutState = f.allowInsecureAuthWithoutState
 	}
-	if cmd.Flags().Changed("oauth-encryption-key") {
-		cfg.OAuth.Security.EncryptionKey = encKey
+	if set("max-clients-per-ip", "oauth.security.maxClientsPerIP") {
+		cfg.OAuth.Security.MaxClientsPerIP = f.maxClientsPerIP
 	}
-	if cmd.Flags().Changed("enable-cimd") {
-		cfg.OAuth.Security.EnableCIMD = &eCIMD
+	if set("oauth-encryption-key", "oauth.security.encryptionKey") {
+		cfg.OAuth.Security.EncryptionKey = f.oauthEncryptionKey
 	}
-	if cmd.Flags().Changed("cimd-allow-private-ips") {
-		cfg.OAuth.Security.CIMDAllowPrivateIPs = cimdPrivIPs
+	if set("enable-cimd", "oauth.security.enableCIMD") {
+		enableCIMD := f.enableCIMD
+		cfg.OAuth.Security.EnableCIMD = &enableCIMD
 	}
-	if cmd.Flags().Changed("trusted-public-registration-schemes") {
-		cfg.OAuth.Security.TrustedPublicRegistrationSchemes = trustedSchemes
+	if set("cimd-allow-private-ips", "oauth.security.cimdAllowPrivateIPs") {
+		cfg.OAuth.Security.CIMDAllowPrivateIPs = f.cimdAllowPrivateIPs
 	}
-	if cmd.Flags().Changed("disable-strict-scheme-matching") {
-		cfg.OAuth.Security.DisableStrictSchemeMatching = disableStrictScheme
+	if set("trusted-public-registration-schemes", "oauth.security.trustedPublicRegistrationSchemes") {
+		cfg.OAuth.Security.TrustedPublicRegistrationSchemes = f.trustedPublicRegistrationSchemes
 	}
-	if cmd.Flags().Changed("tls-cert-file") {
-		cfg.OAuth.TLS.CertFile = tlsCert
+	if set("disable-strict-scheme-matching", "oauth.security.disableStrictSchemeMatching") {
+		cfg.OAuth.Security.DisableStrictSchemeMatching = f.disableStrictSchemeMatching
+	}
+	if set("tls-cert-file", "oauth.tls.certFile") {
+		cfg.OAuth.TLS.CertFile = f.tlsCertFile
+	}
+	if set("tls-key-file", "oauth.tls.keyFile") {
+		cfg.OAuth.TLS.KeyFile = f.tlsKeyFile
+	}
+}
+
+// loadServeConfig loads the config serve runs with: the YAML files, then
+// env var overrides, then flag overrides, then the selected profile. The
+// result is validated after all overrides are applied; on validation
+// errors the config and its sources are returned along with the error.
+func loadServeConfig(cmd *cobra.Command, configPath string, flags *serveFlags) (config.Config, config.Sources, error) {
+	cfg, sources, err := config.LoadWithSources(configPath)
+	if err != nil {
+		return cfg, nil, fmt.Errorf("loading config: %w", err)
+	}
+	flags.apply(cmd, &cfg, sources)
+	// An unknown profile is reported by Validate along with the other
+	// errors.
+	_ = cfg.ApplyProfile(sources)
+	if err := cfg.Validate(); err != nil {
+		return cfg, sources, fmt.Errorf("config validation: %w", err)
+	}
+	return cfg, sources, nil
+}
+
+// setMCPServers reads the servers of claude.mcpConfigPath, so that a
+// broken file fails here instead of silently leaving the tools of its
+// servers blocked, and merges the inline claude.mcpServers with them into a
+// generated config file for the CLI.
+func setMCPServers(opts *claude.Options, cfg config.ClaudeConfig, mcpDir string) error {
+	servers := map[string]claude.MCPServer{}
+	if cfg.MCPConfigPath != "" {
+		var err error
+		if servers, err = claude.ReadMCPConfig(cfg.MCPConfigPath); err != nil {
+			return fmt.Errorf("claude.mcpConfigPath: %w", err)
+		}
+	}
+	opts.MCPConfigPath = cfg.MCPConfigPath
+	opts.MCPServers = servers
+	if len(cfg.MCPServers) == 0 {
+		return nil
+	}
+
+	for name, server := range cfg.MCPServers {
+		if _, ok := servers[name]; ok {
+			return fmt.Errorf("claude.mcpServers.%s: server is also defined in %s", name, cfg.MCPConfigPath)
+		}
+		resolved, err := server.Server()
+		if err != nil {
+			return fmt.Errorf("claude.mcpServers.%s: %w", name, err)
+		}
+		servers[name] = resolved
+	}
+	path, err := claude.WriteMCPConfig(mcpDir, servers)
+	if err != nil {
+		return fmt.Errorf("writing MCP config: %w", err)
+	}
+	opts.MCPConfigPath = path
+	return nil
+}
+
+// pruneMCPConfigs removes the generated MCP config files that neither opts
+// nor its profiles use, e.g. those of servers a reload removed, so that
+// their credentials do not stay on disk.
+func pruneMCPConfigs(opts claude.Options) {
+	keep := []string{opts.MCPConfigPath}
+	for _, profile := range opts.Profiles {
+		keep = append(keep, profile.MCPConfigPath)
 	}
-	if cmd.Flags().Changed("tls-key-file") {
-		cfg.OAuth.TLS.KeyFile = tlsKey
+	if err := claude.PruneMCPConfigs(claude.MCPConfigDir(), keep); err != nil {
+		slog.Warn("failed to remove unused MCP config files", "error", err)
 	}
 }
 
@@ -5,6 +5,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/giantswarm/klaus/pkg/claude"
+	"github.com/giantswarm/klaus/pkg/config"
 )
 
 func TestLoadSOULFile_Exists(t *testing.T) {
@@ -192,3 +195,45 @@ func TestSoulFilePath_EnvOverride(t *testing.T) {
 }
 
 // TestParseBool has moved to pkg/config/config_test.go alongside the config loader.
+
+func TestSetMCPServers(t *testing.T) {
+	mcpDir := t.TempDir()
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "mcp.json")
+	if err := os.WriteFile(filePath, []byte(`{"mcpServers":{"github":{"type":"http","url":"https://example.com/mcp"}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var opts claude.Options
+	cfg := config.ClaudeConfig{
+		MCPConfigPath: filePath,
+		MCPServers:    map[string]config.MCPServerConfig{"muster": {Command: "muster"}},
+	}
+	if err := setMCPServers(&opts, cfg, mcpDir); err != nil {
+		t.Fatalf("setMCPServers: %v", err)
+	}
+	if opts.MCPConfigPath == filePath || len(opts.MCPServers) != 2 {
+		t.Fatalf("expected a generated config with both servers, got %s %+v", opts.MCPConfigPath, opts.MCPServers)
+	}
+	generated, err := claude.ReadMCPConfig(opts.MCPConfigPath)
+	if err != nil || generated["muster"].Command != "muster" || generated["github"].URL != "https://example.com/mcp" {
+		t.Errorf("unexpected generated config %+v, %v", generated, err)
+	}
+
+	// Without inline servers the file is used as is.
+	if err := setMCPServers(&opts, config.ClaudeConfig{MCPConfigPath: filePath}, mcpDir); err != nil || opts.MCPConfigPath != filePath {
+		t.Errorf("expected %s, got %s, %v", filePath, opts.MCPConfigPath, err)
+	}
+
+	cfg.MCPServers = map[string]config.MCPServerConfig{"github": {Command: "gh"}}
+	if err := setMCPServers(&opts, cfg, mcpDir); err == nil || !strings.Contains(err.Error(), "also defined in") {
+		t.Errorf("expected a duplicate server error, got %v", err)
+	}
+
+	if err := os.WriteFile(filePath, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := setMCPServers(&opts, config.ClaudeConfig{MCPConfigPath: filePath}, mcpDir); err == nil || !strings.Contains(err.Error(), "claude.mcpConfigPath") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
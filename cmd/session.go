@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/klaus/pkg/claude"
+	"github.com/giantswarm/klaus/pkg/config"
+)
+
+// newSessionCmd creates the Cobra command for moving sessions between
+// instances.
+func newSessionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "session",
+		Short: "Export and import portable Claude session bundles",
+		Long: `Package a stored Claude session into a bundle and restore it on another
+klaus instance, e.g. to move an in-progress investigation to a bigger pod.
+
+A bundle is a gzip-compressed tar archive with the session transcript and
+tool output files, klaus' last result if it belongs to the session, and
+optionally the uncommitted changes of the workspace. After importing it,
+prompts can resume the session by its ID.
+
+Bundles contain the conversation, which may include secrets; they are
+masked when claude.redaction is enabled.`,
+	}
+
+	cmd.AddCommand(newSessionExportCmd())
+	cmd.AddCommand(newSessionImportCmd())
+
+	return cmd
+}
+
+func newSessionExportCmd() *cobra.Command {
+	var (
+		configPath  string
+		output      string
+		includeDiff bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export <session-id>",
+		Short: "Write a session bundle",
+		Long: `Write a bundle of a stored session of the configured workspace to a file,
+or to stdout with -o -. With --include-diff the bundle also carries the
+uncommitted changes of the workspace (git diff HEAD); untracked files are
+not included.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := sessionStoreFromConfig(configPath)
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				output = args[0] + ".tar.gz"
+			}
+
+			var w io.Writer = cmd.OutOrStdout()
+			if output != "-" {
+				f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600) //#nosec G304 -- operator-provided output path
+				if err != nil {
+					return fmt.Errorf("creating bundle: %w", err)
+				}
+				defer func() { _ = f.Close() }()
+				w = f
+			}
+
+			manifest, err := store.ExportBundle(cmd.Context(), w, args[0], claude.BundleExportOptions{IncludeDiff: includeDiff})
+			if err != nil {
+				if output != "-" {
+					_ = os.Remove(output)
+				}
+				return err
+			}
+			if output != "-" {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "exported session %s to %s\n", manifest.SessionID, output)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&configPath, "config", config.DefaultConfigPath, "Path to YAML config file")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Bundle file to write, - for stdout (default <session-id>.tar.gz)")
+	cmd.Flags().BoolVar(&includeDiff, "include-diff", false, "Include the uncommitted changes of the workspace")
+
+	return cmd
+}
+
+func newSessionImportCmd() *cobra.Command {
+	var (
+		configPath string
+		opts       claude.BundleImportOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import <bundle>",
+		Short: "Restore a session bundle",
+		Long: `Restore a session bundle written by 'klaus session export' or the
+export_session_bundle MCP tool into the configured workspace, reading it
+from stdin with -. Prints what was restored as JSON.
+
+An existing session with the same ID, and a last result of another
+session, are only replaced with --force. With --apply-diff the bundled
+workspace changes are applied with git apply; the import fails without
+changes if they do not apply cleanly.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := sessionStoreFromConfig(configPath)
+			if err != nil {
+				return err
+			}
+
+			var r io.Reader = cmd.InOrStdin()
+			if args[0] != "-" {
+				f, err := os.Open(args[0]) //#nosec G304 -- operator-provided bundle path
+				if err != nil {
+					return fmt.Errorf("opening bundle: %w", err)
+				}
+				defer func() { _ = f.Close() }()
+				r = f
+			}
+
+			imported, err := store.ImportBundle(cmd.Context(), r, opts)
+			if err != nil {
+				return err
+			}
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(imported)
+		},
+	}
+
+	cmd.Flags().StringVar(&configPath, "config", config.DefaultConfigPath, "Path to YAML config file")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Replace an existing session with the same ID and the last result")
+	cmd.Flags().BoolVar(&opts.ApplyDiff, "apply-diff", false, "Apply the bundled workspace changes with git apply")
+
+	return cmd
+}
+
+// sessionStoreFromConfig returns the session store of the workspace
+// configured in the config file and environment.
+func sessionStoreFromConfig(configPath string) (*claude.SessionStore, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, err
+	}
+	opts := claude.DefaultOptions()
+	opts.WorkDir = cfg.Claude.Workspace
+	redactor, err := cfg.Claude.Redaction.Redactor()
+	if err != nil {
+		return nil, fmt.Errorf("claude.redaction: %w", err)
+	}
+	opts.Redactor = redactor
+	return claude.NewWorkspaceSessionStore(opts), nil
+}
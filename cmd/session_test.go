@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/giantswarm/klaus/pkg/claude"
+)
+
+func TestSessionExportImport(t *testing.T) {
+	noConfig := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("CLAUDE_WORKSPACE", t.TempDir())
+	t.Setenv("KLAUS_RESULT_DIR", t.TempDir())
+	t.Setenv("CLAUDE_CONFIG_DIR", t.TempDir())
+
+	sessionsDir := claude.SessionsDir(os.Getenv("CLAUDE_WORKSPACE"))
+	if err := os.MkdirAll(sessionsDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	transcript := `{"type":"user","message":{"role":"user","content":"hello"}}` + "\n"
+	if err := os.WriteFile(filepath.Join(sessionsDir, "abc-123.jsonl"), []byte(transcript), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	export := newSessionCmd()
+	export.SetArgs([]string{"export", "abc-123", "--config", noConfig, "-o", bundle})
+	export.SetErr(&bytes.Buffer{})
+	if err := export.Execute(); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	// Import into another instance.
+	t.Setenv("CLAUDE_CONFIG_DIR", t.TempDir())
+	var out bytes.Buffer
+	imp := newSessionCmd()
+	imp.SetArgs([]string{"import", bundle, "--config", noConfig})
+	imp.SetOut(&out)
+	if err := imp.Execute(); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	var imported claude.BundleImport
+	if err := json.Unmarshal(out.Bytes(), &imported); err != nil {
+		t.Fatalf("import output is not JSON: %v", err)
+	}
+	if imported.Manifest.SessionID != "abc-123" {
+		t.Errorf("unexpected import: %+v", imported)
+	}
+	data, err := os.ReadFile(filepath.Join(claude.SessionsDir(os.Getenv("CLAUDE_WORKSPACE")), "abc-123.jsonl"))
+	if err != nil || string(data) != transcript {
+		t.Errorf("imported transcript: %q, %v", data, err)
+	}
+}
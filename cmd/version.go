@@ -2,17 +2,53 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/klaus/pkg/claude"
+	"github.com/giantswarm/klaus/pkg/config"
 )
 
 func newVersionCmd() *cobra.Command {
-	return &cobra.Command{
+	var configPath string
+
+	cmd := &cobra.Command{
 		Use:   "version",
 		Short: "Print the version number of klaus",
-		Long:  `All software has versions. This is klaus's.`,
-		Run: func(cmd *cobra.Command, args []string) {
-			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "klaus version %s\n", rootCmd.Version)
+		Long: `All software has versions. This is klaus's.
+
+Also prints the version of the configured claude CLI and the capabilities
+klaus enables for it.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			_, _ = fmt.Fprintf(out, "klaus version %s\n", rootCmd.Version)
+
+			// The config names the claude binary; without it the version
+			// of another binary could be reported.
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+			opts := claude.DefaultOptions()
+			opts.Binary = cfg.Claude.Binary
+			opts.BinaryArgs = cfg.Claude.BinaryArgs
+			v, err := claude.DetectCLIVersion(cmd.Context(), opts)
+			if err != nil {
+				_, _ = fmt.Fprintf(out, "claude version unknown (%v)\n", err)
+				return nil
+			}
+			caps := make([]string, 0, len(v.Capabilities()))
+			for _, c := range v.Capabilities() {
+				caps = append(caps, string(c))
+			}
+			_, _ = fmt.Fprintf(out, "claude version %s\n", v)
+			_, _ = fmt.Fprintf(out, "claude capabilities: %s\n", strings.Join(caps, ", "))
+			return nil
 		},
 	}
+
+	cmd.Flags().StringVar(&configPath, "config", config.DefaultConfigPath, "Path to YAML config file")
+
+	return cmd
 }
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVersion_ReportsConfigErrors(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("claude: [\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	cmd := newVersionCmd()
+	cmd.SetArgs([]string{"--config", configPath})
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SilenceUsage = true
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "loading config") {
+		t.Fatalf("expected a config error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "klaus version") {
+		t.Errorf("expected the klaus version before the error, got %q", out.String())
+	}
+}
@@ -0,0 +1,316 @@
+package claude
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Attachment is an image or file sent alongside a prompt. Exactly one of
+// Data or Path must be set.
+//
+// Attachments are delivered to the CLI as Anthropic content blocks in a
+// stream-json user message: images become "image" blocks, PDFs and text
+// files become "document" blocks.
+type Attachment struct {
+	// Name is an optional display name, used as the document title.
+	// Defaults to the base name of Path.
+	Name string `json:"name,omitempty"`
+	// MediaType is the MIME type of the content (e.g. "image/png",
+	// "application/pdf", "text/plain"). Required with Data; detected from
+	// the file extension or content when Path is used.
+	MediaType string `json:"media_type,omitempty"`
+	// Data is the base64-encoded content.
+	Data string `json:"data,omitempty"`
+	// Path is a file path relative to the workspace directory. Paths that
+	// resolve outside the workspace are rejected.
+	Path string `json:"path,omitempty"`
+}
+
+// Attachment limits. Images larger than a few megabytes are rejected by the
+// API anyway; the size limit mainly protects the stdin pipe and memory.
+const (
+	// MaxAttachments is the maximum number of attachments per prompt.
+	MaxAttachments = 20
+	// MaxAttachmentBytes is the maximum decoded size of a single attachment.
+	MaxAttachmentBytes = 10 * 1024 * 1024
+)
+
+// imageMediaTypes lists the image formats accepted by the Anthropic API.
+var imageMediaTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// mediaTypePDF is the only binary document format accepted by the API.
+const mediaTypePDF = "application/pdf"
+
+// contentBlock is an Anthropic message content block as accepted by the
+// CLI's stream-json input format.
+type contentBlock struct {
+	Type   string         `json:"type"`
+	Text   string         `json:"text,omitempty"`
+	Source *contentSource `json:"source,omitempty"`
+	Title  string         `json:"title,omitempty"`
+}
+
+// contentSource is the source of an image or document content block.
+type contentSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// ValidateAttachments performs the checks on attachments that do not require
+// reading the workspace: count, exactly one of data/path, base64 encoding,
+// size and media type of inline data, and path shape. Workspace paths are
+// resolved and read when the prompt is sent.
+func ValidateAttachments(attachments []Attachment) error {
+	if len(attachments) > MaxAttachments {
+		return fmt.Errorf("too many attachments: %d (maximum %d)", len(attachments), MaxAttachments)
+	}
+	for i, a := range attachments {
+		if err := a.validate(); err != nil {
+			return fmt.Errorf("attachment %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (a Attachment) validate() error {
+	switch {
+	case a.Data != "" && a.Path != "":
+		return fmt.Errorf("data and path are mutually exclusive")
+	case a.Data == "" && a.Path == "":
+		return fmt.Errorf("one of data or path is required")
+	case a.Path != "":
+		if filepath.IsAbs(a.Path) {
+			return fmt.Errorf("path %q must be relative to the workspace", a.Path)
+		}
+		if a.MediaType != "" {
+			return validateMediaType(a.MediaType)
+		}
+		return nil
+	}
+
+	if a.MediaType == "" {
+		return fmt.Errorf("media_type is required with data")
+	}
+	if err := validateMediaType(a.MediaType); err != nil {
+		return err
+	}
+	if base64.StdEncoding.DecodedLen(len(a.Data)) > MaxAttachmentBytes+2 {
+		return fmt.Errorf("attachment exceeds %d bytes", MaxAttachmentBytes)
+	}
+	if _, err := base64.StdEncoding.DecodeString(a.Data); err != nil {
+		return fmt.Errorf("data is not valid base64: %w", err)
+	}
+	return nil
+}
+
+// validateMediaType checks that the media type maps to a supported content
+// block: an image, a PDF, or a text document.
+func validateMediaType(mediaType string) error {
+	mt := baseMediaType(mediaType)
+	if imageMediaTypes[mt] || mt == mediaTypePDF || isTextMediaType(mt) {
+		return nil
+	}
+	return fmt.Errorf("unsupported media type %q; supported: image/png, image/jpeg, image/gif, image/webp, application/pdf, text/*", mediaType)
+}
+
+// baseMediaType strips parameters (e.g. "; charset=utf-8") and lowercases.
+func baseMediaType(mediaType string) string {
+	if mt, _, err := mime.ParseMediaType(mediaType); err == nil {
+		return mt
+	}
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+// isTextMediaType reports whether content of this type is sent as a plain
+// text document.
+func isTextMediaType(mt string) bool {
+	switch mt {
+	case "application/json", "application/x-yaml", "application/yaml", "application/xml":
+		return true
+	}
+	return strings.HasPrefix(mt, "text/")
+}
+
+// buildPromptContent returns the stdin message content for a prompt. Without
+// attachments it is the prompt string; with attachments it is a list of
+// content blocks with the attachments first, followed by the prompt text
+// (omitted when empty, since the API rejects empty text blocks).
+func buildPromptContent(workDir, prompt string, attachments []Attachment) (any, error) {
+	if len(attachments) == 0 {
+		return prompt, nil
+	}
+	if err := ValidateAttachments(attachments); err != nil {
+		return nil, err
+	}
+
+	blocks := make([]contentBlock, 0, len(attachments)+1)
+	for i, a := range attachments {
+		block, err := a.contentBlock(workDir)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %d: %w", i, err)
+		}
+		blocks = append(blocks, block)
+	}
+	if prompt != "" {
+		blocks = append(blocks, contentBlock{Type: string(SubtypeText), Text: prompt})
+	}
+	return blocks, nil
+}
+
+// contentBlock converts the attachment into an image or document block,
+// reading Path from the workspace when set.
+func (a Attachment) contentBlock(workDir string) (contentBlock, error) {
+	data, mediaType, name := a.Data, a.MediaType, a.Name
+	var raw []byte
+
+	if a.Path != "" {
+		resolved, err := resolveWorkspacePath(workDir, a.Path)
+		if err != nil {
+			return contentBlock{}, err
+		}
+		raw, err = readAttachmentFile(resolved)
+		if err != nil {
+			return contentBlock{}, err
+		}
+		if mediaType == "" {
+			mediaType = detectMediaType(resolved, raw)
+			if err := validateMediaType(mediaType); err != nil {
+				return contentBlock{}, fmt.Errorf("%s: %w", a.Path, err)
+			}
+		}
+		if name == "" {
+			name = filepath.Base(a.Path)
+		}
+		data = base64.StdEncoding.EncodeToString(raw)
+	}
+
+	mt := baseMediaType(mediaType)
+	switch {
+	case imageMediaTypes[mt]:
+		return contentBlock{
+			Type:   "image",
+			Source: &contentSource{Type: "base64", MediaType: mt, Data: data},
+		}, nil
+	case mt == mediaTypePDF:
+		return contentBlock{
+			Type:   "document",
+			Source: &contentSource{Type: "base64", MediaType: mt, Data: data},
+			Title:  name,
+		}, nil
+	}
+
+	// Text documents are sent decoded with a plain text source.
+	if raw == nil {
+		var err error
+		raw, err = base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return contentBlock{}, fmt.Errorf("data is not valid base64: %w", err)
+		}
+	}
+	if !utf8.Valid(raw) {
+		return contentBlock{}, fmt.Errorf("text attachment %q is not valid UTF-8", name)
+	}
+	return contentBlock{
+		Type:   "document",
+		Source: &contentSource{Type: "text", MediaType: "text/plain", Data: string(raw)},
+		Title:  name,
+	}, nil
+}
+
+// resolveWorkspacePath joins a relative path onto the workspace directory and
+// ensures the result, after following symlinks, stays inside the workspace.
+func resolveWorkspacePath(workDir, rel string) (string, error) {
+	if filepath.IsAbs(rel) {
+		return "", fmt.Errorf("path %q must be relative to the workspace", rel)
+	}
+	if workDir == "" {
+		workDir = "."
+	}
+	root, err := filepath.Abs(workDir)
+	if err != nil {
+		return "", fmt.Errorf("resolving workspace: %w", err)
+	}
+	if r, err := filepath.EvalSymlinks(root); err == nil {
+		root = r
+	}
+
+	resolved, err := filepath.EvalSymlinks(filepath.Join(root, rel))
+	if err != nil {
+		return "", fmt.Errorf("path %q: %w", rel, err)
+	}
+	inside, err := filepath.Rel(root, resolved)
+	if err != nil || inside == ".." || strings.HasPrefix(inside, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q resolves outside the workspace", rel)
+	}
+	return resolved, nil
+}
+
+// readAttachmentFile reads a regular file, enforcing MaxAttachmentBytes.
+func readAttachmentFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	if info.Size() > MaxAttachmentBytes {
+		return nil, fmt.Errorf("%s exceeds %d bytes", path, MaxAttachmentBytes)
+	}
+	return os.ReadFile(path) //nolint:gosec // path is confined to the workspace
+}
+
+// ReadWorkspaceFile reads a file of the workspace workDir, e.g. for the MCP
+// resources of the workspace. rel must be relative and may not resolve
+// outside the workspace; files larger than MaxAttachmentBytes are rejected.
+// It returns the content and its detected media type.
+func ReadWorkspaceFile(workDir, rel string) ([]byte, string, error) {
+	path, err := resolveWorkspacePath(workDir, rel)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := readAttachmentFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, detectMediaType(path, data), nil
+}
+
+// detectMediaType guesses a media type from the file extension, falling back
+// to content sniffing.
+func detectMediaType(path string, data []byte) string {
+	if mt := mime.TypeByExtension(strings.ToLower(filepath.Ext(path))); mt != "" {
+		return mt
+	}
+	return http.DetectContentType(data)
+}
+
+// marshalStdinMessage encodes a user message for the CLI's stream-json input
+// format, including the trailing newline delimiter. content is either the
+// prompt string or a list of content blocks (see buildPromptContent).
+func marshalStdinMessage(content any) ([]byte, error) {
+	data, err := json.Marshal(stdinMessage{
+		Type: string(MessageTypeUser),
+		Message: stdinMessageContent{
+			Role:    string(MessageTypeUser),
+			Content: content,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
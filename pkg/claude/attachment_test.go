@@ -0,0 +1,192 @@
+package claude
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateAttachments(t *testing.T) {
+	png := base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n"))
+
+	tests := []struct {
+		name        string
+		attachments []Attachment
+		wantErr     string
+	}{
+		{name: "none"},
+		{name: "inline image", attachments: []Attachment{{Data: png, MediaType: "image/png"}}},
+		{name: "inline pdf", attachments: []Attachment{{Data: png, MediaType: "application/pdf"}}},
+		{name: "inline text with charset", attachments: []Attachment{{Data: "aGk=", MediaType: "text/plain; charset=utf-8"}}},
+		{name: "workspace path", attachments: []Attachment{{Path: "docs/screenshot.png"}}},
+		{name: "both data and path", attachments: []Attachment{{Data: png, Path: "x.png", MediaType: "image/png"}}, wantErr: "mutually exclusive"},
+		{name: "neither data nor path", attachments: []Attachment{{Name: "x"}}, wantErr: "one of data or path is required"},
+		{name: "missing media type", attachments: []Attachment{{Data: png}}, wantErr: "media_type is required"},
+		{name: "unsupported media type", attachments: []Attachment{{Data: png, MediaType: "application/zip"}}, wantErr: "unsupported media type"},
+		{name: "invalid base64", attachments: []Attachment{{Data: "not base64!", MediaType: "image/png"}}, wantErr: "not valid base64"},
+		{name: "absolute path", attachments: []Attachment{{Path: "/etc/passwd"}}, wantErr: "must be relative"},
+		{name: "too many", attachments: make([]Attachment, MaxAttachments+1), wantErr: "too many attachments"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAttachments(tt.attachments)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestBuildPromptContent_NoAttachments(t *testing.T) {
+	content, err := buildPromptContent("", "hello", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content != "hello" {
+		t.Errorf("expected plain string content, got %#v", content)
+	}
+}
+
+func TestBuildPromptContent_Blocks(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "shots"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	pngBytes := []byte("\x89PNG\r\n\x1a\nfake")
+	if err := os.WriteFile(filepath.Join(dir, "shots", "ui.png"), pngBytes, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("line one\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := buildPromptContent(dir, "What is broken?", []Attachment{
+		{Path: "shots/ui.png"},
+		{Path: "notes.txt"},
+		{Data: "JVBERi0=", MediaType: "application/pdf", Name: "spec.pdf"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	blocks, ok := content.([]contentBlock)
+	if !ok {
+		t.Fatalf("expected []contentBlock, got %T", content)
+	}
+	if len(blocks) != 4 {
+		t.Fatalf("expected 4 blocks, got %d", len(blocks))
+	}
+
+	img := blocks[0]
+	if img.Type != "image" || img.Source == nil || img.Source.MediaType != "image/png" ||
+		img.Source.Data != base64.StdEncoding.EncodeToString(pngBytes) {
+		t.Errorf("unexpected image block: %+v", img)
+	}
+
+	txt := blocks[1]
+	if txt.Type != "document" || txt.Source.Type != "text" || txt.Source.Data != "line one\n" || txt.Title != "notes.txt" {
+		t.Errorf("unexpected text document block: %+v", txt)
+	}
+
+	pdf := blocks[2]
+	if pdf.Type != "document" || pdf.Source.Type != "base64" || pdf.Source.MediaType != "application/pdf" || pdf.Title != "spec.pdf" {
+		t.Errorf("unexpected pdf block: %+v", pdf)
+	}
+
+	if blocks[3].Type != "text" || blocks[3].Text != "What is broken?" {
+		t.Errorf("expected trailing text block, got %+v", blocks[3])
+	}
+}
+
+func TestBuildPromptContent_EmptyPromptOmitsTextBlock(t *testing.T) {
+	content, err := buildPromptContent("", "", []Attachment{{Data: "aGk=", MediaType: "text/plain"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if blocks := content.([]contentBlock); len(blocks) != 1 {
+		t.Errorf("expected only the attachment block, got %+v", blocks)
+	}
+}
+
+func TestResolveWorkspacePath(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "in.txt"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{name: "inside", path: "in.txt"},
+		{name: "dot segments staying inside", path: "./sub/../in.txt"},
+		{name: "traversal", path: "../" + filepath.Base(outside) + "/secret.txt", wantErr: "outside the workspace"},
+		{name: "symlink escape", path: "link.txt", wantErr: "outside the workspace"},
+		{name: "absolute", path: filepath.Join(root, "in.txt"), wantErr: "must be relative"},
+		{name: "missing", path: "nope.txt", wantErr: "no such file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := resolveWorkspacePath(root, tt.path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestMarshalStdinMessage(t *testing.T) {
+	data, err := marshalStdinMessage([]contentBlock{
+		{Type: "image", Source: &contentSource{Type: "base64", MediaType: "image/png", Data: "aGk="}},
+		{Type: "text", Text: "look"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(string(data), "\n") {
+		t.Error("expected trailing newline delimiter")
+	}
+
+	var decoded struct {
+		Type    string `json:"type"`
+		Message struct {
+			Role    string            `json:"role"`
+			Content []json.RawMessage `json:"content"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if decoded.Type != "user" || decoded.Message.Role != "user" || len(decoded.Message.Content) != 2 {
+		t.Errorf("unexpected message: %s", data)
+	}
+	want := `{"type":"image","source":{"type":"base64","media_type":"image/png","data":"aGk="}}`
+	if string(decoded.Message.Content[0]) != want {
+		t.Errorf("image block = %s, want %s", decoded.Message.Content[0], want)
+	}
+}
@@ -0,0 +1,449 @@
+package claude
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrSessionExists is returned when importing a bundle whose session is
+// already stored and overwriting was not requested.
+var ErrSessionExists = errors.New("session already exists")
+
+// ErrInvalidBundle is returned for archives that are not session bundles.
+var ErrInvalidBundle = errors.New("invalid session bundle")
+
+// BundleFormatVersion is the version of the session bundle layout. Bundles
+// with a newer version are rejected on import. Version 2 added the run
+// history.
+const BundleFormatVersion = 2
+
+// Entry names inside a session bundle. The manifest is always the first
+// entry so that an import knows the session before reading its files.
+const (
+	bundleManifestName   = "manifest.json"
+	bundleTranscriptName = "session.jsonl"
+	bundleFilesDir       = "session/"
+	bundleResultName     = "result.json"
+	bundleRunsDir        = "runs/"
+	bundleDiffName       = "workspace.diff"
+)
+
+// maxBundleEntrySize caps the size of a single bundle entry on import.
+const maxBundleEntrySize = 512 << 20
+
+// BundleManifest describes the content of a session bundle.
+type BundleManifest struct {
+	Version   int       `json:"version"`
+	SessionID string    `json:"session_id"`
+	CreatedAt time.Time `json:"created_at"`
+	// WorkDir is the workspace the session was exported from.
+	WorkDir    string `json:"workdir,omitempty"`
+	CLIVersion string `json:"cli_version,omitempty"`
+	// Files is the number of tool output files stored with the session.
+	Files int `json:"files,omitempty"`
+	// HasResult is set when the bundle carries klaus' last result for the
+	// session.
+	HasResult bool `json:"has_result"`
+	// Runs is the number of past runs of the session from klaus' run
+	// history.
+	Runs int `json:"runs,omitempty"`
+	// HasDiff is set when the bundle carries the uncommitted changes of the
+	// workspace.
+	HasDiff bool `json:"has_diff"`
+}
+
+// BundleExportOptions controls what ExportBundle includes.
+type BundleExportOptions struct {
+	// IncludeDiff adds the uncommitted changes of the workspace, as
+	// produced by `git diff HEAD --binary`. Untracked files are not
+	// included.
+	IncludeDiff bool
+}
+
+// BundleImportOptions controls how ImportBundle restores a bundle.
+type BundleImportOptions struct {
+	// Force overwrites a stored session and result with the same ID.
+	Force bool
+	// ApplyDiff applies the workspace diff of the bundle with `git apply`.
+	ApplyDiff bool
+}
+
+// BundleImport reports what ImportBundle restored.
+type BundleImport struct {
+	Manifest BundleManifest `json:"manifest"`
+	// ResultRestored is set when the bundled result replaced the last
+	// result of the result store.
+	ResultRestored bool `json:"result_restored"`
+	// RunsRestored is the number of past runs added to the run history.
+	RunsRestored int `json:"runs_restored"`
+	// DiffApplied is set when the workspace diff was applied.
+	DiffApplied bool `json:"diff_applied"`
+}
+
+// ExportBundle writes a gzip-compressed tar archive with everything needed
+// to resume a session on another instance: the transcript and tool output
+// files of the session, klaus' last result if it belongs to the session and
+// the session's past runs from the run history, and optionally the
+// uncommitted changes of the workspace. Secrets are
+// masked when redaction is enabled.
+func (s *SessionStore) ExportBundle(ctx context.Context, w io.Writer, id string, opts BundleExportOptions) (BundleManifest, error) {
+	detail, err := s.Inspect(id)
+	if err != nil {
+		return BundleManifest{}, err
+	}
+	transcript, err := s.Export(id)
+	if err != nil {
+		return BundleManifest{}, err
+	}
+	manifest := BundleManifest{
+		Version:    BundleFormatVersion,
+		SessionID:  id,
+		CreatedAt:  time.Now().UTC(),
+		WorkDir:    s.workDir,
+		CLIVersion: detail.CLIVersion,
+	}
+
+	files, err := s.sessionFiles(id)
+	if err != nil {
+		return BundleManifest{}, err
+	}
+	manifest.Files = len(files)
+
+	var result []byte
+	var runs []storedRun
+	if s.results != nil {
+		if runs, err = s.results.sessionRuns(id); err != nil {
+			return BundleManifest{}, err
+		}
+		manifest.Runs = len(runs)
+		last, err := s.results.Load()
+		if err != nil {
+			return BundleManifest{}, err
+		}
+		if last != nil && last.SessionID == id {
+			if result, err = json.MarshalIndent(last, "", "  "); err != nil {
+				return BundleManifest{}, fmt.Errorf("marshaling result: %w", err)
+			}
+			manifest.HasResult = true
+		}
+	}
+
+	var diff []byte
+	if opts.IncludeDiff {
+		if diff, err = gitOutput(ctx, s.workDir, nil, "diff", "HEAD", "--binary"); err != nil {
+			return BundleManifest{}, fmt.Errorf("creating workspace diff: %w", err)
+		}
+		manifest.HasDiff = true
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return BundleManifest{}, fmt.Errorf("marshaling manifest: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	entries := []bundleEntry{
+		{bundleManifestName, manifestData},
+		{bundleTranscriptName, transcript},
+	}
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(s.dir, id, filepath.FromSlash(name))) // #nosec G304 -- confined to the session directory
+		if err != nil {
+			return BundleManifest{}, fmt.Errorf("reading session file: %w", err)
+		}
+		data, _ = s.redactor.Redact(data)
+		entries = append(entries, bundleEntry{bundleFilesDir + name, data})
+	}
+	if manifest.HasResult {
+		entries = append(entries, bundleEntry{bundleResultName, result})
+	}
+	for _, run := range runs {
+		entries = append(entries, bundleEntry{bundleRunsDir + run.id + ".json", run.data})
+	}
+	if manifest.HasDiff {
+		entries = append(entries, bundleEntry{bundleDiffName, diff})
+	}
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:    e.name,
+			Mode:    0o600,
+			Size:    int64(len(e.data)),
+			ModTime: manifest.CreatedAt,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return BundleManifest{}, fmt.Errorf("writing bundle: %w", err)
+		}
+		if _, err := tw.Write(e.data); err != nil {
+			return BundleManifest{}, fmt.Errorf("writing bundle: %w", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return BundleManifest{}, fmt.Errorf("writing bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return BundleManifest{}, fmt.Errorf("writing bundle: %w", err)
+	}
+	return manifest, nil
+}
+
+// bundleEntry is a file written to a session bundle.
+type bundleEntry struct {
+	name string
+	data []byte
+}
+
+// sessionFiles returns the tool output files the CLI stored for a session,
+// as slash-separated paths relative to the session directory.
+func (s *SessionStore) sessionFiles(id string) ([]string, error) {
+	root := filepath.Join(s.dir, id)
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && p == root {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading session files: %w", err)
+	}
+	return files, nil
+}
+
+// ImportBundle restores a bundle written by ExportBundle so that the session
+// can be resumed here. An existing session with the same ID is only
+// overwritten with Force, and never while the running agent uses it. The
+// bundled result replaces the last result only when the result store is
+// empty or Force is set; the bundled runs are added to the run history.
+func (s *SessionStore) ImportBundle(ctx context.Context, r io.Reader, opts BundleImportOptions) (BundleImport, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return BundleImport{}, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	defer func() { _ = gz.Close() }()
+	tr := tar.NewReader(gz)
+
+	manifestData, err := readBundleEntry(tr, bundleManifestName)
+	if err != nil {
+		return BundleImport{}, err
+	}
+	var manifest BundleManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return BundleImport{}, fmt.Errorf("%w: parsing manifest: %v", ErrInvalidBundle, err)
+	}
+	if manifest.Version < 1 || manifest.Version > BundleFormatVersion {
+		return BundleImport{}, fmt.Errorf("%w: unsupported version %d", ErrInvalidBundle, manifest.Version)
+	}
+	id := manifest.SessionID
+	if err := checkSessionID(id); err != nil {
+		return BundleImport{}, err
+	}
+	if s.active != nil && s.active() == id {
+		return BundleImport{}, fmt.Errorf("%w: %s", ErrSessionActive, id)
+	}
+	if _, err := os.Stat(s.path(id)); err == nil && !opts.Force {
+		return BundleImport{}, fmt.Errorf("%w: %s", ErrSessionExists, id)
+	}
+
+	// Unpack into a staging directory next to the sessions, so that a
+	// broken bundle leaves no partial session behind.
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return BundleImport{}, fmt.Errorf("creating sessions directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(s.dir, ".import-*")
+	if err != nil {
+		return BundleImport{}, fmt.Errorf("creating staging directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(staging) }()
+
+	var result, diff []byte
+	var runs []storedRun
+	hasTranscript := false
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return BundleImport{}, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return BundleImport{}, fmt.Errorf("%w: unexpected entry %q", ErrInvalidBundle, hdr.Name)
+		}
+		data, err := readBundleData(tr, hdr)
+		if err != nil {
+			return BundleImport{}, err
+		}
+		switch name := hdr.Name; {
+		case name == bundleTranscriptName:
+			hasTranscript = true
+			err = os.WriteFile(filepath.Join(staging, id+sessionFileSuffix), data, 0o600)
+		case name == bundleResultName:
+			result = data
+		case name == bundleDiffName:
+			diff = data
+		case strings.HasPrefix(name, bundleRunsDir):
+			run, err := parseBundleRun(id, strings.TrimPrefix(name, bundleRunsDir), data)
+			if err != nil {
+				return BundleImport{}, err
+			}
+			runs = append(runs, run)
+		case strings.HasPrefix(name, bundleFilesDir):
+			rel := strings.TrimPrefix(name, bundleFilesDir)
+			if rel == "" || path.Clean(rel) != rel || !filepath.IsLocal(filepath.FromSlash(rel)) {
+				return BundleImport{}, fmt.Errorf("%w: unsafe entry %q", ErrInvalidBundle, name)
+			}
+			dst := filepath.Join(staging, id, filepath.FromSlash(rel))
+			if err = os.MkdirAll(filepath.Dir(dst), 0o700); err == nil {
+				err = os.WriteFile(dst, data, 0o600)
+			}
+		default:
+			return BundleImport{}, fmt.Errorf("%w: unexpected entry %q", ErrInvalidBundle, name)
+		}
+		if err != nil {
+			return BundleImport{}, fmt.Errorf("unpacking bundle: %w", err)
+		}
+	}
+	if !hasTranscript {
+		return BundleImport{}, fmt.Errorf("%w: missing %s", ErrInvalidBundle, bundleTranscriptName)
+	}
+
+	var persisted *PersistedResult
+	if result != nil {
+		persisted = &PersistedResult{}
+		if err := json.Unmarshal(result, persisted); err != nil {
+			return BundleImport{}, fmt.Errorf("%w: parsing result: %v", ErrInvalidBundle, err)
+		}
+	}
+	// Check the diff before touching the session, so that a diff that does
+	// not apply fails the whole import.
+	if opts.ApplyDiff && diff != nil {
+		if _, err := gitOutput(ctx, s.workDir, diff, "apply", "--check", "--binary"); err != nil {
+			return BundleImport{}, fmt.Errorf("workspace diff does not apply: %w", err)
+		}
+	}
+
+	// Replace the stored session with the staged one.
+	_ = os.RemoveAll(filepath.Join(s.dir, id))
+	if err := os.Rename(filepath.Join(staging, id+sessionFileSuffix), s.path(id)); err != nil {
+		return BundleImport{}, fmt.Errorf("storing session: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(staging, id)); err == nil {
+		if err := os.Rename(filepath.Join(staging, id), filepath.Join(s.dir, id)); err != nil {
+			return BundleImport{}, fmt.Errorf("storing session files: %w", err)
+		}
+	}
+
+	imported := BundleImport{Manifest: manifest}
+	if s.results != nil {
+		for _, run := range runs {
+			if err := s.results.saveRun(run.id, run.data); err != nil {
+				return imported, fmt.Errorf("restoring run %s: %w", run.id, err)
+			}
+			imported.RunsRestored++
+		}
+	}
+	if persisted != nil && s.results != nil {
+		current, err := s.results.Load()
+		if err != nil && !opts.Force {
+			return imported, err
+		}
+		if current == nil || opts.Force {
+			if err := s.results.Save(*persisted); err != nil {
+				return imported, err
+			}
+			imported.ResultRestored = true
+		}
+	}
+	if opts.ApplyDiff && diff != nil {
+		if _, err := gitOutput(ctx, s.workDir, diff, "apply", "--binary"); err != nil {
+			return imported, fmt.Errorf("applying workspace diff: %w", err)
+		}
+		imported.DiffApplied = true
+	}
+	return imported, nil
+}
+
+// parseBundleRun checks a run of the run history of a bundle, stored as
+// name in the runs directory, and returns it. The run must belong to the
+// bundled session.
+func parseBundleRun(sessionID, name string, data []byte) (storedRun, error) {
+	id, ok := strings.CutSuffix(name, ".json")
+	if !ok || checkRunID(id) != nil {
+		return storedRun{}, fmt.Errorf("%w: unexpected entry %q", ErrInvalidBundle, bundleRunsDir+name)
+	}
+	var run PersistedResult
+	if err := json.Unmarshal(data, &run); err != nil {
+		return storedRun{}, fmt.Errorf("%w: parsing run %s: %v", ErrInvalidBundle, id, err)
+	}
+	if run.SessionID != sessionID {
+		return storedRun{}, fmt.Errorf("%w: run %s belongs to session %q", ErrInvalidBundle, id, run.SessionID)
+	}
+	return storedRun{id: id, data: data}, nil
+}
+
+// readBundleEntry reads the next entry of a bundle, which must be name.
+func readBundleEntry(tr *tar.Reader, name string) ([]byte, error) {
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	if hdr.Name != name || hdr.Typeflag != tar.TypeReg {
+		return nil, fmt.Errorf("%w: expected %s, got %q", ErrInvalidBundle, name, hdr.Name)
+	}
+	return readBundleData(tr, hdr)
+}
+
+func readBundleData(tr *tar.Reader, hdr *tar.Header) ([]byte, error) {
+	if hdr.Size > maxBundleEntrySize {
+		return nil, fmt.Errorf("%w: entry %q is too large", ErrInvalidBundle, hdr.Name)
+	}
+	data, err := io.ReadAll(io.LimitReader(tr, maxBundleEntrySize))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	return data, nil
+}
+
+// gitOutput runs git in dir with stdin as input and returns its output.
+func gitOutput(ctx context.Context, dir string, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...) //nolint:gosec // args are fixed by the caller
+	cmd.Dir = dir
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
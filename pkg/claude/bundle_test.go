@@ -0,0 +1,202 @@
+package claude
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newBundleStore returns a store with its own sessions, result and
+// workspace directories.
+func newBundleStore(t *testing.T, workDir string) *SessionStore {
+	t.Helper()
+	store := NewSessionStore(t.TempDir(), nil)
+	store.results = NewResultStore(t.TempDir())
+	store.workDir = workDir
+	return store
+}
+
+// initGitRepo creates a git repository with one committed file.
+func initGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "main.go"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func TestSessionStore_BundleRoundTrip(t *testing.T) {
+	srcRepo := initGitRepo(t)
+	src := newBundleStore(t, srcRepo)
+	writeSession(t, src.dir, "s1", time.Now(),
+		`{"type":"user","version":"2.1.81","message":{"role":"user","content":"investigate"}}`,
+	)
+	if err := os.MkdirAll(filepath.Join(src.dir, "s1", "tool-results"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src.dir, "s1", "tool-results", "out.txt"), []byte("output"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, run := range []PersistedResult{
+		{RunID: "20261018T100000-00000001", ResultText: "first", SessionID: "s1"},
+		{RunID: "20261018T100100-00000002", ResultText: "elsewhere", SessionID: "s2"},
+		{RunID: "20261018T100200-00000003", ResultText: "halfway", SessionID: "s1", Status: ProcessStatusCompleted},
+	} {
+		if err := src.results.Save(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(srcRepo, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var bundle bytes.Buffer
+	manifest, err := src.ExportBundle(context.Background(), &bundle, "s1", BundleExportOptions{IncludeDiff: true})
+	if err != nil {
+		t.Fatalf("ExportBundle: %v", err)
+	}
+	if !manifest.HasResult || !manifest.HasDiff || manifest.Files != 1 || manifest.Runs != 2 || manifest.CLIVersion != "2.1.81" {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+
+	dstRepo := initGitRepo(t)
+	dst := newBundleStore(t, dstRepo)
+	imported, err := dst.ImportBundle(context.Background(), bytes.NewReader(bundle.Bytes()), BundleImportOptions{ApplyDiff: true})
+	if err != nil {
+		t.Fatalf("ImportBundle: %v", err)
+	}
+	if imported.Manifest.SessionID != "s1" || !imported.ResultRestored || imported.RunsRestored != 2 || !imported.DiffApplied {
+		t.Errorf("unexpected import: %+v", imported)
+	}
+	if run, err := dst.results.LoadRun("20261018T100000-00000001"); err != nil || run.ResultText != "first" {
+		t.Errorf("imported run: %+v, %v", run, err)
+	}
+	if _, err := dst.results.LoadRun("20261018T100100-00000002"); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("expected the run of another session to be left out, got %v", err)
+	}
+	if detail, err := dst.Inspect("s1"); err != nil || detail.FirstPrompt != "investigate" {
+		t.Errorf("imported session: %+v, %v", detail, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst.dir, "s1", "tool-results", "out.txt")); err != nil || string(data) != "output" {
+		t.Errorf("imported session file: %q, %v", data, err)
+	}
+	if result, err := dst.results.Load(); err != nil || result == nil || result.ResultText != "halfway" {
+		t.Errorf("imported result: %+v, %v", result, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dstRepo, "main.go")); err != nil || !strings.Contains(string(data), "func main()") {
+		t.Errorf("diff not applied: %q, %v", data, err)
+	}
+
+	// A second import needs Force, and keeps the result store as is
+	// unless forced too.
+	_, err = dst.ImportBundle(context.Background(), bytes.NewReader(bundle.Bytes()), BundleImportOptions{})
+	if !errors.Is(err, ErrSessionExists) {
+		t.Fatalf("expected ErrSessionExists, got %v", err)
+	}
+	if err := dst.results.Save(PersistedResult{ResultText: "newer", SessionID: "s2"}); err != nil {
+		t.Fatal(err)
+	}
+	imported, err = dst.ImportBundle(context.Background(), bytes.NewReader(bundle.Bytes()), BundleImportOptions{Force: true})
+	if err != nil || !imported.ResultRestored || imported.DiffApplied {
+		t.Errorf("forced import: %+v, %v", imported, err)
+	}
+
+	dst.active = func() string { return "s1" }
+	_, err = dst.ImportBundle(context.Background(), bytes.NewReader(bundle.Bytes()), BundleImportOptions{Force: true})
+	if !errors.Is(err, ErrSessionActive) {
+		t.Errorf("expected ErrSessionActive, got %v", err)
+	}
+}
+
+func TestSessionStore_ExportBundleSkipsOtherResults(t *testing.T) {
+	store := newBundleStore(t, t.TempDir())
+	writeSession(t, store.dir, "s1", time.Now(), `{"type":"user","message":{"content":"hi"}}`)
+	if err := store.results.Save(PersistedResult{SessionID: "other"}); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := store.ExportBundle(context.Background(), &bytes.Buffer{}, "s1", BundleExportOptions{})
+	if err != nil || manifest.HasResult || manifest.HasDiff {
+		t.Errorf("unexpected export: %+v, %v", manifest, err)
+	}
+	if _, err := store.ExportBundle(context.Background(), &bytes.Buffer{}, "missing", BundleExportOptions{}); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+// writeBundle writes a bundle with the given entries, in order.
+func writeBundle(t *testing.T, entries ...bundleEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		if err := tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0o600, Size: int64(len(e.data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(e.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSessionStore_ImportBundleRejectsInvalidBundles(t *testing.T) {
+	manifest := bundleEntry{bundleManifestName, []byte(`{"version":1,"session_id":"s1"}`)}
+	transcript := bundleEntry{bundleTranscriptName, []byte("{}\n")}
+
+	tests := []struct {
+		name   string
+		bundle []byte
+		want   error
+	}{
+		{"not gzip", []byte("plain text"), ErrInvalidBundle},
+		{"manifest not first", writeBundle(t, transcript, manifest), ErrInvalidBundle},
+		{"future version", writeBundle(t, bundleEntry{bundleManifestName, []byte(`{"version":99,"session_id":"s1"}`)}, transcript), ErrInvalidBundle},
+		{"bad session id", writeBundle(t, bundleEntry{bundleManifestName, []byte(`{"version":1,"session_id":"../x"}`)}, transcript), ErrInvalidSessionID},
+		{"missing transcript", writeBundle(t, manifest), ErrInvalidBundle},
+		{"path traversal", writeBundle(t, manifest, transcript, bundleEntry{"session/../../evil", []byte("x")}), ErrInvalidBundle},
+		{"unknown entry", writeBundle(t, manifest, transcript, bundleEntry{"extra.txt", []byte("x")}), ErrInvalidBundle},
+		{"bad run id", writeBundle(t, manifest, transcript, bundleEntry{bundleRunsDir + "../result.json", []byte(`{"session_id":"s1"}`)}), ErrInvalidBundle},
+		{"run of another session", writeBundle(t, manifest, transcript, bundleEntry{bundleRunsDir + "20261018T100000-00000001.json", []byte(`{"session_id":"s2"}`)}), ErrInvalidBundle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newBundleStore(t, t.TempDir())
+			_, err := store.ImportBundle(context.Background(), bytes.NewReader(tt.bundle), BundleImportOptions{})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			entries, _ := os.ReadDir(store.dir)
+			if len(entries) != 0 {
+				t.Errorf("import left files behind: %v", entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package claude
+
+import "sync"
+
+// changeNotifier signals changes of the agent state, i.e. its status, run
+// and message count, to waiters. Each channel it hands out is closed at the
+// next change. The zero value is ready to use.
+type changeNotifier struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+// next returns a channel that is closed at the next change.
+func (n *changeNotifier) next() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.ch == nil {
+		n.ch = make(chan struct{})
+	}
+	return n.ch
+}
+
+// notify closes the channel of the waiters of the current change.
+func (n *changeNotifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.ch != nil {
+		close(n.ch)
+		n.ch = nil
+	}
+}
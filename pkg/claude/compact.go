@@ -0,0 +1,276 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/klaus/pkg/metrics"
+)
+
+// ErrCompactUnsupported is returned by Compact when there is no long-running
+// session to compact (agent mode).
+var ErrCompactUnsupported = errors.New("compaction requires chat mode")
+
+// SubtypeCompactBoundary is the subtype of the system message the CLI emits
+// when it has compacted the conversation.
+const SubtypeCompactBoundary MessageSubtype = "compact_boundary"
+
+// Compaction triggers, used as the trigger of a CompactionEvent and as the
+// label of the compactions metric.
+const (
+	// CompactTriggerManual is a compaction requested through Compact.
+	CompactTriggerManual = "manual"
+	// CompactTriggerAuto is a compaction started by the AutoCompact policy.
+	CompactTriggerAuto = "auto"
+	// CompactTriggerCLI is a compaction the CLI started on its own.
+	CompactTriggerCLI = "cli"
+)
+
+// DefaultContextWindow is the context window assumed when
+// AutoCompactOptions.ContextWindow is not set.
+const DefaultContextWindow = 200_000
+
+// AutoCompactOptions configures automatic compaction of chat-mode sessions.
+// The session's context size is estimated as the input and cache creation
+// tokens reported since the last compaction. Once it reaches Threshold of
+// ContextWindow, /compact is sent ahead of the next prompt.
+type AutoCompactOptions struct {
+	// Threshold is the share of the context window, between 0 and 1, at
+	// which the session is compacted. 0 disables automatic compaction.
+	Threshold float64
+	// ContextWindow is the model's context window in tokens; 0 means
+	// DefaultContextWindow.
+	ContextWindow int64
+	// Instructions are passed to /compact to steer the summary.
+	Instructions string
+}
+
+// Validate checks that the automatic compaction settings are usable.
+func (a AutoCompactOptions) Validate() error {
+	var errs []error
+	if a.Threshold < 0 || a.Threshold > 1 {
+		errs = append(errs, fmt.Errorf("threshold must be between 0 and 1, got %v", a.Threshold))
+	}
+	if a.ContextWindow < 0 {
+		errs = append(errs, fmt.Errorf("contextWindow must be >= 0, got %d", a.ContextWindow))
+	}
+	return errors.Join(errs...)
+}
+
+// due reports whether a session with contextTokens tokens in its context
+// should be compacted.
+func (a AutoCompactOptions) due(contextTokens int64) bool {
+	if a.Threshold <= 0 {
+		return false
+	}
+	window := a.ContextWindow
+	if window <= 0 {
+		window = DefaultContextWindow
+	}
+	return float64(contextTokens) >= a.Threshold*float64(window)
+}
+
+// CompactionEvent records one compaction of a session.
+type CompactionEvent struct {
+	Trigger string    `json:"trigger"`
+	Time    time.Time `json:"time"`
+	// PreTokens is the context size before compaction as reported by the
+	// CLI, if it reported one.
+	PreTokens int64 `json:"pre_tokens,omitempty"`
+	// ContextTokens is klaus' estimate of the context size before
+	// compaction (see AutoCompactOptions).
+	ContextTokens int64 `json:"context_tokens,omitempty"`
+}
+
+// compactRequest is a /compact command written to stdin whose result has
+// not been read yet. The CLI answers commands in order, so the oldest
+// request owns the next compact boundary and result.
+type compactRequest struct {
+	trigger string
+	// waiter receives the outcome of a manual compaction; nil for
+	// automatic ones.
+	waiter chan compactOutcome
+	// prevStatus is restored when a manual compaction completes.
+	prevStatus ProcessStatus
+	// event is set when the compact boundary arrives.
+	event *CompactionEvent
+}
+
+type compactOutcome struct {
+	event CompactionEvent
+	err   error
+}
+
+// parseCompactBoundary extracts the compaction metadata of a compact
+// boundary message.
+func parseCompactBoundary(raw []byte) (trigger string, preTokens int64) {
+	var envelope struct {
+		Metadata struct {
+			Trigger   string `json:"trigger"`
+			PreTokens int64  `json:"pre_tokens"`
+		} `json:"compact_metadata"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return "", 0
+	}
+	return envelope.Metadata.Trigger, envelope.Metadata.PreTokens
+}
+
+// Compact sends /compact with optional instructions to the running session
+// and waits until the CLI has replaced the conversation with a summary. The
+// session must be idle; the status is busy while compacting and returns to
+// its previous value afterwards, so a completed result stays available.
+func (p *PersistentProcess) Compact(ctx context.Context, instructions string) (CompactionEvent, error) {
+	p.mu.Lock()
+	if p.stdin == nil {
+		p.mu.Unlock()
+		return CompactionEvent{}, errors.New("no running session")
+	}
+	if p.status == ProcessStatusBusy || p.status == ProcessStatusStarting {
+		p.mu.Unlock()
+		return CompactionEvent{}, ErrBusy
+	}
+	waiter := make(chan compactOutcome, 1)
+	p.compactQueue = append(p.compactQueue, compactRequest{
+		trigger:    CompactTriggerManual,
+		waiter:     waiter,
+		prevStatus: p.status,
+	})
+	prev := p.status
+	p.status = ProcessStatusBusy
+	p.changes.notify()
+	stdin := p.stdin
+	rec := p.recorder
+	p.mu.Unlock()
+	metrics.SetProcessStatus(string(ProcessStatusBusy))
+
+	if err := p.writeCompact(stdin, rec, instructions); err != nil {
+		p.mu.Lock()
+		if n := len(p.compactQueue); n > 0 {
+			p.compactQueue = p.compactQueue[:n-1]
+		}
+		if p.status == ProcessStatusBusy {
+			p.status = prev
+			p.changes.notify()
+		}
+		p.mu.Unlock()
+		metrics.SetProcessStatus(string(prev))
+		return CompactionEvent{}, fmt.Errorf("failed to write to stdin: %w", err)
+	}
+
+	select {
+	case out := <-waiter:
+		return out.event, out.err
+	case <-ctx.Done():
+		// The compaction itself continues in the session.
+		return CompactionEvent{}, ctx.Err()
+	}
+}
+
+// writeCompact writes a /compact command to stdin.
+func (p *PersistentProcess) writeCompact(stdin io.Writer, rec *recorder, instructions string) error {
+	command := strings.TrimSpace("/compact " + strings.TrimSpace(instructions))
+	data, err := marshalStdinMessage(command)
+	if err != nil {
+		return err
+	}
+	rec.Prompt(command)
+	return p.writeStdin(stdin, data)
+}
+
+// handleCompactBoundary attributes a compact boundary to the oldest pending
+// compaction, or records a compaction the CLI started on its own. The caller
+// must hold p.mu.
+func (p *PersistentProcess) handleCompactBoundary(msg StreamMessage) {
+	cliTrigger, preTokens := parseCompactBoundary(msg.Raw)
+	event := CompactionEvent{
+		Trigger:       CompactTriggerCLI,
+		Time:          time.Now().UTC(),
+		PreTokens:     preTokens,
+		ContextTokens: p.contextTokens,
+	}
+	if len(p.compactQueue) > 0 {
+		event.Trigger = p.compactQueue[0].trigger
+		p.compactQueue[0].event = &event
+		return
+	}
+	if cliTrigger == CompactTriggerManual {
+		// Someone else sent /compact; count it as manual.
+		event.Trigger = CompactTriggerManual
+	}
+	p.recordCompaction(event)
+}
+
+// completeCompaction consumes the result of the oldest pending compaction.
+// It reports false when no compaction is pending, in which case the result
+// belongs to a prompt. The caller must hold p.mu.
+func (p *PersistentProcess) completeCompaction(msg StreamMessage) bool {
+	if len(p.compactQueue) == 0 {
+		return false
+	}
+	req := p.compactQueue[0]
+	p.compactQueue = p.compactQueue[1:]
+
+	var out compactOutcome
+	switch {
+	case msg.IsError:
+		out.err = fmt.Errorf("compaction failed: %s", msg.Result)
+	case req.event != nil:
+		out.event = *req.event
+	default:
+		// The CLI did not announce a boundary; record the compaction with
+		// what klaus knows.
+		out.event = CompactionEvent{Trigger: req.trigger, Time: time.Now().UTC(), ContextTokens: p.contextTokens}
+	}
+	if out.err == nil {
+		p.recordCompaction(out.event)
+	}
+
+	if req.trigger == CompactTriggerManual && p.status == ProcessStatusBusy {
+		p.status = req.prevStatus
+		p.changes.notify()
+	}
+	if req.waiter != nil {
+		req.waiter <- out
+	}
+	return true
+}
+
+// recordCompaction appends a compaction to the session history and resets
+// the context estimate. The caller must hold p.mu.
+func (p *PersistentProcess) recordCompaction(event CompactionEvent) {
+	p.compactions = append(p.compactions, event)
+	p.contextTokens = 0
+	metrics.RecordCompaction(event.Trigger)
+}
+
+// failPendingCompactions resolves all pending compactions with err. The
+// caller must hold p.mu.
+func (p *PersistentProcess) failPendingCompactions(err error) {
+	for _, req := range p.compactQueue {
+		if req.waiter != nil {
+			req.waiter <- compactOutcome{err: err}
+		}
+	}
+	p.compactQueue = nil
+}
+
+// Compact is not supported in agent mode, where every prompt starts with a
+// fresh context.
+func (p *Process) Compact(context.Context, string) (CompactionEvent, error) {
+	return CompactionEvent{}, ErrCompactUnsupported
+}
+
+func copyCompactions(events []CompactionEvent) []CompactionEvent {
+	if len(events) == 0 {
+		return nil
+	}
+	cp := make([]CompactionEvent, len(events))
+	copy(cp, events)
+	return cp
+}
@@ -0,0 +1,143 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// compactFakeClaude answers /compact with a compact boundary and an empty
+// result, like the CLI, and every other prompt with a 600 input token turn.
+// A prompt containing "huge" makes it compact on its own first.
+const compactFakeClaude = `while IFS= read -r line; do
+  case "$line" in
+    *'"/compact'*)
+      echo '{"type":"system","subtype":"compact_boundary","compact_metadata":{"trigger":"manual","pre_tokens":4321}}'
+      echo '{"type":"result","subtype":"success","result":""}' ;;
+    *)
+      case "$line" in
+        *huge*) echo '{"type":"system","subtype":"compact_boundary","compact_metadata":{"trigger":"auto","pre_tokens":190000}}' ;;
+      esac
+      echo '{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":600,"output_tokens":5}}}'
+      echo '{"type":"result","subtype":"success","result":"ok"}' ;;
+  esac
+done
+`
+
+func startCompactProcess(t *testing.T, auto AutoCompactOptions) *PersistentProcess {
+	t.Helper()
+	installFakeClaude(t, compactFakeClaude)
+	opts := DefaultOptions()
+	opts.ResultDir = t.TempDir()
+	opts.AutoCompact = auto
+	p := NewPersistentProcess(opts)
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { _ = p.Stop() })
+	return p
+}
+
+func TestPersistentProcess_Compact(t *testing.T) {
+	p := startCompactProcess(t, AutoCompactOptions{})
+	if _, _, err := p.RunSyncWithOptions(context.Background(), "hello", nil); err != nil {
+		t.Fatalf("RunSyncWithOptions: %v", err)
+	}
+	if got := p.Status().ContextTokens; got != 600 {
+		t.Fatalf("context tokens = %d, want 600", got)
+	}
+
+	event, err := p.Compact(context.Background(), "keep the plan")
+	if err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	if event.Trigger != CompactTriggerManual || event.PreTokens != 4321 || event.ContextTokens != 600 {
+		t.Errorf("unexpected event: %+v", event)
+	}
+
+	status := p.Status()
+	if status.Status != ProcessStatusIdle || status.ContextTokens != 0 || status.Compactions != 1 {
+		t.Errorf("unexpected status after compaction: %+v", status)
+	}
+	detail := p.ResultDetail()
+	if len(detail.Compactions) != 1 || detail.Compactions[0].Trigger != CompactTriggerManual {
+		t.Errorf("compaction missing from result detail: %+v", detail.Compactions)
+	}
+
+	// The session keeps working after compaction.
+	text, _, err := p.RunSyncWithOptions(context.Background(), "again", nil)
+	if err != nil || text != "ok" {
+		t.Fatalf("prompt after compaction: %q, %v", text, err)
+	}
+}
+
+func TestPersistentProcess_AutoCompact(t *testing.T) {
+	p := startCompactProcess(t, AutoCompactOptions{Threshold: 0.5, ContextWindow: 1000})
+
+	if _, _, err := p.RunSyncWithOptions(context.Background(), "first", nil); err != nil {
+		t.Fatalf("RunSyncWithOptions: %v", err)
+	}
+	if got := p.Status().Compactions; got != 0 {
+		t.Fatalf("compacted below the threshold: %d", got)
+	}
+
+	// 600 tokens passed 50% of 1000: the next prompt is preceded by
+	// /compact, and its response is not cut short by the compaction result.
+	text, _, err := p.RunSyncWithOptions(context.Background(), "second", nil)
+	if err != nil || text != "ok" {
+		t.Fatalf("prompt with auto compaction: %q, %v", text, err)
+	}
+	detail := p.ResultDetail()
+	if len(detail.Compactions) != 1 || detail.Compactions[0].Trigger != CompactTriggerAuto {
+		t.Fatalf("expected one auto compaction, got %+v", detail.Compactions)
+	}
+	if got := p.Status().ContextTokens; got != 600 {
+		t.Errorf("context tokens after compaction = %d, want 600", got)
+	}
+}
+
+func TestPersistentProcess_RecordsCLICompaction(t *testing.T) {
+	p := startCompactProcess(t, AutoCompactOptions{})
+
+	if _, _, err := p.RunSyncWithOptions(context.Background(), "huge task", nil); err != nil {
+		t.Fatalf("RunSyncWithOptions: %v", err)
+	}
+	detail := p.ResultDetail()
+	if len(detail.Compactions) != 1 || detail.Compactions[0].Trigger != CompactTriggerCLI || detail.Compactions[0].PreTokens != 190000 {
+		t.Fatalf("expected one cli compaction, got %+v", detail.Compactions)
+	}
+}
+
+func TestPersistentProcess_CompactErrors(t *testing.T) {
+	p := NewPersistentProcess(DefaultOptions())
+	if _, err := p.Compact(context.Background(), ""); err == nil || !strings.Contains(err.Error(), "no running session") {
+		t.Errorf("expected no running session error, got %v", err)
+	}
+	if _, err := NewProcess(DefaultOptions()).Compact(context.Background(), ""); !errors.Is(err, ErrCompactUnsupported) {
+		t.Errorf("expected ErrCompactUnsupported, got %v", err)
+	}
+}
+
+func TestAutoCompactOptions(t *testing.T) {
+	if (AutoCompactOptions{}).due(1 << 40) {
+		t.Error("zero value must never compact")
+	}
+	auto := AutoCompactOptions{Threshold: 0.8}
+	if auto.due(159_999) || !auto.due(160_000) {
+		t.Error("expected threshold at 80% of the default window")
+	}
+
+	if err := auto.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := AutoCompactOptions{Threshold: 1.5, ContextWindow: -1}.Validate()
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{"threshold must be between 0 and 1", "contextWindow must be >= 0"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
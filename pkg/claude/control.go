@@ -0,0 +1,297 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrSessionControlUnsupported is returned by ConfigureSession when there is
+// no long-running session to configure (agent mode).
+var ErrSessionControlUnsupported = errors.New("session configuration requires chat mode")
+
+// ErrInterruptUnsupported is returned by Interrupt when the current turn
+// cannot be interrupted without stopping the agent: in agent mode, and when
+// the CLI does not have CapabilityInterrupt.
+var ErrInterruptUnsupported = errors.New("interrupting a turn is not supported")
+
+// MessageTypeControlResponse is the stdout reply of the CLI to a control
+// request written to its stdin.
+const MessageTypeControlResponse MessageType = "control_response"
+
+// Control request subtypes of the stream-json control protocol.
+const (
+	controlSetModel          = "set_model"
+	controlSetPermissionMode = "set_permission_mode"
+	controlGetContextUsage   = "get_context_usage"
+	controlInterrupt         = "interrupt"
+)
+
+// controlTimeout bounds how long a control request waits for its response.
+const controlTimeout = 30 * time.Second
+
+// SessionConfig describes changes to a live session. Empty fields are left
+// unchanged.
+type SessionConfig struct {
+	// Model switches the model for subsequent turns.
+	Model string
+	// PermissionMode switches the permission mode, e.g. from "plan" to
+	// "acceptEdits".
+	PermissionMode string
+	// ContextUsage queries the context window usage of the session.
+	ContextUsage bool
+}
+
+// SessionSettings reports the effective settings of a session after
+// ConfigureSession.
+type SessionSettings struct {
+	Model          string `json:"model,omitempty"`
+	PermissionMode string `json:"permission_mode,omitempty"`
+	// ContextUsage is the CLI's context usage report, passed through as is.
+	ContextUsage json.RawMessage `json:"context_usage,omitempty"`
+}
+
+// Validate checks the requested changes against the valid values and the
+// override policy. Changing the session is treated like a per-run override,
+// so the model must pass the policy's model checks, and the permission mode
+// must be one of the policy's PermissionModes.
+func (c SessionConfig) Validate(policy OverridePolicy, base Options) error {
+	if c.Model == "" && c.PermissionMode == "" && !c.ContextUsage {
+		return errors.New("nothing to configure: set model, permission_mode or context_usage")
+	}
+	var errs []error
+	if c.PermissionMode != "" {
+		if err := ValidatePermissionMode(c.PermissionMode); err != nil {
+			errs = append(errs, err)
+		} else if err := policy.CheckPermissionMode(c.PermissionMode); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.Model != "" {
+		if err := policy.Check(base, &RunOptions{Model: c.Model}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := base.checkSessionCapabilities(c); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// checkSessionCapabilities rejects control requests the detected CLI does
+// not understand.
+func (o Options) checkSessionCapabilities(c SessionConfig) error {
+	var errs []error
+	require := func(set bool, c Capability, what string) {
+		if set && !o.supports(c) {
+			errs = append(errs, o.unsupported(c, what))
+		}
+	}
+	require(c.Model != "" || c.PermissionMode != "", CapabilitySessionControl, "session configuration")
+	require(c.ContextUsage, CapabilityContextUsage, "context usage")
+	return errors.Join(errs...)
+}
+
+// controlRequest is a stream-json control request written to stdin.
+type controlRequest struct {
+	Type      string         `json:"type"`
+	RequestID string         `json:"request_id"`
+	Request   map[string]any `json:"request"`
+}
+
+// controlResponse is the payload of a control_response message.
+type controlResponse struct {
+	Subtype   string          `json:"subtype"`
+	RequestID string          `json:"request_id"`
+	Response  json.RawMessage `json:"response,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// parseControlResponse extracts the response payload of a control_response
+// message.
+func parseControlResponse(raw []byte) (controlResponse, error) {
+	var envelope struct {
+		Response controlResponse `json:"response"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return controlResponse{}, err
+	}
+	if envelope.Response.RequestID == "" {
+		return controlResponse{}, errors.New("control response without request_id")
+	}
+	return envelope.Response, nil
+}
+
+// controlResult resolves a pending control request.
+type controlResult struct {
+	response json.RawMessage
+	err      error
+}
+
+// ConfigureSession changes the model or permission mode of the running
+// subprocess through the stream-json control protocol, without restarting
+// it, and optionally queries its context usage. Changes are also applied to
+// the options used when the subprocess is restarted, including options set
+// later by UpdateOptions. It may be called while
+// a prompt is running; the change takes effect at the next turn.
+func (p *PersistentProcess) ConfigureSession(ctx context.Context, cfg SessionConfig) (SessionSettings, error) {
+	p.mu.RLock()
+	opts := p.opts
+	p.mu.RUnlock()
+	if err := cfg.Validate(opts.OverridePolicy, opts); err != nil {
+		return SessionSettings{}, err
+	}
+
+	var settings SessionSettings
+	if cfg.PermissionMode != "" {
+		if _, err := p.control(ctx, map[string]any{"subtype": controlSetPermissionMode, "mode": cfg.PermissionMode}); err != nil {
+			return p.sessionSettings(), fmt.Errorf("setting permission mode: %w", err)
+		}
+		p.mu.Lock()
+		p.opts.PermissionMode = cfg.PermissionMode
+		p.sessionPermissionMode = cfg.PermissionMode
+		p.mu.Unlock()
+	}
+	if cfg.Model != "" {
+		if _, err := p.control(ctx, map[string]any{"subtype": controlSetModel, "model": cfg.Model}); err != nil {
+			return p.sessionSettings(), fmt.Errorf("setting model: %w", err)
+		}
+		p.mu.Lock()
+		p.opts.Model = cfg.Model
+		p.sessionModel = cfg.Model
+		p.mu.Unlock()
+	}
+	if cfg.ContextUsage {
+		usage, err := p.control(ctx, map[string]any{"subtype": controlGetContextUsage})
+		if err != nil {
+			return p.sessionSettings(), fmt.Errorf("querying context usage: %w", err)
+		}
+		settings.ContextUsage = usage
+	}
+
+	current := p.sessionSettings()
+	settings.Model = current.Model
+	settings.PermissionMode = current.PermissionMode
+	return settings, nil
+}
+
+// Interrupt ends the current turn through the stream-json control protocol
+// without killing the subprocess, so that the session keeps its context.
+// The CLI ends the turn with a result message.
+func (p *PersistentProcess) Interrupt(ctx context.Context) error {
+	if opts := p.options(); !opts.supports(CapabilityInterrupt) {
+		return fmt.Errorf("%w: %w", ErrInterruptUnsupported, opts.unsupported(CapabilityInterrupt, "interrupt"))
+	}
+	if _, err := p.control(ctx, map[string]any{"subtype": controlInterrupt}); err != nil {
+		return fmt.Errorf("interrupting the turn: %w", err)
+	}
+	return nil
+}
+
+// sessionSettings returns the effective model and permission mode.
+func (p *PersistentProcess) sessionSettings() SessionSettings {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return SessionSettings{Model: p.opts.Model, PermissionMode: p.opts.PermissionMode}
+}
+
+// control sends one control request and waits for its response.
+func (p *PersistentProcess) control(ctx context.Context, request map[string]any) (json.RawMessage, error) {
+	p.mu.Lock()
+	if p.stdin == nil {
+		p.mu.Unlock()
+		return nil, errors.New("no running session")
+	}
+	p.controlSeq++
+	id := fmt.Sprintf("klaus-%d", p.controlSeq)
+	resultCh := make(chan controlResult, 1)
+	p.pendingControl[id] = resultCh
+	stdin := p.stdin
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.pendingControl, id)
+		p.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(controlRequest{Type: "control_request", RequestID: id, Request: request})
+	if err != nil {
+		return nil, err
+	}
+	if err := p.writeStdin(stdin, append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to write to stdin: %w", err)
+	}
+
+	timer := time.NewTimer(controlTimeout)
+	defer timer.Stop()
+	select {
+	case res := <-resultCh:
+		return res.response, res.err
+	case <-timer.C:
+		return nil, fmt.Errorf("no response to %s within %s", request["subtype"], controlTimeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// handleControlResponse resolves the pending control request a
+// control_response message answers.
+func (p *PersistentProcess) handleControlResponse(raw []byte) {
+	resp, err := parseControlResponse(raw)
+	if err != nil {
+		return
+	}
+	p.mu.Lock()
+	ch := p.pendingControl[resp.RequestID]
+	p.mu.Unlock()
+	if ch == nil {
+		return
+	}
+	res := controlResult{response: resp.Response}
+	if resp.Subtype == "error" {
+		res.err = errors.New(resp.Error)
+	}
+	select {
+	case ch <- res:
+	default:
+		// Duplicate response; the first one wins.
+	}
+}
+
+// writeStdin writes one complete line to the subprocess stdin. Prompts and
+// control requests are written from different goroutines, so writes are
+// serialized to keep lines from interleaving.
+func (p *PersistentProcess) writeStdin(stdin io.Writer, line []byte) error {
+	p.stdinMu.Lock()
+	defer p.stdinMu.Unlock()
+	_, err := stdin.Write(line)
+	return err
+}
+
+// failPendingControl resolves all pending control requests with err. The
+// caller must hold p.mu.
+func (p *PersistentProcess) failPendingControl(err error) {
+	for id, ch := range p.pendingControl {
+		select {
+		case ch <- controlResult{err: err}:
+		default:
+		}
+		delete(p.pendingControl, id)
+	}
+}
+
+// Interrupt is not supported in agent mode, where a run is its own
+// subprocess; use Stop instead.
+func (p *Process) Interrupt(context.Context) error {
+	return fmt.Errorf("%w in agent mode", ErrInterruptUnsupported)
+}
+
+// ConfigureSession is not supported in agent mode, where every prompt runs
+// in a new subprocess; use per-run overrides instead.
+func (p *Process) ConfigureSession(context.Context, SessionConfig) (SessionSettings, error) {
+	return SessionSettings{}, ErrSessionControlUnsupported
+}
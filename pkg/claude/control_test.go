@@ -0,0 +1,158 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// controlFakeClaude answers control requests on stdin like the CLI does.
+// Setting the model "broken" is answered with an error.
+const controlFakeClaude = `while IFS= read -r line; do
+  case "$line" in
+    *'"control_request"'*)
+      id=$(printf '%s' "$line" | sed 's/.*"request_id":"\([^"]*\)".*/\1/')
+      case "$line" in
+        *get_context_usage*) echo '{"type":"control_response","response":{"subtype":"success","request_id":"'"$id"'","response":{"total_tokens":1200,"max_tokens":200000}}}' ;;
+        *'"model":"broken"'*) echo '{"type":"control_response","response":{"subtype":"error","request_id":"'"$id"'","error":"unknown model"}}' ;;
+        *) echo '{"type":"control_response","response":{"subtype":"success","request_id":"'"$id"'"}}' ;;
+      esac ;;
+  esac
+done
+`
+
+func startControlProcess(t *testing.T, policy OverridePolicy) *PersistentProcess {
+	t.Helper()
+	installFakeClaude(t, controlFakeClaude)
+	opts := DefaultOptions()
+	opts.ResultDir = t.TempDir()
+	opts.PermissionMode = PermissionModePlan
+	opts.OverridePolicy = policy
+	p := NewPersistentProcess(opts)
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { _ = p.Stop() })
+	return p
+}
+
+func TestPersistentProcess_ConfigureSession(t *testing.T) {
+	p := startControlProcess(t, OverridePolicy{Allow: []string{OverrideModel}, PermissionModes: []string{PermissionModeAccept}})
+
+	settings, err := p.ConfigureSession(context.Background(), SessionConfig{
+		Model:          "opus",
+		PermissionMode: PermissionModeAccept,
+		ContextUsage:   true,
+	})
+	if err != nil {
+		t.Fatalf("ConfigureSession: %v", err)
+	}
+	if settings.Model != "opus" || settings.PermissionMode != PermissionModeAccept {
+		t.Errorf("unexpected settings: %+v", settings)
+	}
+	if !strings.Contains(string(settings.ContextUsage), `"total_tokens":1200`) {
+		t.Errorf("unexpected context usage: %s", settings.ContextUsage)
+	}
+
+	status := p.Status()
+	if status.Model != "opus" || status.PermissionMode != PermissionModeAccept {
+		t.Errorf("status does not reflect the new settings: model=%q permission_mode=%q", status.Model, status.PermissionMode)
+	}
+	if status.MessageCount != 0 {
+		t.Errorf("control responses must not be counted as messages, got %d", status.MessageCount)
+	}
+	// Restarts keep the new settings.
+	if args := strings.Join(p.opts.PersistentArgs(), " "); !strings.Contains(args, "--model opus") || !strings.Contains(args, "--permission-mode acceptEdits") {
+		t.Errorf("restart args do not carry the new settings: %s", args)
+	}
+}
+
+func TestPersistentProcess_ConfigureSessionError(t *testing.T) {
+	p := startControlProcess(t, OverridePolicy{Allow: []string{OverrideModel}})
+
+	_, err := p.ConfigureSession(context.Background(), SessionConfig{Model: "broken"})
+	if err == nil || !strings.Contains(err.Error(), "unknown model") {
+		t.Fatalf("expected CLI error, got %v", err)
+	}
+	if got := p.Status().Model; got != "" {
+		t.Errorf("model changed despite error: %q", got)
+	}
+}
+
+func TestPersistentProcess_ConfigureSessionPolicy(t *testing.T) {
+	p := NewPersistentProcess(DefaultOptions())
+
+	_, err := p.ConfigureSession(context.Background(), SessionConfig{Model: "opus", PermissionMode: PermissionModeAccept})
+	if !errors.Is(err, ErrOverrideDenied) {
+		t.Fatalf("expected policy denial, got %v", err)
+	}
+	for _, want := range []string{"model may not be overridden", "permission mode may not be changed"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+
+	if _, err := p.ConfigureSession(context.Background(), SessionConfig{}); err == nil {
+		t.Error("expected error for empty config")
+	}
+	if _, err := p.ConfigureSession(context.Background(), SessionConfig{ContextUsage: true}); err == nil || !strings.Contains(err.Error(), "no running session") {
+		t.Errorf("expected no running session error, got %v", err)
+	}
+}
+
+func TestSessionConfig_ValidateCapabilities(t *testing.T) {
+	opts := DefaultOptions()
+	opts.CLIVersion = &CLIVersion{2, 0, 5}
+	policy := OverridePolicy{PermissionModes: []string{PermissionModeAccept, PermissionModePlan}}
+
+	if err := (SessionConfig{PermissionMode: PermissionModeAccept}).Validate(policy, opts); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := SessionConfig{ContextUsage: true}.Validate(policy, opts)
+	if err == nil || !strings.Contains(err.Error(), "context usage requires claude >= 2.1.0") {
+		t.Errorf("expected capability error, got %v", err)
+	}
+	if err := (SessionConfig{PermissionMode: "yolo"}).Validate(policy, opts); err == nil {
+		t.Error("expected invalid permission mode error")
+	}
+	err = SessionConfig{PermissionMode: PermissionModeBypass}.Validate(policy, opts)
+	if !errors.Is(err, ErrOverrideDenied) || !strings.Contains(err.Error(), `"bypassPermissions" is not one of acceptEdits, plan`) {
+		t.Errorf("expected policy denial, got %v", err)
+	}
+}
+
+func TestProcess_ConfigureSessionUnsupported(t *testing.T) {
+	_, err := NewProcess(DefaultOptions()).ConfigureSession(context.Background(), SessionConfig{Model: "opus"})
+	if !errors.Is(err, ErrSessionControlUnsupported) {
+		t.Errorf("expected ErrSessionControlUnsupported, got %v", err)
+	}
+}
+
+func TestPersistentProcess_Interrupt(t *testing.T) {
+	p := startControlProcess(t, OverridePolicy{})
+	if err := p.Interrupt(context.Background()); err != nil {
+		t.Fatalf("Interrupt: %v", err)
+	}
+
+	opts := DefaultOptions()
+	opts.CLIVersion = &CLIVersion{0, 2, 9}
+	err := NewPersistentProcess(opts).Interrupt(context.Background())
+	if !errors.Is(err, ErrInterruptUnsupported) || !strings.Contains(err.Error(), "interrupt requires claude >= 1.0.0 (found 0.2.9)") {
+		t.Errorf("expected ErrInterruptUnsupported, got %v", err)
+	}
+}
+
+func TestStopRun_FallsBackToStop(t *testing.T) {
+	p := NewProcess(DefaultOptions())
+	if err := p.Interrupt(context.Background()); !errors.Is(err, ErrInterruptUnsupported) {
+		t.Fatalf("expected ErrInterruptUnsupported, got %v", err)
+	}
+
+	ch := make(chan StreamMessage, 1)
+	ch <- StreamMessage{Type: MessageTypeAssistant}
+	if err := StopRun(context.Background(), p, ch); err != nil {
+		t.Errorf("StopRun: %v", err)
+	}
+	close(ch)
+}
@@ -0,0 +1,90 @@
+package claude
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// ResourceLimits bounds the resources available to the Claude subprocess and
+// everything it spawns (Bash commands, test runners, dev servers). Zero
+// values mean no limit.
+//
+// The rlimits (CPUSeconds, AddressSpaceMiB, OpenFiles, Processes) are set in
+// the subprocess before the CLI is executed and are inherited by its
+// children. MemoryMaxMiB and CPUs are enforced through a cgroup v2 child
+// group, which needs a delegated cgroup hierarchy; without one, runs fail to
+// start. All limits are Linux-only and rejected by Validate elsewhere.
+type ResourceLimits struct {
+	// CPUSeconds is RLIMIT_CPU: CPU time per process before SIGXCPU/SIGKILL.
+	// In chat mode it applies to the lifetime of the long-running subprocess.
+	CPUSeconds int
+	// AddressSpaceMiB is RLIMIT_AS: virtual memory per process. Node.js
+	// reserves large address ranges up front, so values below a few GiB
+	// prevent the CLI from starting.
+	AddressSpaceMiB int
+	// OpenFiles is RLIMIT_NOFILE: open file descriptors per process.
+	OpenFiles int
+	// Processes is RLIMIT_NPROC. The kernel counts all processes of the
+	// user, including klaus itself.
+	Processes int
+	// MemoryMaxMiB is the cgroup v2 memory.max for the subprocess tree.
+	MemoryMaxMiB int
+	// CPUs is the cgroup v2 cpu.max quota in cores (e.g. 1.5).
+	CPUs float64
+}
+
+// hasRlimits reports whether any rlimit is configured.
+func (l ResourceLimits) hasRlimits() bool {
+	return l.CPUSeconds > 0 || l.AddressSpaceMiB > 0 || l.OpenFiles > 0 || l.Processes > 0
+}
+
+// hasCgroupLimits reports whether any cgroup limit is configured.
+func (l ResourceLimits) hasCgroupLimits() bool {
+	return l.MemoryMaxMiB > 0 || l.CPUs > 0
+}
+
+// Validate checks that no limit is negative and that no limit is set on a
+// platform that does not support them.
+func (l ResourceLimits) Validate() error {
+	var errs []error
+	if runtime.GOOS != "linux" && (l.hasRlimits() || l.hasCgroupLimits()) {
+		errs = append(errs, fmt.Errorf("resource limits are only supported on Linux, not %s", runtime.GOOS))
+	}
+	for _, f := range []struct {
+		name  string
+		value float64
+	}{
+		{"cpuSeconds", float64(l.CPUSeconds)},
+		{"addressSpaceMiB", float64(l.AddressSpaceMiB)},
+		{"openFiles", float64(l.OpenFiles)},
+		{"processes", float64(l.Processes)},
+		{"memoryMaxMiB", float64(l.MemoryMaxMiB)},
+		{"cpus", l.CPUs},
+	} {
+		if f.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must be >= 0, got %v", f.name, f.value))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// startCommand starts cmd in its own process group, inside the agent cgroup
+// when one is configured, with the configured rlimits. It fails if any of
+// the limits cannot be applied, so a run never proceeds without the limits
+// the operator asked for.
+func startCommand(cmd *exec.Cmd, limits ResourceLimits, cg *agentCgroup) error {
+	setProcessGroup(cmd)
+	if limits.hasRlimits() {
+		if err := execWithRlimits(cmd, limits); err != nil {
+			return fmt.Errorf("applying resource limits: %w", err)
+		}
+	}
+	release, err := cg.attach(cmd)
+	if err != nil {
+		return fmt.Errorf("starting subprocess in cgroup: %w", err)
+	}
+	defer release()
+	return cmd.Start()
+}
@@ -0,0 +1,274 @@
+//go:build linux
+
+package claude
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// cgroupMountPoint is where the unified cgroup v2 hierarchy is mounted.
+	cgroupMountPoint = "/sys/fs/cgroup"
+	// selfCgroupPath lists the cgroup membership of the klaus process.
+	selfCgroupPath = "/proc/self/cgroup"
+	// cgroupSupervisorName is the leaf group that klaus moves its own
+	// processes into. cgroup v2 only allows enabling controllers for child
+	// groups when the parent itself holds no processes.
+	cgroupSupervisorName = "klaus"
+	// cgroupAgentName is the group the Claude subprocess tree runs in.
+	cgroupAgentName = "claude"
+	// cpuMaxPeriod is the cpu.max period in microseconds.
+	cpuMaxPeriod = 100000
+)
+
+// The rlimits are set by a copy of the klaus binary that runs in the
+// subprocess before the Claude CLI: execWithRlimits makes cmd start
+// /proc/self/exe with these environment variables, and init sets the limits
+// and then execs the CLI in the same process. This way they apply from the
+// CLI's first instruction, without lowering the limits of klaus itself.
+const (
+	// rlimitExecPathEnv holds the path of the binary to exec.
+	rlimitExecPathEnv = "KLAUS_RLIMIT_EXEC_PATH"
+	// rlimitExecLimitsEnv holds the JSON-encoded ResourceLimits to set.
+	rlimitExecLimitsEnv = "KLAUS_RLIMIT_EXEC_LIMITS"
+)
+
+func init() {
+	if path, ok := os.LookupEnv(rlimitExecPathEnv); ok {
+		execRlimited(path)
+	}
+}
+
+// execRlimited sets the rlimits passed by execWithRlimits and replaces the
+// current process with path. It never returns; if the limits cannot be set,
+// the process exits without running path.
+func execRlimited(path string) {
+	encoded := os.Getenv(rlimitExecLimitsEnv)
+	_ = os.Unsetenv(rlimitExecPathEnv)
+	_ = os.Unsetenv(rlimitExecLimitsEnv)
+
+	var l ResourceLimits
+	if err := json.Unmarshal([]byte(encoded), &l); err != nil {
+		fmt.Fprintf(os.Stderr, "klaus: invalid resource limits: %v\n", err)
+		os.Exit(126)
+	}
+	if err := setRlimits(l); err != nil {
+		fmt.Fprintf(os.Stderr, "klaus: applying resource limits: %v\n", err)
+		os.Exit(126)
+	}
+	err := syscall.Exec(path, os.Args, os.Environ()) //nolint:gosec // path is the Claude binary chosen by the parent klaus
+	fmt.Fprintf(os.Stderr, "klaus: exec %s: %v\n", path, err)
+	os.Exit(127)
+}
+
+// execWithRlimits rewrites cmd to start through the klaus binary, which sets
+// the configured rlimits before it execs the original command. cmd.Args is
+// kept, so the CLI sees the same argv.
+func execWithRlimits(cmd *exec.Cmd, l ResourceLimits) error {
+	encoded, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(slices.Clip(env),
+		rlimitExecPathEnv+"="+cmd.Path,
+		rlimitExecLimitsEnv+"="+string(encoded))
+	cmd.Path = "/proc/self/exe"
+	return nil
+}
+
+// setRlimits sets the configured rlimits on the calling process. It uses
+// syscall.Setrlimit rather than prlimit so that the Go runtime does not
+// restore its original RLIMIT_NOFILE on exec.
+func setRlimits(l ResourceLimits) error {
+	var errs []error
+	set := func(resource int, name string, value uint64) {
+		if value == 0 {
+			return
+		}
+		rl := syscall.Rlimit{Cur: value, Max: value}
+		if err := syscall.Setrlimit(resource, &rl); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	set(unix.RLIMIT_CPU, "cpuSeconds", uint64(l.CPUSeconds))
+	set(unix.RLIMIT_NOFILE, "openFiles", uint64(l.OpenFiles))
+	set(unix.RLIMIT_NPROC, "processes", uint64(l.Processes))
+	// The address space limit goes last: the Go runtime may still allocate
+	// until the exec.
+	set(unix.RLIMIT_AS, "addressSpaceMiB", uint64(l.AddressSpaceMiB)<<20)
+	return errors.Join(errs...)
+}
+
+// agentCgroup is a cgroup v2 group with memory and CPU limits that the
+// Claude subprocess is started in.
+type agentCgroup struct {
+	dir string
+	// err is why the group could not be set up; attach returns it, so that
+	// no run starts without the configured limits.
+	err error
+}
+
+// newAgentCgroup sets up the agent cgroup when cgroup limits are configured.
+// When cgroup v2 is not available or not delegated to klaus, it logs an
+// error and returns a group whose attach fails.
+func newAgentCgroup(l ResourceLimits) *agentCgroup {
+	if !l.hasCgroupLimits() {
+		return nil
+	}
+	cg, err := setupAgentCgroup(cgroupMountPoint, selfCgroupPath, l)
+	if err != nil {
+		slog.Error("claude: cgroup v2 limits unavailable, runs will fail to start", "error", err)
+		return &agentCgroup{err: fmt.Errorf("cgroup v2 limits unavailable: %w", err)}
+	}
+	slog.Info("claude: subprocess cgroup configured", "path", cg.dir,
+		"memory_max_mib", l.MemoryMaxMiB, "cpus", l.CPUs)
+	return cg
+}
+
+// setupAgentCgroup creates the agent group next to the group klaus runs in
+// and writes its limits. root is the cgroup mount point and selfCgroup the
+// path of the /proc/<pid>/cgroup file; both are parameters for testing.
+// Setup is idempotent.
+func setupAgentCgroup(root, selfCgroup string, l ResourceLimits) (*agentCgroup, error) {
+	data, err := os.ReadFile(selfCgroup) //nolint:gosec // fixed procfs path
+	if err != nil {
+		return nil, fmt.Errorf("reading own cgroup: %w", err)
+	}
+	var rel string
+	for _, line := range strings.Split(string(data), "\n") {
+		if after, ok := strings.CutPrefix(line, "0::"); ok {
+			rel = after
+			break
+		}
+	}
+	if rel == "" {
+		return nil, errors.New("cgroup v2 unified hierarchy not in use")
+	}
+	// After a previous setup klaus already lives in the supervisor leaf.
+	if filepath.Base(rel) == cgroupSupervisorName {
+		rel = filepath.Dir(rel)
+	}
+	parent := filepath.Join(root, rel)
+
+	var controllers []string
+	if l.MemoryMaxMiB > 0 {
+		controllers = append(controllers, "memory")
+	}
+	if l.CPUs > 0 {
+		controllers = append(controllers, "cpu")
+	}
+	available, err := os.ReadFile(filepath.Join(parent, "cgroup.controllers")) //nolint:gosec // path under the cgroup mount
+	if err != nil {
+		return nil, fmt.Errorf("reading available controllers: %w", err)
+	}
+	for _, c := range controllers {
+		if !slices.Contains(strings.Fields(string(available)), c) {
+			return nil, fmt.Errorf("controller %q is not delegated to %s", c, parent)
+		}
+	}
+
+	enable := "+" + strings.Join(controllers, " +")
+	if err := writeCgroupFile(parent, "cgroup.subtree_control", enable); err != nil {
+		// The parent still holds processes (klaus itself); move them into a
+		// leaf group and try again.
+		if moveErr := moveProcsToLeaf(parent); moveErr != nil {
+			return nil, fmt.Errorf("enabling controllers: %w (moving processes: %v)", err, moveErr)
+		}
+		if err := writeCgroupFile(parent, "cgroup.subtree_control", enable); err != nil {
+			return nil, fmt.Errorf("enabling controllers: %w", err)
+		}
+	}
+
+	dir := filepath.Join(parent, cgroupAgentName)
+	if err := os.Mkdir(dir, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("creating agent cgroup: %w", err)
+	}
+	if l.MemoryMaxMiB > 0 {
+		if err := writeCgroupFile(dir, "memory.max", strconv.FormatUint(uint64(l.MemoryMaxMiB)<<20, 10)); err != nil {
+			return nil, err
+		}
+	}
+	if l.CPUs > 0 {
+		quota := int(l.CPUs * cpuMaxPeriod)
+		if err := writeCgroupFile(dir, "cpu.max", fmt.Sprintf("%d %d", quota, cpuMaxPeriod)); err != nil {
+			return nil, err
+		}
+	}
+	return &agentCgroup{dir: dir}, nil
+}
+
+// moveProcsToLeaf moves every process of the parent group into the
+// supervisor leaf group.
+func moveProcsToLeaf(parent string) error {
+	leaf := filepath.Join(parent, cgroupSupervisorName)
+	if err := os.Mkdir(leaf, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(parent, "cgroup.procs")) //nolint:gosec // path under the cgroup mount
+	if err != nil {
+		return err
+	}
+	for _, pid := range strings.Fields(string(data)) {
+		// Processes may exit while being moved.
+		if err := writeCgroupFile(leaf, "cgroup.procs", pid); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCgroupFile(dir, name, value string) error {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0o644); err != nil { //nolint:gosec // cgroup interface files
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// attach makes cmd start directly inside the cgroup, so that no child can
+// be forked before the limits apply. The returned function releases the
+// directory handle and must be called once cmd has started.
+func (c *agentCgroup) attach(cmd *exec.Cmd) (func(), error) {
+	if c == nil {
+		return func() {}, nil
+	}
+	if c.err != nil {
+		return func() {}, c.err
+	}
+	f, err := os.Open(c.dir)
+	if err != nil {
+		return func() {}, err
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(f.Fd())
+	return func() { _ = f.Close() }, nil
+}
+
+// kill terminates every process in the cgroup, including any that escaped
+// the process group via setsid.
+func (c *agentCgroup) kill() {
+	if c == nil || c.err != nil {
+		return
+	}
+	if err := writeCgroupFile(c.dir, "cgroup.kill", "1"); err != nil {
+		slog.Debug("claude: cgroup.kill failed", "error", err)
+	}
+}
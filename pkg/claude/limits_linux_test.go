@@ -0,0 +1,222 @@
+//go:build linux
+
+package claude
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCgroupTree creates a cgroup mount with a group "/pod" that delegates
+// the given controllers, and a /proc/self/cgroup file pointing at it.
+func fakeCgroupTree(t *testing.T, self, controllers string) (root, selfCgroup string) {
+	t.Helper()
+	root = t.TempDir()
+	pod := filepath.Join(root, "pod")
+	if err := os.MkdirAll(pod, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pod, "cgroup.controllers"), []byte(controllers+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	selfCgroup = filepath.Join(t.TempDir(), "cgroup")
+	if err := os.WriteFile(selfCgroup, []byte(self), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return root, selfCgroup
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path) //nolint:gosec // test fixture
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSetupAgentCgroup(t *testing.T) {
+	root, self := fakeCgroupTree(t, "0::/pod\n", "cpuset cpu io memory pids")
+
+	cg, err := setupAgentCgroup(root, self, ResourceLimits{MemoryMaxMiB: 512, CPUs: 1.5})
+	if err != nil {
+		t.Fatalf("setupAgentCgroup: %v", err)
+	}
+	want := filepath.Join(root, "pod", cgroupAgentName)
+	if cg.dir != want {
+		t.Errorf("dir = %q, want %q", cg.dir, want)
+	}
+	if got := readFile(t, filepath.Join(root, "pod", "cgroup.subtree_control")); got != "+memory +cpu" {
+		t.Errorf("subtree_control = %q", got)
+	}
+	if got := readFile(t, filepath.Join(want, "memory.max")); got != "536870912" {
+		t.Errorf("memory.max = %q", got)
+	}
+	if got := readFile(t, filepath.Join(want, "cpu.max")); got != "150000 100000" {
+		t.Errorf("cpu.max = %q", got)
+	}
+}
+
+func TestSetupAgentCgroup_FromSupervisorLeaf(t *testing.T) {
+	// After a restart of the setup, klaus already runs in the leaf group.
+	root, self := fakeCgroupTree(t, "0::/pod/"+cgroupSupervisorName+"\n", "memory")
+
+	cg, err := setupAgentCgroup(root, self, ResourceLimits{MemoryMaxMiB: 64})
+	if err != nil {
+		t.Fatalf("setupAgentCgroup: %v", err)
+	}
+	if want := filepath.Join(root, "pod", cgroupAgentName); cg.dir != want {
+		t.Errorf("dir = %q, want %q", cg.dir, want)
+	}
+}
+
+func TestSetupAgentCgroup_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		self        string
+		controllers string
+		limits      ResourceLimits
+		wantErr     string
+	}{
+		{name: "cgroup v1", self: "4:memory:/pod\n", controllers: "memory", limits: ResourceLimits{MemoryMaxMiB: 64}, wantErr: "unified hierarchy"},
+		{name: "memory not delegated", self: "0::/pod\n", controllers: "cpu pids", limits: ResourceLimits{MemoryMaxMiB: 64}, wantErr: `"memory" is not delegated`},
+		{name: "cpu not delegated", self: "0::/pod\n", controllers: "memory", limits: ResourceLimits{CPUs: 1}, wantErr: `"cpu" is not delegated`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, self := fakeCgroupTree(t, tt.self, tt.controllers)
+			_, err := setupAgentCgroup(root, self, tt.limits)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewAgentCgroup_NoLimits(t *testing.T) {
+	if cg := newAgentCgroup(ResourceLimits{CPUSeconds: 10}); cg != nil {
+		t.Errorf("expected no cgroup without cgroup limits, got %+v", cg)
+	}
+}
+
+func TestStartCommand_FailsWithoutCgroup(t *testing.T) {
+	installFakeClaude(t, "echo started\n")
+
+	opts := DefaultOptions()
+	opts.ResultDir = t.TempDir()
+	opts.ResourceLimits = ResourceLimits{MemoryMaxMiB: 512}
+	p := NewProcess(opts)
+	p.cgroup = &agentCgroup{err: errors.New("no delegated cgroup")}
+	if err := p.Submit(context.Background(), "hello", nil); err == nil || !strings.Contains(err.Error(), "no delegated cgroup") {
+		t.Fatalf("expected the run to fail without its cgroup, got %v", err)
+	}
+}
+
+// processAlive reports whether pid exists and is not a zombie.
+func processAlive(pid int) bool {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	// The state follows the parenthesised command name.
+	fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+func waitForPID(t *testing.T, path string) int {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(path); err == nil { //nolint:gosec // test fixture
+			if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+				return pid
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for child pid")
+	return 0
+}
+
+func waitForExit(t *testing.T, pid int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("child process %d still running", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProcess_StopKillsProcessTree(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	// The child keeps stdout open, so the run only ends once it is killed too.
+	installFakeClaude(t, "sleep 300 &\necho $! > "+pidFile+"\nwait\n")
+
+	opts := DefaultOptions()
+	opts.ResultDir = t.TempDir()
+	p := NewProcess(opts)
+	if err := p.Submit(context.Background(), "hello", nil); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	child := waitForPID(t, pidFile)
+
+	if err := p.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	select {
+	case <-p.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not finish after Stop")
+	}
+	waitForExit(t, child)
+}
+
+func TestProcess_ReapsOrphansAfterExit(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	// A background server that detaches from stdout outlives the CLI.
+	installFakeClaude(t, "sleep 300 >/dev/null 2>&1 </dev/null &\necho $! > "+pidFile+"\n"+
+		`echo '{"type":"result","subtype":"success","result":"done"}'`+"\n")
+
+	opts := DefaultOptions()
+	opts.ResultDir = t.TempDir()
+	p := NewProcess(opts)
+	if err := p.Submit(context.Background(), "hello", nil); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	select {
+	case <-p.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for run")
+	}
+	waitForExit(t, waitForPID(t, pidFile))
+}
+
+func TestStartCommand_AppliesRlimits(t *testing.T) {
+	outFile := filepath.Join(t.TempDir(), "ulimit")
+	// The limits are inherited by children forked right away.
+	installFakeClaude(t, "sh -c 'ulimit -n' > "+outFile+"\n")
+
+	opts := DefaultOptions()
+	opts.ResultDir = t.TempDir()
+	opts.ResourceLimits = ResourceLimits{OpenFiles: 123}
+	p := NewProcess(opts)
+	if err := p.Submit(context.Background(), "hello", nil); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	select {
+	case <-p.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for run")
+	}
+	if got := strings.TrimSpace(readFile(t, outFile)); got != "123" {
+		t.Errorf("ulimit -n = %q, want 123", got)
+	}
+}
@@ -0,0 +1,36 @@
+//go:build !linux
+
+package claude
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// errLimitsUnsupported is returned when limits reach a run outside Linux;
+// ResourceLimits.Validate rejects them earlier.
+var errLimitsUnsupported = errors.New("resource limits are only supported on Linux")
+
+// execWithRlimits is not supported outside Linux.
+func execWithRlimits(*exec.Cmd, ResourceLimits) error {
+	return errLimitsUnsupported
+}
+
+// agentCgroup is a placeholder; cgroups only exist on Linux.
+type agentCgroup struct{}
+
+func newAgentCgroup(l ResourceLimits) *agentCgroup {
+	if l.hasCgroupLimits() {
+		return &agentCgroup{}
+	}
+	return nil
+}
+
+func (c *agentCgroup) attach(*exec.Cmd) (func(), error) {
+	if c == nil {
+		return func() {}, nil
+	}
+	return func() {}, errLimitsUnsupported
+}
+
+func (c *agentCgroup) kill() {}
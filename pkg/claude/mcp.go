@@ -0,0 +1,209 @@
+package claude
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// MCP server transports recognised by the Claude Code CLI.
+const (
+	MCPTransportStdio = "stdio"
+	MCPTransportHTTP  = "http"
+	MCPTransportSSE   = "sse"
+)
+
+// defaultMCPConfigSubdir is the directory under $HOME where generated MCP
+// config files are written.
+const defaultMCPConfigSubdir = ".klaus/mcp"
+
+// MCPServer is an entry of the mcpServers object of a CLI MCP config file.
+// Stdio servers set Command, Args and Env; HTTP and SSE servers set URL and
+// Headers. Values may contain ${VAR} references, which the CLI expands.
+type MCPServer struct {
+	Type    string            `json:"type,omitempty"`
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Transport returns the transport of the server. Like the CLI, servers
+// without a type are stdio servers.
+func (s MCPServer) Transport() string {
+	if s.Type == "" {
+		return MCPTransportStdio
+	}
+	return s.Type
+}
+
+// Validate checks that the server sets the fields of its transport and no
+// others.
+func (s MCPServer) Validate() error {
+	var errs []error
+	switch s.Transport() {
+	case MCPTransportStdio:
+		if s.Command == "" {
+			errs = append(errs, errors.New("command is required for stdio servers"))
+		}
+		if s.URL != "" || len(s.Headers) > 0 {
+			errs = append(errs, errors.New("url and headers are only supported by http and sse servers"))
+		}
+	case MCPTransportHTTP, MCPTransportSSE:
+		if s.URL == "" {
+			errs = append(errs, fmt.Errorf("url is required for %s servers", s.Type))
+		} else if u, err := url.Parse(s.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			// The host may be a ${VAR} reference, which url.Parse rejects.
+			if !envRefRE.MatchString(s.URL) {
+				errs = append(errs, fmt.Errorf("url %q must be an absolute http or https URL", s.URL))
+			}
+		}
+		if s.Command != "" || len(s.Args) > 0 || len(s.Env) > 0 {
+			errs = append(errs, fmt.Errorf("command, args and env are only supported by stdio servers, not %s", s.Type))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid type %q (must be %q, %q or %q)", s.Type, MCPTransportStdio, MCPTransportHTTP, MCPTransportSSE))
+	}
+	return errors.Join(errs...)
+}
+
+// envRefRE matches ${VAR} references the CLI expands in MCP configs.
+var envRefRE = regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*(:-[^}]*)?\}`)
+
+// mcpServerNameRE matches server names that form valid tool names, since
+// the tools of a server are named mcp__<server>__<tool>.
+var mcpServerNameRE = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidateMCPServerName checks that an MCP server name may be used in tool
+// names.
+func ValidateMCPServerName(name string) error {
+	if !mcpServerNameRE.MatchString(name) {
+		return fmt.Errorf("invalid MCP server name %q (may only contain letters, digits, _ and -)", name)
+	}
+	return nil
+}
+
+// mcpConfigFile is the format of a CLI MCP config file.
+type mcpConfigFile struct {
+	MCPServers map[string]MCPServer `json:"mcpServers"`
+}
+
+// ReadMCPConfig parses the MCP config file at path and returns its servers.
+func ReadMCPConfig(path string) (map[string]MCPServer, error) {
+	data, err := os.ReadFile(path) //#nosec G304 -- operator-provided config path
+	if err != nil {
+		return nil, err
+	}
+	var cfg mcpConfigFile
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if cfg.MCPServers == nil {
+		cfg.MCPServers = map[string]MCPServer{}
+	}
+	return cfg.MCPServers, nil
+}
+
+// WriteMCPConfig writes servers as an MCP config file to dir and returns
+// its path. The file is named after a hash of its content, so that
+// unchanged servers reuse the file and the files of other configs, e.g.
+// profiles, are left alone. It is only readable by the owner, as env and
+// headers may hold credentials.
+func WriteMCPConfig(dir string, servers map[string]MCPServer) (string, error) {
+	data, err := json.MarshalIndent(mcpConfigFile{MCPServers: servers}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	path := filepath.Join(dir, "mcp-"+hex.EncodeToString(sum[:8])+".json")
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(dir, ".mcp-*.json")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// PruneMCPConfigs removes the files written by WriteMCPConfig to dir that
+// are not in keep, so that the credentials of servers that are no longer
+// configured do not stay on disk.
+func PruneMCPConfigs(dir string, keep []string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		if !strings.HasPrefix(name, "mcp-") || filepath.Ext(name) != ".json" || slices.Contains(keep, path) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MCPConfigDir returns the directory generated MCP config files are
+// written to: $HOME/.klaus/mcp, or a per-user directory under the temp
+// directory when there is no home directory.
+func MCPConfigDir() string {
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		return filepath.Join(home, defaultMCPConfigSubdir)
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("klaus-mcp-%d", os.Getuid()))
+}
+
+// mcpServerNames returns the sorted names of the MCP servers. These are
+// used to auto-generate --allowedTools patterns so that MCP tools are not
+// blocked by Claude's permission system. Without MCPServers, the servers
+// are read from MCPConfigPath.
+func (o Options) mcpServerNames() []string {
+	servers := o.MCPServers
+	if servers == nil && o.MCPConfigPath != "" {
+		var err error
+		if servers, err = ReadMCPConfig(o.MCPConfigPath); err != nil {
+			slog.Warn("failed to read MCP config, its tools are not allowed", "path", o.MCPConfigPath, "error", err)
+			return nil
+		}
+	}
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
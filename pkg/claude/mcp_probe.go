@@ -0,0 +1,304 @@
+package claude
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/giantswarm/klaus/pkg/metrics"
+	"github.com/giantswarm/klaus/pkg/project"
+)
+
+// Defaults of the MCP server probes.
+const (
+	DefaultMCPProbeInterval = 5 * time.Minute
+	DefaultMCPProbeTimeout  = 30 * time.Second
+)
+
+// MCPServerHealth is the outcome of the last probe of an MCP server.
+type MCPServerHealth struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Reachable is true when the server completed the initialize handshake
+	// and listed its tools.
+	Reachable bool `json:"reachable"`
+	// ToolCount is the number of tools of the last successful probe.
+	ToolCount int `json:"tool_count"`
+	// ServerName and ServerVersion are reported by the server itself.
+	ServerName    string `json:"server_name,omitempty"`
+	ServerVersion string `json:"server_version,omitempty"`
+	// LastError is the error of the last probe, empty if it succeeded.
+	LastError string    `json:"last_error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// MCPProber probes the configured MCP servers at startup and periodically
+// the way the CLI connects to them: stdio servers are spawned, http and sse
+// servers are connected to, and each gets an initialize handshake followed
+// by tools/list. A broken server is then visible in the status instead of
+// only in a confused transcript.
+type MCPProber struct {
+	interval time.Duration
+	timeout  time.Duration
+	// probe probes one server; replaced in tests.
+	probe func(ctx context.Context, server MCPServer, workDir string) (MCPServerHealth, error)
+
+	mu      sync.RWMutex
+	servers map[string]MCPServer
+	workDir string
+	health  map[string]MCPServerHealth
+	// changed is signalled when the servers change, so that they are
+	// probed without waiting for the interval.
+	changed chan struct{}
+}
+
+// NewMCPProber returns a prober that probes every interval with the given
+// per-server timeout; zero values select the defaults.
+func NewMCPProber(interval, timeout time.Duration) *MCPProber {
+	if interval <= 0 {
+		interval = DefaultMCPProbeInterval
+	}
+	if timeout <= 0 {
+		timeout = DefaultMCPProbeTimeout
+	}
+	return &MCPProber{
+		interval: interval,
+		timeout:  timeout,
+		probe:    probeMCPServer,
+		health:   make(map[string]MCPServerHealth),
+		changed:  make(chan struct{}, 1),
+	}
+}
+
+// SetServers replaces the servers to probe, e.g. after the configuration
+// was reloaded. Stdio servers are started in workDir, like the CLI does.
+// Servers that are gone are no longer reported. It is a no-op on a nil
+// prober.
+func (p *MCPProber) SetServers(servers map[string]MCPServer, workDir string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	if maps.EqualFunc(p.servers, servers, mcpServerEqual) && p.workDir == workDir {
+		p.mu.Unlock()
+		return
+	}
+	p.servers = maps.Clone(servers)
+	p.workDir = workDir
+	for name := range p.health {
+		if _, ok := servers[name]; !ok {
+			delete(p.health, name)
+			metrics.DeleteMCPServer(name)
+		}
+	}
+	p.mu.Unlock()
+
+	select {
+	case p.changed <- struct{}{}:
+	default:
+	}
+}
+
+func mcpServerEqual(a, b MCPServer) bool {
+	return a.Type == b.Type && a.Command == b.Command && slices.Equal(a.Args, b.Args) &&
+		maps.Equal(a.Env, b.Env) && a.URL == b.URL && maps.Equal(a.Headers, b.Headers)
+}
+
+// Run probes the servers right away and then every interval, and whenever
+// they change, until ctx is cancelled.
+func (p *MCPProber) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		// Changes made before this probe are covered by it.
+		select {
+		case <-p.changed:
+		default:
+		}
+		p.ProbeAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.changed:
+		}
+	}
+}
+
+// ProbeAll probes every server concurrently and records the results.
+func (p *MCPProber) ProbeAll(ctx context.Context) {
+	p.mu.RLock()
+	servers := maps.Clone(p.servers)
+	workDir := p.workDir
+	p.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for name, server := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, p.timeout)
+			defer cancel()
+			health, err := p.probe(probeCtx, server, workDir)
+			health.Name = name
+			health.Type = server.Transport()
+			health.CheckedAt = time.Now().UTC()
+			if err != nil {
+				health.LastError = err.Error()
+			}
+			p.record(health)
+		}()
+	}
+	wg.Wait()
+}
+
+// record stores the result of a probe, keeping the tool count of the last
+// successful probe, and logs changes in reachability.
+func (p *MCPProber) record(health MCPServerHealth) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.servers[health.Name]; !ok {
+		// Removed while being probed.
+		return
+	}
+	prev, seen := p.health[health.Name]
+	if !health.Reachable {
+		health.ToolCount = prev.ToolCount
+		health.ServerName, health.ServerVersion = prev.ServerName, prev.ServerVersion
+		if !seen || prev.Reachable {
+			slog.Warn("MCP server unreachable, its tools are unavailable to the agent", "server", health.Name, "error", health.LastError)
+		}
+	} else if seen && !prev.Reachable {
+		slog.Info("MCP server reachable again", "server", health.Name, "tools", health.ToolCount)
+	}
+	p.health[health.Name] = health
+	metrics.SetMCPServerHealth(health.Name, health.Reachable, health.ToolCount)
+}
+
+// Health returns the result of the last probe of every server that has
+// been probed, sorted by name. It returns nil on a nil prober.
+func (p *MCPProber) Health() []MCPServerHealth {
+	if p == nil {
+		return nil
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if len(p.health) == 0 {
+		return nil
+	}
+	health := make([]MCPServerHealth, 0, len(p.health))
+	for _, name := range slices.Sorted(maps.Keys(p.health)) {
+		health = append(health, p.health[name])
+	}
+	return health
+}
+
+// probeMCPServer connects to server, performs the initialize handshake and
+// lists its tools.
+func probeMCPServer(ctx context.Context, server MCPServer, workDir string) (MCPServerHealth, error) {
+	var health MCPServerHealth
+	c, err := newMCPProbeClient(ctx, server, workDir)
+	if err != nil {
+		return health, err
+	}
+	defer func() { _ = c.Close() }()
+
+	initResult, err := c.Initialize(ctx, mcp.InitializeRequest{Params: mcp.InitializeParams{
+		ProtocolVersion: mcp.LATEST_PROTOCOL_VERSION,
+		ClientInfo:      mcp.Implementation{Name: project.Name, Version: project.Version()},
+	}})
+	if err != nil {
+		return health, fmt.Errorf("initialize: %w", err)
+	}
+	health.ServerName = initResult.ServerInfo.Name
+	health.ServerVersion = initResult.ServerInfo.Version
+
+	if initResult.Capabilities.Tools != nil {
+		tools, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+		if err != nil {
+			return health, fmt.Errorf("listing tools: %w", err)
+		}
+		health.ToolCount = len(tools.Tools)
+	}
+	health.Reachable = true
+	return health, nil
+}
+
+// newMCPProbeClient returns a started client for server. ${VAR} references
+// are expanded like the CLI does.
+func newMCPProbeClient(ctx context.Context, server MCPServer, workDir string) (*client.Client, error) {
+	headers := make(map[string]string, len(server.Headers))
+	for k, v := range server.Headers {
+		headers[k] = expandMCPVars(v)
+	}
+
+	var c *client.Client
+	switch server.Transport() {
+	case MCPTransportStdio:
+		env := make([]string, 0, len(server.Env))
+		for k, v := range server.Env {
+			env = append(env, k+"="+expandMCPVars(v))
+		}
+		args := make([]string, len(server.Args))
+		for i, a := range server.Args {
+			args[i] = expandMCPVars(a)
+		}
+		command := expandMCPVars(server.Command)
+		t := transport.NewStdioWithOptions(command, env, args, transport.WithCommandFunc(
+			func(ctx context.Context, command string, env, args []string) (*exec.Cmd, error) {
+				cmd := exec.CommandContext(ctx, command, args...) //#nosec G204 -- operator-configured MCP server
+				cmd.Env = append(os.Environ(), env...)
+				cmd.Dir = workDir
+				return cmd, nil
+			}))
+		c = client.NewClient(t)
+	case MCPTransportHTTP:
+		var err error
+		if c, err = client.NewStreamableHttpClient(expandMCPVars(server.URL), transport.WithHTTPHeaders(headers)); err != nil {
+			return nil, err
+		}
+	case MCPTransportSSE:
+		var err error
+		if c, err = client.NewSSEMCPClient(expandMCPVars(server.URL), client.WithHeaders(headers)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %q", server.Type)
+	}
+
+	if err := c.Start(ctx); err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("connecting: %w", err)
+	}
+	if stderr, ok := client.GetStderr(c); ok {
+		// Drain stderr so that a chatty server does not block on it.
+		go func() { _, _ = io.Copy(io.Discard, stderr) }()
+	}
+	return c, nil
+}
+
+// expandMCPVars expands ${VAR} and ${VAR:-default} references.
+func expandMCPVars(s string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+	return envRefRE.ReplaceAllStringFunc(s, func(ref string) string {
+		name, def, hasDefault := strings.Cut(ref[2:len(ref)-1], ":-")
+		if value, ok := os.LookupEnv(name); ok && (value != "" || !hasDefault) {
+			return value
+		}
+		return def
+	})
+}
@@ -0,0 +1,95 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+func TestMCPProber_ProbeHTTP(t *testing.T) {
+	s := mcpserver.NewMCPServer("test-server", "1.2.3", mcpserver.WithToolCapabilities(false))
+	s.AddTool(mcp.NewTool("echo"), func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	ts := httptest.NewServer(mcpserver.NewStreamableHTTPServer(s))
+	defer ts.Close()
+
+	p := NewMCPProber(0, 0)
+	p.SetServers(map[string]MCPServer{
+		"test":    {Type: MCPTransportHTTP, URL: ts.URL + "/mcp"},
+		"missing": {Command: filepath.Join(t.TempDir(), "does-not-exist")},
+	}, t.TempDir())
+	p.ProbeAll(context.Background())
+
+	health := p.Health()
+	if len(health) != 2 {
+		t.Fatalf("expected 2 servers, got %+v", health)
+	}
+	missing, ok := health[0], health[1]
+	if missing.Name != "missing" || missing.Reachable || missing.LastError == "" || missing.Type != MCPTransportStdio {
+		t.Errorf("unexpected health of missing server: %+v", missing)
+	}
+	if ok.Name != "test" || !ok.Reachable || ok.ToolCount != 1 || ok.ServerName != "test-server" || ok.ServerVersion != "1.2.3" || ok.LastError != "" {
+		t.Errorf("unexpected health of test server: %+v", ok)
+	}
+}
+
+func TestMCPProber_KeepsToolCountOnFailure(t *testing.T) {
+	p := NewMCPProber(0, 0)
+	fail := false
+	p.probe = func(context.Context, MCPServer, string) (MCPServerHealth, error) {
+		if fail {
+			return MCPServerHealth{}, errors.New("connection refused")
+		}
+		return MCPServerHealth{Reachable: true, ToolCount: 3, ServerName: "s"}, nil
+	}
+	p.SetServers(map[string]MCPServer{"a": {Command: "a"}, "b": {Command: "b"}}, "")
+	p.ProbeAll(context.Background())
+
+	fail = true
+	p.ProbeAll(context.Background())
+	health := p.Health()
+	if len(health) != 2 {
+		t.Fatalf("expected 2 servers, got %+v", health)
+	}
+	if h := health[0]; h.Reachable || h.ToolCount != 3 || h.ServerName != "s" || h.LastError != "connection refused" {
+		t.Errorf("unexpected health after failure: %+v", h)
+	}
+
+	// Removed servers are no longer reported.
+	p.SetServers(map[string]MCPServer{"b": {Command: "b"}}, "")
+	if health := p.Health(); len(health) != 1 || health[0].Name != "b" {
+		t.Errorf("expected only b, got %+v", health)
+	}
+}
+
+func TestMCPProber_Nil(t *testing.T) {
+	var p *MCPProber
+	p.SetServers(map[string]MCPServer{"a": {Command: "a"}}, "")
+	if health := p.Health(); health != nil {
+		t.Errorf("expected nil health, got %+v", health)
+	}
+}
+
+func TestExpandMCPVars(t *testing.T) {
+	t.Setenv("KLAUS_TEST_SET", "value")
+	t.Setenv("KLAUS_TEST_EMPTY", "")
+	tests := map[string]string{
+		"plain":                           "plain",
+		"${KLAUS_TEST_SET}":               "value",
+		"a-${KLAUS_TEST_UNSET:-def}-b":    "a-def-b",
+		"${KLAUS_TEST_EMPTY:-def}":        "def",
+		"${KLAUS_TEST_EMPTY}":             "",
+		"Bearer ${KLAUS_TEST_SET:-other}": "Bearer value",
+	}
+	for in, want := range tests {
+		if got := expandMCPVars(in); got != want {
+			t.Errorf("expandMCPVars(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package claude
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMCPServer_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		server  MCPServer
+		wantErr string
+	}{
+		{name: "stdio", server: MCPServer{Command: "muster", Args: []string{"serve"}, Env: map[string]string{"A": "b"}}},
+		{name: "http", server: MCPServer{Type: MCPTransportHTTP, URL: "https://example.com/mcp", Headers: map[string]string{"Authorization": "Bearer x"}}},
+		{name: "sse with env ref", server: MCPServer{Type: MCPTransportSSE, URL: "${MCP_URL}"}},
+		{name: "stdio without command", server: MCPServer{Type: MCPTransportStdio}, wantErr: "command is required"},
+		{name: "stdio with url", server: MCPServer{Command: "x", URL: "https://example.com"}, wantErr: "only supported by http and sse servers"},
+		{name: "http without url", server: MCPServer{Type: MCPTransportHTTP}, wantErr: "url is required for http servers"},
+		{name: "http with relative url", server: MCPServer{Type: MCPTransportHTTP, URL: "/mcp"}, wantErr: "must be an absolute http or https URL"},
+		{name: "http with command", server: MCPServer{Type: MCPTransportHTTP, URL: "https://example.com", Command: "x"}, wantErr: "only supported by stdio servers"},
+		{name: "unknown type", server: MCPServer{Type: "ws"}, wantErr: `invalid type "ws"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.server.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateMCPServerName(t *testing.T) {
+	for _, name := range []string{"github", "my-server_2"} {
+		if err := ValidateMCPServerName(name); err != nil {
+			t.Errorf("ValidateMCPServerName(%q): %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "my server", "a.b"} {
+		if err := ValidateMCPServerName(name); err == nil {
+			t.Errorf("expected ValidateMCPServerName(%q) to fail", name)
+		}
+	}
+}
+
+func TestWriteAndReadMCPConfig(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mcp")
+	servers := map[string]MCPServer{
+		"github": {Type: MCPTransportHTTP, URL: "https://example.com/mcp"},
+		"muster": {Command: "muster"},
+	}
+	path, err := WriteMCPConfig(dir, servers)
+	if err != nil {
+		t.Fatalf("WriteMCPConfig: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+	if again, err := WriteMCPConfig(dir, servers); err != nil || again != path {
+		t.Errorf("expected the same servers to reuse %s, got %s, %v", path, again, err)
+	}
+
+	read, err := ReadMCPConfig(path)
+	if err != nil {
+		t.Fatalf("ReadMCPConfig: %v", err)
+	}
+	if len(read) != 2 || read["muster"].Command != "muster" || read["github"].Transport() != MCPTransportHTTP {
+		t.Errorf("unexpected servers %+v", read)
+	}
+
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadMCPConfig(broken); err == nil || !strings.Contains(err.Error(), "parsing "+broken) {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
+
+func TestPruneMCPConfigs(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mcp")
+	if err := PruneMCPConfigs(dir, nil); err != nil {
+		t.Fatalf("expected a missing directory to be fine, got %v", err)
+	}
+	kept, err := WriteMCPConfig(dir, map[string]MCPServer{"muster": {Command: "muster"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, err := WriteMCPConfig(dir, map[string]MCPServer{"github": {Type: MCPTransportHTTP, URL: "https://example.com/mcp", Headers: map[string]string{"Authorization": "Bearer old"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(other, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := PruneMCPConfigs(dir, []string{kept, "/etc/klaus/mcp.json"}); err != nil {
+		t.Fatalf("PruneMCPConfigs: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed, got %v", stale, err)
+	}
+	for _, path := range []string{kept, other} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to be kept, got %v", path, err)
+		}
+	}
+}
+
+func TestBaseArgs_MCPAllowedToolsFromServers(t *testing.T) {
+	// MCPServers take precedence over reading MCPConfigPath.
+	opts := Options{
+		MCPConfigPath: "/nonexistent/mcp.json",
+		MCPServers:    map[string]MCPServer{"muster": {Command: "muster"}},
+	}
+	args := opts.baseArgs()
+
+	assertContainsSequence(t, args, "--mcp-config", "/nonexistent/mcp.json")
+	assertContainsSequence(t, args, "--allowedTools", "mcp__muster__*")
+}
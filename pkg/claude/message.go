@@ -99,14 +99,21 @@ type StreamMessage struct {
 	Raw json.RawMessage `json:"-"`
 }
 
-// ParseStreamMessage unmarshals a single line of stream-json output.
-// It stamps each message with the current UTC time in RFC3339 format.
+// ParseStreamMessage unmarshals a single line of stream-json output of a
+// current CLI. It stamps each message with the current UTC time in RFC3339
+// format.
 //
 // Claude Code 2.1+ nests assistant message content inside message.content[]
-// instead of using top-level fields. When top-level subtype is empty and
-// msg.Message is populated, this function extracts content from the nested
-// structure to populate Subtype, Text, ToolName, ToolID, ToolArgs, and Usage.
+// instead of using top-level fields (see CapabilityNestedContent). This
+// function extracts content from the nested structure to populate Subtype,
+// Text, ToolName, ToolID, ToolArgs, and Usage.
 func ParseStreamMessage(data []byte) (StreamMessage, error) {
+	return parseStreamMessage(data, true)
+}
+
+// parseStreamMessage is ParseStreamMessage for a CLI that nests assistant
+// content, or one that uses the older flat top-level fields.
+func parseStreamMessage(data []byte, nestedContent bool) (StreamMessage, error) {
 	var msg StreamMessage
 	if err := json.Unmarshal(data, &msg); err != nil {
 		return msg, err
@@ -133,7 +140,7 @@ func ParseStreamMessage(data []byte) (StreamMessage, error) {
 	}
 
 	// Extract nested content for assistant messages in the new format.
-	if msg.Type == MessageTypeAssistant && msg.Subtype == "" && len(msg.Message) > 0 {
+	if nestedContent && msg.Type == MessageTypeAssistant && len(msg.Message) > 0 {
 		var env assistantMessageEnvelope
 		if err := json.Unmarshal(msg.Message, &env); err == nil {
 			// The Claude CLI emits at most one tool_use block per assistant
@@ -209,8 +216,10 @@ const maxNestedTextLen = 1 << 20 // 1 MiB
 const maxStatusResultLen = 4000
 
 type StatusInfo struct {
-	Status        ProcessStatus  `json:"status"`
-	SessionID     string         `json:"session_id,omitempty"`
+	Status    ProcessStatus `json:"status"`
+	SessionID string        `json:"session_id,omitempty"`
+	// RunID identifies the current or last run, see RunDetail.
+	RunID         string         `json:"run_id,omitempty"`
 	ErrorMessage  string         `json:"error,omitempty"`
 	PreviousError string         `json:"previous_error,omitempty"`
 	TotalCost     *float64       `json:"total_cost_usd"`
@@ -224,6 +233,28 @@ type StatusInfo struct {
 	SubagentCalls []SubagentCall `json:"subagent_calls,omitempty"`
 	ModelUsage    map[string]int `json:"model_usage,omitempty"`
 	ErrorCount    int            `json:"error_count,omitempty"`
+	// RedactionCount is the number of secrets masked in the current run's
+	// output (see Options.Redactor).
+	RedactionCount int `json:"redaction_count,omitempty"`
+	// Stderr holds the last lines of subprocess stderr when the status is
+	// "error". Use the logs tool for the full buffer.
+	Stderr []string `json:"stderr,omitempty"`
+	// CLIVersion is the detected Claude Code CLI version, empty if unknown.
+	CLIVersion string `json:"cli_version,omitempty"`
+	// Model and PermissionMode are the effective session settings,
+	// including changes made through ConfigureSession. Empty means the CLI
+	// default.
+	Model          string `json:"model,omitempty"`
+	PermissionMode string `json:"permission_mode,omitempty"`
+	// Profile is the config profile of the current or last run.
+	Profile string `json:"profile,omitempty"`
+	// MCPServers is the outcome of the last probe of each configured MCP
+	// server (see Options.MCPProber).
+	MCPServers []MCPServerHealth `json:"mcp_servers,omitempty"`
+	// ContextTokens estimates the context size of a chat-mode session since
+	// its last compaction; Compactions counts the session's compactions.
+	ContextTokens int64 `json:"context_tokens,omitempty"`
+	Compactions   int   `json:"compaction_count,omitempty"`
 	// Result contains the agent's final output text from the last completed
 	// non-blocking Submit run, truncated to maxStatusResultLen runes. It is
 	// populated when the status is "completed". Use the result debug tool
@@ -240,19 +271,33 @@ type StatusInfo struct {
 // from the last completed run. Intended for debugging and troubleshooting.
 // Unlike StatusInfo.Result, ResultText is never truncated.
 type ResultDetailInfo struct {
-	ResultText    string          `json:"result_text"`
-	Messages      []StreamMessage `json:"messages,omitempty"`
-	MessageCount  int             `json:"message_count"`
-	ToolCalls     map[string]int  `json:"tool_calls,omitempty"`
-	SubagentCalls []SubagentCall  `json:"subagent_calls,omitempty"`
-	ModelUsage    map[string]int  `json:"model_usage,omitempty"`
-	PRURLs        []string        `json:"pr_urls,omitempty"`
-	ErrorCount    int             `json:"error_count,omitempty"`
-	TokenUsage    *TokenUsage     `json:"token_usage,omitempty"`
-	TotalCost     *float64        `json:"total_cost_usd"`
-	SessionID     string          `json:"session_id,omitempty"`
-	Status        ProcessStatus   `json:"status"`
-	ErrorMessage  string          `json:"error,omitempty"`
+	ResultText     string          `json:"result_text"`
+	Messages       []StreamMessage `json:"messages,omitempty"`
+	MessageCount   int             `json:"message_count"`
+	ToolCalls      map[string]int  `json:"tool_calls,omitempty"`
+	SubagentCalls  []SubagentCall  `json:"subagent_calls,omitempty"`
+	ModelUsage     map[string]int  `json:"model_usage,omitempty"`
+	PRURLs         []string        `json:"pr_urls,omitempty"`
+	ErrorCount     int             `json:"error_count,omitempty"`
+	RedactionCount int             `json:"redaction_count,omitempty"`
+	TokenUsage     *TokenUsage     `json:"token_usage,omitempty"`
+	TotalCost      *float64        `json:"total_cost_usd"`
+	SessionID      string          `json:"session_id,omitempty"`
+	RunID          string          `json:"run_id,omitempty"`
+	Status         ProcessStatus   `json:"status"`
+	ErrorMessage   string          `json:"error,omitempty"`
+	// Compactions is the compaction history of a chat-mode session.
+	Compactions []CompactionEvent `json:"compactions,omitempty"`
+}
+
+// LogsInfo holds the most recent stderr lines captured from the Claude
+// subprocess. Used by the logs MCP tool.
+type LogsInfo struct {
+	Status ProcessStatus `json:"status"`
+	// Total is the number of lines retained in the buffer, which may exceed
+	// len(Lines) when a limit was requested.
+	Total int      `json:"total"`
+	Lines []string `json:"lines"`
 }
 
 // MessagesInfo holds the current conversation messages along with the
@@ -680,8 +725,9 @@ type resultState struct {
 
 // submitDrain starts a background goroutine that reads all messages from ch,
 // collects the result text, and calls storeFn with the result when complete.
-// ctx controls the drain goroutine's lifetime.
-func submitDrain(ctx context.Context, ch <-chan StreamMessage, storeFn func(string, []StreamMessage)) {
+// onMessage, if not nil, observes every message. ctx controls the drain
+// goroutine's lifetime.
+func submitDrain(ctx context.Context, ch <-chan StreamMessage, onMessage func(StreamMessage), storeFn func(string, []StreamMessage)) {
 	go func() {
 		var messages []StreamMessage
 		for {
@@ -694,6 +740,9 @@ func submitDrain(ctx context.Context, ch <-chan StreamMessage, storeFn func(stri
 					storeFn(CollectResultText(messages), messages)
 					return
 				}
+				if onMessage != nil {
+					onMessage(msg)
+				}
 				if msg.Type == MessageTypeStreamEvent {
 					continue
 				}
@@ -706,7 +755,9 @@ func submitDrain(ctx context.Context, ch <-chan StreamMessage, storeFn func(stri
 // submitAsync is the shared implementation for non-blocking prompt submission.
 // It calls runFn to start the prompt; on success it clears previous results
 // and spawns a background drain goroutine that stores new results via setResult.
-// Previous results are preserved if runFn fails (e.g. "already busy").
+// Previous results are preserved if runFn fails (e.g. "already busy"). The
+// run's Done channel is closed once the result is stored, before OnDone is
+// called.
 func submitAsync(
 	ctx context.Context,
 	prompt string,
@@ -714,15 +765,30 @@ func submitAsync(
 	runFn func(context.Context, string, *RunOptions) (<-chan StreamMessage, error),
 	setResult func(resultState),
 ) error {
-	ch, err := runFn(ctx, prompt, opts)
+	var runOpts RunOptions
+	if opts != nil {
+		runOpts = *opts
+	}
+	done := make(chan struct{})
+	runOpts.done = done
+	ch, err := runFn(ctx, prompt, &runOpts)
 	if err != nil {
 		return err
 	}
 
 	setResult(resultState{}) // Clear previous result now that the new run started.
 
-	submitDrain(ctx, ch, func(text string, messages []StreamMessage) {
+	var onMessage func(StreamMessage)
+	var onDone func()
+	if opts != nil {
+		onMessage, onDone = opts.OnMessage, opts.OnDone
+	}
+	submitDrain(ctx, ch, onMessage, func(text string, messages []StreamMessage) {
 		setResult(resultState{text: text, messages: messages, completed: true})
+		close(done)
+		if onDone != nil {
+			onDone()
+		}
 	})
 	return nil
 }
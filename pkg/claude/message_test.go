@@ -3,6 +3,7 @@ package claude
 import (
 	"context"
 	"encoding/json"
+	"slices"
 	"testing"
 	"time"
 )
@@ -184,6 +185,18 @@ func TestParseStreamMessage_OldFormatStillWorks(t *testing.T) {
 	}
 }
 
+func TestParseStreamMessage_FlatFormatIgnoresMessage(t *testing.T) {
+	// A CLI without nested content uses the top-level fields only.
+	data := []byte(`{"type":"assistant","subtype":"text","text":"Hello","message":{"content":[{"type":"tool_use","name":"Bash","id":"t1"}]}}`)
+	msg, err := Options{CLIVersion: &CLIVersion{2, 0, 14}}.parseStreamMessage(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Subtype != SubtypeText || msg.Text != "Hello" || msg.ToolName != "" {
+		t.Errorf("expected the top-level text only, got subtype %q text %q tool %q", msg.Subtype, msg.Text, msg.ToolName)
+	}
+}
+
 func TestParseStreamMessage_NestedTextConcatenation(t *testing.T) {
 	// Multiple text blocks should be concatenated.
 	data := []byte(`{"type":"assistant","message":{"content":[{"type":"text","text":"Hello "},{"type":"text","text":"world!"}]}}`)
@@ -668,7 +681,7 @@ func TestSubmitDrain(t *testing.T) {
 		var gotMessages []StreamMessage
 		done := make(chan struct{})
 
-		submitDrain(context.Background(), ch, func(text string, messages []StreamMessage) {
+		submitDrain(context.Background(), ch, nil, func(text string, messages []StreamMessage) {
 			gotText = text
 			gotMessages = messages
 			close(done)
@@ -700,7 +713,7 @@ func TestSubmitDrain(t *testing.T) {
 		var gotMessages []StreamMessage
 		done := make(chan struct{})
 
-		submitDrain(ctx, ch, func(text string, messages []StreamMessage) {
+		submitDrain(ctx, ch, nil, func(text string, messages []StreamMessage) {
 			gotText = text
 			gotMessages = messages
 			close(done)
@@ -741,7 +754,7 @@ func TestSubmitDrain(t *testing.T) {
 		var gotMessages []StreamMessage
 		done := make(chan struct{})
 
-		submitDrain(context.Background(), ch, func(text string, messages []StreamMessage) {
+		submitDrain(context.Background(), ch, nil, func(text string, messages []StreamMessage) {
 			gotText = text
 			gotMessages = messages
 			close(done)
@@ -775,7 +788,7 @@ func TestSubmitDrain(t *testing.T) {
 		var gotMessages []StreamMessage
 		done := make(chan struct{})
 
-		submitDrain(context.Background(), ch, func(text string, messages []StreamMessage) {
+		submitDrain(context.Background(), ch, nil, func(text string, messages []StreamMessage) {
 			gotText = text
 			gotMessages = messages
 			close(done)
@@ -891,6 +904,44 @@ func TestSubmitAsync(t *testing.T) {
 			t.Errorf("expected result %q, got %q", "new result", current.text)
 		}
 	})
+
+	t.Run("calls the observers of the run", func(t *testing.T) {
+		var observed []MessageType
+		var completedBeforeDone bool
+		var current resultState
+		done := make(chan struct{})
+
+		runFn := func(_ context.Context, _ string, _ *RunOptions) (<-chan StreamMessage, error) {
+			ch := make(chan StreamMessage, 2)
+			ch <- StreamMessage{Type: MessageTypeStreamEvent, EventType: "content_block_delta", DeltaText: "hi"}
+			ch <- StreamMessage{Type: MessageTypeResult, Result: "done"}
+			close(ch)
+			return ch, nil
+		}
+		opts := &RunOptions{
+			OnMessage: func(msg StreamMessage) { observed = append(observed, msg.Type) },
+			OnDone: func() {
+				completedBeforeDone = current.completed
+				close(done)
+			},
+		}
+
+		if err := submitAsync(context.Background(), "go", opts, runFn, func(rs resultState) { current = rs }); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("OnDone was not called")
+		}
+
+		if want := []MessageType{MessageTypeStreamEvent, MessageTypeResult}; !slices.Equal(observed, want) {
+			t.Errorf("expected observed messages %v, got %v", want, observed)
+		}
+		if !completedBeforeDone {
+			t.Error("expected the result to be stored before OnDone")
+		}
+	})
 }
 
 func TestSummarizeMessages_DeduplicatesSystem(t *testing.T) {
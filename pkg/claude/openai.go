@@ -49,6 +49,9 @@ type OpenAIMetadata struct {
 	DurationMS        float64          `json:"duration_ms,omitempty"`
 	NumTurns          int              `json:"num_turns,omitempty"`
 	Usage             *TokenUsage      `json:"usage,omitempty"`
+	// MCPServers is the outcome of the last probe of each configured MCP
+	// server, so that missing tools can be traced to a broken server.
+	MCPServers []MCPServerHealth `json:"mcp_servers,omitempty"`
 }
 
 // OpenAIPlugin holds plugin metadata from system/init messages.
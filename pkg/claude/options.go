@@ -3,10 +3,12 @@ package claude
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/giantswarm/klaus/pkg/redact"
 )
 
 // AgentConfig defines a custom subagent for Claude Code.
@@ -45,6 +47,12 @@ type AgentConfig struct {
 
 // Options configures how a Claude CLI subprocess is spawned.
 type Options struct {
+	// Binary is the CLI executable to run; empty means "claude" on PATH.
+	Binary string
+	// BinaryArgs are passed to Binary before the CLI flags, e.g.
+	// ["fake-claude"] to run `klaus fake-claude`.
+	BinaryArgs []string
+
 	// Model selects the Claude model (e.g. "claude-sonnet-4-20250514", "sonnet", "opus").
 	Model string
 	// SystemPrompt overrides the default system prompt entirely.
@@ -63,6 +71,13 @@ type Options struct {
 	MaxTurns int
 	// MCPConfigPath is a path to an MCP servers configuration file.
 	MCPConfigPath string
+	// MCPServers are the servers of MCPConfigPath, read when klaus starts
+	// so that parse errors are reported then. Their tools are allowed
+	// automatically. When nil, MCPConfigPath is read on every run.
+	MCPServers map[string]MCPServer
+	// MCPProber probes MCPServers; its results are reported in the status
+	// and the message metadata. Nil reports none.
+	MCPProber *MCPProber
 	// StrictMCPConfig when true only uses MCP servers from MCPConfigPath,
 	// ignoring user, project, and local MCP configurations.
 	StrictMCPConfig bool
@@ -121,6 +136,62 @@ type Options struct {
 	// Subagents found via AddDirs have lower priority than those in Agents (--agents).
 	// See https://code.claude.com/docs/en/sub-agents#choose-the-subagent-scope
 	AddDirs []string
+
+	// OverridePolicy governs which model, max-turns, tool and system-prompt
+	// overrides callers may pass per run. The zero value permits none.
+	OverridePolicy OverridePolicy
+
+	// ResourceLimits bounds the resources of the subprocess tree (rlimits and
+	// cgroup v2 memory/CPU limits). The zero value applies no limits.
+	ResourceLimits ResourceLimits
+
+	// Recording tees raw subprocess stdout to per-run JSONL files for later
+	// replay. The zero value records nothing.
+	Recording RecordingOptions
+
+	// AutoCompact compacts chat-mode sessions before their context window
+	// overflows. The zero value disables automatic compaction.
+	AutoCompact AutoCompactOptions
+
+	// ResumeOnStart makes the persistent subprocess resume the session of
+	// the last persisted result when it starts, and the current session when
+	// it restarts, instead of starting a new conversation. It requires
+	// session persistence (chat mode).
+	ResumeOnStart bool
+
+	// CLIVersion is the detected version of the Claude Code CLI (see
+	// DetectCLIVersion). It gates version-specific flags and tool names; nil
+	// means unknown, in which case every capability is assumed.
+	CLIVersion *CLIVersion
+
+	// Redactor masks secrets in subprocess output before it is stored,
+	// logged or returned. Nil disables redaction.
+	Redactor *redact.Redactor
+
+	// Profile is the name of the config profile these options were built
+	// from; empty means none.
+	Profile string
+	// Profiles are the options of the config profiles a run may select
+	// with RunOptions.Profile, keyed by name.
+	Profiles map[string]Options
+}
+
+// ForProfile returns the options of the named profile, or o itself for the
+// empty name and the profile o was built from.
+func (o Options) ForProfile(name string) (Options, error) {
+	if name == "" || name == o.Profile {
+		return o, nil
+	}
+	profile, ok := o.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(o.Profiles))
+		for n := range o.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return Options{}, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return profile, nil
 }
 
 // Permission modes recognised by the Claude Code CLI.
@@ -143,6 +214,24 @@ const (
 // streamJSONFormat is the CLI value for stream-json input/output formatting.
 const streamJSONFormat = "stream-json"
 
+// defaultBinary is the CLI executable used when Options.Binary is empty.
+const defaultBinary = "claude"
+
+// commandLine returns the executable and the full argument list for running
+// the configured CLI binary with the given CLI arguments.
+func (o Options) commandLine(args []string) (string, []string) {
+	return o.BinaryName(), append(append([]string{}, o.BinaryArgs...), args...)
+}
+
+// BinaryName returns the CLI binary klaus runs: Binary, or "claude" when
+// unset.
+func (o Options) BinaryName() string {
+	if o.Binary == "" {
+		return defaultBinary
+	}
+	return o.Binary
+}
+
 // DefaultOptions returns sensible defaults for headless operation.
 func DefaultOptions() Options {
 	return Options{
@@ -152,31 +241,6 @@ func DefaultOptions() Options {
 	}
 }
 
-// mcpServerNames parses the MCP config file and returns sorted server names.
-// These are used to auto-generate --allowedTools patterns so that MCP tools
-// are not blocked by Claude's permission system.
-func (o Options) mcpServerNames() []string {
-	if o.MCPConfigPath == "" {
-		return nil
-	}
-	data, err := os.ReadFile(o.MCPConfigPath)
-	if err != nil {
-		return nil
-	}
-	var cfg struct {
-		McpServers map[string]any `json:"mcpServers"`
-	}
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return nil
-	}
-	names := make([]string, 0, len(cfg.McpServers))
-	for name := range cfg.McpServers {
-		names = append(names, name)
-	}
-	sort.Strings(names)
-	return names
-}
-
 // ValidPermissionModes lists all valid permission mode values for Claude Code.
 var ValidPermissionModes = []string{
 	PermissionModeDefault,
@@ -197,6 +261,20 @@ func ValidatePermissionMode(mode string) error {
 	return fmt.Errorf("invalid permission mode %q; valid modes: %s", mode, strings.Join(ValidPermissionModes, ", "))
 }
 
+// toolPatternRE matches a tool permission rule: a tool name such as "Bash"
+// or "mcp__github__create_issue", where "*" matches any suffix, optionally
+// followed by a non-empty specifier in parentheses, e.g. "Bash(git:*)".
+var toolPatternRE = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_*-]*(\(.+\))?$`)
+
+// ValidateToolPattern checks the syntax of a tool name or permission rule as
+// accepted by --tools, --allowedTools and --disallowedTools.
+func ValidateToolPattern(pattern string) error {
+	if !toolPatternRE.MatchString(pattern) {
+		return fmt.Errorf("invalid tool pattern %q; expected a tool name, optionally with a specifier such as Bash(git:*)", pattern)
+	}
+	return nil
+}
+
 // ValidEffortLevels lists all valid effort level values for Claude Code.
 var ValidEffortLevels = []string{
 	EffortLow,
@@ -263,7 +341,7 @@ func (o Options) baseArgs() []string {
 	}
 
 	if len(o.AllowedTools) > 0 {
-		args = append(args, "--allowedTools", strings.Join(o.AllowedTools, ","))
+		args = append(args, "--allowedTools", strings.Join(o.adaptToolNames(o.AllowedTools), ","))
 	}
 
 	for _, name := range o.mcpServerNames() {
@@ -271,11 +349,11 @@ func (o Options) baseArgs() []string {
 	}
 
 	if len(o.DisallowedTools) > 0 {
-		args = append(args, "--disallowedTools", strings.Join(o.DisallowedTools, ","))
+		args = append(args, "--disallowedTools", strings.Join(o.adaptToolNames(o.DisallowedTools), ","))
 	}
 
 	if len(o.Tools) > 0 {
-		args = append(args, "--tools", strings.Join(o.Tools, ","))
+		args = append(args, "--tools", strings.Join(o.adaptToolNames(o.Tools), ","))
 	}
 
 	// Operational controls.
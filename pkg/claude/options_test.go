@@ -208,6 +208,19 @@ func TestValidatePermissionMode(t *testing.T) {
 	}
 }
 
+func TestValidateToolPattern(t *testing.T) {
+	for _, pattern := range []string{"Bash", "Bash(git:*)", "Edit(/src/**)", "mcp__github__create_issue", "mcp__github__*", "WebFetch(domain:example.com)"} {
+		if err := ValidateToolPattern(pattern); err != nil {
+			t.Errorf("expected pattern %q to be valid, got error: %v", pattern, err)
+		}
+	}
+	for _, pattern := range []string{"", "Bash()", "Bash(git", "Bash git", "(git)", "1Bash"} {
+		if err := ValidateToolPattern(pattern); err == nil {
+			t.Errorf("expected pattern %q to be invalid, but got no error", pattern)
+		}
+	}
+}
+
 func TestValidateEffort(t *testing.T) {
 	// Empty string should be valid (uses default).
 	if err := ValidateEffort(""); err != nil {
@@ -471,3 +484,21 @@ func findFlagValue(t *testing.T, args []string, flag string) string {
 	t.Fatalf("flag %q not found in args %v", flag, args)
 	return ""
 }
+
+func TestCommandLine(t *testing.T) {
+	opts := DefaultOptions()
+	binary, argv := opts.commandLine([]string{"--version"})
+	if binary != "claude" || strings.Join(argv, " ") != "--version" {
+		t.Errorf("default command line = %s %v", binary, argv)
+	}
+
+	opts.Binary = "/usr/local/bin/klaus"
+	opts.BinaryArgs = []string{"fake-claude", "--scenario", "s.yaml"}
+	binary, argv = opts.commandLine([]string{"--print"})
+	if binary != "/usr/local/bin/klaus" || strings.Join(argv, " ") != "fake-claude --scenario s.yaml --print" {
+		t.Errorf("command line = %s %v", binary, argv)
+	}
+	if len(opts.BinaryArgs) != 3 {
+		t.Error("BinaryArgs must not be modified")
+	}
+}
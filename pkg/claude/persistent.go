@@ -24,9 +24,11 @@ import (
 type PersistentProcess struct {
 	opts Options
 
-	mu            sync.RWMutex
-	cmd           *exec.Cmd
-	stdin         io.WriteCloser
+	mu    sync.RWMutex
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	// stdinMu serializes writes to stdin (see writeStdin).
+	stdinMu       sync.Mutex
 	status        ProcessStatus
 	sessionID     string
 	lastError     string
@@ -45,6 +47,10 @@ type PersistentProcess struct {
 	lastToolName  string
 	subagents     *subagentTracker
 	liveMessages  []StreamMessage
+	// redactionCount is the number of secrets masked in the current run.
+	redactionCount int
+	// runID identifies the current or last run.
+	runID string
 
 	// stderrTail captures the last few lines of stderr for crash diagnostics.
 	stderrTail *ringBuffer
@@ -57,6 +63,35 @@ type PersistentProcess struct {
 	// Nil means no persistence.
 	resultStore *ResultStore
 
+	// cgroup is the cgroup v2 group the subprocess tree runs in, or nil
+	// when no memory/CPU limits are configured. When cgroups are unavailable,
+	// starting the subprocess in it fails.
+	cgroup *agentCgroup
+
+	// recorder tees the stdout of the running subprocess to a recording;
+	// nil when recording is disabled.
+	recorder *recorder
+
+	// pendingControl maps the IDs of control requests awaiting a
+	// control_response to the channel of the waiting ConfigureSession call.
+	pendingControl map[string]chan controlResult
+	controlSeq     int
+
+	// sessionModel and sessionPermissionMode are the settings changed by
+	// ConfigureSession. UpdateOptions applies them to the new options, so
+	// that they survive a reload and the restart that follows it.
+	sessionModel          string
+	sessionPermissionMode string
+
+	// compactQueue holds the /compact commands awaiting their result, oldest
+	// first.
+	compactQueue []compactRequest
+	// compactions is the compaction history of the session.
+	compactions []CompactionEvent
+	// contextTokens estimates the context size of the session: input and
+	// cache creation tokens since the last compaction.
+	contextTokens int64
+
 	// responseCh receives stream-json messages during an active prompt.
 	// It is set by Send and cleared when the response is complete.
 	responseCh chan StreamMessage
@@ -67,8 +102,11 @@ type PersistentProcess struct {
 	// final results.
 	sawContent bool
 
-	// done is closed when the current prompt response is complete.
-	done chan struct{}
+	// done is closed when the current prompt response is complete. For a
+	// prompt sent with Submit, whose doneAfterStore is set, it is closed by
+	// Submit once the result is stored instead.
+	done           chan struct{}
+	doneAfterStore bool
 
 	// processDone is closed when the subprocess exits entirely.
 	processDone chan struct{}
@@ -83,6 +121,18 @@ type PersistentProcess struct {
 	// watchdogCtx controls the lifetime of the watchdog goroutine.
 	watchdogCtx    context.Context
 	watchdogCancel context.CancelFunc
+
+	// optionsChanged is set by UpdateOptions while the subprocess runs with
+	// the previous options; the next prompt restarts it first.
+	optionsChanged bool
+	// restarting is set while the subprocess is restarted for new options.
+	restarting bool
+	// resumeNext is the session the next start resumes regardless of
+	// Options.ResumeOnStart, set when restarting for new options.
+	resumeNext string
+
+	// changes signals state changes to the channels returned by Changes.
+	changes changeNotifier
 }
 
 // NewPersistentProcess returns a PersistentProcess. Call Start() to launch
@@ -101,8 +151,11 @@ func NewPersistentProcess(opts Options) *PersistentProcess {
 		done:        done,
 		processDone: processDone,
 		autoRestart: true,
-		stderrTail:  newRingBuffer(20),
+		stderrTail:  newRingBuffer(stderrBufferLines),
 		resultStore: NewResultStore(resultStoreDir(opts)),
+		cgroup:      newAgentCgroup(opts.ResourceLimits),
+
+		pendingControl: make(map[string]chan controlResult),
 	}
 }
 
@@ -117,10 +170,20 @@ func (p *PersistentProcess) Start(ctx context.Context) error {
 	}
 
 	p.status = ProcessStatusStarting
+	defer p.changes.notify()
 
 	args := p.opts.PersistentArgs()
+	resume := p.resumeNext
+	p.resumeNext = ""
+	if resume == "" {
+		resume = p.resumeSession()
+	}
+	if resume != "" {
+		args = append(args, "--resume", resume)
+	}
 
-	cmd := exec.Command("claude", args...) //nolint:gosec // args are controlled
+	binary, argv := p.opts.commandLine(args)
+	cmd := exec.Command(binary, argv...) //nolint:gosec // binary and args are controlled
 	if p.opts.WorkDir != "" {
 		cmd.Dir = p.opts.WorkDir
 	}
@@ -146,7 +209,7 @@ func (p *PersistentProcess) Start(ctx context.Context) error {
 		return err
 	}
 
-	if err := cmd.Start(); err != nil {
+	if err := startCommand(cmd, p.opts.ResourceLimits, p.cgroup); err != nil {
 		p.status = ProcessStatusError
 		p.lastError = fmt.Sprintf("failed to start claude: %v", err)
 		return fmt.Errorf("failed to start claude: %w", err)
@@ -154,6 +217,9 @@ func (p *PersistentProcess) Start(ctx context.Context) error {
 
 	p.cmd = cmd
 	p.stdin = stdinPipe
+	p.contextTokens = 0
+	p.optionsChanged = false
+	p.recorder = newRecorder(p.opts.Recording, append([]string{binary}, argv...), "")
 	p.status = ProcessStatusIdle
 	metrics.SetProcessStatus(string(ProcessStatusIdle))
 
@@ -166,10 +232,12 @@ func (p *PersistentProcess) Start(ctx context.Context) error {
 	p.cancel = cancel
 
 	// Read stderr in background and capture recent lines for crash diagnostics.
+	redactor := p.opts.Redactor
 	go func() {
 		scanner := bufio.NewScanner(stderr)
 		for scanner.Scan() {
-			line := scanner.Text()
+			line, _ := redactor.RedactString(scanner.Text())
+			line = Truncate(line, maxStderrLineLen)
 			slog.Debug("claude persistent stderr", "line", line)
 			p.mu.Lock()
 			p.stderrTail.add(line)
@@ -178,7 +246,7 @@ func (p *PersistentProcess) Start(ctx context.Context) error {
 	}()
 
 	// Read stdout stream-json messages and dispatch to active response channel.
-	go p.readLoop(readerCtx, stdout, cmd, processDone)
+	go p.readLoop(readerCtx, stdout, cmd, p.recorder, processDone)
 
 	// Start the background watchdog that auto-restarts on unexpected exit.
 	if p.autoRestart {
@@ -189,6 +257,52 @@ func (p *PersistentProcess) Start(ctx context.Context) error {
 	return nil
 }
 
+// resumeSession returns the session a starting subprocess resumes, or ""
+// to start a new conversation (see Options.ResumeOnStart). On the first
+// start, the conversation history of the last persisted result is restored
+// so that messages keep returning the whole session. The caller must hold
+// p.mu.
+func (p *PersistentProcess) resumeSession() string {
+	if !p.opts.ResumeOnStart || p.opts.NoSessionPersistence {
+		return ""
+	}
+	id := p.sessionID
+	var stored *PersistedResult
+	if id == "" {
+		if p.resultStore == nil {
+			return ""
+		}
+		pr, err := p.resultStore.Load()
+		if err != nil {
+			slog.Warn("claude: cannot load last result, starting a new session", "error", err)
+			return ""
+		}
+		if pr == nil || pr.SessionID == "" {
+			return ""
+		}
+		id, stored = pr.SessionID, pr
+	}
+
+	// Resuming a session the CLI does not know makes it exit right away,
+	// so check that its transcript exists.
+	if _, err := NewSessionStore(SessionsDir(p.opts.WorkDir), nil).Inspect(id); err != nil {
+		slog.Warn("claude: cannot resume session, starting a new one", "session_id", id, "error", err)
+		return ""
+	}
+
+	if stored != nil && len(p.liveMessages) == 0 {
+		history := stored.History
+		if len(history) == 0 {
+			history = stored.Messages
+		}
+		p.liveMessages = copyStreamMessages(history)
+		p.messageCount = len(p.liveMessages)
+		p.sessionID = id
+	}
+	slog.Info("claude: resuming session", "session_id", id)
+	return id
+}
+
 // restartBackoff is the minimum time to wait between restart attempts to
 // prevent tight restart loops when the subprocess fails immediately.
 const restartBackoff = 2 * time.Second
@@ -248,12 +362,18 @@ func (p *PersistentProcess) startWatchdog(ctx context.Context, processDone chan
 // readLoop continuously reads stream-json messages from stdout and dispatches
 // them to the active response channel. It detects result messages to mark the
 // end of a response cycle.
-func (p *PersistentProcess) readLoop(ctx context.Context, stdout io.ReadCloser, cmd *exec.Cmd, processDone chan struct{}) {
+func (p *PersistentProcess) readLoop(ctx context.Context, stdout io.ReadCloser, cmd *exec.Cmd, rec *recorder, processDone chan struct{}) {
 	defer func() {
 		waitErr := cmd.Wait()
+		rec.Close()
+		// Kill orphans the CLI left behind (e.g. background servers).
+		reapProcessGroup(cmd)
 		p.mu.Lock()
 		p.cmd = nil
 		p.stdin = nil
+		p.recorder = nil
+		p.failPendingControl(errors.New("subprocess exited"))
+		p.failPendingCompactions(errors.New("subprocess exited"))
 
 		var exitErrStr string
 		if waitErr != nil {
@@ -270,12 +390,13 @@ func (p *PersistentProcess) readLoop(ctx context.Context, stdout io.ReadCloser,
 		// If there's an active response channel, send a crash error message
 		// so the user sees something instead of silence.
 		if p.responseCh != nil && p.status != ProcessStatusStopped {
-			stderrLines := strings.Join(p.stderrTail.contents(), "\n")
-			crashText := fmt.Sprintf(
-				"The agent subprocess exited unexpectedly (exit code: %s). "+
-					"A new session will start on the next message, but conversation context has been lost.",
-				exitCodeFromError(waitErr),
-			)
+			stderrLines := strings.Join(p.stderrTail.tail(errorStderrLines), "\n")
+			next := "A new session will start on the next message, but conversation context has been lost."
+			if p.opts.ResumeOnStart && !p.opts.NoSessionPersistence && p.sessionID != "" {
+				next = "The session will be resumed when the subprocess restarts."
+			}
+			crashText := fmt.Sprintf("The agent subprocess exited unexpectedly (exit code: %s). %s",
+				exitCodeFromError(waitErr), next)
 			if stderrLines != "" {
 				crashText += fmt.Sprintf("\n\nStderr:\n%s", stderrLines)
 			}
@@ -297,20 +418,23 @@ func (p *PersistentProcess) readLoop(ctx context.Context, stdout io.ReadCloser,
 			p.responseCh = nil
 		}
 		// Close the done channel for any active prompt.
-		select {
-		case <-p.done:
-			// Already closed.
-		default:
-			close(p.done)
-		}
+		p.closeDone()
 		status := p.status
 		lastErr := p.lastError
 		close(processDone)
+		p.changes.notify()
 		p.mu.Unlock()
 		metrics.SetProcessStatus(string(status))
 		slog.Info("claude: persistent subprocess exited", "wait_err", waitErr, "last_error", lastErr, "status", status)
 	}()
 
+	// The subprocess keeps the options it was started with, see
+	// UpdateOptions.
+	p.mu.RLock()
+	redactor := p.opts.Redactor
+	nestedContent := p.opts.supports(CapabilityNestedContent)
+	p.mu.RUnlock()
+
 	scanner := bufio.NewScanner(stdout)
 	scanner.Buffer(make([]byte, 0, 1024*1024), 10*1024*1024)
 
@@ -325,8 +449,19 @@ func (p *PersistentProcess) readLoop(ctx context.Context, stdout io.ReadCloser,
 		if len(line) == 0 {
 			continue
 		}
+		rec.Line(line)
+
+		// Redact before parsing so that the message text, tool arguments,
+		// raw JSON and log output all see masked content.
+		line, redactions := redactor.Redact(line)
+		if redactions > 0 {
+			p.mu.Lock()
+			p.redactionCount += redactions
+			p.mu.Unlock()
+			metrics.RecordRedactions(redactions)
+		}
 
-		msg, parseErr := ParseStreamMessage(line)
+		msg, parseErr := parseStreamMessage(line, nestedContent)
 		if parseErr != nil {
 			slog.Warn("claude persistent: failed to parse stream message", "error", parseErr, "line", string(line))
 			continue
@@ -351,6 +486,13 @@ func (p *PersistentProcess) readLoop(ctx context.Context, stdout io.ReadCloser,
 			continue
 		}
 
+		// Control responses answer ConfigureSession and are not part of
+		// the conversation.
+		if msg.Type == MessageTypeControlResponse {
+			p.handleControlResponse(line)
+			continue
+		}
+
 		p.mu.Lock()
 		p.messageCount++
 		p.liveMessages = append(p.liveMessages, msg)
@@ -358,6 +500,9 @@ func (p *PersistentProcess) readLoop(ctx context.Context, stdout io.ReadCloser,
 		if msg.Type == MessageTypeSystem && msg.SessionID != "" {
 			p.sessionID = msg.SessionID
 		}
+		if msg.Type == MessageTypeSystem && msg.Subtype == SubtypeCompactBoundary {
+			p.handleCompactBoundary(msg)
+		}
 		if msg.Type == MessageTypeAssistant {
 			p.sawContent = true
 			if model := ExtractModel(msg); model != "" {
@@ -399,6 +544,9 @@ func (p *PersistentProcess) readLoop(ctx context.Context, stdout io.ReadCloser,
 			p.tokenUsage.OutputTokens += msg.Usage.OutputTokens
 			p.tokenUsage.CacheCreationInputTokens += msg.Usage.CacheCreationInputTokens
 			p.tokenUsage.CacheReadInputTokens += msg.Usage.CacheReadInputTokens
+			if msg.Type == MessageTypeAssistant {
+				p.contextTokens += msg.Usage.InputTokens + msg.Usage.CacheCreationInputTokens
+			}
 		}
 		// Compute the cost delta before overwriting the running total so we
 		// only add the incremental cost to the Prometheus counter.
@@ -415,8 +563,14 @@ func (p *PersistentProcess) readLoop(ctx context.Context, stdout io.ReadCloser,
 			p.costSeen = true
 		}
 
+		// The result of a /compact command ends the compaction, not the
+		// response cycle of a prompt.
+		compacted := msg.Type == MessageTypeResult && p.completeCompaction(msg)
+		status := p.status
+
 		// Dispatch to the active response channel if one exists.
 		ch := p.responseCh
+		p.changes.notify()
 		p.mu.Unlock()
 
 		// Record Prometheus metrics.
@@ -424,6 +578,10 @@ func (p *PersistentProcess) readLoop(ctx context.Context, stdout io.ReadCloser,
 		if msg.Type == MessageTypeResult {
 			metrics.RecordCost(costDelta)
 		}
+		if compacted {
+			metrics.SetProcessStatus(string(status))
+			continue
+		}
 
 		if ch != nil {
 			select {
@@ -454,12 +612,8 @@ func (p *PersistentProcess) readLoop(ctx context.Context, stdout io.ReadCloser,
 					p.status = ProcessStatusIdle
 				}
 				// Signal done for this prompt.
-				select {
-				case <-p.done:
-					// Already closed.
-				default:
-					close(p.done)
-				}
+				p.closeDone()
+				p.changes.notify()
 			}
 			status := p.status
 			p.mu.Unlock()
@@ -473,10 +627,11 @@ func (p *PersistentProcess) readLoop(ctx context.Context, stdout io.ReadCloser,
 }
 
 // stdinMessageContent represents the nested message payload in the
-// stream-json input format expected by claude-code v2.1+.
+// stream-json input format expected by claude-code v2.1+. Content is either
+// the prompt string or a list of content blocks when attachments are sent.
 type stdinMessageContent struct {
 	Role    string `json:"role"`
-	Content string `json:"content"`
+	Content any    `json:"content"`
 }
 
 // stdinMessage is the JSON structure written to the subprocess stdin
@@ -500,14 +655,46 @@ func (p *PersistentProcess) Run(ctx context.Context, prompt string) (<-chan Stre
 // subprocess flags were set at Start time. If non-empty overrides are provided,
 // a warning is logged listing which fields were ignored.
 func (p *PersistentProcess) RunWithOptions(ctx context.Context, prompt string, runOpts *RunOptions) (<-chan StreamMessage, error) {
+	// Unlike other overrides, a profile is not ignored: the run would not
+	// be limited the way the caller expects.
+	if runOpts != nil && runOpts.Profile != "" && runOpts.Profile != p.options().Profile {
+		return nil, fmt.Errorf("profile %q cannot be selected per run in chat mode; select it at startup", runOpts.Profile)
+	}
 	if runOpts != nil {
 		if ignored := runOpts.ignoredFields(); len(ignored) > 0 {
 			slog.Warn("claude persistent: per-invocation overrides ignored in persistent mode", "ignored", strings.Join(ignored, ", "))
 		}
 	}
 
+	// Resolve attachments before changing state so that a bad attachment
+	// is reported to the caller without disturbing the session.
+	var attachments []Attachment
+	if runOpts != nil {
+		attachments = runOpts.Attachments
+	}
+	content, err := buildPromptContent(p.options().WorkDir, prompt, attachments)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attachments: %w", err)
+	}
+
 	p.mu.Lock()
 
+	if p.restarting {
+		p.mu.Unlock()
+		return nil, ErrBusy
+	}
+	if p.optionsChanged && p.cmd != nil && p.status != ProcessStatusBusy {
+		p.restarting = true
+		p.mu.Unlock()
+		err := p.restartWithOptions()
+		p.mu.Lock()
+		p.restarting = false
+		if err != nil {
+			p.mu.Unlock()
+			return nil, err
+		}
+	}
+
 	if p.cmd == nil {
 		p.mu.Unlock()
 		// Auto-start if not yet started.
@@ -523,6 +710,7 @@ func (p *PersistentProcess) RunWithOptions(ctx context.Context, prompt string, r
 	}
 
 	p.status = ProcessStatusBusy
+	p.runID = newRunID()
 	if p.lastError != "" {
 		p.previousError = p.lastError
 	}
@@ -546,29 +734,41 @@ func (p *PersistentProcess) RunWithOptions(ctx context.Context, prompt string, r
 	// Inject a synthetic user message so the prompt appears in liveMessages.
 	// The Claude CLI only emits assistant/system/result on stdout; the user's
 	// input prompt is never echoed back, so we record it here (#179).
-	p.liveMessages = append(p.liveMessages, syntheticUserMessage(prompt))
+	// Only the recorded copy is redacted; the CLI receives the prompt as is.
+	recorded, redactions := p.opts.Redactor.RedactString(prompt)
+	p.redactionCount = redactions
+	p.liveMessages = append(p.liveMessages, syntheticUserMessage(recorded))
 	p.messageCount++
 
+	// Compact ahead of the prompt once the context estimate passes the
+	// threshold. The CLI handles stdin in order, so the prompt runs on the
+	// compacted conversation.
+	autoCompact := p.opts.AutoCompact.due(p.contextTokens)
+	if autoCompact {
+		p.compactQueue = append(p.compactQueue, compactRequest{trigger: CompactTriggerAuto})
+	}
+	compactInstructions := p.opts.AutoCompact.Instructions
+
 	// Create response channel and done channel for this prompt.
 	ch := make(chan StreamMessage, 100)
 	p.responseCh = ch
 	p.sawContent = false
 	done := make(chan struct{})
+	if runOpts != nil && runOpts.done != nil {
+		done = runOpts.done
+	}
 	p.done = done
+	p.doneAfterStore = runOpts != nil && runOpts.done != nil
 
 	stdin := p.stdin
+	rec := p.recorder
+	p.changes.notify()
 	p.mu.Unlock()
 	metrics.SetProcessStatus(string(ProcessStatusBusy))
+	metrics.RecordRedactions(redactions)
 
 	// Write the user message to stdin as stream-json.
-	msg := stdinMessage{
-		Type: string(MessageTypeUser),
-		Message: stdinMessageContent{
-			Role:    string(MessageTypeUser),
-			Content: prompt,
-		},
-	}
-	data, err := json.Marshal(msg)
+	data, err := marshalStdinMessage(content)
 	if err != nil {
 		p.setError(fmt.Sprintf("failed to marshal stdin message: %v", err))
 		close(ch)
@@ -576,10 +776,16 @@ func (p *PersistentProcess) RunWithOptions(ctx context.Context, prompt string, r
 		return nil, fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	// Append newline delimiter for stream-json protocol.
-	data = append(data, '\n')
-
-	if _, err := stdin.Write(data); err != nil {
+	if autoCompact {
+		if err := p.writeCompact(stdin, rec, compactInstructions); err != nil {
+			p.setError(fmt.Sprintf("failed to write to stdin: %v", err))
+			close(ch)
+			close(done)
+			return nil, fmt.Errorf("failed to write to stdin: %w", err)
+		}
+	}
+	rec.Prompt(prompt)
+	if err := p.writeStdin(stdin, data); err != nil {
 		p.setError(fmt.Sprintf("failed to write to stdin: %v", err))
 		close(ch)
 		close(done)
@@ -616,8 +822,8 @@ loop:
 	return CollectResultText(messages), messages, nil
 }
 
-// Stop sends SIGTERM to the persistent subprocess and waits for it to exit.
-// It also cancels the background watchdog to prevent auto-restart.
+// Stop sends SIGTERM to the persistent subprocess and everything it spawned,
+// and waits for it to exit. It also cancels the background watchdog to prevent auto-restart.
 func (p *PersistentProcess) Stop() error {
 	// Cancel the watchdog first to prevent restart during shutdown.
 	if p.watchdogCancel != nil {
@@ -640,22 +846,52 @@ func (p *PersistentProcess) Stop() error {
 		_ = p.stdin.Close()
 		p.stdin = nil
 	}
+	p.changes.notify()
 	p.mu.Unlock()
 	metrics.SetProcessStatus(string(ProcessStatusStopped))
 
-	// Send SIGTERM first.
-	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+	// Send SIGTERM first. If that fails, the process has usually exited
+	// already; it is waited for all the same.
+	if err := signalProcessGroup(cmd, syscall.SIGTERM); err != nil {
 		slog.Warn("claude persistent: SIGTERM failed (process may have already exited)", "error", err)
+	}
+
+	// Wait for a graceful shutdown. Stop only returns nil once the process
+	// has been reaped, so that it can be started again.
+	if waitProcessDone(processDone, stopGracePeriod) {
 		return nil
 	}
+	slog.Warn("claude persistent: process did not exit after SIGTERM, sending SIGKILL")
+	killErr := signalProcessGroup(cmd, syscall.SIGKILL)
+	p.cgroup.kill()
+	if waitProcessDone(processDone, stopKillTimeout) {
+		return nil
+	}
+	if killErr != nil {
+		return fmt.Errorf("claude process did not exit: %w", killErr)
+	}
+	return errors.New("claude process did not exit after SIGKILL")
+}
 
-	// Wait up to 10 seconds for graceful shutdown.
+// Timeouts of PersistentProcess.Stop.
+var (
+	// stopGracePeriod is how long the process may take to exit after
+	// SIGTERM before it is killed.
+	stopGracePeriod = 10 * time.Second
+	// stopKillTimeout is how long to wait for the process to be reaped
+	// after SIGKILL.
+	stopKillTimeout = 5 * time.Second
+)
+
+// waitProcessDone reports whether processDone is closed within timeout.
+func waitProcessDone(processDone <-chan struct{}, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
 	select {
 	case <-processDone:
-		return nil
-	case <-time.After(10 * time.Second):
-		slog.Warn("claude persistent: process did not exit after SIGTERM, sending SIGKILL")
-		return cmd.Process.Kill()
+		return true
+	case <-timer.C:
+		return false
 	}
 }
 
@@ -673,25 +909,31 @@ func (p *PersistentProcess) Submit(ctx context.Context, prompt string, opts *Run
 		// "finished with results" from "never ran" (idle).
 		if rs.completed && p.status == ProcessStatusIdle {
 			p.status = ProcessStatusCompleted
+			p.changes.notify()
+		}
+		meta := runMeta{
+			status:     p.status,
+			sessionID:  p.sessionID,
+			runID:      p.runID,
+			lastError:  p.lastError,
+			redactions: p.redactionCount,
+		}
+		if p.costSeen {
+			meta.totalCost = Float64Ptr(p.totalCost)
+		}
+		if tu := p.tokenUsage; tu != (TokenUsage{}) {
+			meta.tokenUsage = &tu
+		}
+		if meta.status == ProcessStatusError {
+			meta.stderr = p.stderrTail.tail(errorStderrLines)
+		}
+		if p.opts.ResumeOnStart {
+			meta.history = copyStreamMessages(p.liveMessages)
 		}
-		status := p.status
-		sessionID := p.sessionID
-		totalCost := p.totalCost
-		costSeen := p.costSeen
-		lastError := p.lastError
-		tu := p.tokenUsage
 		store := p.resultStore
 		p.mu.Unlock()
 
-		var costPtr *float64
-		if costSeen {
-			costPtr = Float64Ptr(totalCost)
-		}
-		var tuPtr *TokenUsage
-		if tu != (TokenUsage{}) {
-			tuPtr = &tu
-		}
-		persistResult(store, rs, status, sessionID, costPtr, lastError, tuPtr)
+		persistResult(store, rs, meta)
 	})
 }
 
@@ -699,18 +941,25 @@ func (p *PersistentProcess) Submit(ctx context.Context, prompt string, opts *Run
 func (p *PersistentProcess) Status() StatusInfo {
 	p.mu.RLock()
 	info := StatusInfo{
-		Status:        p.status,
-		SessionID:     p.sessionID,
-		ErrorMessage:  p.lastError,
-		PreviousError: p.previousError,
-		MessageCount:  p.messageCount,
-		ToolCallCount: p.toolCallCount,
-		ToolCalls:     copyToolCalls(p.toolCalls),
-		LastMessage:   p.lastMessage,
-		LastToolName:  p.lastToolName,
-		SubagentCalls: copySubagentCalls(p.subagents.calls()),
-		ModelUsage:    copyToolCalls(p.modelUsage),
-		ErrorCount:    p.errorCount,
+		Status:         p.status,
+		SessionID:      p.sessionID,
+		RunID:          p.runID,
+		ErrorMessage:   p.lastError,
+		PreviousError:  p.previousError,
+		MessageCount:   p.messageCount,
+		ToolCallCount:  p.toolCallCount,
+		ToolCalls:      copyToolCalls(p.toolCalls),
+		LastMessage:    p.lastMessage,
+		LastToolName:   p.lastToolName,
+		SubagentCalls:  copySubagentCalls(p.subagents.calls()),
+		ModelUsage:     copyToolCalls(p.modelUsage),
+		ErrorCount:     p.errorCount,
+		RedactionCount: p.redactionCount,
+		Model:          p.opts.Model,
+		PermissionMode: p.opts.PermissionMode,
+		Profile:        p.opts.Profile,
+		ContextTokens:  p.contextTokens,
+		Compactions:    len(p.compactions),
 	}
 
 	if p.costSeen {
@@ -725,6 +974,13 @@ func (p *PersistentProcess) Status() StatusInfo {
 	if p.status == ProcessStatusCompleted {
 		info.Result = Truncate(p.result.text, maxStatusResultLen)
 	}
+	if p.status == ProcessStatusError {
+		info.Stderr = p.stderrTail.tail(errorStderrLines)
+	}
+	if p.opts.CLIVersion != nil {
+		info.CLIVersion = p.opts.CLIVersion.String()
+	}
+	info.MCPServers = p.opts.MCPProber.Health()
 
 	store := p.resultStore
 	p.mu.RUnlock()
@@ -737,6 +993,9 @@ func (p *PersistentProcess) Status() StatusInfo {
 			if info.SessionID == "" {
 				info.SessionID = pr.SessionID
 			}
+			if info.RunID == "" {
+				info.RunID = pr.RunID
+			}
 			if info.TotalCost == nil && pr.TotalCost != nil {
 				info.TotalCost = pr.TotalCost
 			}
@@ -765,17 +1024,20 @@ func (p *PersistentProcess) Status() StatusInfo {
 func (p *PersistentProcess) ResultDetail() ResultDetailInfo {
 	p.mu.RLock()
 	detail := ResultDetailInfo{
-		ResultText:    p.result.text,
-		Messages:      p.result.messages,
-		MessageCount:  p.messageCount,
-		ToolCalls:     copyToolCalls(p.toolCalls),
-		SubagentCalls: copySubagentCalls(p.subagents.calls()),
-		ModelUsage:    copyToolCalls(p.modelUsage),
-		PRURLs:        copyStringSlice(p.prURLs),
-		ErrorCount:    p.errorCount,
-		SessionID:     p.sessionID,
-		Status:        p.status,
-		ErrorMessage:  p.lastError,
+		ResultText:     p.result.text,
+		Messages:       p.result.messages,
+		MessageCount:   p.messageCount,
+		ToolCalls:      copyToolCalls(p.toolCalls),
+		SubagentCalls:  copySubagentCalls(p.subagents.calls()),
+		ModelUsage:     copyToolCalls(p.modelUsage),
+		PRURLs:         copyStringSlice(p.prURLs),
+		ErrorCount:     p.errorCount,
+		RedactionCount: p.redactionCount,
+		SessionID:      p.sessionID,
+		RunID:          p.runID,
+		Status:         p.status,
+		ErrorMessage:   p.lastError,
+		Compactions:    copyCompactions(p.compactions),
 	}
 	if p.costSeen {
 		detail.TotalCost = Float64Ptr(p.totalCost)
@@ -797,6 +1059,14 @@ func (p *PersistentProcess) ResultDetail() ResultDetailInfo {
 	return detail
 }
 
+// Logs returns the most recent stderr lines captured from the subprocess.
+// The buffer spans restarts, so it includes the output leading up to a crash.
+func (p *PersistentProcess) Logs(limit int) LogsInfo {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return stderrLogs(p.status, p.stderrTail, limit)
+}
+
 // Messages returns the current conversation messages. liveMessages accumulates
 // across turns, so it is preferred over result.messages (which only contains
 // the last Submit run). Falls back to persisted state on disk when empty.
@@ -876,29 +1146,52 @@ func (p *PersistentProcess) OpenAIMessages(offset int) OpenAIMessagesInfo {
 	live := copyStreamMessages(p.liveMessages)
 	resMessages := copyStreamMessages(p.result.messages)
 	store := p.resultStore
+	prober := p.opts.MCPProber
 	p.mu.RUnlock()
 
+	info := OpenAIMessagesInfo{Messages: []OpenAIMessage{}, Metadata: OpenAIMetadata{}}
 	if len(live) > 0 {
-		return collectOpenAIMessages(status, live, offset)
-	}
-	if len(resMessages) > 0 {
-		return collectOpenAIMessages(status, resMessages, offset)
-	}
-	if store != nil {
+		info = collectOpenAIMessages(status, live, offset)
+	} else if len(resMessages) > 0 {
+		info = collectOpenAIMessages(status, resMessages, offset)
+	} else if store != nil {
 		if pr, err := store.Load(); err == nil && pr != nil && len(pr.Messages) > 0 {
-			return collectOpenAIMessages(status, pr.Messages, offset)
+			info = collectOpenAIMessages(status, pr.Messages, offset)
 		}
 	}
-	return OpenAIMessagesInfo{Messages: []OpenAIMessage{}, Metadata: OpenAIMetadata{}}
+	info.Metadata.MCPServers = prober.Health()
+	return info
 }
 
-// Done returns a channel closed when the current prompt response is complete.
+// closeDone closes the done channel of the current prompt unless it is
+// already closed or closed by Submit. p.mu must be held.
+func (p *PersistentProcess) closeDone() {
+	if p.doneAfterStore {
+		return
+	}
+	select {
+	case <-p.done:
+		// Already closed.
+	default:
+		close(p.done)
+	}
+}
+
+// Done returns a channel closed when the current prompt response is
+// complete. For a prompt sent with Submit, it is closed once the result is
+// stored, so that Status reports the final status.
 func (p *PersistentProcess) Done() <-chan struct{} {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 	return p.done
 }
 
+// Changes returns a channel that is closed at the next change of the
+// status, run or message count.
+func (p *PersistentProcess) Changes() <-chan struct{} {
+	return p.changes.next()
+}
+
 // MarshalStatus returns the status as JSON.
 func (p *PersistentProcess) MarshalStatus() ([]byte, error) {
 	return json.Marshal(p.Status())
@@ -909,6 +1202,7 @@ func (p *PersistentProcess) setError(msg string) {
 	defer p.mu.Unlock()
 	p.status = ProcessStatusError
 	p.lastError = msg
+	p.changes.notify()
 	metrics.SetProcessStatus(string(ProcessStatusError))
 }
 
@@ -923,33 +1217,3 @@ func exitCodeFromError(err error) string {
 	}
 	return err.Error()
 }
-
-// ringBuffer is a simple fixed-size circular buffer for strings.
-type ringBuffer struct {
-	lines []string
-	pos   int
-	full  bool
-}
-
-func newRingBuffer(size int) *ringBuffer {
-	return &ringBuffer{lines: make([]string, size)}
-}
-
-func (r *ringBuffer) add(line string) {
-	r.lines[r.pos] = line
-	r.pos++
-	if r.pos >= len(r.lines) {
-		r.pos = 0
-		r.full = true
-	}
-}
-
-func (r *ringBuffer) contents() []string {
-	if !r.full {
-		return append([]string(nil), r.lines[:r.pos]...)
-	}
-	result := make([]string, 0, len(r.lines))
-	result = append(result, r.lines[r.pos:]...)
-	result = append(result, r.lines[:r.pos]...)
-	return result
-}
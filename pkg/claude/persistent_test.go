@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/giantswarm/klaus/pkg/redact"
 )
 
 func TestNewPersistentProcess_InitialState(t *testing.T) {
@@ -141,6 +144,34 @@ func TestPersistentProcess_StopWhenNotRunning(t *testing.T) {
 	}
 }
 
+func TestPersistentProcess_StopKillsAndReaps(t *testing.T) {
+	// The CLI ignores SIGTERM, so it has to be killed.
+	installFakeClaude(t, "trap '' TERM\nexec sleep 60\n")
+	grace := stopGracePeriod
+	stopGracePeriod = 100 * time.Millisecond
+	t.Cleanup(func() { stopGracePeriod = grace })
+
+	opts := DefaultOptions()
+	opts.WorkDir = t.TempDir()
+	opts.ResultDir = t.TempDir()
+	p := NewPersistentProcess(opts)
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	p.mu.RLock()
+	processDone := p.processDone
+	p.mu.RUnlock()
+
+	if err := p.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	select {
+	case <-processDone:
+	default:
+		t.Error("expected Stop to return only once the process has been reaped")
+	}
+}
+
 func TestPersistentProcess_ImplementsPrompter(t *testing.T) {
 	// Compile-time check that PersistentProcess implements Prompter.
 	var _ Prompter = (*PersistentProcess)(nil)
@@ -570,7 +601,7 @@ func main() {
 	defer cancel()
 
 	// Start the readLoop (it will read stdout and handle the crash).
-	go p.readLoop(readerCtx, stdout, cmd, newProcessDone)
+	go p.readLoop(readerCtx, stdout, cmd, nil, newProcessDone)
 
 	// Write a message to stdin to trigger the helper to run.
 	_, _ = stdinPipe.Write([]byte(`{"type":"user","message":{"role":"user","content":"hello"}}` + "\n"))
@@ -760,3 +791,152 @@ func TestExitCodeFromError(t *testing.T) {
 		}
 	})
 }
+
+func TestPersistentProcess_ReadLoopRedactsSecrets(t *testing.T) {
+	keyID := "AKIA" + "IOSFODNN7EXAMPLE"
+	fixture := filepath.Join(t.TempDir(), "out.jsonl")
+	lines := `{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"key ` + keyID + `"}]}}` + "\n" +
+		`{"type":"result","subtype":"success","result":"done ` + keyID + `"}` + "\n"
+	if err := os.WriteFile(fixture, []byte(lines), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := redact.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.Redactor = r
+	p := NewPersistentProcess(opts)
+	p.autoRestart = false
+
+	cmd := exec.Command("cat", fixture) // #nosec G204 -- test fixture with trusted path
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	processDone := make(chan struct{})
+	ch := make(chan StreamMessage, 10)
+	p.mu.Lock()
+	p.cmd = cmd
+	p.processDone = processDone
+	p.status = ProcessStatusBusy
+	p.responseCh = ch
+	p.done = make(chan struct{})
+	p.mu.Unlock()
+
+	go p.readLoop(context.Background(), stdout, cmd, nil, processDone)
+
+	select {
+	case <-processDone:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for readLoop")
+	}
+
+	for msg := range ch {
+		if strings.Contains(msg.Text, keyID) || strings.Contains(msg.Result, keyID) || strings.Contains(string(msg.Raw), keyID) {
+			t.Errorf("message not redacted: %s", msg.Raw)
+		}
+	}
+	if got := p.Status().RedactionCount; got != 2 {
+		t.Errorf("RedactionCount = %d, want 2", got)
+	}
+}
+
+// resumeFakeClaude records its arguments and answers every prompt as
+// session "s-next".
+const resumeFakeClaude = `echo "$@" > "$ARGS_FILE"
+while IFS= read -r line; do
+  echo '{"type":"system","subtype":"init","session_id":"s-next"}'
+  echo '{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"ok"}]}}'
+  echo '{"type":"result","subtype":"success","result":"ok"}'
+done
+`
+
+func TestPersistentProcess_ResumeOnStart(t *testing.T) {
+	argsFile := filepath.Join(t.TempDir(), "args")
+	t.Setenv("ARGS_FILE", argsFile)
+	t.Setenv("CLAUDE_CONFIG_DIR", t.TempDir())
+	installFakeClaude(t, resumeFakeClaude)
+
+	opts := DefaultOptions()
+	opts.NoSessionPersistence = false
+	opts.ResumeOnStart = true
+	opts.WorkDir = t.TempDir()
+	opts.ResultDir = t.TempDir()
+
+	sessionsDir := SessionsDir(opts.WorkDir)
+	if err := os.MkdirAll(sessionsDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeSession(t, sessionsDir, "s-prev", time.Now(), `{"type":"user","message":{"content":"hi"}}`)
+	history := []StreamMessage{
+		syntheticUserMessage("hi"),
+		{Type: MessageTypeAssistant, Raw: json.RawMessage(`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"hello"}]}}`)},
+	}
+	if err := NewResultStore(opts.ResultDir).Save(PersistedResult{SessionID: "s-prev", History: history, Status: ProcessStatusCompleted}); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPersistentProcess(opts)
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { _ = p.Stop() })
+
+	if got := p.Status(); got.SessionID != "s-prev" || got.MessageCount != 2 {
+		t.Errorf("history not restored: session %q, %d messages", got.SessionID, got.MessageCount)
+	}
+	if err := p.Submit(context.Background(), "continue", nil); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Status().Status != ProcessStatusCompleted {
+		if time.Now().After(deadline) {
+			t.Fatalf("prompt did not complete: %+v", p.Status())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if args, err := os.ReadFile(argsFile); err != nil || !strings.Contains(string(args), "--resume s-prev") {
+		t.Errorf("expected --resume s-prev in %q, %v", args, err)
+	}
+
+	// The next start resumes the session the CLI reported, with the whole
+	// conversation persisted.
+	pr, err := NewResultStore(opts.ResultDir).Load()
+	if err != nil || pr == nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if pr.SessionID != "s-next" || len(pr.History) < 4 {
+		t.Errorf("unexpected persisted result: session %q, %d history messages", pr.SessionID, len(pr.History))
+	}
+}
+
+func TestPersistentProcess_ResumeOnStartSkipsMissingSession(t *testing.T) {
+	t.Setenv("CLAUDE_CONFIG_DIR", t.TempDir())
+	opts := DefaultOptions()
+	opts.NoSessionPersistence = false
+	opts.ResumeOnStart = true
+	opts.WorkDir = t.TempDir()
+	opts.ResultDir = t.TempDir()
+	if err := NewResultStore(opts.ResultDir).Save(PersistedResult{SessionID: "gone", Messages: []StreamMessage{syntheticUserMessage("hi")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPersistentProcess(opts)
+	if got := p.resumeSession(); got != "" {
+		t.Errorf("resumed missing session %q", got)
+	}
+	if len(p.liveMessages) != 0 {
+		t.Errorf("restored messages of a missing session: %d", len(p.liveMessages))
+	}
+
+	opts.ResumeOnStart = false
+	if got := NewPersistentProcess(opts).resumeSession(); got != "" {
+		t.Errorf("resumed without ResumeOnStart: %q", got)
+	}
+}
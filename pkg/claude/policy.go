@@ -0,0 +1,144 @@
+package claude
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Override field names governed by OverridePolicy. They match the parameter
+// names of the prompt MCP tool.
+const (
+	OverrideModel              = "model"
+	OverrideMaxTurns           = "max_turns"
+	OverrideAllowedTools       = "allowed_tools"
+	OverrideDisallowedTools    = "disallowed_tools"
+	OverrideAppendSystemPrompt = "append_system_prompt"
+	OverrideProfile            = "profile"
+)
+
+// ValidOverrideFields lists all per-run override fields governed by OverridePolicy.
+var ValidOverrideFields = []string{
+	OverrideModel,
+	OverrideMaxTurns,
+	OverrideAllowedTools,
+	OverrideDisallowedTools,
+	OverrideAppendSystemPrompt,
+	OverrideProfile,
+}
+
+// ErrOverrideDenied is returned when a per-run override is not permitted by
+// the configured OverridePolicy.
+var ErrOverrideDenied = errors.New("override not permitted by policy")
+
+// OverridePolicy governs which privilege-relevant per-run overrides callers
+// may apply (model, max turns, tool lists, appended system prompt and
+// profile) and within what bounds. The zero value permits none of them, so
+// a deployment must opt in explicitly.
+//
+// Disallowed tools and the appended system prompt are always merged with the
+// base configuration rather than replacing it, so they can only restrict the
+// agent further.
+type OverridePolicy struct {
+	// Allow lists the fields callers may override (see ValidOverrideFields).
+	Allow []string
+	// Models restricts model overrides to these values; empty permits any model.
+	Models []string
+	// MaxTurns is the upper bound for max_turns overrides; 0 means no bound.
+	MaxTurns int
+	// NarrowToolsOnly requires allowed_tools overrides to be a subset of the
+	// base AllowedTools. When the base list is empty (all tools allowed),
+	// any list is a narrowing.
+	NarrowToolsOnly bool
+	// PermissionModes lists the permission modes a live session may be
+	// switched to with ConfigureSession; empty permits no switch.
+	PermissionModes []string
+}
+
+// ValidateOverrideField checks whether name is a known override field.
+func ValidateOverrideField(name string) error {
+	if slices.Contains(ValidOverrideFields, name) {
+		return nil
+	}
+	return fmt.Errorf("invalid override field %q; valid fields: %s", name, strings.Join(ValidOverrideFields, ", "))
+}
+
+func (p OverridePolicy) allows(field string) bool {
+	return slices.Contains(p.Allow, field)
+}
+
+// Check reports whether the policy-governed overrides in ro are permitted
+// on top of the base options. All violations are returned joined; each wraps
+// ErrOverrideDenied.
+func (p OverridePolicy) Check(base Options, ro *RunOptions) error {
+	if ro == nil {
+		return nil
+	}
+	var errs []error
+	deny := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrOverrideDenied, fmt.Sprintf(format, args...)))
+	}
+
+	if ro.Model != "" {
+		switch {
+		case !p.allows(OverrideModel):
+			deny("%s may not be overridden", OverrideModel)
+		case len(p.Models) > 0 && !slices.Contains(p.Models, ro.Model):
+			deny("model %q is not one of %s", ro.Model, strings.Join(p.Models, ", "))
+		}
+	}
+
+	if ro.MaxTurns != 0 {
+		switch {
+		case !p.allows(OverrideMaxTurns):
+			deny("%s may not be overridden", OverrideMaxTurns)
+		case ro.MaxTurns < 0:
+			errs = append(errs, fmt.Errorf("%s must be > 0, got %d", OverrideMaxTurns, ro.MaxTurns))
+		case p.MaxTurns > 0 && ro.MaxTurns > p.MaxTurns:
+			deny("%s %d exceeds the limit of %d", OverrideMaxTurns, ro.MaxTurns, p.MaxTurns)
+		}
+	}
+
+	if len(ro.AllowedTools) > 0 {
+		if !p.allows(OverrideAllowedTools) {
+			deny("%s may not be overridden", OverrideAllowedTools)
+		} else if p.NarrowToolsOnly && len(base.AllowedTools) > 0 {
+			var widened []string
+			for _, tool := range ro.AllowedTools {
+				if !slices.Contains(base.AllowedTools, tool) {
+					widened = append(widened, tool)
+				}
+			}
+			if len(widened) > 0 {
+				deny("%s may only narrow the configured tools; not allowed: %s", OverrideAllowedTools, strings.Join(widened, ", "))
+			}
+		}
+	}
+
+	if len(ro.DisallowedTools) > 0 && !p.allows(OverrideDisallowedTools) {
+		deny("%s may not be overridden", OverrideDisallowedTools)
+	}
+
+	if ro.AppendSystemPrompt != "" && !p.allows(OverrideAppendSystemPrompt) {
+		deny("%s may not be overridden", OverrideAppendSystemPrompt)
+	}
+
+	if ro.Profile != "" && !p.allows(OverrideProfile) {
+		deny("%s may not be overridden", OverrideProfile)
+	}
+
+	return errors.Join(errs...)
+}
+
+// CheckPermissionMode reports whether a live session may be switched to
+// the given permission mode.
+func (p OverridePolicy) CheckPermissionMode(mode string) error {
+	if !slices.Contains(p.PermissionModes, mode) {
+		if len(p.PermissionModes) == 0 {
+			return fmt.Errorf("%w: permission mode may not be changed", ErrOverrideDenied)
+		}
+		return fmt.Errorf("%w: permission mode %q is not one of %s", ErrOverrideDenied, mode, strings.Join(p.PermissionModes, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOverridePolicy_Check(t *testing.T) {
+	base := Options{AllowedTools: []string{"Read", "Edit", "Bash(git:*)"}}
+	permissive := OverridePolicy{Allow: ValidOverrideFields}
+
+	tests := []struct {
+		name    string
+		policy  OverridePolicy
+		base    Options
+		ro      *RunOptions
+		wantErr string
+	}{
+		{name: "nil run options", policy: OverridePolicy{}, ro: nil},
+		{name: "no governed overrides", policy: OverridePolicy{}, ro: &RunOptions{Effort: "high", ActiveAgent: "x"}},
+		{name: "zero policy denies model", policy: OverridePolicy{}, ro: &RunOptions{Model: "opus"}, wantErr: "model may not be overridden"},
+		{name: "zero policy denies tools", policy: OverridePolicy{}, ro: &RunOptions{DisallowedTools: []string{"Bash"}}, wantErr: "disallowed_tools may not be overridden"},
+		{name: "zero policy denies system prompt", policy: OverridePolicy{}, ro: &RunOptions{AppendSystemPrompt: "x"}, wantErr: "append_system_prompt may not be overridden"},
+		{name: "permissive allows all", policy: permissive, base: base, ro: &RunOptions{
+			Model: "opus", MaxTurns: 100, AllowedTools: []string{"WebFetch"}, DisallowedTools: []string{"Bash"}, AppendSystemPrompt: "x",
+		}},
+		{name: "model allowlist", policy: OverridePolicy{Allow: []string{OverrideModel}, Models: []string{"haiku", "sonnet"}}, ro: &RunOptions{Model: "haiku"}},
+		{name: "model outside allowlist", policy: OverridePolicy{Allow: []string{OverrideModel}, Models: []string{"haiku", "sonnet"}}, ro: &RunOptions{Model: "opus"}, wantErr: `model "opus" is not one of haiku, sonnet`},
+		{name: "max turns within bound", policy: OverridePolicy{Allow: []string{OverrideMaxTurns}, MaxTurns: 50}, ro: &RunOptions{MaxTurns: 50}},
+		{name: "max turns above bound", policy: OverridePolicy{Allow: []string{OverrideMaxTurns}, MaxTurns: 50}, ro: &RunOptions{MaxTurns: 51}, wantErr: "max_turns 51 exceeds the limit of 50"},
+		{name: "negative max turns", policy: OverridePolicy{Allow: []string{OverrideMaxTurns}}, ro: &RunOptions{MaxTurns: -1}, wantErr: "must be > 0"},
+		{name: "narrowing subset", policy: OverridePolicy{Allow: []string{OverrideAllowedTools}, NarrowToolsOnly: true}, base: base, ro: &RunOptions{AllowedTools: []string{"Read", "Bash(git:*)"}}},
+		{name: "narrowing rejects widening", policy: OverridePolicy{Allow: []string{OverrideAllowedTools}, NarrowToolsOnly: true}, base: base, ro: &RunOptions{AllowedTools: []string{"Read", "WebFetch", "Bash"}}, wantErr: "not allowed: WebFetch, Bash"},
+		{name: "narrowing with unrestricted base", policy: OverridePolicy{Allow: []string{OverrideAllowedTools}, NarrowToolsOnly: true}, ro: &RunOptions{AllowedTools: []string{"WebFetch"}}},
+		{name: "zero policy denies profile", policy: OverridePolicy{}, ro: &RunOptions{Profile: "reviewer"}, wantErr: "profile may not be overridden"},
+		{name: "multiple violations joined", policy: OverridePolicy{}, ro: &RunOptions{Model: "opus", MaxTurns: 3}, wantErr: "max_turns may not be overridden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.base, tt.ro)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestOverridePolicy_CheckWrapsErrOverrideDenied(t *testing.T) {
+	err := OverridePolicy{}.Check(Options{}, &RunOptions{Model: "opus"})
+	if !errors.Is(err, ErrOverrideDenied) {
+		t.Errorf("expected ErrOverrideDenied, got %v", err)
+	}
+}
+
+func TestValidateOverrideField(t *testing.T) {
+	for _, f := range ValidOverrideFields {
+		if err := ValidateOverrideField(f); err != nil {
+			t.Errorf("unexpected error for %q: %v", f, err)
+		}
+	}
+	if err := ValidateOverrideField("permission_mode"); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
+
+func TestProcess_RunWithOptions_RejectsDeniedOverride(t *testing.T) {
+	p := NewProcess(Options{})
+	_, err := p.RunWithOptions(context.Background(), "hi", &RunOptions{Model: "opus"})
+	if !errors.Is(err, ErrOverrideDenied) {
+		t.Fatalf("expected ErrOverrideDenied, got %v", err)
+	}
+	if status := p.Status().Status; status != ProcessStatusIdle {
+		t.Errorf("expected status to remain idle, got %q", status)
+	}
+}
+
+func TestRunOptions_IgnoredFieldsIncludesOverrides(t *testing.T) {
+	ro := &RunOptions{Model: "opus", MaxTurns: 3, AllowedTools: []string{"Read"}, DisallowedTools: []string{"Bash"}, AppendSystemPrompt: "x"}
+	got := strings.Join(ro.ignoredFields(), ",")
+	want := "model,max_turns,allowed_tools,disallowed_tools,append_system_prompt"
+	if got != want {
+		t.Errorf("ignoredFields() = %q, want %q", got, want)
+	}
+}
+
+func TestProcess_RunWithOptions_Profile(t *testing.T) {
+	opts := Options{
+		AllowedTools:   []string{"Read", "Edit"},
+		OverridePolicy: OverridePolicy{Allow: []string{OverrideProfile, OverrideAllowedTools}, NarrowToolsOnly: true},
+		Profiles: map[string]Options{
+			"reviewer": {Profile: "reviewer", AllowedTools: []string{"Read"}},
+		},
+	}
+	p := NewProcess(opts)
+
+	_, err := p.RunWithOptions(context.Background(), "hi", &RunOptions{Profile: "implementer"})
+	if err == nil || !strings.Contains(err.Error(), `unknown profile "implementer" (available: reviewer)`) {
+		t.Fatalf("expected an unknown profile error, got %v", err)
+	}
+
+	// Tool overrides narrow the tools of the profile, not of the base.
+	_, err = p.RunWithOptions(context.Background(), "hi", &RunOptions{Profile: "reviewer", AllowedTools: []string{"Edit"}})
+	if !errors.Is(err, ErrOverrideDenied) {
+		t.Fatalf("expected ErrOverrideDenied, got %v", err)
+	}
+
+	p = NewProcess(Options{Profiles: opts.Profiles})
+	_, err = p.RunWithOptions(context.Background(), "hi", &RunOptions{Profile: "reviewer"})
+	if !errors.Is(err, ErrOverrideDenied) {
+		t.Fatalf("expected the zero policy to deny profiles, got %v", err)
+	}
+}
+
+func TestOptions_ForProfile(t *testing.T) {
+	opts := Options{Model: "sonnet", Profile: "default", Profiles: map[string]Options{"fast": {Model: "haiku", Profile: "fast"}}}
+	for _, name := range []string{"", "default"} {
+		if got, err := opts.ForProfile(name); err != nil || got.Model != "sonnet" {
+			t.Errorf("ForProfile(%q) = %q, %v; want the options themselves", name, got.Model, err)
+		}
+	}
+	if got, err := opts.ForProfile("fast"); err != nil || got.Model != "haiku" {
+		t.Errorf("ForProfile(fast) = %q, %v", got.Model, err)
+	}
+}
+
+func TestPersistentProcess_RejectsProfile(t *testing.T) {
+	p := NewPersistentProcess(Options{Profile: "reviewer"})
+	_, err := p.RunWithOptions(context.Background(), "hi", &RunOptions{Profile: "implementer"})
+	if err == nil || !strings.Contains(err.Error(), "cannot be selected per run in chat mode") {
+		t.Fatalf("expected the profile to be rejected, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package claude
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -33,6 +34,40 @@ type RunOptions struct {
 	MaxBudgetUSD float64
 	// Effort overrides Options.Effort for this run.
 	Effort string
+	// Profile selects one of Options.Profiles as the base options of this
+	// run; the other overrides apply on top of it. Governed by
+	// OverridePolicy. Agent mode only.
+	Profile string
+	// Attachments are images or files sent with the prompt as content
+	// blocks. Supported in both single-shot and persistent mode.
+	Attachments []Attachment
+
+	// The following overrides are governed by Options.OverridePolicy and
+	// rejected when the policy does not permit them.
+
+	// Model overrides Options.Model for this run.
+	Model string
+	// MaxTurns overrides Options.MaxTurns for this run.
+	MaxTurns int
+	// AllowedTools replaces Options.AllowedTools for this run.
+	AllowedTools []string
+	// DisallowedTools are added to Options.DisallowedTools for this run.
+	DisallowedTools []string
+	// AppendSystemPrompt is appended to Options.AppendSystemPrompt for this run.
+	AppendSystemPrompt string
+
+	// OnMessage is called with every message of a run started with Submit,
+	// including stream events, from the goroutine draining the run. It must
+	// not block.
+	OnMessage func(StreamMessage)
+	// OnDone is called once a run started with Submit has finished and its
+	// result is stored, so that Status reports the final status.
+	OnDone func()
+
+	// done, set by Submit, is returned by Done for this run instead of a
+	// channel closed when the run ends. Submit closes it once the result is
+	// stored.
+	done chan struct{}
 }
 
 // ignoredFields returns the names of fields that have non-zero values.
@@ -65,6 +100,21 @@ func (ro *RunOptions) ignoredFields() []string {
 	if ro.Effort != "" {
 		fields = append(fields, "effort")
 	}
+	if ro.Model != "" {
+		fields = append(fields, OverrideModel)
+	}
+	if ro.MaxTurns != 0 {
+		fields = append(fields, OverrideMaxTurns)
+	}
+	if len(ro.AllowedTools) > 0 {
+		fields = append(fields, OverrideAllowedTools)
+	}
+	if len(ro.DisallowedTools) > 0 {
+		fields = append(fields, OverrideDisallowedTools)
+	}
+	if ro.AppendSystemPrompt != "" {
+		fields = append(fields, OverrideAppendSystemPrompt)
+	}
 	return fields
 }
 
@@ -91,6 +141,15 @@ type Process struct {
 	lastToolName  string
 	subagents     *subagentTracker
 	liveMessages  []StreamMessage
+	// redactionCount is the number of secrets masked in the current run.
+	redactionCount int
+	// runProfile is the profile of the current or last run.
+	runProfile string
+	// runID identifies the current or last run.
+	runID string
+
+	// stderrTail captures the most recent stderr lines of the current run.
+	stderrTail *ringBuffer
 
 	// result stores the output of the last completed Submit run,
 	// allowing callers to retrieve results asynchronously.
@@ -100,8 +159,25 @@ type Process struct {
 	// Nil means no persistence.
 	resultStore *ResultStore
 
+	// cgroup is the cgroup v2 group the subprocess tree runs in, or nil
+	// when no memory/CPU limits are configured. When cgroups are unavailable,
+	// starting the subprocess in it fails.
+	cgroup *agentCgroup
+
+	// replay holds recorded CLI stdout that is replayed instead of spawning
+	// the CLI (see NewReplayProcess). Nil for normal operation.
+	replay []byte
+
+	// done is returned by Done. exited is closed once the subprocess of
+	// the current run has exited; it is the same channel as done except
+	// for runs started with Submit, whose done is closed once their result
+	// is stored.
 	done      chan struct{}
+	exited    chan struct{}
 	runCancel context.CancelFunc // cancels the stdout-reading goroutine
+
+	// changes signals state changes to the channels returned by Changes.
+	changes changeNotifier
 }
 
 // NewProcess returns a Process ready to run. The Done channel is pre-closed
@@ -112,15 +188,23 @@ func NewProcess(opts Options) *Process {
 	return &Process{
 		opts:        opts,
 		status:      ProcessStatusIdle,
+		runProfile:  opts.Profile,
 		subagents:   newSubagentTracker(),
+		stderrTail:  newRingBuffer(stderrBufferLines),
 		done:        done,
+		exited:      done,
 		resultStore: NewResultStore(resultStoreDir(opts)),
+		cgroup:      newAgentCgroup(opts.ResourceLimits),
 	}
 }
 
 // mergedOpts returns a copy of the base options with per-run overrides applied.
 func (p *Process) mergedOpts(ro *RunOptions) Options {
-	opts := p.opts
+	return mergeRunOptions(p.options(), ro)
+}
+
+// mergeRunOptions returns a copy of opts with per-run overrides applied.
+func mergeRunOptions(opts Options, ro *RunOptions) Options {
 	if ro == nil {
 		return opts
 	}
@@ -152,6 +236,26 @@ func (p *Process) mergedOpts(ro *RunOptions) Options {
 	if ro.Effort != "" {
 		opts.Effort = ro.Effort
 	}
+	if ro.Model != "" {
+		opts.Model = ro.Model
+	}
+	if ro.MaxTurns > 0 {
+		opts.MaxTurns = ro.MaxTurns
+	}
+	if len(ro.AllowedTools) > 0 {
+		opts.AllowedTools = copyStringSlice(ro.AllowedTools)
+	}
+	// Disallowed tools and the appended system prompt extend the base
+	// configuration so that a run can never drop operator restrictions.
+	if len(ro.DisallowedTools) > 0 {
+		opts.DisallowedTools = appendUnique(copyStringSlice(opts.DisallowedTools), ro.DisallowedTools...)
+	}
+	if ro.AppendSystemPrompt != "" {
+		if opts.AppendSystemPrompt != "" {
+			opts.AppendSystemPrompt += "\n\n"
+		}
+		opts.AppendSystemPrompt += ro.AppendSystemPrompt
+	}
 	return opts
 }
 
@@ -163,6 +267,39 @@ func (p *Process) Run(ctx context.Context, prompt string) (<-chan StreamMessage,
 
 // RunWithOptions spawns a claude subprocess with per-run option overrides.
 func (p *Process) RunWithOptions(ctx context.Context, prompt string, runOpts *RunOptions) (<-chan StreamMessage, error) {
+	// The policy of the server's options governs the overrides, including
+	// the choice of profile; tool lists are narrowed relative to the
+	// profile's.
+	base := p.options()
+	var profile string
+	if runOpts != nil {
+		profile = runOpts.Profile
+	}
+	runBase, err := base.ForProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := base.OverridePolicy.Check(runBase, runOpts); err != nil {
+		return nil, err
+	}
+	opts := mergeRunOptions(runBase, runOpts)
+	if err := opts.CheckCLICapabilities(); err != nil {
+		return nil, err
+	}
+
+	// Prompts with attachments are sent as a stream-json user message on
+	// stdin, since content blocks cannot be passed as a CLI argument.
+	var stdinData []byte
+	if runOpts != nil && len(runOpts.Attachments) > 0 {
+		content, err := buildPromptContent(opts.WorkDir, prompt, runOpts.Attachments)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attachments: %w", err)
+		}
+		if stdinData, err = marshalStdinMessage(content); err != nil {
+			return nil, fmt.Errorf("failed to marshal message: %w", err)
+		}
+	}
+
 	p.mu.Lock()
 	if p.status == ProcessStatusBusy {
 		p.mu.Unlock()
@@ -183,52 +320,86 @@ func (p *Process) RunWithOptions(ctx context.Context, prompt string, runOpts *Ru
 	p.totalCost = 0
 	p.costSeen = false
 	p.lastMessage = ""
+	p.runProfile = opts.Profile
+	p.runID = newRunID()
 	p.lastToolName = ""
 	p.subagents.reset()
+	// Each run gets its own buffer so that late stderr output from a previous
+	// run cannot leak into this one.
+	stderrTail := newRingBuffer(stderrBufferLines)
+	p.stderrTail = stderrTail
 
 	// Inject a synthetic user message so the prompt appears in liveMessages.
 	// The Claude CLI only emits assistant/system/result on stdout; the user's
 	// input prompt is never echoed back, so we record it here (#179).
-	p.liveMessages = append(p.liveMessages, syntheticUserMessage(prompt))
+	// Only the recorded copy is redacted; the CLI receives the prompt as is.
+	recorded, redactions := opts.Redactor.RedactString(prompt)
+	p.redactionCount = redactions
+	p.liveMessages = append(p.liveMessages, syntheticUserMessage(recorded))
 	p.messageCount++
 
-	// Create a new done channel for this run while holding the lock,
+	// Create new done channels for this run while holding the lock,
 	// ensuring no race between concurrent callers.
-	done := make(chan struct{})
-	p.done = done
+	exited := make(chan struct{})
+	done := exited
+	if runOpts != nil && runOpts.done != nil {
+		done = runOpts.done
+	}
+	p.done, p.exited = done, exited
+	p.changes.notify()
 	p.mu.Unlock()
+	metrics.RecordRedactions(redactions)
+	// failed ends a run that could not be started.
+	failed := func() {
+		close(exited)
+		if done != exited {
+			close(done)
+		}
+	}
+
+	if p.replay != nil {
+		return p.startReplay(exited, opts), nil
+	}
 
-	opts := p.mergedOpts(runOpts)
 	args := opts.args()
-	args = append(args, "--", prompt)
+	if stdinData != nil {
+		args = append(args, "--input-format", streamJSONFormat)
+	} else {
+		args = append(args, "--", prompt)
+	}
 
 	// Use exec.Command (not CommandContext) so that cancellation goes through
 	// Stop() which sends SIGTERM for graceful shutdown, rather than the
 	// immediate SIGKILL that CommandContext would send.
-	cmd := exec.Command("claude", args...) //nolint:gosec // args are controlled
+	binary, argv := opts.commandLine(args)
+	cmd := exec.Command(binary, argv...) //nolint:gosec // binary and args are controlled
 	if opts.WorkDir != "" {
 		cmd.Dir = opts.WorkDir
 	}
+	if stdinData != nil {
+		cmd.Stdin = bytes.NewReader(stdinData)
+	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		close(done)
+		failed()
 		p.setError(fmt.Sprintf("failed to create stdout pipe: %v", err))
 		return nil, err
 	}
 
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		close(done)
+		failed()
 		p.setError(fmt.Sprintf("failed to create stderr pipe: %v", err))
 		return nil, err
 	}
 
-	if err := cmd.Start(); err != nil {
-		close(done)
+	if err := startCommand(cmd, opts.ResourceLimits, p.cgroup); err != nil {
+		failed()
 		p.setError(fmt.Sprintf("failed to start claude: %v", err))
 		return nil, fmt.Errorf("failed to start claude: %w", err)
 	}
+	rec := newRecorder(opts.Recording, append([]string{binary}, argv...), prompt)
 
 	// Create a context for the stdout-reading goroutine so it can be
 	// cancelled by Stop() without blocking on a full output channel.
@@ -238,6 +409,7 @@ func (p *Process) RunWithOptions(ctx context.Context, prompt string, runOpts *Ru
 	p.cmd = cmd
 	p.status = ProcessStatusBusy
 	p.runCancel = runCancel
+	p.changes.notify()
 	p.mu.Unlock()
 	metrics.SetProcessStatus(string(ProcessStatusBusy))
 
@@ -248,24 +420,33 @@ func (p *Process) RunWithOptions(ctx context.Context, prompt string, runOpts *Ru
 	var stderrWg sync.WaitGroup
 	stderrWg.Add(1)
 
-	// Read stderr in background for logging.
+	// Read stderr in background for logging and capture recent lines for
+	// failure diagnostics and the logs tool.
 	go func() {
 		defer stderrWg.Done()
 		scanner := bufio.NewScanner(stderr)
 		for scanner.Scan() {
-			slog.Debug("claude stderr", "line", scanner.Text())
+			line, _ := opts.Redactor.RedactString(scanner.Text())
+			line = Truncate(line, maxStderrLineLen)
+			slog.Debug("claude stderr", "line", line)
+			p.mu.Lock()
+			stderrTail.add(line)
+			p.mu.Unlock()
 		}
 	}()
 
 	// Read stdout stream-json messages.
-	// Use the local done variable captured at creation time so that
+	// Use the local exited variable captured at creation time so that
 	// closing it cannot race with a subsequent Run call.
 	go func() {
 		defer close(out)
+		defer rec.Close()
 		defer func() {
 			// Wait for stderr reader to drain before calling Wait.
 			stderrWg.Wait()
 			waitErr := cmd.Wait()
+			// Kill orphans the CLI left behind (e.g. background servers).
+			reapProcessGroup(cmd)
 
 			p.mu.Lock()
 			p.cmd = nil
@@ -276,7 +457,8 @@ func (p *Process) RunWithOptions(ctx context.Context, prompt string, runOpts *Ru
 				p.status = ProcessStatusIdle
 			}
 			status := p.status
-			close(done)
+			close(exited)
+			p.changes.notify()
 			p.mu.Unlock()
 			metrics.SetProcessStatus(string(status))
 		}()
@@ -289,98 +471,13 @@ func (p *Process) RunWithOptions(ctx context.Context, prompt string, runOpts *Ru
 			if len(line) == 0 {
 				continue
 			}
+			rec.Line(line)
 
-			msg, parseErr := ParseStreamMessage(line)
-			if parseErr != nil {
-				slog.Warn("claude: failed to parse stream message", "error", parseErr, "line", string(line))
-				continue
-			}
-
-			if msg.Type == MessageTypeStreamEvent {
-				select {
-				case out <- msg:
-				case <-runCtx.Done():
-					return
-				}
+			msg, ok := p.handleLine(line, opts)
+			if !ok {
 				continue
 			}
 
-			p.mu.Lock()
-			p.messageCount++
-			p.liveMessages = append(p.liveMessages, msg)
-			if msg.Type == MessageTypeSystem && msg.SessionID != "" {
-				p.sessionID = msg.SessionID
-			}
-			if msg.Type == MessageTypeAssistant {
-				if model := ExtractModel(msg); model != "" {
-					p.modelUsage[model]++
-				}
-				if msg.Subtype == SubtypeText && msg.Text != "" {
-					p.lastMessage = Truncate(msg.Text, 200)
-				}
-				if msg.Subtype == SubtypeToolUse {
-					p.toolCallCount++
-					p.lastToolName = msg.ToolName
-					p.toolCalls[msg.ToolName]++
-					if msg.ToolID != "" {
-						p.toolUseIDs[msg.ToolID] = msg.ToolName
-					}
-					p.subagents.handleToolUse(msg)
-				} else {
-					p.subagents.handleMessage(msg)
-				}
-			} else {
-				p.subagents.handleMessage(msg)
-			}
-			// Extract PR URLs and count errors from tool_result content blocks.
-			if blocks := ExtractToolResults(msg); len(blocks) > 0 {
-				for _, block := range blocks {
-					// Only extract PR URLs from Bash tool results to avoid
-					// false positives from file content (Read, Write, etc.).
-					if block.ToolUseID == "" || isBashTool(p.toolUseIDs[block.ToolUseID]) {
-						p.prURLs = appendUnique(p.prURLs, extractPRURLs(block.Content)...)
-					}
-					if block.IsError {
-						p.errorCount++
-					}
-				}
-			}
-			// Aggregate token usage from assistant messages.
-			if msg.Usage != nil {
-				p.tokenUsage.InputTokens += msg.Usage.InputTokens
-				p.tokenUsage.OutputTokens += msg.Usage.OutputTokens
-				p.tokenUsage.CacheCreationInputTokens += msg.Usage.CacheCreationInputTokens
-				p.tokenUsage.CacheReadInputTokens += msg.Usage.CacheReadInputTokens
-			}
-			// Track cost from result messages (total_cost_usd) and also
-			// accumulate per-message cost_usd from any message type to
-			// avoid reporting $0.00 when total_cost_usd is missing (#62).
-			// Note: StreamMessage.TotalCost remains float64 (not *float64)
-			// because it represents the raw wire protocol value from the
-			// Claude CLI; the > 0 guard is appropriate since the CLI does
-			// not emit total_cost_usd: 0.0 for zero-cost operations.
-			var costToRecord float64
-			if msg.Type == MessageTypeResult && msg.TotalCost > 0 {
-				p.totalCost = msg.TotalCost
-				p.costSeen = true
-				costToRecord = msg.TotalCost
-			} else if msg.Cost > 0 {
-				p.totalCost += msg.Cost
-				p.costSeen = true
-			}
-			// When the result message lacks total_cost_usd, use the
-			// accumulated per-message cost for Prometheus.
-			if msg.Type == MessageTypeResult && costToRecord == 0 {
-				costToRecord = p.totalCost
-			}
-			p.mu.Unlock()
-
-			// Record Prometheus metrics.
-			metrics.RecordStreamMessage(string(msg.Type), string(msg.Subtype), msg.ToolName)
-			if msg.Type == MessageTypeResult {
-				metrics.RecordCost(costToRecord)
-			}
-
 			// Use select to prevent blocking if the consumer stops reading
 			// (e.g., after context cancellation in the MCP handler).
 			select {
@@ -398,6 +495,109 @@ func (p *Process) RunWithOptions(ctx context.Context, prompt string, runOpts *Ru
 	return out, nil
 }
 
+// handleLine redacts and parses one line of stream-json output of a run with
+// opts and updates the run accounting and metrics. It reports false when the
+// line is not a valid message and must be skipped.
+func (p *Process) handleLine(line []byte, opts Options) (StreamMessage, bool) {
+	// Redact before parsing so that the message text, tool
+	// arguments, raw JSON and log output all see masked content.
+	line, redactions := opts.Redactor.Redact(line)
+	if redactions > 0 {
+		p.mu.Lock()
+		p.redactionCount += redactions
+		p.mu.Unlock()
+		metrics.RecordRedactions(redactions)
+	}
+
+	msg, parseErr := opts.parseStreamMessage(line)
+	if parseErr != nil {
+		slog.Warn("claude: failed to parse stream message", "error", parseErr, "line", string(line))
+		return msg, false
+	}
+
+	if msg.Type == MessageTypeStreamEvent {
+		return msg, true
+	}
+
+	p.mu.Lock()
+	p.messageCount++
+	p.liveMessages = append(p.liveMessages, msg)
+	if msg.Type == MessageTypeSystem && msg.SessionID != "" {
+		p.sessionID = msg.SessionID
+	}
+	if msg.Type == MessageTypeAssistant {
+		if model := ExtractModel(msg); model != "" {
+			p.modelUsage[model]++
+		}
+		if msg.Subtype == SubtypeText && msg.Text != "" {
+			p.lastMessage = Truncate(msg.Text, 200)
+		}
+		if msg.Subtype == SubtypeToolUse {
+			p.toolCallCount++
+			p.lastToolName = msg.ToolName
+			p.toolCalls[msg.ToolName]++
+			if msg.ToolID != "" {
+				p.toolUseIDs[msg.ToolID] = msg.ToolName
+			}
+			p.subagents.handleToolUse(msg)
+		} else {
+			p.subagents.handleMessage(msg)
+		}
+	} else {
+		p.subagents.handleMessage(msg)
+	}
+	// Extract PR URLs and count errors from tool_result content blocks.
+	if blocks := ExtractToolResults(msg); len(blocks) > 0 {
+		for _, block := range blocks {
+			// Only extract PR URLs from Bash tool results to avoid
+			// false positives from file content (Read, Write, etc.).
+			if block.ToolUseID == "" || isBashTool(p.toolUseIDs[block.ToolUseID]) {
+				p.prURLs = appendUnique(p.prURLs, extractPRURLs(block.Content)...)
+			}
+			if block.IsError {
+				p.errorCount++
+			}
+		}
+	}
+	// Aggregate token usage from assistant messages.
+	if msg.Usage != nil {
+		p.tokenUsage.InputTokens += msg.Usage.InputTokens
+		p.tokenUsage.OutputTokens += msg.Usage.OutputTokens
+		p.tokenUsage.CacheCreationInputTokens += msg.Usage.CacheCreationInputTokens
+		p.tokenUsage.CacheReadInputTokens += msg.Usage.CacheReadInputTokens
+	}
+	// Track cost from result messages (total_cost_usd) and also
+	// accumulate per-message cost_usd from any message type to
+	// avoid reporting $0.00 when total_cost_usd is missing (#62).
+	// Note: StreamMessage.TotalCost remains float64 (not *float64)
+	// because it represents the raw wire protocol value from the
+	// Claude CLI; the > 0 guard is appropriate since the CLI does
+	// not emit total_cost_usd: 0.0 for zero-cost operations.
+	var costToRecord float64
+	if msg.Type == MessageTypeResult && msg.TotalCost > 0 {
+		p.totalCost = msg.TotalCost
+		p.costSeen = true
+		costToRecord = msg.TotalCost
+	} else if msg.Cost > 0 {
+		p.totalCost += msg.Cost
+		p.costSeen = true
+	}
+	// When the result message lacks total_cost_usd, use the
+	// accumulated per-message cost for Prometheus.
+	if msg.Type == MessageTypeResult && costToRecord == 0 {
+		costToRecord = p.totalCost
+	}
+	p.changes.notify()
+	p.mu.Unlock()
+
+	// Record Prometheus metrics.
+	metrics.RecordStreamMessage(string(msg.Type), string(msg.Subtype), msg.ToolName)
+	if msg.Type == MessageTypeResult {
+		metrics.RecordCost(costToRecord)
+	}
+	return msg, true
+}
+
 // RunSync runs a prompt and blocks until completion, returning the result text
 // and all messages. It respects context cancellation.
 func (p *Process) RunSync(ctx context.Context, prompt string) (string, []StreamMessage, error) {
@@ -430,12 +630,19 @@ loop:
 	return CollectResultText(messages), messages, nil
 }
 
-// Stop sends SIGTERM and waits up to 10s before SIGKILL.
+// Stop sends SIGTERM to the subprocess and everything it spawned, and waits
+// up to 10s before sending SIGKILL.
 func (p *Process) Stop() error {
 	p.mu.Lock()
 	cmd := p.cmd
-	done := p.done
+	exited := p.exited
 	if cmd == nil || cmd.Process == nil {
+		// A replay has no subprocess; cancelling its reader ends the run.
+		if p.replay != nil && p.status == ProcessStatusBusy && p.runCancel != nil {
+			p.status = ProcessStatusStopped
+			p.runCancel()
+			p.changes.notify()
+		}
 		p.mu.Unlock()
 		return nil
 	}
@@ -445,23 +652,26 @@ func (p *Process) Stop() error {
 	if p.runCancel != nil {
 		p.runCancel()
 	}
+	p.changes.notify()
 	p.mu.Unlock()
 	metrics.SetProcessStatus(string(ProcessStatusStopped))
 
 	// Send SIGTERM first.
-	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+	if err := signalProcessGroup(cmd, syscall.SIGTERM); err != nil {
 		slog.Warn("claude: SIGTERM failed (process may have already exited)", "error", err)
 		return nil
 	}
 
 	// Wait up to 10 seconds for graceful shutdown.
 	select {
-	case <-done:
+	case <-exited:
 		return nil
 	case <-time.After(10 * time.Second):
 		// Force kill.
 		slog.Warn("claude: process did not exit after SIGTERM, sending SIGKILL")
-		return cmd.Process.Kill()
+		err := signalProcessGroup(cmd, syscall.SIGKILL)
+		p.cgroup.kill()
+		return err
 	}
 }
 
@@ -479,42 +689,50 @@ func (p *Process) Submit(ctx context.Context, prompt string, opts *RunOptions) e
 		// "finished with results" from "never ran" (idle).
 		if rs.completed && p.status == ProcessStatusIdle {
 			p.status = ProcessStatusCompleted
+			p.changes.notify()
+		}
+		meta := runMeta{
+			status:     p.status,
+			sessionID:  p.sessionID,
+			runID:      p.runID,
+			lastError:  p.lastError,
+			redactions: p.redactionCount,
+		}
+		if p.costSeen {
+			meta.totalCost = Float64Ptr(p.totalCost)
+		}
+		if tu := p.tokenUsage; tu != (TokenUsage{}) {
+			meta.tokenUsage = &tu
+		}
+		if meta.status == ProcessStatusError {
+			meta.stderr = p.stderrTail.tail(errorStderrLines)
 		}
-		status := p.status
-		sessionID := p.sessionID
-		totalCost := p.totalCost
-		costSeen := p.costSeen
-		lastError := p.lastError
-		tu := p.tokenUsage
 		store := p.resultStore
 		p.mu.Unlock()
 
-		var costPtr *float64
-		if costSeen {
-			costPtr = Float64Ptr(totalCost)
-		}
-		var tuPtr *TokenUsage
-		if tu != (TokenUsage{}) {
-			tuPtr = &tu
-		}
-		persistResult(store, rs, status, sessionID, costPtr, lastError, tuPtr)
+		persistResult(store, rs, meta)
 	})
 }
 
 func (p *Process) Status() StatusInfo {
 	p.mu.RLock()
 	info := StatusInfo{
-		Status:        p.status,
-		SessionID:     p.sessionID,
-		ErrorMessage:  p.lastError,
-		MessageCount:  p.messageCount,
-		ToolCallCount: p.toolCallCount,
-		ToolCalls:     copyToolCalls(p.toolCalls),
-		LastMessage:   p.lastMessage,
-		LastToolName:  p.lastToolName,
-		SubagentCalls: copySubagentCalls(p.subagents.calls()),
-		ModelUsage:    copyToolCalls(p.modelUsage),
-		ErrorCount:    p.errorCount,
+		Status:         p.status,
+		SessionID:      p.sessionID,
+		RunID:          p.runID,
+		ErrorMessage:   p.lastError,
+		MessageCount:   p.messageCount,
+		ToolCallCount:  p.toolCallCount,
+		ToolCalls:      copyToolCalls(p.toolCalls),
+		LastMessage:    p.lastMessage,
+		LastToolName:   p.lastToolName,
+		SubagentCalls:  copySubagentCalls(p.subagents.calls()),
+		ModelUsage:     copyToolCalls(p.modelUsage),
+		ErrorCount:     p.errorCount,
+		RedactionCount: p.redactionCount,
+		Model:          p.opts.Model,
+		PermissionMode: p.opts.PermissionMode,
+		Profile:        p.runProfile,
 	}
 
 	if p.costSeen {
@@ -529,6 +747,13 @@ func (p *Process) Status() StatusInfo {
 	if p.status == ProcessStatusCompleted {
 		info.Result = Truncate(p.result.text, maxStatusResultLen)
 	}
+	if p.status == ProcessStatusError {
+		info.Stderr = p.stderrTail.tail(errorStderrLines)
+	}
+	if p.opts.CLIVersion != nil {
+		info.CLIVersion = p.opts.CLIVersion.String()
+	}
+	info.MCPServers = p.opts.MCPProber.Health()
 
 	store := p.resultStore
 	p.mu.RUnlock()
@@ -543,6 +768,9 @@ func (p *Process) Status() StatusInfo {
 			if info.SessionID == "" {
 				info.SessionID = pr.SessionID
 			}
+			if info.RunID == "" {
+				info.RunID = pr.RunID
+			}
 			if info.TotalCost == nil && pr.TotalCost != nil {
 				info.TotalCost = pr.TotalCost
 			}
@@ -571,17 +799,19 @@ func (p *Process) Status() StatusInfo {
 func (p *Process) ResultDetail() ResultDetailInfo {
 	p.mu.RLock()
 	detail := ResultDetailInfo{
-		ResultText:    p.result.text,
-		Messages:      p.result.messages,
-		MessageCount:  p.messageCount,
-		ToolCalls:     copyToolCalls(p.toolCalls),
-		SubagentCalls: copySubagentCalls(p.subagents.calls()),
-		ModelUsage:    copyToolCalls(p.modelUsage),
-		PRURLs:        copyStringSlice(p.prURLs),
-		ErrorCount:    p.errorCount,
-		SessionID:     p.sessionID,
-		Status:        p.status,
-		ErrorMessage:  p.lastError,
+		ResultText:     p.result.text,
+		Messages:       p.result.messages,
+		MessageCount:   p.messageCount,
+		ToolCalls:      copyToolCalls(p.toolCalls),
+		SubagentCalls:  copySubagentCalls(p.subagents.calls()),
+		ModelUsage:     copyToolCalls(p.modelUsage),
+		PRURLs:         copyStringSlice(p.prURLs),
+		ErrorCount:     p.errorCount,
+		RedactionCount: p.redactionCount,
+		SessionID:      p.sessionID,
+		RunID:          p.runID,
+		Status:         p.status,
+		ErrorMessage:   p.lastError,
 	}
 	if p.costSeen {
 		detail.TotalCost = Float64Ptr(p.totalCost)
@@ -603,6 +833,14 @@ func (p *Process) ResultDetail() ResultDetailInfo {
 	return detail
 }
 
+// Logs returns the most recent stderr lines captured from the current or
+// last run.
+func (p *Process) Logs(limit int) LogsInfo {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return stderrLogs(p.status, p.stderrTail, limit)
+}
+
 // Messages returns the current conversation messages. liveMessages accumulates
 // across turns, so it is preferred over result.messages (which only contains
 // the last Submit run). Falls back to persisted state on disk when empty.
@@ -684,29 +922,38 @@ func (p *Process) OpenAIMessages(offset int) OpenAIMessagesInfo {
 	live := copyStreamMessages(p.liveMessages)
 	resMessages := copyStreamMessages(p.result.messages)
 	store := p.resultStore
+	prober := p.opts.MCPProber
 	p.mu.RUnlock()
 
+	info := OpenAIMessagesInfo{Messages: []OpenAIMessage{}, Metadata: OpenAIMetadata{}}
 	if len(live) > 0 {
-		return collectOpenAIMessages(status, live, offset)
-	}
-	if len(resMessages) > 0 {
-		return collectOpenAIMessages(status, resMessages, offset)
-	}
-	if store != nil {
+		info = collectOpenAIMessages(status, live, offset)
+	} else if len(resMessages) > 0 {
+		info = collectOpenAIMessages(status, resMessages, offset)
+	} else if store != nil {
 		if pr, err := store.Load(); err == nil && pr != nil && len(pr.Messages) > 0 {
-			return collectOpenAIMessages(status, pr.Messages, offset)
+			info = collectOpenAIMessages(status, pr.Messages, offset)
 		}
 	}
-	return OpenAIMessagesInfo{Messages: []OpenAIMessage{}, Metadata: OpenAIMetadata{}}
+	info.Metadata.MCPServers = prober.Health()
+	return info
 }
 
-// Done returns a channel closed when the current run completes.
+// Done returns a channel closed when the current run completes. For a run
+// started with Submit, it is closed once the result is stored, so that
+// Status reports the final status.
 func (p *Process) Done() <-chan struct{} {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 	return p.done
 }
 
+// Changes returns a channel that is closed at the next change of the
+// status, run or message count.
+func (p *Process) Changes() <-chan struct{} {
+	return p.changes.next()
+}
+
 func (p *Process) MarshalStatus() ([]byte, error) {
 	return json.Marshal(p.Status())
 }
@@ -716,5 +963,6 @@ func (p *Process) setError(msg string) {
 	defer p.mu.Unlock()
 	p.status = ProcessStatusError
 	p.lastError = msg
+	p.changes.notify()
 	metrics.SetProcessStatus(string(ProcessStatusError))
 }
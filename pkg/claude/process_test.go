@@ -1,9 +1,17 @@
 package claude
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/giantswarm/klaus/pkg/redact"
 )
 
 func TestNewProcess_InitialState(t *testing.T) {
@@ -66,6 +74,27 @@ func TestProcess_MarshalStatus(t *testing.T) {
 	}
 }
 
+func TestProcess_StatusReportsMCPServers(t *testing.T) {
+	prober := NewMCPProber(0, 0)
+	prober.probe = func(context.Context, MCPServer, string) (MCPServerHealth, error) {
+		return MCPServerHealth{Reachable: true, ToolCount: 2}, nil
+	}
+	prober.SetServers(map[string]MCPServer{"github": {Type: MCPTransportHTTP, URL: "https://example.com"}}, "")
+	prober.ProbeAll(context.Background())
+
+	opts := DefaultOptions()
+	opts.MCPProber = prober
+	process := NewProcess(opts)
+
+	status := process.Status().MCPServers
+	if len(status) != 1 || status[0].Name != "github" || !status[0].Reachable || status[0].ToolCount != 2 {
+		t.Errorf("unexpected MCP servers in status: %+v", status)
+	}
+	if meta := process.OpenAIMessages(0).Metadata.MCPServers; len(meta) != 1 || meta[0].Type != MCPTransportHTTP {
+		t.Errorf("unexpected MCP servers in metadata: %+v", meta)
+	}
+}
+
 func TestProcess_ImplementsPrompter(t *testing.T) {
 	// Compile-time check that Process implements Prompter.
 	var _ Prompter = (*Process)(nil)
@@ -796,6 +825,41 @@ func TestProcess_MergedOpts(t *testing.T) {
 		}
 	})
 
+	t.Run("policy-governed overrides", func(t *testing.T) {
+		p := NewProcess(Options{
+			Model:              "sonnet",
+			MaxTurns:           20,
+			AllowedTools:       []string{"Read", "Edit", "Bash"},
+			DisallowedTools:    []string{"WebFetch"},
+			AppendSystemPrompt: "Be careful.",
+		})
+		merged := p.mergedOpts(&RunOptions{
+			Model:              "haiku",
+			MaxTurns:           5,
+			AllowedTools:       []string{"Read"},
+			DisallowedTools:    []string{"Bash", "WebFetch"},
+			AppendSystemPrompt: "Only review.",
+		})
+		if merged.Model != "haiku" {
+			t.Errorf("expected model %q, got %q", "haiku", merged.Model)
+		}
+		if merged.MaxTurns != 5 {
+			t.Errorf("expected max turns 5, got %d", merged.MaxTurns)
+		}
+		if !reflect.DeepEqual(merged.AllowedTools, []string{"Read"}) {
+			t.Errorf("expected allowed tools to be replaced, got %v", merged.AllowedTools)
+		}
+		if !reflect.DeepEqual(merged.DisallowedTools, []string{"WebFetch", "Bash"}) {
+			t.Errorf("expected disallowed tools to be extended, got %v", merged.DisallowedTools)
+		}
+		if merged.AppendSystemPrompt != "Be careful.\n\nOnly review." {
+			t.Errorf("expected appended system prompt, got %q", merged.AppendSystemPrompt)
+		}
+		if !reflect.DeepEqual(p.opts.DisallowedTools, []string{"WebFetch"}) {
+			t.Errorf("base disallowed tools must not be mutated, got %v", p.opts.DisallowedTools)
+		}
+	})
+
 	t.Run("zero-value RunOptions fields do not override base", func(t *testing.T) {
 		runOpts := &RunOptions{} // All zero values.
 		merged := process.mergedOpts(runOpts)
@@ -875,3 +939,189 @@ func TestCollectResultText(t *testing.T) {
 		}
 	})
 }
+
+// writeFakeClaude installs a "claude" shell script on PATH that prints the
+// given stream-json lines and exits.
+func writeFakeClaude(t *testing.T, lines ...string) {
+	t.Helper()
+	installFakeClaude(t, "cat <<'EOF'\n"+strings.Join(lines, "\n")+"\nEOF\n")
+}
+
+// installFakeClaude installs a "claude" shell script with the given body on PATH.
+func installFakeClaude(t *testing.T, body string) {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" + body
+	if err := os.WriteFile(filepath.Join(dir, "claude"), []byte(script), 0o700); err != nil { // #nosec G306 -- test script must be executable
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestProcess_RedactsSecrets(t *testing.T) {
+	token := "ghp_" + strings.Repeat("A1b2", 9)
+	writeFakeClaude(t,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Found `+token+`"}]}}`,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Bash","input":{"command":"gh auth login --with-token `+token+`"}}]}}`,
+		`{"type":"result","subtype":"success","result":"Token is `+token+`","total_cost_usd":0.01}`,
+	)
+
+	r, err := redact.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.Redactor = r
+	opts.ResultDir = t.TempDir()
+	p := NewProcess(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	text, msgs, err := p.RunSync(ctx, "use "+token)
+	if err != nil {
+		t.Fatalf("RunSync: %v", err)
+	}
+
+	if strings.Contains(text, token) {
+		t.Errorf("result text not redacted: %q", text)
+	}
+	for _, msg := range msgs {
+		for _, field := range []string{msg.Text, msg.Result, string(msg.ToolArgs), string(msg.Raw)} {
+			if strings.Contains(field, token) {
+				t.Errorf("message %s/%s not redacted: %q", msg.Type, msg.Subtype, field)
+			}
+		}
+	}
+	raw := p.RawMessages(0, nil)
+	for _, m := range raw.Messages {
+		if strings.Contains(string(m), token) {
+			t.Errorf("live message not redacted: %s", m)
+		}
+	}
+
+	// One in the prompt, one each in the text, tool input and result.
+	if got := p.Status().RedactionCount; got != 4 {
+		t.Errorf("RedactionCount = %d, want 4", got)
+	}
+}
+
+func TestProcess_SubmitDoneAfterResultIsStored(t *testing.T) {
+	writeFakeClaude(t, `{"type":"result","subtype":"success","result":"done"}`)
+	opts := DefaultOptions()
+	opts.ResultDir = t.TempDir()
+	p := NewProcess(opts)
+
+	for i := range 5 {
+		onDone := make(chan StatusInfo, 1)
+		runOpts := &RunOptions{OnDone: func() { onDone <- p.Status() }}
+		if err := p.Submit(context.Background(), "hello", runOpts); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+		select {
+		case <-p.Done():
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for run")
+		}
+		// Done is closed once the result is stored, not when the
+		// subprocess exits.
+		if status := p.Status(); status.Status != ProcessStatusCompleted || status.Result != "done" {
+			t.Fatalf("run %d: expected the completed result once done, got %s %q", i, status.Status, status.Result)
+		}
+		if loaded, err := p.resultStore.Load(); err != nil || loaded == nil {
+			t.Fatalf("run %d: expected the result to be persisted once done, got %v", i, err)
+		}
+		if status := <-onDone; status.Status != ProcessStatusCompleted {
+			t.Errorf("run %d: expected OnDone to see the completed status, got %s", i, status.Status)
+		}
+	}
+}
+
+func TestProcess_ChangesSignalsStatusTransitions(t *testing.T) {
+	writeFakeClaude(t, `{"type":"result","subtype":"success","result":"done"}`)
+	opts := DefaultOptions()
+	opts.ResultDir = t.TempDir()
+	p := NewProcess(opts)
+
+	changed := p.Changes()
+	if err := p.Submit(context.Background(), "hello", nil); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected a change once the run started")
+	}
+
+	// Every change until the run completes is signalled, the last being
+	// the transition to completed.
+	deadline := time.After(10 * time.Second)
+	for p.Status().Status != ProcessStatusCompleted {
+		changed = p.Changes()
+		if p.Status().Status == ProcessStatusCompleted {
+			break
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			t.Fatalf("expected a change to completed, status is %s", p.Status().Status)
+		}
+	}
+}
+
+func TestProcess_CapturesStderrOnFailure(t *testing.T) {
+	installFakeClaude(t, `i=0
+while [ $i -lt 30 ]; do echo "line $i" >&2; i=$((i+1)); done
+echo "Error: invalid API key" >&2
+exit 1
+`)
+
+	opts := DefaultOptions()
+	opts.ResultDir = t.TempDir()
+	p := NewProcess(opts)
+
+	if err := p.Submit(context.Background(), "hello", nil); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	select {
+	case <-p.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for run")
+	}
+
+	// Wait for the drain goroutine to persist the result.
+	deadline := time.Now().Add(5 * time.Second)
+	var pr *PersistedResult
+	for time.Now().Before(deadline) {
+		if loaded, _ := p.resultStore.Load(); loaded != nil {
+			pr = loaded
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	status := p.Status()
+	if status.Status != ProcessStatusError {
+		t.Fatalf("expected error status, got %q", status.Status)
+	}
+	if len(status.Stderr) != errorStderrLines {
+		t.Fatalf("expected %d stderr lines in status, got %d", errorStderrLines, len(status.Stderr))
+	}
+	if last := status.Stderr[len(status.Stderr)-1]; last != "Error: invalid API key" {
+		t.Errorf("expected last stderr line to be the error, got %q", last)
+	}
+
+	if pr == nil {
+		t.Fatal("expected a persisted result")
+	}
+	if len(pr.Stderr) == 0 || pr.Stderr[len(pr.Stderr)-1] != "Error: invalid API key" {
+		t.Errorf("expected stderr in persisted result, got %v", pr.Stderr)
+	}
+
+	logs := p.Logs(0)
+	if logs.Total != 31 || len(logs.Lines) != 31 {
+		t.Errorf("expected all 31 lines, got total=%d lines=%d", logs.Total, len(logs.Lines))
+	}
+	if logs := p.Logs(5); len(logs.Lines) != 5 || logs.Lines[0] != "line 26" {
+		t.Errorf("expected last 5 lines starting at \"line 26\", got %v", logs.Lines)
+	}
+}
@@ -2,6 +2,8 @@ package claude
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 )
 
 // Prompter defines the interface for sending prompts to a Claude agent.
@@ -36,9 +38,18 @@ type Prompter interface {
 	// Stop stops the current operation or subprocess.
 	Stop() error
 
+	// Interrupt ends the current turn of a live session without stopping
+	// the subprocess. It returns ErrInterruptUnsupported when there is no
+	// long-running session or the CLI cannot interrupt a turn.
+	Interrupt(ctx context.Context) error
+
 	// Done returns a channel that is closed when the current run completes.
 	Done() <-chan struct{}
 
+	// Changes returns a channel that is closed at the next change of the
+	// status, run or message count. Call it again for the change after that.
+	Changes() <-chan struct{}
+
 	// ResultDetail returns the full untruncated result and detailed metadata
 	// from the last completed run. Intended for debugging and troubleshooting.
 	ResultDetail() ResultDetailInfo
@@ -59,6 +70,56 @@ type Prompter interface {
 	// offset skips the first N converted messages (after consolidation).
 	OpenAIMessages(offset int) OpenAIMessagesInfo
 
+	// Logs returns the most recent stderr lines from the subprocess. limit
+	// caps the number of lines returned; limit <= 0 returns all retained lines.
+	Logs(limit int) LogsInfo
+
+	// ConfigureSession changes the model or permission mode of a live
+	// session and optionally queries its context usage. It returns
+	// ErrSessionControlUnsupported when there is no long-running session.
+	ConfigureSession(ctx context.Context, cfg SessionConfig) (SessionSettings, error)
+
+	// Compact summarizes the conversation of a live session to free context
+	// space, steered by optional instructions. It returns
+	// ErrCompactUnsupported when there is no long-running session.
+	Compact(ctx context.Context, instructions string) (CompactionEvent, error)
+
+	// UpdateOptions replaces the options used from the next run on, e.g.
+	// after the configuration was reloaded. In chat mode the subprocess is
+	// restarted with --resume before the next prompt.
+	UpdateOptions(opts Options)
+
+	// Sessions returns the sessions the CLI stored for the workspace, for
+	// listing, inspecting, exporting and deleting them.
+	Sessions() *SessionStore
+
+	// Results returns klaus' store of run results, for reading the results
+	// of past runs (see RunDetail).
+	Results() *ResultStore
+
+	// WorkDir returns the workspace directory the agent runs in; empty
+	// means the current directory.
+	WorkDir() string
+
 	// MarshalStatus returns the status as JSON.
 	MarshalStatus() ([]byte, error)
 }
+
+// StopRun ends the current run of p, whose messages are sent to ch. It
+// interrupts the turn when it can, so that a chat session survives with its
+// context, and stops p otherwise. The rest of ch is drained in the
+// background, since nobody reads it anymore.
+func StopRun(ctx context.Context, p Prompter, ch <-chan StreamMessage) error {
+	go func() {
+		for range ch {
+		}
+	}()
+	err := p.Interrupt(ctx)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, ErrInterruptUnsupported) {
+		slog.Warn("claude: interrupting the turn failed, stopping the agent", "error", err)
+	}
+	return p.Stop()
+}
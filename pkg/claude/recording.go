@@ -0,0 +1,323 @@
+package claude
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Recording metadata line types. Metadata lines are interleaved with the raw
+// CLI output in a recording and are skipped when it is replayed.
+const (
+	// RecordingTypeHeader starts every recording file.
+	RecordingTypeHeader = "klaus_recording"
+	// RecordingTypePrompt records a prompt written to the subprocess stdin
+	// in chat mode, where one recording spans many prompts.
+	RecordingTypePrompt = "klaus_prompt"
+)
+
+const (
+	// recordingPrefix and recordingSuffix delimit recording file names;
+	// only matching files are pruned.
+	recordingPrefix = "run-"
+	recordingSuffix = ".jsonl"
+	// recordingTimeFormat names recording files so that they sort by start
+	// time.
+	recordingTimeFormat = "20060102T150405.000000000Z"
+)
+
+// RecordingOptions configures raw stdout recording. Every line the CLI
+// writes to stdout is appended byte-exact, before parsing and redaction, to a
+// per-run JSONL file under Dir, after a header line holding the argv and the
+// prompt. Recordings can be fed to `klaus replay` to reproduce protocol
+// issues after the fact.
+//
+// Recordings are not redacted and may contain secrets; they are written with
+// 0600 permissions to a 0700 directory.
+type RecordingOptions struct {
+	// Dir is the directory recordings are written to. Empty disables
+	// recording.
+	Dir string
+	// MaxFileBytes rotates a recording into a new numbered file once it
+	// reaches this size; 0 means no rotation.
+	MaxFileBytes int64
+	// MaxTotalBytes prunes the oldest recordings once all recordings in Dir
+	// together exceed this size; 0 means no size limit.
+	MaxTotalBytes int64
+	// MaxAge prunes recordings last written longer ago than this; 0 means
+	// recordings never expire.
+	MaxAge time.Duration
+}
+
+// Validate checks that the recording settings are usable.
+func (r RecordingOptions) Validate() error {
+	var errs []error
+	if r.Dir != "" && !filepath.IsAbs(r.Dir) {
+		errs = append(errs, fmt.Errorf("dir must be an absolute path, got %q", r.Dir))
+	}
+	if r.MaxFileBytes < 0 {
+		errs = append(errs, fmt.Errorf("maxFileBytes must be >= 0, got %d", r.MaxFileBytes))
+	}
+	if r.MaxTotalBytes < 0 {
+		errs = append(errs, fmt.Errorf("maxTotalBytes must be >= 0, got %d", r.MaxTotalBytes))
+	}
+	if r.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("maxAge must be >= 0, got %s", r.MaxAge))
+	}
+	return errors.Join(errs...)
+}
+
+// RecordingHeader is the first line of every recording file.
+type RecordingHeader struct {
+	Type      string    `json:"type"`
+	Argv      []string  `json:"argv"`
+	Prompt    string    `json:"prompt,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	// Part numbers the files of a rotated recording, starting at 0.
+	Part int `json:"part"`
+}
+
+// recordingPromptLine is a RecordingTypePrompt metadata line.
+type recordingPromptLine struct {
+	Type   string    `json:"type"`
+	Prompt string    `json:"prompt"`
+	Time   time.Time `json:"time"`
+}
+
+// ReadRecordingHeader returns the header of a recording, or false when the
+// data does not start with one (e.g. plain captured stdout).
+func ReadRecordingHeader(data []byte) (RecordingHeader, bool) {
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+	var h RecordingHeader
+	if err := json.Unmarshal(first, &h); err != nil || h.Type != RecordingTypeHeader {
+		return RecordingHeader{}, false
+	}
+	return h, true
+}
+
+// isRecordingMetadata reports whether a line is klaus metadata rather than
+// CLI output.
+func isRecordingMetadata(line []byte) bool {
+	if !bytes.Contains(line, []byte(`"klaus_`)) {
+		return false
+	}
+	var probe struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(line, &probe); err != nil {
+		return false
+	}
+	return probe.Type == RecordingTypeHeader || probe.Type == RecordingTypePrompt
+}
+
+// recorder tees raw CLI stdout into recording files. A nil recorder records
+// nothing, so callers need not check whether recording is enabled. Write
+// errors are logged once and disable the recorder; recording never fails a
+// run.
+type recorder struct {
+	opts RecordingOptions
+
+	mu      sync.Mutex
+	header  RecordingHeader
+	base    string
+	file    *os.File
+	written int64
+	// lines counts the lines after the header in the current file, so that
+	// a header larger than MaxFileBytes cannot cause endless rotation.
+	lines  int
+	failed bool
+}
+
+// newRecorder prunes old recordings and opens a new one for a subprocess
+// started with argv. It returns nil when recording is disabled or the file
+// cannot be created.
+func newRecorder(opts RecordingOptions, argv []string, prompt string) *recorder {
+	if opts.Dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(opts.Dir, 0o700); err != nil {
+		slog.Warn("claude: cannot create recording directory", "dir", opts.Dir, "error", err)
+		return nil
+	}
+	pruneRecordings(opts, time.Now())
+
+	now := time.Now().UTC()
+	r := &recorder{
+		opts: opts,
+		header: RecordingHeader{
+			Type:      RecordingTypeHeader,
+			Argv:      argv,
+			Prompt:    prompt,
+			StartedAt: now,
+		},
+		base: filepath.Join(opts.Dir, recordingPrefix+now.Format(recordingTimeFormat)),
+	}
+	if err := r.open(); err != nil {
+		slog.Warn("claude: cannot create recording", "error", err)
+		return nil
+	}
+	return r
+}
+
+// open creates the file for the current part and writes its header.
+func (r *recorder) open() error {
+	path := r.base + recordingSuffix
+	if r.header.Part > 0 {
+		path = fmt.Sprintf("%s.%d%s", r.base, r.header.Part, recordingSuffix)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) //nolint:gosec // path is built from the configured directory
+	if err != nil {
+		return err
+	}
+	r.file = f
+	r.written = 0
+	r.lines = 0
+	data, err := json.Marshal(r.header)
+	if err != nil {
+		return err
+	}
+	return r.write(data)
+}
+
+// write appends one line to the current file.
+func (r *recorder) write(line []byte) error {
+	buf := make([]byte, 0, len(line)+1)
+	buf = append(append(buf, line...), '\n')
+	n, err := r.file.Write(buf)
+	r.written += int64(n)
+	return err
+}
+
+// record appends a line, rotating first when the file is full.
+func (r *recorder) record(line []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failed || r.file == nil {
+		return
+	}
+	err := r.rotateIfFull()
+	if err == nil {
+		err = r.write(line)
+		r.lines++
+	}
+	if err != nil {
+		r.failed = true
+		slog.Warn("claude: recording failed, disabling it for this run", "error", err)
+	}
+}
+
+func (r *recorder) rotateIfFull() error {
+	if r.opts.MaxFileBytes <= 0 || r.lines == 0 || r.written < r.opts.MaxFileBytes {
+		return nil
+	}
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+	r.header.Part++
+	pruneRecordings(r.opts, time.Now())
+	return r.open()
+}
+
+// Line records one raw line of CLI stdout.
+func (r *recorder) Line(line []byte) {
+	if r == nil {
+		return
+	}
+	r.record(line)
+}
+
+// Prompt records a prompt sent to a long-running subprocess.
+func (r *recorder) Prompt(prompt string) {
+	if r == nil {
+		return
+	}
+	data, err := json.Marshal(recordingPromptLine{Type: RecordingTypePrompt, Prompt: prompt, Time: time.Now().UTC()})
+	if err != nil {
+		return
+	}
+	r.record(data)
+}
+
+// Close closes the current recording file.
+func (r *recorder) Close() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file != nil {
+		_ = r.file.Close()
+		r.file = nil
+	}
+}
+
+// pruneRecordings removes recordings older than MaxAge, then the oldest
+// recordings until the directory fits within MaxTotalBytes.
+func pruneRecordings(opts RecordingOptions, now time.Time) {
+	if opts.MaxAge <= 0 && opts.MaxTotalBytes <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(opts.Dir)
+	if err != nil {
+		return
+	}
+
+	type recording struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []recording
+	var total int64
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, recordingPrefix) || !strings.HasSuffix(name, recordingSuffix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(opts.Dir, name)
+		if opts.MaxAge > 0 && now.Sub(info.ModTime()) > opts.MaxAge {
+			removeRecording(path)
+			continue
+		}
+		files = append(files, recording{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+	}
+
+	if opts.MaxTotalBytes <= 0 || total <= opts.MaxTotalBytes {
+		return
+	}
+	// Oldest first by last write; names embed the start time and part
+	// number, which orders files written within the same instant.
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].modTime.Equal(files[j].modTime) {
+			return files[i].modTime.Before(files[j].modTime)
+		}
+		return files[i].path < files[j].path
+	})
+	for _, f := range files {
+		if total <= opts.MaxTotalBytes {
+			break
+		}
+		removeRecording(f.path)
+		total -= f.size
+	}
+}
+
+func removeRecording(path string) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		slog.Warn("claude: cannot prune recording", "path", path, "error", err)
+	}
+}
@@ -0,0 +1,191 @@
+package claude
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/klaus/pkg/redact"
+)
+
+func recordingFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, recordingPrefix+"*"+recordingSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(matches)
+	return matches
+}
+
+func TestProcess_RecordsRawStdout(t *testing.T) {
+	token := "ghp_" + strings.Repeat("A1b2", 9)
+	lines := []string{
+		`{"type":"system","subtype":"init","session_id":"s1"}`,
+		`not json at all`,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Found ` + token + `"}]}}`,
+		`{"type":"result","subtype":"success","result":"done","total_cost_usd":0.01}`,
+	}
+	writeFakeClaude(t, lines...)
+
+	opts := DefaultOptions()
+	opts.ResultDir = t.TempDir()
+	opts.Recording.Dir = t.TempDir()
+	redactor, err := redact.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Redactor = redactor
+	p := NewProcess(opts)
+
+	if _, _, err := p.RunSyncWithOptions(context.Background(), "find the token", nil); err != nil {
+		t.Fatalf("RunSyncWithOptions: %v", err)
+	}
+
+	files := recordingFiles(t, opts.Recording.Dir)
+	if len(files) != 1 {
+		t.Fatalf("expected one recording, got %v", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, ok := ReadRecordingHeader(data)
+	if !ok {
+		t.Fatalf("missing header in %s", data)
+	}
+	if header.Prompt != "find the token" || header.Argv[0] != "claude" || !slices.Contains(header.Argv, "--output-format") {
+		t.Errorf("unexpected header: %+v", header)
+	}
+
+	// Everything after the header is the unmodified stdout, including the
+	// unparseable line and the unredacted secret.
+	_, body, _ := bytes.Cut(data, []byte("\n"))
+	if want := strings.Join(lines, "\n") + "\n"; string(body) != want {
+		t.Errorf("recorded body =\n%s\nwant\n%s", body, want)
+	}
+
+	if info, err := os.Stat(files[0]); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("expected 0600 recording, got %v %v", info, err)
+	}
+}
+
+func TestProcess_RecordingDisabledByDefault(t *testing.T) {
+	writeFakeClaude(t, `{"type":"result","subtype":"success","result":"done"}`)
+	opts := DefaultOptions()
+	opts.ResultDir = t.TempDir()
+	if newRecorder(opts.Recording, nil, "") != nil {
+		t.Fatal("expected nil recorder without a directory")
+	}
+	if _, _, err := NewProcess(opts).RunSyncWithOptions(context.Background(), "hi", nil); err != nil {
+		t.Fatalf("RunSyncWithOptions: %v", err)
+	}
+}
+
+func TestRecorder_Rotates(t *testing.T) {
+	dir := t.TempDir()
+	r := newRecorder(RecordingOptions{Dir: dir, MaxFileBytes: 100}, []string{"claude"}, "p")
+	line := []byte(`{"type":"assistant","message":{"content":"` + strings.Repeat("x", 80) + `"}}`)
+	for range 3 {
+		r.Line(line)
+	}
+	r.Close()
+
+	files := recordingFiles(t, dir)
+	if len(files) != 3 {
+		t.Fatalf("expected 3 parts, got %v", files)
+	}
+	var parts []int
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		header, ok := ReadRecordingHeader(data)
+		if !ok {
+			t.Fatalf("part %s has no header", f)
+		}
+		if header.Prompt != "p" {
+			t.Errorf("part %d lost the prompt: %+v", header.Part, header)
+		}
+		parts = append(parts, header.Part)
+	}
+	slices.Sort(parts)
+	if !slices.Equal(parts, []int{0, 1, 2}) {
+		t.Errorf("parts = %v", parts)
+	}
+}
+
+func TestPruneRecordings(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	write := func(name string, size int, age time.Duration) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, bytes.Repeat([]byte("x"), size), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	expired := write("run-a.jsonl", 10, 48*time.Hour)
+	oldest := write("run-b.jsonl", 100, 3*time.Hour)
+	older := write("run-c.jsonl", 100, 2*time.Hour)
+	newest := write("run-d.jsonl", 100, time.Hour)
+	unrelated := write("notes.txt", 1000, 100*time.Hour)
+
+	pruneRecordings(RecordingOptions{Dir: dir, MaxAge: 24 * time.Hour, MaxTotalBytes: 250}, now)
+
+	for path, want := range map[string]bool{expired: false, oldest: false, older: true, newest: true, unrelated: true} {
+		_, err := os.Stat(path)
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists = %v, want %v", filepath.Base(path), exists, want)
+		}
+	}
+}
+
+func TestRecordingOptions_Validate(t *testing.T) {
+	if err := (RecordingOptions{}).Validate(); err != nil {
+		t.Errorf("zero value must be valid: %v", err)
+	}
+	err := RecordingOptions{Dir: "relative", MaxFileBytes: -1, MaxTotalBytes: -1, MaxAge: -time.Second}.Validate()
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{"dir must be an absolute path", "maxFileBytes", "maxTotalBytes", "maxAge"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestReplayProcess_SkipsRecordingMetadata(t *testing.T) {
+	recording := strings.Join([]string{
+		`{"type":"klaus_recording","argv":["claude"],"prompt":"hello","started_at":"2026-01-01T00:00:00Z","part":0}`,
+		`{"type":"klaus_prompt","prompt":"hello","time":"2026-01-01T00:00:00Z"}`,
+		`{"type":"result","subtype":"success","result":"done"}`,
+	}, "\n")
+	opts := DefaultOptions()
+	opts.ResultDir = t.TempDir()
+	p := NewReplayProcess(opts, []byte(recording))
+
+	text, messages, err := p.RunSyncWithOptions(context.Background(), "hello", nil)
+	if err != nil {
+		t.Fatalf("RunSyncWithOptions: %v", err)
+	}
+	if text != "done" {
+		t.Errorf("result = %q", text)
+	}
+	for _, m := range messages {
+		if strings.HasPrefix(string(m.Type), "klaus_") {
+			t.Errorf("metadata replayed as message: %+v", m)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package claude
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// options returns a snapshot of the options of the next run.
+func (p *Process) options() Options {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.opts
+}
+
+// UpdateOptions replaces the options used from the next run on. A running
+// prompt keeps the options it was started with.
+func (p *Process) UpdateOptions(opts Options) {
+	p.mu.Lock()
+	p.opts = opts
+	p.mu.Unlock()
+}
+
+// options returns a snapshot of the options the subprocess is started with.
+func (p *PersistentProcess) options() Options {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.opts
+}
+
+// UpdateOptions replaces the options the subprocess is started with. A
+// running subprocess keeps its options until the next prompt, which first
+// restarts it gracefully with --resume so that the conversation continues
+// under the new options. A prompt that is running when UpdateOptions is
+// called is not interrupted. The model and permission mode set by
+// ConfigureSession are kept unless the new policy no longer permits them.
+func (p *PersistentProcess) UpdateOptions(opts Options) {
+	p.mu.Lock()
+	p.opts = p.withSessionSettings(opts)
+	p.optionsChanged = p.cmd != nil
+	p.mu.Unlock()
+}
+
+// withSessionSettings returns opts with the model and permission mode set by
+// ConfigureSession. A setting the policy of opts does not permit is dropped
+// with a warning, and the session falls back to the configured value. The
+// caller must hold p.mu.
+func (p *PersistentProcess) withSessionSettings(opts Options) Options {
+	if p.sessionModel != "" {
+		if err := (SessionConfig{Model: p.sessionModel}).Validate(opts.OverridePolicy, opts); err != nil {
+			slog.Warn("claude: dropping the session model after options change", "model", p.sessionModel, "configured_model", opts.Model, "error", err)
+			p.sessionModel = ""
+		} else {
+			opts.Model = p.sessionModel
+		}
+	}
+	if p.sessionPermissionMode != "" {
+		if err := (SessionConfig{PermissionMode: p.sessionPermissionMode}).Validate(opts.OverridePolicy, opts); err != nil {
+			slog.Warn("claude: dropping the session permission mode after options change", "permission_mode", p.sessionPermissionMode, "configured_permission_mode", opts.PermissionMode, "error", err)
+			p.sessionPermissionMode = ""
+		} else {
+			opts.PermissionMode = p.sessionPermissionMode
+		}
+	}
+	return opts
+}
+
+// restartWithOptions stops the subprocess and starts it again with the
+// current options, resuming its session. The caller must set p.restarting
+// so that no prompt is sent to the stopping subprocess.
+func (p *PersistentProcess) restartWithOptions() error {
+	p.mu.Lock()
+	sessionID := p.sessionID
+	resumable := sessionID != "" && !p.opts.NoSessionPersistence
+	workDir := p.opts.WorkDir
+	p.mu.Unlock()
+
+	if resumable {
+		// Resuming a session the CLI does not know makes it exit right
+		// away, so check that its transcript exists.
+		if _, err := NewSessionStore(SessionsDir(workDir), nil).Inspect(sessionID); err != nil {
+			slog.Warn("claude: cannot resume session after options change, starting a new one", "session_id", sessionID, "error", err)
+			resumable = false
+		}
+	}
+
+	slog.Info("claude: restarting persistent subprocess to apply new options", "session_id", sessionID, "resume", resumable)
+	if err := p.Stop(); err != nil {
+		// The old process may still be running; starting another one
+		// would race with it.
+		return fmt.Errorf("stopping claude to apply new options: %w", err)
+	}
+
+	p.mu.Lock()
+	if resumable {
+		p.resumeNext = sessionID
+	}
+	p.mu.Unlock()
+	if err := p.Start(context.Background()); err != nil {
+		return fmt.Errorf("restarting claude with new options: %w", err)
+	}
+	return nil
+}
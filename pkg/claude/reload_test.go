@@ -0,0 +1,108 @@
+package claude
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcess_UpdateOptions(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Model = "old"
+	p := NewProcess(opts)
+
+	opts.Model = "new"
+	p.UpdateOptions(opts)
+	if got := p.mergedOpts(nil).Model; got != "new" {
+		t.Errorf("expected the next run to use model new, got %q", got)
+	}
+	if got := p.Status().Model; got != "new" {
+		t.Errorf("expected status to report model new, got %q", got)
+	}
+}
+
+func TestPersistentProcess_UpdateOptionsRestartsWithResume(t *testing.T) {
+	argsFile := filepath.Join(t.TempDir(), "args")
+	t.Setenv("ARGS_FILE", argsFile)
+	t.Setenv("CLAUDE_CONFIG_DIR", t.TempDir())
+	installFakeClaude(t, resumeFakeClaude)
+
+	opts := DefaultOptions()
+	opts.NoSessionPersistence = false
+	opts.Model = "old"
+	opts.WorkDir = t.TempDir()
+	opts.ResultDir = t.TempDir()
+
+	p := NewPersistentProcess(opts)
+	t.Cleanup(func() { _ = p.Stop() })
+	if _, _, err := p.RunSyncWithOptions(context.Background(), "hello", nil); err != nil {
+		t.Fatalf("first prompt: %v", err)
+	}
+
+	// The CLI stores the transcript of the session it reported.
+	sessionsDir := SessionsDir(opts.WorkDir)
+	if err := os.MkdirAll(sessionsDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeSession(t, sessionsDir, "s-next", time.Now(), `{"type":"user","message":{"content":"hello"}}`)
+
+	opts.Model = "new"
+	p.UpdateOptions(opts)
+	if args, _ := os.ReadFile(argsFile); !strings.Contains(string(args), "--model old") {
+		t.Errorf("expected the subprocess to keep running until the next prompt, args %q", args)
+	}
+
+	if _, _, err := p.RunSyncWithOptions(context.Background(), "again", nil); err != nil {
+		t.Fatalf("second prompt: %v", err)
+	}
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--model new", "--resume s-next"} {
+		if !strings.Contains(string(args), want) {
+			t.Errorf("expected %q in restarted args %q", want, args)
+		}
+	}
+	if got := p.Status(); got.Model != "new" || got.MessageCount < 6 {
+		t.Errorf("unexpected status after restart: model %q, %d messages", got.Model, got.MessageCount)
+	}
+}
+
+func TestPersistentProcess_UpdateOptionsKeepsSessionSettings(t *testing.T) {
+	policy := OverridePolicy{Allow: []string{OverrideModel}, PermissionModes: []string{PermissionModeAccept}}
+	p := startControlProcess(t, policy)
+	if _, err := p.ConfigureSession(context.Background(), SessionConfig{Model: "opus", PermissionMode: PermissionModeAccept}); err != nil {
+		t.Fatalf("ConfigureSession: %v", err)
+	}
+
+	opts := p.options()
+	opts.Model = "sonnet"
+	opts.PermissionMode = PermissionModePlan
+	opts.MaxTurns = 7
+	p.UpdateOptions(opts)
+	args := strings.Join(p.options().PersistentArgs(), " ")
+	for _, want := range []string{"--model opus", "--permission-mode acceptEdits", "--max-turns 7"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("expected %q in restart args %q", want, args)
+		}
+	}
+	if got := p.Status(); got.Model != "opus" || got.PermissionMode != PermissionModeAccept {
+		t.Errorf("status lost the session settings: model=%q permission_mode=%q", got.Model, got.PermissionMode)
+	}
+
+	// A policy that no longer permits the settings drops them.
+	opts.OverridePolicy = OverridePolicy{}
+	p.UpdateOptions(opts)
+	if got := p.options(); got.Model != "sonnet" || got.PermissionMode != PermissionModePlan {
+		t.Errorf("expected the configured settings, got model=%q permission_mode=%q", got.Model, got.PermissionMode)
+	}
+	opts.OverridePolicy = policy
+	p.UpdateOptions(opts)
+	if got := p.options().Model; got != "sonnet" {
+		t.Errorf("dropped session model came back: %q", got)
+	}
+}
@@ -0,0 +1,82 @@
+package claude
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/klaus/pkg/metrics"
+)
+
+// NewReplayProcess returns a Process that answers every prompt by replaying
+// recorded CLI stdout (stream-json, one message per line) instead of
+// spawning the claude binary. The recording goes through the same parsing,
+// redaction, status accounting and subagent tracking as live output, which
+// makes it possible to reproduce parsing bugs from production transcripts.
+func NewReplayProcess(opts Options, recording []byte) *Process {
+	p := NewProcess(opts)
+	p.replay = recording
+	return p
+}
+
+// startReplay feeds the recording through handleLine on a background
+// goroutine. It mirrors the stdout loop of RunWithOptions; done is closed
+// once the whole recording has been consumed.
+func (p *Process) startReplay(done chan struct{}, opts Options) <-chan StreamMessage {
+	runCtx, runCancel := context.WithCancel(context.Background())
+
+	p.mu.Lock()
+	p.status = ProcessStatusBusy
+	p.runCancel = runCancel
+	p.changes.notify()
+	p.mu.Unlock()
+	metrics.SetProcessStatus(string(ProcessStatusBusy))
+
+	out := make(chan StreamMessage, 100)
+	go func() {
+		defer close(out)
+		defer func() {
+			p.mu.Lock()
+			if p.status == ProcessStatusBusy {
+				p.status = ProcessStatusIdle
+			}
+			status := p.status
+			close(done)
+			p.changes.notify()
+			p.mu.Unlock()
+			metrics.SetProcessStatus(string(status))
+		}()
+
+		scanner := bufio.NewScanner(bytes.NewReader(p.replay))
+		scanner.Buffer(make([]byte, 0, 1024*1024), 10*1024*1024)
+
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(line) == 0 || isRecordingMetadata(line) {
+				continue
+			}
+
+			msg, ok := p.handleLine(line, opts)
+			if !ok {
+				continue
+			}
+
+			select {
+			case out <- msg:
+			case <-runCtx.Done():
+				return
+			}
+		}
+
+		if scanErr := scanner.Err(); scanErr != nil {
+			p.mu.Lock()
+			p.status = ProcessStatusError
+			p.lastError = fmt.Sprintf("reading recording: %v", scanErr)
+			p.changes.notify()
+			p.mu.Unlock()
+		}
+	}()
+
+	return out
+}
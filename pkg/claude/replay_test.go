@@ -0,0 +1,88 @@
+package claude
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func readFixture(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/stream_json_fixture.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestReplayProcess_RunSync(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ResultDir = t.TempDir()
+	p := NewReplayProcess(opts, readFixture(t))
+
+	text, messages, err := p.RunSyncWithOptions(context.Background(), "read the hostname", nil)
+	if err != nil {
+		t.Fatalf("RunSyncWithOptions: %v", err)
+	}
+	if text == "" {
+		t.Error("expected result text from the recording")
+	}
+	if len(messages) == 0 {
+		t.Fatal("expected replayed messages")
+	}
+
+	status := p.Status()
+	if status.Status != ProcessStatusIdle {
+		t.Errorf("status = %q, want idle", status.Status)
+	}
+	if status.SessionID == "" {
+		t.Error("expected session ID from the recorded system message")
+	}
+	if status.ToolCallCount != 3 || status.ToolCalls["Read"] != 2 {
+		t.Errorf("unexpected tool accounting: %d %v", status.ToolCallCount, status.ToolCalls)
+	}
+	if status.TotalCost == nil {
+		t.Error("expected cost from the recorded result message")
+	}
+
+	// The prompt is recorded as the first message, like a live run.
+	oa := p.OpenAIMessages(0)
+	if len(oa.Messages) == 0 || oa.Messages[0].Role != "user" {
+		t.Errorf("expected the prompt as first OpenAI message, got %+v", oa.Messages)
+	}
+}
+
+func TestReplayProcess_SubmitCompletes(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ResultDir = t.TempDir()
+	p := NewReplayProcess(opts, readFixture(t))
+
+	if err := p.Submit(context.Background(), "hello", nil); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Status().Status != ProcessStatusCompleted {
+		if time.Now().After(deadline) {
+			t.Fatalf("status = %q, want completed", p.Status().Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if p.Status().Result == "" || p.ResultDetail().ResultText == "" {
+		t.Error("expected the recorded result to be reported")
+	}
+}
+
+func TestReplayProcess_SkipsInvalidLines(t *testing.T) {
+	recording := []byte("not json\n\n" +
+		`{"type":"system","subtype":"init","session_id":"s1"}` + "\n" +
+		`{"type":"result","subtype":"success","result":"ok"}` + "\n")
+	p := NewReplayProcess(DefaultOptions(), recording)
+	text, messages, err := p.RunSyncWithOptions(context.Background(), "x", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "ok" || len(messages) != 2 {
+		t.Errorf("got text=%q messages=%d", text, len(messages))
+	}
+}
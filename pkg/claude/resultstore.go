@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -35,38 +36,48 @@ const (
 // PersistedResult is the on-disk representation of a session result.
 // It extends ResultDetailInfo with metadata for post-mortem retrieval.
 type PersistedResult struct {
-	ResultText    string          `json:"result_text"`
-	Messages      []StreamMessage `json:"messages,omitempty"`
-	MessageCount  int             `json:"message_count"`
-	ToolCalls     map[string]int  `json:"tool_calls,omitempty"`
-	SubagentCalls []SubagentCall  `json:"subagent_calls,omitempty"`
-	ModelUsage    map[string]int  `json:"model_usage,omitempty"`
-	PRURLs        []string        `json:"pr_urls,omitempty"`
-	ErrorCount    int             `json:"error_count,omitempty"`
-	TokenUsage    *TokenUsage     `json:"token_usage,omitempty"`
-	TotalCost     *float64        `json:"total_cost_usd"`
-	SessionID     string          `json:"session_id,omitempty"`
-	Status        ProcessStatus   `json:"status"`
-	ErrorMessage  string          `json:"error,omitempty"`
-	StopReason    StopReason      `json:"stop_reason,omitempty"`
-	Timestamp     time.Time       `json:"timestamp"`
+	ResultText     string          `json:"result_text"`
+	Messages       []StreamMessage `json:"messages,omitempty"`
+	MessageCount   int             `json:"message_count"`
+	ToolCalls      map[string]int  `json:"tool_calls,omitempty"`
+	SubagentCalls  []SubagentCall  `json:"subagent_calls,omitempty"`
+	ModelUsage     map[string]int  `json:"model_usage,omitempty"`
+	PRURLs         []string        `json:"pr_urls,omitempty"`
+	ErrorCount     int             `json:"error_count,omitempty"`
+	RedactionCount int             `json:"redaction_count,omitempty"`
+	TokenUsage     *TokenUsage     `json:"token_usage,omitempty"`
+	TotalCost      *float64        `json:"total_cost_usd"`
+	SessionID      string          `json:"session_id,omitempty"`
+	RunID          string          `json:"run_id,omitempty"`
+	Status         ProcessStatus   `json:"status"`
+	ErrorMessage   string          `json:"error,omitempty"`
+	// Stderr holds the last lines of subprocess stderr when the run failed.
+	Stderr     []string   `json:"stderr,omitempty"`
+	StopReason StopReason `json:"stop_reason,omitempty"`
+	// History holds the messages of all turns of a chat session, while
+	// Messages only holds the last turn. It is used to restore the
+	// conversation when the session is resumed (see Options.ResumeOnStart).
+	History   []StreamMessage `json:"history,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
 }
 
 // ToResultDetailInfo converts a PersistedResult back to a ResultDetailInfo.
 func (pr *PersistedResult) ToResultDetailInfo() ResultDetailInfo {
 	info := ResultDetailInfo{
-		ResultText:    pr.ResultText,
-		Messages:      pr.Messages,
-		MessageCount:  pr.MessageCount,
-		ToolCalls:     pr.ToolCalls,
-		SubagentCalls: copySubagentCalls(pr.SubagentCalls),
-		ModelUsage:    copyToolCalls(pr.ModelUsage),
-		PRURLs:        copyStringSlice(pr.PRURLs),
-		ErrorCount:    pr.ErrorCount,
-		TotalCost:     pr.TotalCost,
-		SessionID:     pr.SessionID,
-		Status:        pr.Status,
-		ErrorMessage:  pr.ErrorMessage,
+		ResultText:     pr.ResultText,
+		Messages:       pr.Messages,
+		MessageCount:   pr.MessageCount,
+		ToolCalls:      pr.ToolCalls,
+		SubagentCalls:  copySubagentCalls(pr.SubagentCalls),
+		ModelUsage:     copyToolCalls(pr.ModelUsage),
+		PRURLs:         copyStringSlice(pr.PRURLs),
+		ErrorCount:     pr.ErrorCount,
+		RedactionCount: pr.RedactionCount,
+		TotalCost:      pr.TotalCost,
+		SessionID:      pr.SessionID,
+		RunID:          pr.RunID,
+		Status:         pr.Status,
+		ErrorMessage:   pr.ErrorMessage,
 	}
 	if pr.TokenUsage != nil {
 		tu := *pr.TokenUsage
@@ -142,11 +153,24 @@ func (s *ResultStore) Save(result PersistedResult) error {
 		return fmt.Errorf("marshaling result: %w", err)
 	}
 
-	path := filepath.Join(s.dir, resultFileName)
+	if err := writeFileAtomic(s.dir, resultFileName, data); err != nil {
+		return err
+	}
+	if result.RunID != "" {
+		if err := s.saveRun(result.RunID, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to dir/name via a temp file and rename to
+// avoid partial reads. The file is only readable by the owner.
+func writeFileAtomic(dir, name string, data []byte) error {
+	path := filepath.Join(dir, name)
 
-	// Write atomically via temp file + rename to avoid partial reads.
 	// Use CreateTemp for an unpredictable filename to prevent symlink attacks.
-	tmp, err := os.CreateTemp(s.dir, ".last-result-*.tmp")
+	tmp, err := os.CreateTemp(dir, "."+strings.TrimSuffix(name, ".json")+"-*.tmp")
 	if err != nil {
 		return fmt.Errorf("creating temp file: %w", err)
 	}
@@ -196,32 +220,52 @@ func (s *ResultStore) Load() (*PersistedResult, error) {
 	return &result, nil
 }
 
+// runMeta is the process state persisted alongside a completed result.
+type runMeta struct {
+	status     ProcessStatus
+	sessionID  string
+	runID      string
+	totalCost  *float64
+	lastError  string
+	tokenUsage *TokenUsage
+	redactions int
+	// stderr holds the last subprocess stderr lines; only set on error.
+	stderr []string
+	// history holds the messages of all turns of a chat session; only set
+	// when the session is resumed on start.
+	history []StreamMessage
+}
+
 // persistResult is a helper called from the setResult callbacks in both
 // Process and PersistentProcess. It saves the result to disk if a store
 // is configured and the result is complete.
-func persistResult(store *ResultStore, rs resultState, status ProcessStatus, sessionID string, totalCost *float64, lastError string, tokenUsage *TokenUsage) {
+func persistResult(store *ResultStore, rs resultState, meta runMeta) {
 	if store == nil || !rs.completed {
 		return
 	}
 
-	reason := stopReasonFromStatus(status)
+	reason := stopReasonFromStatus(meta.status)
 
 	pr := PersistedResult{
-		ResultText:    rs.text,
-		Messages:      rs.messages,
-		MessageCount:  len(rs.messages),
-		ToolCalls:     collectToolCalls(rs.messages),
-		SubagentCalls: collectSubagentCalls(rs.messages),
-		ModelUsage:    CollectModelUsage(rs.messages),
-		PRURLs:        CollectPRURLs(rs.messages),
-		ErrorCount:    CollectErrorCount(rs.messages),
-		TotalCost:     totalCost,
-		TokenUsage:    tokenUsage,
-		SessionID:     sessionID,
-		Status:        status,
-		ErrorMessage:  lastError,
-		StopReason:    reason,
-		Timestamp:     time.Now(),
+		ResultText:     rs.text,
+		Messages:       rs.messages,
+		MessageCount:   len(rs.messages),
+		ToolCalls:      collectToolCalls(rs.messages),
+		SubagentCalls:  collectSubagentCalls(rs.messages),
+		ModelUsage:     CollectModelUsage(rs.messages),
+		PRURLs:         CollectPRURLs(rs.messages),
+		ErrorCount:     CollectErrorCount(rs.messages),
+		RedactionCount: meta.redactions,
+		TotalCost:      meta.totalCost,
+		TokenUsage:     meta.tokenUsage,
+		SessionID:      meta.sessionID,
+		RunID:          meta.runID,
+		Status:         meta.status,
+		ErrorMessage:   meta.lastError,
+		Stderr:         meta.stderr,
+		StopReason:     reason,
+		History:        meta.history,
+		Timestamp:      time.Now(),
 	}
 
 	if err := store.Save(pr); err != nil {
@@ -382,7 +382,7 @@ func TestCollectToolCalls_Empty(t *testing.T) {
 func TestPersistResult_NilStore(t *testing.T) {
 	// Should not panic with nil store.
 	rs := resultState{text: "result", completed: true}
-	persistResult(nil, rs, ProcessStatusCompleted, "sess", Float64Ptr(0.1), "", nil)
+	persistResult(nil, rs, runMeta{status: ProcessStatusCompleted, sessionID: "sess", totalCost: Float64Ptr(0.1)})
 }
 
 func TestPersistResult_IncompleteResult(t *testing.T) {
@@ -391,7 +391,7 @@ func TestPersistResult_IncompleteResult(t *testing.T) {
 
 	// Incomplete result should not be persisted.
 	rs := resultState{text: "partial", completed: false}
-	persistResult(store, rs, ProcessStatusBusy, "sess", nil, "", nil)
+	persistResult(store, rs, runMeta{status: ProcessStatusBusy, sessionID: "sess"})
 
 	loaded, err := store.Load()
 	if err != nil {
@@ -411,7 +411,7 @@ func TestPersistResult_CompletedResult(t *testing.T) {
 		messages:  []StreamMessage{{Type: MessageTypeResult, Result: "final result"}},
 		completed: true,
 	}
-	persistResult(store, rs, ProcessStatusCompleted, "sess-abc", Float64Ptr(0.50), "", nil)
+	persistResult(store, rs, runMeta{status: ProcessStatusCompleted, sessionID: "sess-abc", totalCost: Float64Ptr(0.50)})
 
 	loaded, err := store.Load()
 	if err != nil {
@@ -559,7 +559,7 @@ func TestPersistResult_WithTokenUsage(t *testing.T) {
 		CacheCreationInputTokens: 5000,
 		CacheReadInputTokens:     3000,
 	}
-	persistResult(store, rs, ProcessStatusCompleted, "sess-tu", Float64Ptr(0.42), "", tu)
+	persistResult(store, rs, runMeta{status: ProcessStatusCompleted, sessionID: "sess-tu", totalCost: Float64Ptr(0.42), tokenUsage: tu})
 
 	loaded, err := store.Load()
 	if err != nil {
@@ -593,7 +593,7 @@ func TestPersistResult_NilCost(t *testing.T) {
 		text:      "result without cost",
 		completed: true,
 	}
-	persistResult(store, rs, ProcessStatusCompleted, "sess", nil, "", nil)
+	persistResult(store, rs, runMeta{status: ProcessStatusCompleted, sessionID: "sess"})
 
 	loaded, err := store.Load()
 	if err != nil {
@@ -0,0 +1,246 @@
+package claude
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrRunNotFound is returned when no run has the given ID.
+var ErrRunNotFound = errors.New("run not found")
+
+// ErrInvalidRunID is returned for run IDs that cannot name a stored run.
+var ErrInvalidRunID = errors.New("invalid run id")
+
+const (
+	// runsSubdir is the subdirectory of the result store holding the
+	// results of past runs, one file per run.
+	runsSubdir = "runs"
+
+	// maxStoredRuns caps the number of past runs kept in the result store;
+	// the oldest are removed first.
+	maxStoredRuns = 50
+)
+
+// Run IDs naming the current run, or the last one when none is in progress,
+// wherever a run ID is accepted.
+const (
+	CurrentRunID = "current"
+	LastRunID    = "last"
+)
+
+// validRunID matches the run IDs newRunID generates. IDs are used as file
+// names, so anything else is rejected to prevent path traversal.
+var validRunID = regexp.MustCompile(`^[0-9]{8}T[0-9]{6}-[0-9a-f]{8}$`)
+
+// newRunID returns a new run ID. IDs sort by their start time.
+func newRunID() string {
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+	return time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(b[:])
+}
+
+func checkRunID(id string) error {
+	if !validRunID.MatchString(id) {
+		return fmt.Errorf("%w: %q", ErrInvalidRunID, id)
+	}
+	return nil
+}
+
+// RunInfo describes a past run kept in the result store.
+type RunInfo struct {
+	RunID        string        `json:"run_id"`
+	SessionID    string        `json:"session_id,omitempty"`
+	Status       ProcessStatus `json:"status"`
+	StopReason   StopReason    `json:"stop_reason,omitempty"`
+	MessageCount int           `json:"message_count"`
+	TotalCost    *float64      `json:"total_cost_usd"`
+	Timestamp    time.Time     `json:"timestamp"`
+}
+
+// saveRun writes the result of a run to the runs directory and removes the
+// oldest runs beyond maxStoredRuns.
+func (s *ResultStore) saveRun(id string, data []byte) error {
+	if err := checkRunID(id); err != nil {
+		return err
+	}
+	dir := filepath.Join(s.dir, runsSubdir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("creating runs directory: %w", err)
+	}
+	if err := writeFileAtomic(dir, id+".json", data); err != nil {
+		return err
+	}
+
+	ids, err := s.runIDs()
+	if err != nil {
+		return err
+	}
+	for len(ids) > maxStoredRuns {
+		if err := os.Remove(filepath.Join(dir, ids[0]+".json")); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing old run: %w", err)
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// runIDs returns the IDs of the stored runs, oldest first.
+func (s *ResultStore) runIDs() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, runsSubdir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading runs directory: %w", err)
+	}
+	var ids []string
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if ok && e.Type().IsRegular() && validRunID.MatchString(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// storedRun is the raw JSON result of a past run in the result store.
+type storedRun struct {
+	id   string
+	data []byte
+}
+
+// sessionRuns returns the stored runs of a session, oldest first.
+func (s *ResultStore) sessionRuns(sessionID string) ([]storedRun, error) {
+	ids, err := s.runIDs()
+	if err != nil {
+		return nil, err
+	}
+	var runs []storedRun
+	for _, id := range ids {
+		data, err := os.ReadFile(filepath.Join(s.dir, runsSubdir, id+".json")) // #nosec G304 -- id is validated
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("reading run: %w", err)
+		}
+		var run struct {
+			SessionID string `json:"session_id"`
+		}
+		if json.Unmarshal(data, &run) != nil || run.SessionID != sessionID {
+			continue
+		}
+		runs = append(runs, storedRun{id: id, data: data})
+	}
+	return runs, nil
+}
+
+// LoadRun reads the result of a past run. It returns ErrRunNotFound if the
+// run is not stored, e.g. because it was a blocking run or has been removed
+// to make room for newer ones. It is safe to call on a nil store.
+func (s *ResultStore) LoadRun(id string) (*PersistedResult, error) {
+	if err := checkRunID(id); err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, runsSubdir, id+".json")) // #nosec G304 -- id is validated
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+		}
+		return nil, fmt.Errorf("reading run: %w", err)
+	}
+	var result PersistedResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("parsing run %s: %w", id, err)
+	}
+	return &result, nil
+}
+
+// ListRuns returns the stored runs, newest first. Runs that cannot be read
+// are skipped. It is safe to call on a nil store.
+func (s *ResultStore) ListRuns() ([]RunInfo, error) {
+	if s == nil {
+		return []RunInfo{}, nil
+	}
+	ids, err := s.runIDs()
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]RunInfo, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		pr, err := s.LoadRun(ids[i])
+		if err != nil {
+			continue
+		}
+		runs = append(runs, RunInfo{
+			RunID:        ids[i],
+			SessionID:    pr.SessionID,
+			Status:       pr.Status,
+			StopReason:   pr.StopReason,
+			MessageCount: pr.MessageCount,
+			TotalCost:    pr.TotalCost,
+			Timestamp:    pr.Timestamp,
+		})
+	}
+	return runs, nil
+}
+
+// RunDetail returns the detail of a run of p: the current or last run if id
+// is its ID, empty, CurrentRunID or LastRunID, otherwise a past run from the
+// result store. Only non-blocking runs are stored; other past runs return
+// ErrRunNotFound.
+func RunDetail(p Prompter, id string) (ResultDetailInfo, error) {
+	if id == CurrentRunID || id == LastRunID {
+		id = ""
+	}
+	if id == "" || id == p.Status().RunID {
+		detail := p.ResultDetail()
+		if id == "" || detail.RunID == id {
+			return detail, nil
+		}
+	}
+	pr, err := p.Results().LoadRun(id)
+	if err != nil {
+		return ResultDetailInfo{}, err
+	}
+	return pr.ToResultDetailInfo(), nil
+}
+
+// Results returns the result store holding the results of past runs.
+func (p *Process) Results() *ResultStore {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.resultStore
+}
+
+// Results returns the result store holding the results of past runs.
+func (p *PersistentProcess) Results() *ResultStore {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.resultStore
+}
+
+// WorkDir returns the workspace directory the agent runs in; empty means
+// the current directory.
+func (p *Process) WorkDir() string {
+	return p.options().WorkDir
+}
+
+// WorkDir returns the workspace directory the agent runs in; empty means
+// the current directory.
+func (p *PersistentProcess) WorkDir() string {
+	return p.options().WorkDir
+}
@@ -0,0 +1,145 @@
+package claude
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewRunID(t *testing.T) {
+	a, b := newRunID(), newRunID()
+	if err := checkRunID(a); err != nil {
+		t.Errorf("generated run ID is invalid: %v", err)
+	}
+	if a == b {
+		t.Errorf("expected unique run IDs, got %q twice", a)
+	}
+}
+
+func TestResultStore_Runs(t *testing.T) {
+	store := NewResultStore(t.TempDir())
+
+	if runs, err := store.ListRuns(); err != nil || len(runs) != 0 {
+		t.Fatalf("expected no runs, got %v, %v", runs, err)
+	}
+
+	for i, id := range []string{"20260101T100000-00000001", "20260101T110000-00000002"} {
+		pr := PersistedResult{
+			RunID:      id,
+			ResultText: fmt.Sprintf("result %d", i),
+			Status:     ProcessStatusCompleted,
+			StopReason: StopReasonCompleted,
+			Timestamp:  time.Now(),
+		}
+		if err := store.Save(pr); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+	// Results without a run ID are only kept as the last result.
+	if err := store.Save(PersistedResult{ResultText: "no id"}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	runs, err := store.ListRuns()
+	if err != nil {
+		t.Fatalf("ListRuns: %v", err)
+	}
+	if len(runs) != 2 || runs[0].RunID != "20260101T110000-00000002" || runs[1].Status != ProcessStatusCompleted {
+		t.Errorf("expected both runs newest first, got %+v", runs)
+	}
+
+	pr, err := store.LoadRun("20260101T100000-00000001")
+	if err != nil {
+		t.Fatalf("LoadRun: %v", err)
+	}
+	if pr.ResultText != "result 0" || pr.RunID != "20260101T100000-00000001" {
+		t.Errorf("unexpected run %+v", pr)
+	}
+	if last, _ := store.Load(); last == nil || last.ResultText != "no id" {
+		t.Errorf("expected the last result to be kept, got %+v", last)
+	}
+
+	if _, err := store.LoadRun("20260101T120000-00000003"); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("expected ErrRunNotFound, got %v", err)
+	}
+	if _, err := store.LoadRun("../last-result"); !errors.Is(err, ErrInvalidRunID) {
+		t.Errorf("expected ErrInvalidRunID, got %v", err)
+	}
+	var nilStore *ResultStore
+	if _, err := nilStore.LoadRun("20260101T100000-00000001"); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("expected ErrRunNotFound from nil store, got %v", err)
+	}
+}
+
+func TestResultStore_PrunesOldRuns(t *testing.T) {
+	store := NewResultStore(t.TempDir())
+	for i := range maxStoredRuns + 2 {
+		id := fmt.Sprintf("20260101T%06d-00000000", i)
+		if err := store.Save(PersistedResult{RunID: id}); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(store.dir, runsSubdir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != maxStoredRuns {
+		t.Errorf("expected %d runs to be kept, got %d", maxStoredRuns, len(entries))
+	}
+	if _, err := store.LoadRun("20260101T000000-00000000"); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("expected the oldest run to be removed, got %v", err)
+	}
+}
+
+func TestRunDetail(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ResultDir = t.TempDir()
+	p := NewProcess(opts)
+
+	past := "20260101T100000-00000001"
+	if err := p.Results().Save(PersistedResult{RunID: past, ResultText: "past"}); err != nil {
+		t.Fatal(err)
+	}
+	p.mu.Lock()
+	p.runID = "20260101T110000-00000002"
+	p.result = resultState{text: "current", completed: true}
+	p.mu.Unlock()
+
+	for id, want := range map[string]string{"": "current", CurrentRunID: "current", LastRunID: "current", "20260101T110000-00000002": "current", past: "past"} {
+		detail, err := RunDetail(p, id)
+		if err != nil {
+			t.Fatalf("RunDetail(%q): %v", id, err)
+		}
+		if detail.ResultText != want {
+			t.Errorf("RunDetail(%q) = %q, want %q", id, detail.ResultText, want)
+		}
+	}
+	if _, err := RunDetail(p, "20260101T120000-00000003"); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("expected ErrRunNotFound, got %v", err)
+	}
+}
+
+func TestReadWorkspaceFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes.json"), []byte(`{"a":1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	data, mediaType, err := ReadWorkspaceFile(dir, "notes.json")
+	if err != nil {
+		t.Fatalf("ReadWorkspaceFile: %v", err)
+	}
+	if string(data) != `{"a":1}` || mediaType != "application/json" {
+		t.Errorf("unexpected file %q of type %q", data, mediaType)
+	}
+	if _, _, err := ReadWorkspaceFile(dir, "../outside"); err == nil {
+		t.Error("expected a path outside the workspace to be rejected")
+	}
+	if _, _, err := ReadWorkspaceFile(dir, "."); err == nil {
+		t.Error("expected a directory to be rejected")
+	}
+}
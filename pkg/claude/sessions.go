@@ -0,0 +1,368 @@
+package claude
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/klaus/pkg/redact"
+)
+
+// ErrSessionNotFound is returned when no stored session has the given ID.
+var ErrSessionNotFound = errors.New("session not found")
+
+// ErrInvalidSessionID is returned for session IDs that cannot name a stored
+// session.
+var ErrInvalidSessionID = errors.New("invalid session id")
+
+// ErrSessionActive is returned when deleting the session the running agent
+// is using.
+var ErrSessionActive = errors.New("session is in use by the running agent")
+
+// sessionFileSuffix is the extension of the CLI's session transcripts.
+const sessionFileSuffix = ".jsonl"
+
+// maxSessionPromptLen caps the prompts reported per session.
+const maxSessionPromptLen = 200
+
+// validSessionID matches the session IDs the CLI generates (UUIDs). IDs are
+// used as file names, so anything else is rejected to prevent path
+// traversal.
+var validSessionID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// SessionInfo describes a session stored by the CLI.
+type SessionInfo struct {
+	ID         string    `json:"session_id"`
+	CreatedAt  time.Time `json:"created_at,omitzero"`
+	ModifiedAt time.Time `json:"modified_at"`
+	// FirstPrompt is the first user prompt, truncated.
+	FirstPrompt  string `json:"first_prompt,omitempty"`
+	SizeBytes    int64  `json:"size_bytes"`
+	MessageCount int    `json:"message_count"`
+}
+
+// SessionDetail extends SessionInfo with metadata read from the whole
+// transcript.
+type SessionDetail struct {
+	SessionInfo
+	LastPrompt string `json:"last_prompt,omitempty"`
+	Cwd        string `json:"cwd,omitempty"`
+	GitBranch  string `json:"git_branch,omitempty"`
+	CLIVersion string `json:"cli_version,omitempty"`
+}
+
+// SessionStore gives access to the sessions the CLI stored for a workspace.
+// The CLI writes one JSONL transcript per session to
+// <config dir>/projects/<encoded workspace path>/<session id>.jsonl.
+type SessionStore struct {
+	dir      string
+	redactor *redact.Redactor
+	// active returns the ID of the session the running agent uses, or ""
+	// when none is in use.
+	active func() string
+	// workDir is the workspace the sessions belong to; session bundles
+	// diff and patch it.
+	workDir string
+	// results is klaus' result store, bundled with the session it belongs
+	// to. nil leaves results out of bundles.
+	results *ResultStore
+}
+
+// NewSessionStore returns a store for the session transcripts in dir.
+// Exported transcripts are masked with redactor; nil disables redaction.
+func NewSessionStore(dir string, redactor *redact.Redactor) *SessionStore {
+	return &SessionStore{dir: dir, redactor: redactor}
+}
+
+// NewWorkspaceSessionStore returns a store for the sessions of
+// opts.WorkDir that also bundles klaus' results from the result store of
+// opts.
+func NewWorkspaceSessionStore(opts Options) *SessionStore {
+	store := NewSessionStore(SessionsDir(opts.WorkDir), opts.Redactor)
+	store.workDir = opts.WorkDir
+	store.results = NewResultStore(resultStoreDir(opts))
+	return store
+}
+
+// Dir returns the directory the store reads sessions from.
+func (s *SessionStore) Dir() string {
+	return s.dir
+}
+
+// SessionsDir returns the directory the CLI stores the sessions of workDir
+// in. It honours CLAUDE_CONFIG_DIR like the CLI; an empty workDir means the
+// current directory.
+func SessionsDir(workDir string) string {
+	return sessionsDir(workDir, os.Getenv, os.UserHomeDir)
+}
+
+// sessionsDir is the testable inner implementation of SessionsDir.
+func sessionsDir(workDir string, getenv func(string) string, homeDir func() (string, error)) string {
+	if workDir == "" {
+		workDir, _ = os.Getwd()
+	}
+	if abs, err := filepath.Abs(workDir); err == nil {
+		workDir = abs
+	}
+	return filepath.Join(configDir(getenv, homeDir), "projects", encodeProjectPath(workDir))
+}
+
+// ConfigDir returns the directory the CLI keeps its state in:
+// $CLAUDE_CONFIG_DIR, or ~/.claude.
+func ConfigDir() string {
+	return configDir(os.Getenv, os.UserHomeDir)
+}
+
+func configDir(getenv func(string) string, homeDir func() (string, error)) string {
+	if dir := getenv("CLAUDE_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	home, err := homeDir()
+	if err != nil || home == "" {
+		home = os.TempDir()
+	}
+	return filepath.Join(home, ".claude")
+}
+
+// encodeProjectPath encodes a workspace path the way the CLI names its
+// project directories: every character other than ASCII letters and digits
+// becomes '-'.
+func encodeProjectPath(path string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '-'
+	}, path)
+}
+
+// List returns the stored sessions, most recently modified first. A missing
+// directory yields no sessions.
+func (s *SessionStore) List() ([]SessionInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []SessionInfo{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading sessions: %w", err)
+	}
+	sessions := []SessionInfo{}
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), sessionFileSuffix)
+		if e.IsDir() || !ok || !validSessionID.MatchString(id) {
+			continue
+		}
+		detail, err := s.read(id)
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, detail.SessionInfo)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ModifiedAt.After(sessions[j].ModifiedAt)
+	})
+	return sessions, nil
+}
+
+// Inspect returns the details of one session.
+func (s *SessionStore) Inspect(id string) (SessionDetail, error) {
+	if err := checkSessionID(id); err != nil {
+		return SessionDetail{}, err
+	}
+	return s.read(id)
+}
+
+// Export returns the transcript of a session as JSONL, with secrets masked
+// line by line.
+func (s *SessionStore) Export(id string) ([]byte, error) {
+	if err := checkSessionID(id); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading session: %w", err)
+	}
+	if s.redactor == nil {
+		return data, nil
+	}
+	var out bytes.Buffer
+	for line := range bytes.Lines(data) {
+		masked, _ := s.redactor.Redact(bytes.TrimRight(line, "\n"))
+		out.Write(masked)
+		out.WriteByte('\n')
+	}
+	return out.Bytes(), nil
+}
+
+// Delete removes a stored session. The session the running agent uses
+// cannot be deleted.
+func (s *SessionStore) Delete(id string) error {
+	if err := checkSessionID(id); err != nil {
+		return err
+	}
+	if s.active != nil && s.active() == id {
+		return fmt.Errorf("%w: %s", ErrSessionActive, id)
+	}
+	err := os.Remove(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	if err != nil {
+		return fmt.Errorf("deleting session: %w", err)
+	}
+	// The CLI keeps tool output of a session in a directory of the same
+	// name; it is useless without the transcript.
+	_ = os.RemoveAll(filepath.Join(s.dir, id))
+	return nil
+}
+
+func (s *SessionStore) path(id string) string {
+	return filepath.Join(s.dir, id+sessionFileSuffix)
+}
+
+func checkSessionID(id string) error {
+	if !validSessionID.MatchString(id) {
+		return fmt.Errorf("%w %q", ErrInvalidSessionID, id)
+	}
+	return nil
+}
+
+// sessionLine holds the transcript fields used for session metadata.
+type sessionLine struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	IsMeta    bool      `json:"isMeta"`
+	Cwd       string    `json:"cwd"`
+	GitBranch string    `json:"gitBranch"`
+	Version   string    `json:"version"`
+	Message   struct {
+		Content json.RawMessage `json:"content"`
+	} `json:"message"`
+}
+
+// read scans a session transcript for its metadata.
+func (s *SessionStore) read(id string) (SessionDetail, error) {
+	f, err := os.Open(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return SessionDetail{}, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	if err != nil {
+		return SessionDetail{}, fmt.Errorf("reading session: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return SessionDetail{}, fmt.Errorf("reading session: %w", err)
+	}
+
+	detail := SessionDetail{SessionInfo: SessionInfo{
+		ID:         id,
+		ModifiedAt: info.ModTime().UTC(),
+		SizeBytes:  info.Size(),
+	}}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var line sessionLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			continue
+		}
+		if line.Type != string(MessageTypeUser) && line.Type != string(MessageTypeAssistant) {
+			continue
+		}
+		detail.MessageCount++
+		if detail.CreatedAt.IsZero() && !line.Timestamp.IsZero() {
+			detail.CreatedAt = line.Timestamp.UTC()
+		}
+		detail.Cwd = firstNonEmptyString(line.Cwd, detail.Cwd)
+		detail.GitBranch = firstNonEmptyString(line.GitBranch, detail.GitBranch)
+		detail.CLIVersion = firstNonEmptyString(line.Version, detail.CLIVersion)
+		if line.Type != string(MessageTypeUser) || line.IsMeta {
+			continue
+		}
+		if prompt := userPromptText(line.Message.Content); prompt != "" {
+			prompt, _ = s.redactor.RedactString(Truncate(prompt, maxSessionPromptLen))
+			if detail.FirstPrompt == "" {
+				detail.FirstPrompt = prompt
+			}
+			detail.LastPrompt = prompt
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return SessionDetail{}, fmt.Errorf("reading session: %w", err)
+	}
+	return detail, nil
+}
+
+// userPromptText returns the text a user typed, or "" for tool results.
+func userPromptText(content json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return strings.TrimSpace(text)
+	}
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return ""
+	}
+	var parts []string
+	for _, b := range blocks {
+		if b.Type == "text" && b.Text != "" {
+			parts = append(parts, b.Text)
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
+
+func firstNonEmptyString(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Sessions returns the sessions stored for the workspace. The session of a
+// running prompt cannot be deleted.
+func (p *Process) Sessions() *SessionStore {
+	store := NewWorkspaceSessionStore(p.options())
+	store.active = func() string {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		if p.status != ProcessStatusBusy {
+			return ""
+		}
+		return p.sessionID
+	}
+	return store
+}
+
+// Sessions returns the sessions stored for the workspace. The session of the
+// running subprocess cannot be deleted.
+func (p *PersistentProcess) Sessions() *SessionStore {
+	store := NewWorkspaceSessionStore(p.options())
+	store.active = func() string {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		if p.cmd == nil {
+			return ""
+		}
+		return p.sessionID
+	}
+	return store
+}
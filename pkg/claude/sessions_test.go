@@ -0,0 +1,177 @@
+package claude
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/klaus/pkg/redact"
+)
+
+// writeSession writes a CLI session transcript with the given lines and
+// modification time.
+func writeSession(t *testing.T, dir, id string, modTime time.Time, lines ...string) {
+	t.Helper()
+	path := filepath.Join(dir, id+".jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSessionsDir(t *testing.T) {
+	home := func() (string, error) { return "/home/klaus", nil }
+	noEnv := func(string) string { return "" }
+
+	if got := sessionsDir("/workspace/my.repo", noEnv, home); got != "/home/klaus/.claude/projects/-workspace-my-repo" {
+		t.Errorf("sessionsDir = %q", got)
+	}
+	configDir := func(k string) string {
+		if k == "CLAUDE_CONFIG_DIR" {
+			return "/etc/claude"
+		}
+		return ""
+	}
+	if got := sessionsDir("/workspace", configDir, home); got != "/etc/claude/projects/-workspace" {
+		t.Errorf("sessionsDir with CLAUDE_CONFIG_DIR = %q", got)
+	}
+}
+
+func TestSessionStore_ListAndInspect(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	writeSession(t, dir, "older", now.Add(-time.Hour),
+		`{"type":"user","timestamp":"2026-01-01T10:00:00Z","message":{"role":"user","content":"fix the build"}}`,
+	)
+	writeSession(t, dir, "newer", now,
+		`{"type":"summary","summary":"Pod debugging"}`,
+		`{"type":"user","isMeta":true,"timestamp":"2026-01-02T09:00:00Z","message":{"role":"user","content":"<command-name>/init</command-name>"}}`,
+		`{"type":"user","timestamp":"2026-01-02T09:00:01Z","cwd":"/workspace","gitBranch":"main","version":"2.1.81","message":{"role":"user","content":[{"type":"text","text":"why is the pod pending?"}]}}`,
+		`{"type":"assistant","timestamp":"2026-01-02T09:00:05Z","message":{"role":"assistant","content":[{"type":"text","text":"Checking."}]}}`,
+		`{"type":"user","timestamp":"2026-01-02T09:00:06Z","message":{"role":"user","content":[{"type":"tool_result","content":"Pending"}]}}`,
+		`{"type":"user","timestamp":"2026-01-02T09:01:00Z","message":{"role":"user","content":"now fix it"}}`,
+		`not json`,
+	)
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewSessionStore(dir, nil)
+	sessions, err := store.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].ID != "newer" || sessions[1].ID != "older" {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+	newer := sessions[0]
+	if newer.FirstPrompt != "why is the pod pending?" || newer.MessageCount != 5 || newer.SizeBytes == 0 {
+		t.Errorf("unexpected session info: %+v", newer)
+	}
+	if want := time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC); !newer.CreatedAt.Equal(want) {
+		t.Errorf("CreatedAt = %v, want %v", newer.CreatedAt, want)
+	}
+
+	detail, err := store.Inspect("newer")
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if detail.LastPrompt != "now fix it" || detail.Cwd != "/workspace" || detail.GitBranch != "main" || detail.CLIVersion != "2.1.81" {
+		t.Errorf("unexpected detail: %+v", detail)
+	}
+
+	if _, err := store.Inspect("missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound, got %v", err)
+	}
+	if _, err := store.Inspect("../etc/passwd"); !errors.Is(err, ErrInvalidSessionID) {
+		t.Errorf("expected ErrInvalidSessionID, got %v", err)
+	}
+}
+
+func TestSessionStore_ListMissingDir(t *testing.T) {
+	sessions, err := NewSessionStore(filepath.Join(t.TempDir(), "none"), nil).List()
+	if err != nil || sessions == nil || len(sessions) != 0 {
+		t.Errorf("expected empty list, got %v, %v", sessions, err)
+	}
+}
+
+func TestSessionStore_ExportRedacts(t *testing.T) {
+	dir := t.TempDir()
+	token := "ghp_" + strings.Repeat("A1b2", 9)
+	writeSession(t, dir, "s1", time.Now(),
+		`{"type":"user","message":{"role":"user","content":"use `+token+`"}}`,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"ok"}]}}`,
+	)
+	redactor, err := redact.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := NewSessionStore(dir, redactor).Export("s1")
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if strings.Contains(string(data), token) {
+		t.Errorf("token not redacted: %s", data)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 {
+		t.Errorf("expected 2 lines, got %d", len(lines))
+	}
+
+	detail, err := NewSessionStore(dir, redactor).Inspect("s1")
+	if err != nil || strings.Contains(detail.FirstPrompt, token) {
+		t.Errorf("first prompt not redacted: %+v, %v", detail, err)
+	}
+}
+
+func TestSessionStore_Delete(t *testing.T) {
+	dir := t.TempDir()
+	writeSession(t, dir, "s1", time.Now(), `{"type":"user","message":{"content":"hi"}}`)
+	writeSession(t, dir, "s2", time.Now(), `{"type":"user","message":{"content":"hi"}}`)
+	if err := os.Mkdir(filepath.Join(dir, "s1"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewSessionStore(dir, nil)
+	store.active = func() string { return "s2" }
+
+	if err := store.Delete("s1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	for _, name := range []string{"s1.jsonl", "s1"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s still exists", name)
+		}
+	}
+	if err := store.Delete("s1"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound, got %v", err)
+	}
+	if err := store.Delete("s2"); !errors.Is(err, ErrSessionActive) {
+		t.Errorf("expected ErrSessionActive, got %v", err)
+	}
+	if err := store.Delete(""); !errors.Is(err, ErrInvalidSessionID) {
+		t.Errorf("expected ErrInvalidSessionID, got %v", err)
+	}
+}
+
+func TestPersistentProcess_SessionsProtectsActiveSession(t *testing.T) {
+	t.Setenv("CLAUDE_CONFIG_DIR", t.TempDir())
+	opts := DefaultOptions()
+	opts.WorkDir = t.TempDir()
+	p := NewPersistentProcess(opts)
+	p.sessionID = "live"
+
+	store := p.Sessions()
+	if !strings.HasPrefix(store.Dir(), os.Getenv("CLAUDE_CONFIG_DIR")) {
+		t.Errorf("store ignores CLAUDE_CONFIG_DIR: %s", store.Dir())
+	}
+	// Without a running subprocess the session is not in use.
+	if err := store.Delete("live"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound, got %v", err)
+	}
+}
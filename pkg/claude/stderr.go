@@ -0,0 +1,71 @@
+package claude
+
+// Subprocess stderr capture limits. Every line the CLI writes to stderr is
+// kept in a bounded ring buffer so that the reason for a failed start or a
+// crash is not lost, and so the logs tool can show recent output.
+const (
+	// stderrBufferLines is the number of stderr lines retained per process.
+	stderrBufferLines = 200
+	// errorStderrLines is the number of trailing stderr lines attached to
+	// status, persisted results and crash messages when a run fails.
+	errorStderrLines = 20
+	// maxStderrLineLen is the maximum number of runes kept per stderr line.
+	maxStderrLineLen = 1000
+)
+
+// ringBuffer is a simple fixed-size circular buffer for strings.
+type ringBuffer struct {
+	lines []string
+	pos   int
+	full  bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{lines: make([]string, size)}
+}
+
+func (r *ringBuffer) add(line string) {
+	r.lines[r.pos] = line
+	r.pos++
+	if r.pos >= len(r.lines) {
+		r.pos = 0
+		r.full = true
+	}
+}
+
+// len returns the number of lines currently held.
+func (r *ringBuffer) len() int {
+	if r.full {
+		return len(r.lines)
+	}
+	return r.pos
+}
+
+func (r *ringBuffer) contents() []string {
+	if !r.full {
+		return append([]string(nil), r.lines[:r.pos]...)
+	}
+	result := make([]string, 0, len(r.lines))
+	result = append(result, r.lines[r.pos:]...)
+	result = append(result, r.lines[:r.pos]...)
+	return result
+}
+
+// tail returns the last n lines, oldest first. n <= 0 returns all lines.
+func (r *ringBuffer) tail(n int) []string {
+	lines := r.contents()
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// stderrLogs builds a LogsInfo from a ring buffer. limit <= 0 returns all
+// retained lines.
+func stderrLogs(status ProcessStatus, buf *ringBuffer, limit int) LogsInfo {
+	lines := buf.tail(limit)
+	if lines == nil {
+		lines = []string{}
+	}
+	return LogsInfo{Status: status, Total: buf.len(), Lines: lines}
+}
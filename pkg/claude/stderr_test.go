@@ -0,0 +1,36 @@
+package claude
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRingBuffer_Tail(t *testing.T) {
+	r := newRingBuffer(3)
+	if got := r.tail(0); len(got) != 0 {
+		t.Errorf("expected empty tail, got %v", got)
+	}
+
+	for _, l := range []string{"a", "b", "c", "d"} {
+		r.add(l)
+	}
+	if r.len() != 3 {
+		t.Errorf("len = %d, want 3", r.len())
+	}
+	if got := r.tail(0); !reflect.DeepEqual(got, []string{"b", "c", "d"}) {
+		t.Errorf("tail(0) = %v", got)
+	}
+	if got := r.tail(2); !reflect.DeepEqual(got, []string{"c", "d"}) {
+		t.Errorf("tail(2) = %v", got)
+	}
+	if got := r.tail(10); !reflect.DeepEqual(got, []string{"b", "c", "d"}) {
+		t.Errorf("tail(10) = %v", got)
+	}
+}
+
+func TestStderrLogs_EmptyLinesNotNil(t *testing.T) {
+	info := stderrLogs(ProcessStatusIdle, newRingBuffer(5), 0)
+	if info.Lines == nil || info.Total != 0 {
+		t.Errorf("expected empty non-nil lines, got %+v", info)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !unix
+
+package claude
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup is a no-op on platforms without process groups.
+func setProcessGroup(*exec.Cmd) {}
+
+// signalProcessGroup signals only the process itself on platforms without
+// process groups.
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	if sig == syscall.SIGKILL {
+		return cmd.Process.Kill()
+	}
+	return cmd.Process.Signal(sig)
+}
+
+// reapProcessGroup is a no-op on platforms without process groups.
+func reapProcessGroup(*exec.Cmd) {}
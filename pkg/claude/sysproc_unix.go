@@ -0,0 +1,42 @@
+//go:build unix
+
+package claude
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the command in its own process group so that the
+// CLI and every process it spawns can be signalled together.
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// inOwnProcessGroup reports whether cmd was started by setProcessGroup.
+func inOwnProcessGroup(cmd *exec.Cmd) bool {
+	return cmd.SysProcAttr != nil && cmd.SysProcAttr.Setpgid
+}
+
+// signalProcessGroup sends sig to the whole process group of cmd, falling
+// back to the process itself when it does not lead its own group.
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	if !inOwnProcessGroup(cmd) {
+		return cmd.Process.Signal(sig)
+	}
+	return syscall.Kill(-cmd.Process.Pid, sig)
+}
+
+// reapProcessGroup kills any processes left in the group after the CLI has
+// exited, such as background dev servers started by the Bash tool. It must
+// be called after cmd.Wait.
+func reapProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process == nil || !inOwnProcessGroup(cmd) {
+		return
+	}
+	// ESRCH means the group is already empty.
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
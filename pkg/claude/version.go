@@ -0,0 +1,242 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// versionTimeout bounds how long `claude --version` may take.
+const versionTimeout = 10 * time.Second
+
+// CLIVersion is the semantic version of the installed Claude Code CLI.
+type CLIVersion struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// String returns the version as "major.minor.patch".
+func (v CLIVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether v is the same as or newer than other.
+func (v CLIVersion) AtLeast(other CLIVersion) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor > other.Minor
+	}
+	return v.Patch >= other.Patch
+}
+
+var cliVersionPattern = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
+
+// ParseCLIVersion extracts the version from `claude --version` output, e.g.
+// "2.1.81 (Claude Code)".
+func ParseCLIVersion(output string) (CLIVersion, error) {
+	m := cliVersionPattern.FindStringSubmatch(output)
+	if m == nil {
+		return CLIVersion{}, fmt.Errorf("no version found in %q", strings.TrimSpace(output))
+	}
+	var parts [3]int
+	for i := range parts {
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return CLIVersion{}, fmt.Errorf("invalid version %q: %w", m[0], err)
+		}
+		parts[i] = n
+	}
+	return CLIVersion{Major: parts[0], Minor: parts[1], Patch: parts[2]}, nil
+}
+
+// DetectCLIVersion runs `claude --version` with the binary configured in
+// opts and parses its output.
+func DetectCLIVersion(ctx context.Context, opts Options) (CLIVersion, error) {
+	ctx, cancel := context.WithTimeout(ctx, versionTimeout)
+	defer cancel()
+	binary, argv := opts.commandLine([]string{"--version"})
+	out, err := exec.CommandContext(ctx, binary, argv...).Output() //nolint:gosec // binary is controlled by the operator
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return CLIVersion{}, fmt.Errorf("claude --version: %w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return CLIVersion{}, fmt.Errorf("claude --version: %w", err)
+	}
+	return ParseCLIVersion(string(out))
+}
+
+// Capability is a CLI feature that only some Claude Code versions support.
+type Capability string
+
+// Capabilities klaus depends on.
+const (
+	// CapabilityNestedContent is the stream-json shape that nests assistant
+	// content in message.content[] and expects user messages on stdin as a
+	// "message" object. Chat mode requires it.
+	CapabilityNestedContent Capability = "nested_content"
+	// CapabilityInterrupt is the stream-json control request that interrupts
+	// the current turn without killing the subprocess.
+	CapabilityInterrupt Capability = "interrupt"
+	// CapabilityAgentTool means the subagent dispatch tool is named "Agent"
+	// instead of "Task".
+	CapabilityAgentTool Capability = "agent_tool"
+	// CapabilityEffort is the --effort flag.
+	CapabilityEffort Capability = "effort"
+	// CapabilityMaxBudget is the --max-budget-usd flag.
+	CapabilityMaxBudget Capability = "max_budget"
+	// CapabilityJSONSchema is the --json-schema flag.
+	CapabilityJSONSchema Capability = "json_schema"
+	// CapabilityPlugins is the --plugin-dir flag.
+	CapabilityPlugins Capability = "plugins"
+	// CapabilitySessionControl is the set_model and set_permission_mode
+	// control requests used by ConfigureSession.
+	CapabilitySessionControl Capability = "session_control"
+	// CapabilityContextUsage is the get_context_usage control request.
+	CapabilityContextUsage Capability = "context_usage"
+)
+
+// capabilityMatrix maps each capability to the first CLI version that
+// supports it. Keep in sync when the CLI in the image is upgraded, and cite
+// the source of a version next to its entry.
+var capabilityMatrix = []struct {
+	capability Capability
+	since      CLIVersion
+}{
+	// claude-code 2.1 switched stream-json to the nested shape; the flat
+	// stdin messages made it exit on every prompt (see "Persistent mode
+	// stream-json input format updated for claude-code v2.1+" in
+	// CHANGELOG.md).
+	{CapabilityNestedContent, CLIVersion{2, 1, 0}},
+
+	// The versions below are not confirmed against the Claude Code release
+	// notes; they are the versions klaus assumes, as stated in CHANGELOG.md.
+	// Replace them with the first version the release notes name.
+	{CapabilityInterrupt, CLIVersion{1, 0, 0}},
+	{CapabilityPlugins, CLIVersion{2, 0, 0}},
+	{CapabilityMaxBudget, CLIVersion{2, 0, 0}},
+	{CapabilityJSONSchema, CLIVersion{2, 0, 0}},
+	{CapabilitySessionControl, CLIVersion{2, 0, 0}},
+	{CapabilityEffort, CLIVersion{2, 1, 0}},
+	{CapabilityContextUsage, CLIVersion{2, 1, 0}},
+	{CapabilityAgentTool, CLIVersion{2, 1, 63}},
+}
+
+// Supports reports whether CLI version v has the given capability. Unknown
+// capabilities are reported as unsupported.
+func (v CLIVersion) Supports(c Capability) bool {
+	for _, entry := range capabilityMatrix {
+		if entry.capability == c {
+			return v.AtLeast(entry.since)
+		}
+	}
+	return false
+}
+
+// Capabilities lists the capabilities supported by v.
+func (v CLIVersion) Capabilities() []Capability {
+	var caps []Capability
+	for _, entry := range capabilityMatrix {
+		if v.AtLeast(entry.since) {
+			caps = append(caps, entry.capability)
+		}
+	}
+	return caps
+}
+
+// supports reports whether the configured CLI has capability c. When the
+// CLI version is unknown every capability is assumed, which matches the
+// behaviour before version detection existed.
+func (o Options) supports(c Capability) bool {
+	return o.CLIVersion == nil || o.CLIVersion.Supports(c)
+}
+
+// CheckCLICapabilities returns an error listing every configured option the
+// detected CLI version does not support. It returns nil when the version is
+// unknown.
+func (o Options) CheckCLICapabilities() error {
+	if o.CLIVersion == nil {
+		return nil
+	}
+	var errs []error
+	require := func(set bool, c Capability, option string) {
+		if set && !o.supports(c) {
+			errs = append(errs, o.unsupported(c, option))
+		}
+	}
+	require(o.Effort != "", CapabilityEffort, "effort")
+	require(o.MaxBudgetUSD > 0, CapabilityMaxBudget, "maxBudgetUSD")
+	require(o.JSONSchema != "", CapabilityJSONSchema, "jsonSchema")
+	require(len(o.PluginDirs) > 0, CapabilityPlugins, "pluginDirs")
+	return errors.Join(errs...)
+}
+
+// unsupported returns the error for an option that requires capability c.
+func (o Options) unsupported(c Capability, option string) error {
+	return fmt.Errorf("%s requires claude >= %s (found %s)", option, capabilitySince(c), o.CLIVersion)
+}
+
+// CheckChatMode returns an error when the detected CLI is too old for the
+// bidirectional stream-json protocol used in chat mode.
+func (o Options) CheckChatMode() error {
+	if o.supports(CapabilityNestedContent) {
+		return nil
+	}
+	return o.unsupported(CapabilityNestedContent, "chat mode")
+}
+
+func capabilitySince(c Capability) CLIVersion {
+	for _, entry := range capabilityMatrix {
+		if entry.capability == c {
+			return entry.since
+		}
+	}
+	return CLIVersion{}
+}
+
+// parseStreamMessage parses a line of stream-json output in the shape of
+// the configured CLI (see CapabilityNestedContent).
+func (o Options) parseStreamMessage(line []byte) (StreamMessage, error) {
+	return parseStreamMessage(line, o.supports(CapabilityNestedContent))
+}
+
+// subagentToolName returns the name of the subagent dispatch tool of the
+// configured CLI.
+func (o Options) subagentToolName() string {
+	if o.supports(CapabilityAgentTool) {
+		return ToolNameAgent
+	}
+	return ToolNameTask
+}
+
+// adaptToolNames rewrites references to the subagent dispatch tool in tool
+// lists to the name the configured CLI uses, so that allow and deny lists
+// written for one CLI version keep working after an upgrade. Patterns such
+// as "Task(reviewer)" are rewritten too.
+func (o Options) adaptToolNames(tools []string) []string {
+	if o.CLIVersion == nil || len(tools) == 0 {
+		return tools
+	}
+	want := o.subagentToolName()
+	out := make([]string, len(tools))
+	for i, t := range tools {
+		out[i] = t
+		for _, name := range []string{ToolNameTask, ToolNameAgent} {
+			if name == want {
+				continue
+			}
+			if t == name || strings.HasPrefix(t, name+"(") {
+				out[i] = want + strings.TrimPrefix(t, name)
+			}
+		}
+	}
+	return out
+}
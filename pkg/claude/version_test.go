@@ -0,0 +1,189 @@
+package claude
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseCLIVersion(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    CLIVersion
+		wantErr bool
+	}{
+		{input: "2.1.81 (Claude Code)\n", want: CLIVersion{2, 1, 81}},
+		{input: "1.0.128", want: CLIVersion{1, 0, 128}},
+		{input: "claude-code v2.0.3-beta", want: CLIVersion{2, 0, 3}},
+		{input: "Claude Code", wantErr: true},
+		{input: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseCLIVersion(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCLIVersion: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCLIVersion_AtLeast(t *testing.T) {
+	v := CLIVersion{2, 1, 5}
+	for _, tt := range []struct {
+		other CLIVersion
+		want  bool
+	}{
+		{CLIVersion{2, 1, 5}, true},
+		{CLIVersion{2, 1, 4}, true},
+		{CLIVersion{2, 0, 99}, true},
+		{CLIVersion{1, 9, 9}, true},
+		{CLIVersion{2, 1, 6}, false},
+		{CLIVersion{2, 2, 0}, false},
+		{CLIVersion{3, 0, 0}, false},
+	} {
+		if got := v.AtLeast(tt.other); got != tt.want {
+			t.Errorf("%v.AtLeast(%v) = %v, want %v", v, tt.other, got, tt.want)
+		}
+	}
+}
+
+func TestCLIVersion_Supports(t *testing.T) {
+	old := CLIVersion{1, 0, 50}
+	if old.Supports(CapabilityNestedContent) || old.Supports(CapabilityEffort) {
+		t.Error("1.0.50 should not support nested content or effort")
+	}
+	if !old.Supports(CapabilityInterrupt) {
+		t.Error("1.0.50 should support interrupt")
+	}
+	current := CLIVersion{2, 1, 81}
+	for _, c := range []Capability{CapabilityNestedContent, CapabilityEffort, CapabilityAgentTool} {
+		if !current.Supports(c) {
+			t.Errorf("2.1.81 should support %s", c)
+		}
+	}
+	if current.Supports("unknown") {
+		t.Error("unknown capabilities must be unsupported")
+	}
+	if caps := old.Capabilities(); !slices.Equal(caps, []Capability{CapabilityInterrupt}) {
+		t.Errorf("Capabilities() = %v", caps)
+	}
+}
+
+func TestOptions_CheckCLICapabilities(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Effort = EffortHigh
+	opts.JSONSchema = `{"type":"object"}`
+
+	if err := opts.CheckCLICapabilities(); err != nil {
+		t.Errorf("unknown version must not be rejected: %v", err)
+	}
+
+	opts.CLIVersion = &CLIVersion{1, 0, 50}
+	err := opts.CheckCLICapabilities()
+	if err == nil {
+		t.Fatal("expected error for unsupported options")
+	}
+	for _, want := range []string{"effort requires claude >= 2.1.0 (found 1.0.50)", "jsonSchema requires claude >= 2.0.0"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+
+	opts.CLIVersion = &CLIVersion{2, 1, 81}
+	if err := opts.CheckCLICapabilities(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestProcess_RunWithOptions_RejectsUnsupportedOption(t *testing.T) {
+	opts := DefaultOptions()
+	opts.CLIVersion = &CLIVersion{1, 0, 50}
+	p := NewProcess(opts)
+
+	_, err := p.RunWithOptions(context.Background(), "hi", &RunOptions{Effort: EffortHigh})
+	if err == nil || !strings.Contains(err.Error(), "effort requires claude >= 2.1.0 (found 1.0.50)") {
+		t.Fatalf("expected capability error, got %v", err)
+	}
+	if status := p.Status().Status; status != ProcessStatusIdle {
+		t.Errorf("expected status to remain idle, got %q", status)
+	}
+}
+
+func TestOptions_CheckChatMode(t *testing.T) {
+	opts := DefaultOptions()
+	if err := opts.CheckChatMode(); err != nil {
+		t.Errorf("unknown version must not be rejected: %v", err)
+	}
+	opts.CLIVersion = &CLIVersion{2, 0, 14}
+	if err := opts.CheckChatMode(); err == nil || !strings.Contains(err.Error(), "chat mode requires claude >= 2.1.0") {
+		t.Errorf("expected chat mode error, got %v", err)
+	}
+}
+
+func TestOptions_AdaptsSubagentToolName(t *testing.T) {
+	opts := DefaultOptions()
+	opts.AllowedTools = []string{"Bash", "Task", "Task(reviewer)", "TaskOutput"}
+	opts.DisallowedTools = []string{"Agent"}
+
+	// Unknown version: tool lists are passed through unchanged.
+	args := strings.Join(opts.args(), " ")
+	if !strings.Contains(args, "--allowedTools Bash,Task,Task(reviewer),TaskOutput") || !strings.Contains(args, "--disallowedTools Agent") {
+		t.Errorf("expected tool lists unchanged, got %s", args)
+	}
+
+	opts.CLIVersion = &CLIVersion{2, 1, 81}
+	args = strings.Join(opts.args(), " ")
+	if !strings.Contains(args, "--allowedTools Bash,Agent,Agent(reviewer),TaskOutput") {
+		t.Errorf("expected Task renamed to Agent, got %s", args)
+	}
+
+	opts.CLIVersion = &CLIVersion{2, 0, 0}
+	args = strings.Join(opts.PersistentArgs(), " ")
+	if !strings.Contains(args, "--disallowedTools Task") {
+		t.Errorf("expected Agent renamed to Task, got %s", args)
+	}
+}
+
+func TestDetectCLIVersion(t *testing.T) {
+	installFakeClaude(t, `[ "$1" = "--version" ] && echo "2.1.81 (Claude Code)"`+"\n")
+	v, err := DetectCLIVersion(context.Background(), DefaultOptions())
+	if err != nil {
+		t.Fatalf("DetectCLIVersion: %v", err)
+	}
+	if v != (CLIVersion{2, 1, 81}) {
+		t.Errorf("got %v, want 2.1.81", v)
+	}
+}
+
+func TestDetectCLIVersion_Failure(t *testing.T) {
+	installFakeClaude(t, "echo 'boom' >&2\nexit 3\n")
+	_, err := DetectCLIVersion(context.Background(), DefaultOptions())
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("expected error with stderr, got %v", err)
+	}
+}
+
+func TestProcess_StatusReportsCLIVersion(t *testing.T) {
+	opts := DefaultOptions()
+	if got := NewProcess(opts).Status().CLIVersion; got != "" {
+		t.Errorf("expected empty version, got %q", got)
+	}
+	opts.CLIVersion = &CLIVersion{2, 1, 81}
+	if got := NewProcess(opts).Status().CLIVersion; got != "2.1.81" {
+		t.Errorf("Process CLIVersion = %q", got)
+	}
+	if got := NewPersistentProcess(opts).Status().CLIVersion; got != "2.1.81" {
+		t.Errorf("PersistentProcess CLIVersion = %q", got)
+	}
+}
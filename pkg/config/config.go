@@ -12,15 +12,14 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"os"
 	"strconv"
 	"strings"
-
-	"gopkg.in/yaml.v3"
+	"time"
 
 	"github.com/giantswarm/klaus/pkg/claude"
+	"github.com/giantswarm/klaus/pkg/redact"
 )
 
 const (
@@ -36,16 +35,37 @@ type Config struct {
 	// Claude holds settings passed to the Claude Code subprocess.
 	Claude ClaudeConfig `yaml:"claude"`
 
+	// Profile selects one of Profiles at startup; empty uses the claude
+	// settings as they are.
+	Profile string `yaml:"profile"`
+	// Profiles are named overrides of a subset of the claude settings,
+	// e.g. a "reviewer" limited to read-only tools. One is applied at
+	// startup with Profile, and prompts may select one per run.
+	Profiles map[string]Profile `yaml:"profiles"`
+
 	// Server holds settings consumed by the klaus HTTP server itself.
 	Server ServerConfig `yaml:"server"`
 
 	// OAuth holds OAuth 2.1 authentication settings.
 	OAuth OAuthFileConfig `yaml:"oauth"`
+
+	// unknownKeys are the keys of the config files that match no field,
+	// reported by Validate.
+	unknownKeys []unknownKey
+	// baseClaude is Claude before ApplyProfile applied the selected
+	// profile, the base of every profile.
+	baseClaude *ClaudeConfig
 }
 
 // ClaudeConfig holds settings that are forwarded to the Claude Code CLI.
 // These map directly to claude CLI flags and are not consumed by klaus itself.
 type ClaudeConfig struct {
+	// Binary is the Claude Code CLI executable; empty uses "claude" on PATH.
+	// Set it to the klaus binary with BinaryArgs ["fake-claude"] to run a
+	// scripted agent for integration tests.
+	Binary string `yaml:"binary"`
+	// BinaryArgs are passed to Binary before the CLI flags.
+	BinaryArgs []string `yaml:"binaryArgs"`
 	// Model selects the Claude model (e.g. "claude-sonnet-4-20250514", "sonnet", "opus").
 	// Left empty by default so the Claude CLI's own (auto-upgrading) default is
 	// used; klaus does not pin a model.
@@ -61,7 +81,12 @@ type ClaudeConfig struct {
 	PermissionMode string `yaml:"permissionMode"`
 	// MCPConfigPath is a path to an MCP servers configuration file.
 	MCPConfigPath string `yaml:"mcpConfigPath"`
-	// StrictMCPConfig when true only uses MCP servers from MCPConfigPath.
+	// MCPServers declares MCP servers inline, keyed by name. They are
+	// merged with the servers of MCPConfigPath into a generated config file
+	// for the CLI.
+	MCPServers map[string]MCPServerConfig `yaml:"mcpServers"`
+	// StrictMCPConfig when true only uses MCP servers from MCPConfigPath
+	// and MCPServers.
 	StrictMCPConfig bool `yaml:"strictMcpConfig"`
 	// Workspace is the working directory for the Claude subprocess.
 	Workspace string `yaml:"workspace"`
@@ -96,6 +121,186 @@ type ClaudeConfig struct {
 	// agent: single-shot Process subprocess with --no-session-persistence.
 	// chat: PersistentProcess subprocess with sessions saved to disk.
 	Mode string `yaml:"mode"`
+	// OverridePolicy governs which per-run model, max-turns, tool and
+	// system-prompt overrides prompt callers may apply. Unset permits none.
+	OverridePolicy OverridePolicyConfig `yaml:"overridePolicy"`
+	// Redaction masks secrets in agent output before it is stored, logged
+	// or returned.
+	Redaction RedactionConfig `yaml:"redaction"`
+	// ResourceLimits bounds CPU, memory, open files and processes of the
+	// Claude subprocess tree. Unset applies no limits.
+	ResourceLimits ResourceLimitsConfig `yaml:"resourceLimits"`
+	// Recording tees raw subprocess stdout to per-run JSONL files for
+	// `klaus replay`. Unset records nothing.
+	Recording RecordingConfig `yaml:"recording"`
+	// AutoCompact compacts chat-mode sessions before they overflow the
+	// context window. Unset disables automatic compaction.
+	AutoCompact AutoCompactConfig `yaml:"autoCompact"`
+	// ResumeOnStart resumes the last chat session when klaus starts, so
+	// that conversations survive restarts. Requires chat mode.
+	ResumeOnStart bool `yaml:"resumeOnStart"`
+}
+
+// OverridePolicyConfig mirrors claude.OverridePolicy for YAML configuration.
+type OverridePolicyConfig struct {
+	// Allow lists the fields callers may override: "model", "max_turns",
+	// "allowed_tools", "disallowed_tools", "append_system_prompt".
+	Allow []string `yaml:"allow"`
+	// Models restricts model overrides to these values; empty permits any model.
+	Models []string `yaml:"models"`
+	// MaxTurns is the upper bound for max_turns overrides; 0 means no bound.
+	MaxTurns int `yaml:"maxTurns"`
+	// NarrowToolsOnly requires allowed_tools overrides to be a subset of
+	// claude.allowedTools.
+	NarrowToolsOnly bool `yaml:"narrowToolsOnly"`
+	// PermissionModes lists the permission modes the configure_session tool
+	// may switch a chat session to; empty permits no switch.
+	PermissionModes []string `yaml:"permissionModes"`
+}
+
+// Policy converts the YAML settings into a claude.OverridePolicy.
+func (o OverridePolicyConfig) Policy() claude.OverridePolicy {
+	return claude.OverridePolicy{
+		Allow:           o.Allow,
+		Models:          o.Models,
+		MaxTurns:        o.MaxTurns,
+		NarrowToolsOnly: o.NarrowToolsOnly,
+		PermissionModes: o.PermissionModes,
+	}
+}
+
+// MCPServerConfig declares an MCP server. Stdio servers set Command, Args
+// and Env; http and sse servers set URL and Headers.
+type MCPServerConfig struct {
+	// Type is the transport: "stdio" (default), "http" or "sse".
+	Type string `yaml:"type" json:"type,omitempty"`
+	// Command is the executable of a stdio server.
+	Command string `yaml:"command" json:"command,omitempty"`
+	// Args are the arguments of Command.
+	Args []string `yaml:"args" json:"args,omitempty"`
+	// Env is added to the environment of Command.
+	Env map[string]string `yaml:"env" json:"env,omitempty"`
+	// URL is the endpoint of an http or sse server.
+	URL string `yaml:"url" json:"url,omitempty"`
+	// Headers are sent with every request to URL, e.g. Authorization.
+	Headers map[string]string `yaml:"headers" json:"headers,omitempty"`
+	// EnvFiles and HeadersFiles name files, e.g. mounted Kubernetes
+	// secrets, to read the values of env vars and headers from. A name
+	// may not be set both inline and as a file.
+	EnvFiles     map[string]string `yaml:"envFiles" json:"envFiles,omitempty"`
+	HeadersFiles map[string]string `yaml:"headersFiles" json:"headersFiles,omitempty"`
+}
+
+// Server returns the server in the format of the CLI MCP config file, with
+// the env vars and headers of EnvFiles and HeadersFiles read from their
+// files.
+func (m MCPServerConfig) Server() (claude.MCPServer, error) {
+	env, envErr := withSecretFiles("env", m.Env, m.EnvFiles)
+	headers, headersErr := withSecretFiles("headers", m.Headers, m.HeadersFiles)
+	return claude.MCPServer{
+		Type:    m.Type,
+		Command: m.Command,
+		Args:    m.Args,
+		Env:     env,
+		URL:     m.URL,
+		Headers: headers,
+	}, errors.Join(append(unwrapJoined(envErr), unwrapJoined(headersErr)...)...)
+}
+
+// RedactionConfig configures secret redaction of agent output.
+type RedactionConfig struct {
+	// Enabled turns on redaction with the built-in detectors (AWS keys,
+	// GitHub tokens, JWTs, private keys, kubeconfig credentials).
+	Enabled bool `yaml:"enabled"`
+	// Patterns are additional regular expressions to redact. A capture
+	// group named "secret" limits redaction to that part of the match.
+	Patterns []string `yaml:"patterns"`
+}
+
+// Redactor builds the configured redact.Redactor. It returns nil when
+// redaction is disabled.
+func (r RedactionConfig) Redactor() (*redact.Redactor, error) {
+	if !r.Enabled {
+		return nil, nil
+	}
+	return redact.New(r.Patterns)
+}
+
+// ResourceLimitsConfig mirrors claude.ResourceLimits for YAML configuration.
+// Zero values mean no limit.
+type ResourceLimitsConfig struct {
+	// CPUSeconds is the RLIMIT_CPU per process.
+	CPUSeconds int `yaml:"cpuSeconds"`
+	// AddressSpaceMiB is the RLIMIT_AS per process in MiB.
+	AddressSpaceMiB int `yaml:"addressSpaceMiB"`
+	// OpenFiles is the RLIMIT_NOFILE per process.
+	OpenFiles int `yaml:"openFiles"`
+	// Processes is the RLIMIT_NPROC (counted per user).
+	Processes int `yaml:"processes"`
+	// MemoryMaxMiB is the cgroup v2 memory limit for the subprocess tree.
+	MemoryMaxMiB int `yaml:"memoryMaxMiB"`
+	// CPUs is the cgroup v2 CPU quota for the subprocess tree in cores.
+	CPUs float64 `yaml:"cpus"`
+}
+
+// Limits converts the YAML settings into claude.ResourceLimits.
+func (r ResourceLimitsConfig) Limits() claude.ResourceLimits {
+	return claude.ResourceLimits{
+		CPUSeconds:      r.CPUSeconds,
+		AddressSpaceMiB: r.AddressSpaceMiB,
+		OpenFiles:       r.OpenFiles,
+		Processes:       r.Processes,
+		MemoryMaxMiB:    r.MemoryMaxMiB,
+		CPUs:            r.CPUs,
+	}
+}
+
+// RecordingConfig mirrors claude.RecordingOptions for YAML configuration.
+type RecordingConfig struct {
+	// Dir is the absolute directory recordings are written to. Empty
+	// disables recording.
+	Dir string `yaml:"dir"`
+	// MaxFileMiB rotates a recording into a new file at this size; 0 means
+	// no rotation.
+	MaxFileMiB int `yaml:"maxFileMiB"`
+	// MaxTotalMiB prunes the oldest recordings beyond this total size; 0
+	// means no size limit.
+	MaxTotalMiB int `yaml:"maxTotalMiB"`
+	// MaxAge prunes recordings older than this, e.g. "168h"; 0 means
+	// recordings never expire.
+	MaxAge time.Duration `yaml:"maxAge"`
+}
+
+// Options converts the YAML settings into claude.RecordingOptions. Negative
+// sizes, which Validate rejects, are treated as unset.
+func (r RecordingConfig) Options() claude.RecordingOptions {
+	return claude.RecordingOptions{
+		Dir:           r.Dir,
+		MaxFileBytes:  int64(max(r.MaxFileMiB, 0)) << 20,
+		MaxTotalBytes: int64(max(r.MaxTotalMiB, 0)) << 20,
+		MaxAge:        r.MaxAge,
+	}
+}
+
+// AutoCompactConfig mirrors claude.AutoCompactOptions for YAML configuration.
+type AutoCompactConfig struct {
+	// Threshold is the share of the context window, e.g. 0.8, at which the
+	// session is compacted before the next prompt; 0 disables.
+	Threshold float64 `yaml:"threshold"`
+	// ContextWindow is the model's context window in tokens (default
+	// 200000).
+	ContextWindow int `yaml:"contextWindow"`
+	// Instructions steer the summary written by /compact.
+	Instructions string `yaml:"instructions"`
+}
+
+// Options converts the YAML settings into claude.AutoCompactOptions.
+func (a AutoCompactConfig) Options() claude.AutoCompactOptions {
+	return claude.AutoCompactOptions{
+		Threshold:     a.Threshold,
+		ContextWindow: int64(a.ContextWindow),
+		Instructions:  a.Instructions,
+	}
 }
 
 // ServerConfig holds settings consumed by the klaus server process itself
@@ -105,6 +310,60 @@ type ServerConfig struct {
 	Port string `yaml:"port"`
 	// OwnerSubject restricts /mcp to the configured identity.
 	OwnerSubject string `yaml:"ownerSubject"`
+	// ConfigReload controls watching the config file and SOUL.md for
+	// changes.
+	ConfigReload ConfigReloadConfig `yaml:"configReload"`
+	// MCPProbe controls probing the configured MCP servers.
+	MCPProbe MCPProbeConfig `yaml:"mcpProbe"`
+	// ReadinessChecks makes /readyz run the configuration checks of
+	// `klaus doctor` and fail when one of them fails, not only when the
+	// Claude process is unhealthy.
+	ReadinessChecks bool `yaml:"readinessChecks"`
+}
+
+// DefaultConfigReloadInterval is how often the config file and SOUL.md are
+// checked for changes when server.configReload.interval is not set.
+const DefaultConfigReloadInterval = 10 * time.Second
+
+// ConfigReloadConfig holds the settings of the config hot reload. Changes
+// are applied from the next run on; in chat mode the subprocess is
+// restarted with --resume before the next prompt.
+type ConfigReloadConfig struct {
+	// Disabled turns off watching for changes.
+	Disabled bool `yaml:"disabled"`
+	// Interval is how often the files are checked for changes, e.g. "30s"
+	// (default 10s).
+	Interval time.Duration `yaml:"interval"`
+}
+
+// EffectiveInterval returns the configured interval, defaulting to
+// DefaultConfigReloadInterval.
+func (r ConfigReloadConfig) EffectiveInterval() time.Duration {
+	if r.Interval > 0 {
+		return r.Interval
+	}
+	return DefaultConfigReloadInterval
+}
+
+// MCPProbeConfig holds the settings of the MCP server probes, whose results
+// are reported in /status, the message metadata and the klaus_mcp_server_*
+// metrics.
+type MCPProbeConfig struct {
+	// Disabled turns off probing.
+	Disabled bool `yaml:"disabled"`
+	// Interval is how often the servers are probed, e.g. "1m" (default 5m).
+	Interval time.Duration `yaml:"interval"`
+	// Timeout bounds a single probe (default 30s).
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Prober returns the configured claude.MCPProber, or nil when probing is
+// disabled.
+func (m MCPProbeConfig) Prober() *claude.MCPProber {
+	if m.Disabled {
+		return nil
+	}
+	return claude.NewMCPProber(m.Interval, m.Timeout)
 }
 
 // OAuthFileConfig mirrors the OAuth flags for YAML configuration.
@@ -133,6 +392,9 @@ type OAuthFileConfig struct {
 type GoogleConfig struct {
 	ClientID     string `yaml:"clientID"`
 	ClientSecret string `yaml:"clientSecret"`
+	// ClientSecretFile is read into ClientSecret, e.g. from a mounted
+	// Kubernetes secret.
+	ClientSecretFile string `yaml:"clientSecretFile"`
 }
 
 // DexConfig holds Dex OIDC provider settings.
@@ -142,6 +404,8 @@ type DexConfig struct {
 	ClientSecret string `yaml:"clientSecret"`
 	ConnectorID  string `yaml:"connectorID"`
 	CAFile       string `yaml:"caFile"`
+	// ClientSecretFile is read into ClientSecret.
+	ClientSecretFile string `yaml:"clientSecretFile"`
 }
 
 // SecurityFileConfig holds OAuth security settings in YAML.
@@ -156,6 +420,10 @@ type SecurityFileConfig struct {
 	CIMDAllowPrivateIPs              bool     `yaml:"cimdAllowPrivateIPs"`
 	TrustedPublicRegistrationSchemes []string `yaml:"trustedPublicRegistrationSchemes"`
 	DisableStrictSchemeMatching      bool     `yaml:"disableStrictSchemeMatching"`
+	// EncryptionKeyFile and RegistrationTokenFile are read into
+	// EncryptionKey and RegistrationToken.
+	EncryptionKeyFile     string `yaml:"encryptionKeyFile"`
+	RegistrationTokenFile string `yaml:"registrationTokenFile"`
 }
 
 // TLSFileConfig holds TLS certificate paths.
@@ -164,35 +432,50 @@ type TLSFileConfig struct {
 	KeyFile  string `yaml:"keyFile"`
 }
 
-// Load reads configuration from a YAML file, then applies environment variable
-// overrides for backward compatibility. If the file does not exist, a zero-value
-// Config is used as the base (all settings come from env vars).
+// Load reads configuration from a YAML file and the config directory next
+// to it (see ConfigPaths), then applies environment variable overrides for
+// backward compatibility and the selected profile. If no config file
+// exists, a zero-value Config is used as the base (all settings come from
+// env vars).
 func Load(path string) (Config, error) {
+	cfg, sources, err := LoadWithSources(path)
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.ApplyProfile(sources)
+}
+
+// LoadWithSources is like Load and also reports where the value of each
+// field came from. It does not apply the selected profile, so that callers
+// can override Profile before calling ApplyProfile.
+func LoadWithSources(path string) (Config, Sources, error) {
 	var cfg Config
+	sources := Sources{}
 
-	f, err := os.Open(path) //#nosec G304 -- operator-provided config path
+	files, err := ConfigFiles(path)
 	if err != nil {
-		if !errors.Is(err, os.ErrNotExist) {
-			return cfg, fmt.Errorf("reading config file %s: %w", path, err)
-		}
-		// File doesn't exist -- env-only mode (backward compat).
-	} else {
-		defer func() { _ = f.Close() }()
-		limited := io.LimitReader(f, maxConfigFileSize+1)
-		data, readErr := io.ReadAll(limited)
-		if readErr != nil {
-			return cfg, fmt.Errorf("reading config file %s: %w", path, readErr)
-		}
-		if len(data) > maxConfigFileSize {
-			return cfg, fmt.Errorf("config file %s exceeds maximum size of %d bytes", path, maxConfigFileSize)
-		}
-		if err := yaml.Unmarshal(data, &cfg); err != nil {
-			return cfg, fmt.Errorf("parsing config file %s: %w", path, err)
+		return cfg, nil, err
+	}
+	// Later files override the values of earlier ones; mappings are merged
+	// and lists replaced.
+	for _, file := range files {
+		if err := cfg.decodeFile(file, sources); err != nil {
+			return cfg, nil, err
 		}
 	}
 
 	// Apply environment variable overrides.
-	applyEnvOverrides(&cfg)
+	env := envOverrides{applied: make(map[any]string)}
+	env.apply(&cfg)
+	for _, field := range fieldsOf(&cfg) {
+		if key, ok := env.applied[field.ptr]; ok {
+			sources[field.Path] = Source{Kind: SourceEnv, Name: key}
+		}
+	}
+
+	if err := cfg.readSecretFiles(sources); err != nil {
+		return cfg, nil, err
+	}
 
 	// Note: Claude.Model is intentionally left empty when unset. klaus must not
 	// pin a default model: an empty model means no --model flag is passed to the
@@ -200,56 +483,99 @@ func Load(path string) (Config, error) {
 	// Hardcoding a model here (see the reverted "fable" default) silently breaks
 	// every run if that model is later retired or gated.
 
-	return cfg, nil
+	return cfg, sources, nil
+}
+
+// envOverrides applies environment variables and records which fields they
+// set.
+type envOverrides struct {
+	// applied maps the address of each overridden field to its env var.
+	applied map[any]string
 }
 
-// applyEnvOverrides layers environment variables on top of the YAML config.
+// apply layers environment variables on top of the YAML config.
 // Env vars only override when set to a non-empty value, preserving YAML values
 // when the env var is absent.
-func applyEnvOverrides(cfg *Config) {
+func (env *envOverrides) apply(cfg *Config) {
 	// Claude subprocess settings.
-	envOverrideString(&cfg.Claude.Model, "CLAUDE_MODEL")
-	envOverrideString(&cfg.Claude.SystemPrompt, "CLAUDE_SYSTEM_PROMPT")
-	envOverrideString(&cfg.Claude.AppendSystemPrompt, "CLAUDE_APPEND_SYSTEM_PROMPT")
-	envOverrideInt(&cfg.Claude.MaxTurns, "CLAUDE_MAX_TURNS")
-	envOverrideString(&cfg.Claude.PermissionMode, "CLAUDE_PERMISSION_MODE")
-	envOverrideString(&cfg.Claude.MCPConfigPath, "CLAUDE_MCP_CONFIG")
-	envOverrideBool(&cfg.Claude.StrictMCPConfig, "CLAUDE_STRICT_MCP_CONFIG")
-	envOverrideString(&cfg.Claude.Workspace, "CLAUDE_WORKSPACE")
-	envOverrideFloat64(&cfg.Claude.MaxBudgetUSD, "CLAUDE_MAX_BUDGET_USD")
-	envOverrideString(&cfg.Claude.Effort, "CLAUDE_EFFORT")
-	envOverrideString(&cfg.Claude.FallbackModel, "CLAUDE_FALLBACK_MODEL")
-	envOverrideString(&cfg.Claude.JSONSchema, "CLAUDE_JSON_SCHEMA")
-	envOverrideString(&cfg.Claude.SettingsFile, "CLAUDE_SETTINGS_FILE")
-	envOverrideString(&cfg.Claude.SettingSources, "CLAUDE_SETTING_SOURCES")
-	envOverrideCSV(&cfg.Claude.Tools, "CLAUDE_TOOLS")
-	envOverrideCSV(&cfg.Claude.AllowedTools, "CLAUDE_ALLOWED_TOOLS")
-	envOverrideCSV(&cfg.Claude.DisallowedTools, "CLAUDE_DISALLOWED_TOOLS")
-	envOverrideCSV(&cfg.Claude.PluginDirs, "CLAUDE_PLUGIN_DIRS")
-	envOverrideCSV(&cfg.Claude.AddDirs, "CLAUDE_ADD_DIRS")
-	envOverrideString(&cfg.Claude.Agents, "CLAUDE_AGENTS")
-	envOverrideString(&cfg.Claude.ActiveAgent, "CLAUDE_ACTIVE_AGENT")
-	envOverrideString(&cfg.Claude.Mode, "CLAUDE_MODE")
+	env.overrideString(&cfg.Claude.Binary, "CLAUDE_BINARY")
+	env.overrideCSV(&cfg.Claude.BinaryArgs, "CLAUDE_BINARY_ARGS")
+	env.overrideString(&cfg.Claude.Model, "CLAUDE_MODEL")
+	env.overrideString(&cfg.Claude.SystemPrompt, "CLAUDE_SYSTEM_PROMPT")
+	env.overrideString(&cfg.Claude.AppendSystemPrompt, "CLAUDE_APPEND_SYSTEM_PROMPT")
+	env.overrideInt(&cfg.Claude.MaxTurns, "CLAUDE_MAX_TURNS")
+	env.overrideString(&cfg.Claude.PermissionMode, "CLAUDE_PERMISSION_MODE")
+	env.overrideString(&cfg.Claude.MCPConfigPath, "CLAUDE_MCP_CONFIG")
+	env.overrideBool(&cfg.Claude.StrictMCPConfig, "CLAUDE_STRICT_MCP_CONFIG")
+	env.overrideString(&cfg.Claude.Workspace, "CLAUDE_WORKSPACE")
+	env.overrideFloat64(&cfg.Claude.MaxBudgetUSD, "CLAUDE_MAX_BUDGET_USD")
+	env.overrideString(&cfg.Claude.Effort, "CLAUDE_EFFORT")
+	env.overrideString(&cfg.Claude.FallbackModel, "CLAUDE_FALLBACK_MODEL")
+	env.overrideString(&cfg.Claude.JSONSchema, "CLAUDE_JSON_SCHEMA")
+	env.overrideString(&cfg.Claude.SettingsFile, "CLAUDE_SETTINGS_FILE")
+	env.overrideString(&cfg.Claude.SettingSources, "CLAUDE_SETTING_SOURCES")
+	env.overrideCSV(&cfg.Claude.Tools, "CLAUDE_TOOLS")
+	env.overrideCSV(&cfg.Claude.AllowedTools, "CLAUDE_ALLOWED_TOOLS")
+	env.overrideCSV(&cfg.Claude.DisallowedTools, "CLAUDE_DISALLOWED_TOOLS")
+	env.overrideCSV(&cfg.Claude.PluginDirs, "CLAUDE_PLUGIN_DIRS")
+	env.overrideCSV(&cfg.Claude.AddDirs, "CLAUDE_ADD_DIRS")
+	env.overrideString(&cfg.Claude.Agents, "CLAUDE_AGENTS")
+	env.overrideString(&cfg.Claude.ActiveAgent, "CLAUDE_ACTIVE_AGENT")
+	env.overrideString(&cfg.Claude.Mode, "CLAUDE_MODE")
+	env.overrideBool(&cfg.Claude.ResumeOnStart, "CLAUDE_RESUME_ON_START")
+	env.overrideCSV(&cfg.Claude.OverridePolicy.Allow, "CLAUDE_OVERRIDE_ALLOW")
+	env.overrideCSV(&cfg.Claude.OverridePolicy.Models, "CLAUDE_OVERRIDE_MODELS")
+	env.overrideInt(&cfg.Claude.OverridePolicy.MaxTurns, "CLAUDE_OVERRIDE_MAX_TURNS")
+	env.overrideBool(&cfg.Claude.OverridePolicy.NarrowToolsOnly, "CLAUDE_OVERRIDE_NARROW_TOOLS_ONLY")
+	env.overrideCSV(&cfg.Claude.OverridePolicy.PermissionModes, "CLAUDE_OVERRIDE_PERMISSION_MODES")
+	env.overrideBool(&cfg.Claude.Redaction.Enabled, "CLAUDE_REDACTION_ENABLED")
+	env.overrideLines(&cfg.Claude.Redaction.Patterns, "CLAUDE_REDACTION_PATTERNS")
+	env.overrideInt(&cfg.Claude.ResourceLimits.CPUSeconds, "CLAUDE_LIMIT_CPU_SECONDS")
+	env.overrideInt(&cfg.Claude.ResourceLimits.AddressSpaceMiB, "CLAUDE_LIMIT_ADDRESS_SPACE_MIB")
+	env.overrideInt(&cfg.Claude.ResourceLimits.OpenFiles, "CLAUDE_LIMIT_OPEN_FILES")
+	env.overrideInt(&cfg.Claude.ResourceLimits.Processes, "CLAUDE_LIMIT_PROCESSES")
+	env.overrideInt(&cfg.Claude.ResourceLimits.MemoryMaxMiB, "CLAUDE_LIMIT_MEMORY_MIB")
+	env.overrideFloat64(&cfg.Claude.ResourceLimits.CPUs, "CLAUDE_LIMIT_CPUS")
+	env.overrideString(&cfg.Claude.Recording.Dir, "CLAUDE_RECORDING_DIR")
+	env.overrideInt(&cfg.Claude.Recording.MaxFileMiB, "CLAUDE_RECORDING_MAX_FILE_MIB")
+	env.overrideInt(&cfg.Claude.Recording.MaxTotalMiB, "CLAUDE_RECORDING_MAX_TOTAL_MIB")
+	env.overrideDuration(&cfg.Claude.Recording.MaxAge, "CLAUDE_RECORDING_MAX_AGE")
+	env.overrideFloat64(&cfg.Claude.AutoCompact.Threshold, "CLAUDE_AUTO_COMPACT_THRESHOLD")
+	env.overrideInt(&cfg.Claude.AutoCompact.ContextWindow, "CLAUDE_AUTO_COMPACT_CONTEXT_WINDOW")
+	env.overrideString(&cfg.Claude.AutoCompact.Instructions, "CLAUDE_AUTO_COMPACT_INSTRUCTIONS")
+
+	env.overrideString(&cfg.Profile, "KLAUS_PROFILE")
 
 	// Server settings.
-	envOverrideString(&cfg.Server.Port, "PORT")
-	envOverrideString(&cfg.Server.OwnerSubject, "KLAUS_OWNER_SUBJECT")
+	env.overrideString(&cfg.Server.Port, "PORT")
+	env.overrideString(&cfg.Server.OwnerSubject, "KLAUS_OWNER_SUBJECT")
+	env.overrideBool(&cfg.Server.ConfigReload.Disabled, "KLAUS_CONFIG_RELOAD_DISABLED")
+	env.overrideDuration(&cfg.Server.ConfigReload.Interval, "KLAUS_CONFIG_RELOAD_INTERVAL")
+	env.overrideBool(&cfg.Server.MCPProbe.Disabled, "KLAUS_MCP_PROBE_DISABLED")
+	env.overrideDuration(&cfg.Server.MCPProbe.Interval, "KLAUS_MCP_PROBE_INTERVAL")
+	env.overrideDuration(&cfg.Server.MCPProbe.Timeout, "KLAUS_MCP_PROBE_TIMEOUT")
+	env.overrideBool(&cfg.Server.ReadinessChecks, "KLAUS_READINESS_CHECKS")
 
 	// OAuth settings.
-	envOverrideString(&cfg.OAuth.Google.ClientID, "GOOGLE_CLIENT_ID")
-	envOverrideString(&cfg.OAuth.Google.ClientSecret, "GOOGLE_CLIENT_SECRET")
-	envOverrideString(&cfg.OAuth.Dex.IssuerURL, "DEX_ISSUER_URL")
-	envOverrideString(&cfg.OAuth.Dex.ClientID, "DEX_CLIENT_ID")
-	envOverrideString(&cfg.OAuth.Dex.ClientSecret, "DEX_CLIENT_SECRET")
-	envOverrideString(&cfg.OAuth.Dex.ConnectorID, "DEX_CONNECTOR_ID")
-	envOverrideString(&cfg.OAuth.Dex.CAFile, "DEX_CA_FILE")
-	envOverrideString(&cfg.OAuth.Security.EncryptionKey, "OAUTH_ENCRYPTION_KEY")
-	envOverrideString(&cfg.OAuth.TLS.CertFile, "TLS_CERT_FILE")
-	envOverrideString(&cfg.OAuth.TLS.KeyFile, "TLS_KEY_FILE")
-}
-
-// Validate checks that the loaded configuration is internally consistent.
-// It returns all validation errors joined together.
+	env.overrideString(&cfg.OAuth.Google.ClientID, "GOOGLE_CLIENT_ID")
+	env.overrideString(&cfg.OAuth.Google.ClientSecret, "GOOGLE_CLIENT_SECRET")
+	env.overrideString(&cfg.OAuth.Google.ClientSecretFile, "GOOGLE_CLIENT_SECRET_FILE")
+	env.overrideString(&cfg.OAuth.Dex.IssuerURL, "DEX_ISSUER_URL")
+	env.overrideString(&cfg.OAuth.Dex.ClientID, "DEX_CLIENT_ID")
+	env.overrideString(&cfg.OAuth.Dex.ClientSecret, "DEX_CLIENT_SECRET")
+	env.overrideString(&cfg.OAuth.Dex.ClientSecretFile, "DEX_CLIENT_SECRET_FILE")
+	env.overrideString(&cfg.OAuth.Dex.ConnectorID, "DEX_CONNECTOR_ID")
+	env.overrideString(&cfg.OAuth.Dex.CAFile, "DEX_CA_FILE")
+	env.overrideString(&cfg.OAuth.Security.EncryptionKey, "OAUTH_ENCRYPTION_KEY")
+	env.overrideString(&cfg.OAuth.Security.EncryptionKeyFile, "OAUTH_ENCRYPTION_KEY_FILE")
+	env.overrideString(&cfg.OAuth.Security.RegistrationTokenFile, "OAUTH_REGISTRATION_TOKEN_FILE")
+	env.overrideString(&cfg.OAuth.TLS.CertFile, "TLS_CERT_FILE")
+	env.overrideString(&cfg.OAuth.TLS.KeyFile, "TLS_KEY_FILE")
+}
+
+// Validate checks that the loaded configuration is internally consistent
+// and that the config file has no unknown keys. It returns all validation
+// errors joined together.
 func (c *Config) Validate() error {
 	var errs []error
 
@@ -268,12 +594,60 @@ func (c *Config) Validate() error {
 	default:
 		errs = append(errs, fmt.Errorf("claude.mode: invalid value %q (must be \"agent\" or \"chat\")", c.Claude.Mode))
 	}
+	if c.Claude.ResumeOnStart && c.Claude.Mode != "chat" {
+		errs = append(errs, errors.New("claude.resumeOnStart requires claude.mode \"chat\""))
+	}
 	if c.Claude.MaxTurns < 0 {
 		errs = append(errs, fmt.Errorf("claude.maxTurns must be >= 0, got %d", c.Claude.MaxTurns))
 	}
 	if c.Claude.MaxBudgetUSD < 0 {
 		errs = append(errs, fmt.Errorf("claude.maxBudgetUSD must be >= 0, got %f", c.Claude.MaxBudgetUSD))
 	}
+	for _, field := range c.Claude.OverridePolicy.Allow {
+		if err := claude.ValidateOverrideField(field); err != nil {
+			errs = append(errs, fmt.Errorf("claude.overridePolicy.allow: %w", err))
+		}
+	}
+	for _, mode := range c.Claude.OverridePolicy.PermissionModes {
+		if err := claude.ValidatePermissionMode(mode); err != nil {
+			errs = append(errs, fmt.Errorf("claude.overridePolicy.permissionModes: %w", err))
+		}
+	}
+	if c.Claude.OverridePolicy.MaxTurns < 0 {
+		errs = append(errs, fmt.Errorf("claude.overridePolicy.maxTurns must be >= 0, got %d", c.Claude.OverridePolicy.MaxTurns))
+	}
+	if err := c.Claude.ResourceLimits.Limits().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("claude.resourceLimits: %w", err))
+	}
+	// Sizes are checked here rather than by claude.RecordingOptions so that
+	// errors name the MiB fields.
+	if c.Claude.Recording.MaxFileMiB < 0 {
+		errs = append(errs, fmt.Errorf("claude.recording.maxFileMiB must be >= 0, got %d", c.Claude.Recording.MaxFileMiB))
+	}
+	if c.Claude.Recording.MaxTotalMiB < 0 {
+		errs = append(errs, fmt.Errorf("claude.recording.maxTotalMiB must be >= 0, got %d", c.Claude.Recording.MaxTotalMiB))
+	}
+	if err := c.Claude.Recording.Options().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("claude.recording: %w", err))
+	}
+	if err := c.Claude.AutoCompact.Options().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("claude.autoCompact: %w", err))
+	}
+	// Patterns are compiled even when redaction is disabled so that a typo
+	// is caught before the feature is switched on.
+	if _, err := redact.New(c.Claude.Redaction.Patterns); err != nil {
+		errs = append(errs, fmt.Errorf("claude.redaction.patterns: %w", err))
+	}
+
+	if c.Server.ConfigReload.Interval < 0 {
+		errs = append(errs, fmt.Errorf("server.configReload.interval must be >= 0, got %s", c.Server.ConfigReload.Interval))
+	}
+	if c.Server.MCPProbe.Interval < 0 {
+		errs = append(errs, fmt.Errorf("server.mcpProbe.interval must be >= 0, got %s", c.Server.MCPProbe.Interval))
+	}
+	if c.Server.MCPProbe.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("server.mcpProbe.timeout must be >= 0, got %s", c.Server.MCPProbe.Timeout))
+	}
 
 	// Validate encryption key format if set.
 	if c.OAuth.Security.EncryptionKey != "" {
@@ -285,6 +659,8 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	errs = append(errs, c.validateSchema()...)
+	errs = append(errs, c.validateProfiles(errs)...)
 	return errors.Join(errs...)
 }
 
@@ -326,19 +702,21 @@ func (c *Config) EffectivePort(flagPort string) string {
 
 // --- env override helpers ---
 
-func envOverrideString(target *string, key string) {
+func (env *envOverrides) overrideString(target *string, key string) {
 	if v := os.Getenv(key); v != "" {
 		*target = v
+		env.applied[target] = key
 	}
 }
 
-func envOverrideBool(target *bool, key string) {
+func (env *envOverrides) overrideBool(target *bool, key string) {
 	if v := os.Getenv(key); v != "" {
 		*target = parseBool(v)
+		env.applied[target] = key
 	}
 }
 
-func envOverrideInt(target *int, key string) {
+func (env *envOverrides) overrideInt(target *int, key string) {
 	if v := os.Getenv(key); v != "" {
 		n, err := strconv.Atoi(v)
 		if err != nil {
@@ -346,10 +724,11 @@ func envOverrideInt(target *int, key string) {
 			return
 		}
 		*target = n
+		env.applied[target] = key
 	}
 }
 
-func envOverrideFloat64(target *float64, key string) {
+func (env *envOverrides) overrideFloat64(target *float64, key string) {
 	if v := os.Getenv(key); v != "" {
 		f, err := strconv.ParseFloat(v, 64)
 		if err != nil {
@@ -357,13 +736,45 @@ func envOverrideFloat64(target *float64, key string) {
 			return
 		}
 		*target = f
+		env.applied[target] = key
+	}
+}
+
+func (env *envOverrides) overrideDuration(target *time.Duration, key string) {
+	if v := os.Getenv(key); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			slog.Warn("ignoring invalid duration env var", "key", key, "value", v, "error", err)
+			return
+		}
+		*target = d
+		env.applied[target] = key
 	}
 }
 
-func envOverrideCSV(target *[]string, key string) {
+func (env *envOverrides) overrideCSV(target *[]string, key string) {
 	if v := os.Getenv(key); v != "" {
 		*target = strings.Split(v, ",")
+		env.applied[target] = key
+	}
+}
+
+// overrideLines splits on newlines rather than commas, for values such as
+// regular expressions that may themselves contain commas. Blank lines are
+// skipped.
+func (env *envOverrides) overrideLines(target *[]string, key string) {
+	v := os.Getenv(key)
+	if v == "" {
+		return
+	}
+	var lines []string
+	for _, line := range strings.Split(v, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
 	}
+	*target = lines
+	env.applied[target] = key
 }
 
 // parseBool returns true for "true", "1", "yes" (case-insensitive).
@@ -6,6 +6,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/giantswarm/klaus/pkg/claude"
 )
 
 func TestLoad_FromYAMLFile(t *testing.T) {
@@ -139,6 +142,7 @@ func TestLoad_FileNotFound_EnvOnly(t *testing.T) {
 	t.Setenv("CLAUDE_MODEL", "opus")
 	t.Setenv("PORT", "3000")
 	t.Setenv("KLAUS_OWNER_SUBJECT", "admin@example.com")
+	t.Setenv("KLAUS_READINESS_CHECKS", "true")
 
 	cfg, err := Load("/nonexistent/config.yaml")
 	if err != nil {
@@ -148,6 +152,7 @@ func TestLoad_FileNotFound_EnvOnly(t *testing.T) {
 	assertEqual(t, "claude.model", "opus", cfg.Claude.Model)
 	assertEqual(t, "server.port", "3000", cfg.Server.Port)
 	assertEqual(t, "server.ownerSubject", "admin@example.com", cfg.Server.OwnerSubject)
+	assertEqualBool(t, "server.readinessChecks", true, cfg.Server.ReadinessChecks)
 }
 
 func TestLoad_DefaultModel(t *testing.T) {
@@ -577,3 +582,371 @@ func assertEqualSlice(t *testing.T, field string, want, got []string) {
 		}
 	}
 }
+
+func TestOverridePolicy_YAMLAndEnv(t *testing.T) {
+	t.Setenv("CLAUDE_OVERRIDE_ALLOW", "")
+	t.Setenv("CLAUDE_OVERRIDE_MODELS", "")
+	t.Setenv("CLAUDE_OVERRIDE_MAX_TURNS", "")
+	t.Setenv("CLAUDE_OVERRIDE_NARROW_TOOLS_ONLY", "")
+	t.Setenv("CLAUDE_OVERRIDE_PERMISSION_MODES", "")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	yaml := `
+claude:
+  overridePolicy:
+    allow: [model, max_turns, allowed_tools]
+    models: [haiku, sonnet]
+    maxTurns: 50
+    narrowToolsOnly: true
+    permissionModes: [plan, acceptEdits]
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	policy := cfg.Claude.OverridePolicy.Policy()
+	assertEqual(t, "allow", "model,max_turns,allowed_tools", strings.Join(policy.Allow, ","))
+	assertEqual(t, "models", "haiku,sonnet", strings.Join(policy.Models, ","))
+	assertEqualSlice(t, "permissionModes", []string{"plan", "acceptEdits"}, policy.PermissionModes)
+	if policy.MaxTurns != 50 || !policy.NarrowToolsOnly {
+		t.Errorf("unexpected policy: %+v", policy)
+	}
+
+	t.Setenv("CLAUDE_OVERRIDE_ALLOW", "max_turns")
+	t.Setenv("CLAUDE_OVERRIDE_MAX_TURNS", "10")
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	assertEqual(t, "allow", "max_turns", strings.Join(cfg.Claude.OverridePolicy.Allow, ","))
+	if cfg.Claude.OverridePolicy.MaxTurns != 10 {
+		t.Errorf("expected env max turns 10, got %d", cfg.Claude.OverridePolicy.MaxTurns)
+	}
+}
+
+func TestValidate_OverridePolicy(t *testing.T) {
+	cfg := Config{Claude: ClaudeConfig{OverridePolicy: OverridePolicyConfig{
+		Allow:           []string{"model", "permission_mode"},
+		MaxTurns:        -1,
+		PermissionModes: []string{"plan", "yolo"},
+	}}}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{`invalid override field "permission_mode"`, "claude.overridePolicy.maxTurns must be >= 0",
+		`claude.overridePolicy.permissionModes: invalid permission mode "yolo"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
+
+func TestRedaction_YAMLAndEnv(t *testing.T) {
+	t.Setenv("CLAUDE_REDACTION_ENABLED", "")
+	t.Setenv("CLAUDE_REDACTION_PATTERNS", "")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	yaml := `
+claude:
+  redaction:
+    enabled: true
+    patterns:
+      - 'sk-[a-z0-9]{8,}'
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	r, err := cfg.Claude.Redaction.Redactor()
+	if err != nil || r == nil {
+		t.Fatalf("Redactor() = %v, %v; want a redactor", r, err)
+	}
+	if got, n := r.RedactString("key sk-abcdefgh1"); n != 1 || got != "key [REDACTED:custom]" {
+		t.Errorf("custom pattern not applied: %q (%d)", got, n)
+	}
+
+	// Newline-separated env patterns may contain commas.
+	t.Setenv("CLAUDE_REDACTION_PATTERNS", "a{2,3}\n\n  b{1,2}  ")
+	t.Setenv("CLAUDE_REDACTION_ENABLED", "false")
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	assertEqual(t, "patterns", "a{2,3}|b{1,2}", strings.Join(cfg.Claude.Redaction.Patterns, "|"))
+	if r, err := cfg.Claude.Redaction.Redactor(); r != nil || err != nil {
+		t.Errorf("expected nil redactor when disabled, got %v, %v", r, err)
+	}
+}
+
+func TestValidate_RedactionPatterns(t *testing.T) {
+	cfg := Config{Claude: ClaudeConfig{Redaction: RedactionConfig{Patterns: []string{"("}}}}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "claude.redaction.patterns") {
+		t.Errorf("expected redaction pattern error, got %v", err)
+	}
+}
+
+func TestResourceLimits_YAMLAndEnv(t *testing.T) {
+	for _, k := range []string{"CLAUDE_LIMIT_CPU_SECONDS", "CLAUDE_LIMIT_ADDRESS_SPACE_MIB", "CLAUDE_LIMIT_OPEN_FILES",
+		"CLAUDE_LIMIT_PROCESSES", "CLAUDE_LIMIT_MEMORY_MIB", "CLAUDE_LIMIT_CPUS"} {
+		t.Setenv(k, "")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	yaml := `
+claude:
+  resourceLimits:
+    cpuSeconds: 3600
+    openFiles: 4096
+    memoryMaxMiB: 2048
+    cpus: 1.5
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := claude.ResourceLimits{CPUSeconds: 3600, OpenFiles: 4096, MemoryMaxMiB: 2048, CPUs: 1.5}
+	if got := cfg.Claude.ResourceLimits.Limits(); got != want {
+		t.Errorf("Limits() = %+v, want %+v", got, want)
+	}
+
+	t.Setenv("CLAUDE_LIMIT_PROCESSES", "256")
+	t.Setenv("CLAUDE_LIMIT_CPUS", "0.5")
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	assertEqualInt(t, "processes", 256, cfg.Claude.ResourceLimits.Processes)
+	assertEqualFloat(t, "cpus", 0.5, cfg.Claude.ResourceLimits.CPUs)
+}
+
+func TestValidate_ResourceLimits(t *testing.T) {
+	cfg := Config{Claude: ClaudeConfig{ResourceLimits: ResourceLimitsConfig{OpenFiles: -1, CPUs: -0.5}}}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected error for negative limits")
+	}
+	for _, want := range []string{"claude.resourceLimits: openFiles must be >= 0", "cpus must be >= 0"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestBinary_YAMLAndEnv(t *testing.T) {
+	t.Setenv("CLAUDE_BINARY", "")
+	t.Setenv("CLAUDE_BINARY_ARGS", "")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	yaml := `
+claude:
+  binary: /usr/local/bin/klaus
+  binaryArgs: ["fake-claude", "--scenario", "/etc/klaus/scenario.yaml"]
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	assertEqual(t, "binary", "/usr/local/bin/klaus", cfg.Claude.Binary)
+	assertEqualSlice(t, "binaryArgs", []string{"fake-claude", "--scenario", "/etc/klaus/scenario.yaml"}, cfg.Claude.BinaryArgs)
+
+	t.Setenv("CLAUDE_BINARY", "/opt/claude")
+	t.Setenv("CLAUDE_BINARY_ARGS", "fake-claude")
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	assertEqual(t, "binary", "/opt/claude", cfg.Claude.Binary)
+	assertEqualSlice(t, "binaryArgs", []string{"fake-claude"}, cfg.Claude.BinaryArgs)
+}
+
+func TestRecording_YAMLAndEnv(t *testing.T) {
+	for _, k := range []string{"CLAUDE_RECORDING_DIR", "CLAUDE_RECORDING_MAX_FILE_MIB", "CLAUDE_RECORDING_MAX_TOTAL_MIB", "CLAUDE_RECORDING_MAX_AGE"} {
+		t.Setenv(k, "")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	yaml := `
+claude:
+  recording:
+    dir: /var/lib/klaus/recordings
+    maxFileMiB: 16
+    maxTotalMiB: 512
+    maxAge: 168h
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := claude.RecordingOptions{
+		Dir:           "/var/lib/klaus/recordings",
+		MaxFileBytes:  16 << 20,
+		MaxTotalBytes: 512 << 20,
+		MaxAge:        168 * time.Hour,
+	}
+	if got := cfg.Claude.Recording.Options(); got != want {
+		t.Errorf("Options() = %+v, want %+v", got, want)
+	}
+
+	t.Setenv("CLAUDE_RECORDING_DIR", "/tmp/recordings")
+	t.Setenv("CLAUDE_RECORDING_MAX_AGE", "24h")
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	assertEqual(t, "dir", "/tmp/recordings", cfg.Claude.Recording.Dir)
+	assertEqual(t, "maxAge", "24h0m0s", cfg.Claude.Recording.MaxAge.String())
+}
+
+func TestValidate_Recording(t *testing.T) {
+	cfg := Config{Claude: ClaudeConfig{Recording: RecordingConfig{Dir: "recordings", MaxTotalMiB: -1}}}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected error for invalid recording settings")
+	}
+	for _, want := range []string{"claude.recording: dir must be an absolute path", "claude.recording.maxTotalMiB must be >= 0"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestAutoCompact_YAMLAndEnv(t *testing.T) {
+	for _, k := range []string{"CLAUDE_AUTO_COMPACT_THRESHOLD", "CLAUDE_AUTO_COMPACT_CONTEXT_WINDOW", "CLAUDE_AUTO_COMPACT_INSTRUCTIONS"} {
+		t.Setenv(k, "")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	yaml := `
+claude:
+  autoCompact:
+    threshold: 0.8
+    contextWindow: 1000000
+    instructions: Keep the open review comments.
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := claude.AutoCompactOptions{Threshold: 0.8, ContextWindow: 1000000, Instructions: "Keep the open review comments."}
+	if got := cfg.Claude.AutoCompact.Options(); got != want {
+		t.Errorf("Options() = %+v, want %+v", got, want)
+	}
+
+	t.Setenv("CLAUDE_AUTO_COMPACT_THRESHOLD", "0.6")
+	t.Setenv("CLAUDE_AUTO_COMPACT_INSTRUCTIONS", "Keep the plan.")
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	assertEqualFloat(t, "threshold", 0.6, cfg.Claude.AutoCompact.Threshold)
+	assertEqual(t, "instructions", "Keep the plan.", cfg.Claude.AutoCompact.Instructions)
+}
+
+func TestValidate_AutoCompact(t *testing.T) {
+	cfg := Config{Claude: ClaudeConfig{AutoCompact: AutoCompactConfig{Threshold: 1.2}}}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "claude.autoCompact: threshold must be between 0 and 1") {
+		t.Errorf("expected threshold error, got %v", err)
+	}
+}
+
+func TestResumeOnStart_EnvAndValidation(t *testing.T) {
+	t.Setenv("CLAUDE_MODE", "chat")
+	t.Setenv("CLAUDE_RESUME_ON_START", "true")
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	assertEqualBool(t, "resumeOnStart", true, cfg.Claude.ResumeOnStart)
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cfg.Claude.Mode = "agent"
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `claude.resumeOnStart requires claude.mode "chat"`) {
+		t.Errorf("expected mode error, got %v", err)
+	}
+}
+
+func TestConfigReload_LoadAndValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("server:\n  configReload:\n    interval: 30s\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KLAUS_CONFIG_RELOAD_INTERVAL", "")
+	t.Setenv("KLAUS_CONFIG_RELOAD_DISABLED", "true")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	assertEqualBool(t, "configReload.disabled", true, cfg.Server.ConfigReload.Disabled)
+	if got := cfg.Server.ConfigReload.EffectiveInterval(); got != 30*time.Second {
+		t.Errorf("expected interval 30s, got %s", got)
+	}
+	if got := (ConfigReloadConfig{}).EffectiveInterval(); got != DefaultConfigReloadInterval {
+		t.Errorf("expected default interval, got %s", got)
+	}
+
+	cfg.Server.ConfigReload.Interval = -time.Second
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "server.configReload.interval must be >= 0") {
+		t.Errorf("expected interval error, got %v", err)
+	}
+}
+
+func TestMCPProbe_LoadAndValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("server:\n  mcpProbe:\n    interval: 1m\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KLAUS_MCP_PROBE_DISABLED", "")
+	t.Setenv("KLAUS_MCP_PROBE_INTERVAL", "")
+	t.Setenv("KLAUS_MCP_PROBE_TIMEOUT", "5s")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Server.MCPProbe.Interval != time.Minute || cfg.Server.MCPProbe.Timeout != 5*time.Second {
+		t.Errorf("unexpected probe settings %+v", cfg.Server.MCPProbe)
+	}
+	if cfg.Server.MCPProbe.Prober() == nil {
+		t.Error("expected a prober")
+	}
+	if (MCPProbeConfig{Disabled: true}).Prober() != nil {
+		t.Error("expected no prober when disabled")
+	}
+
+	cfg.Server.MCPProbe.Timeout = -time.Second
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "server.mcpProbe.timeout must be >= 0") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
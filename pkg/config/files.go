@@ -0,0 +1,116 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configDirName is the directory next to the config file whose *.yaml
+// files are layered on top of it.
+const configDirName = "config.d"
+
+// unknownKey is a key of a config file that matches no field.
+type unknownKey struct {
+	file string
+	key  string
+}
+
+// ConfigPaths returns the paths the config at path is read from: the
+// config file and the config.d directory next to it, e.g.
+// /etc/klaus/config.yaml and /etc/klaus/config.d. If path is itself a
+// directory, only that directory is read.
+func ConfigPaths(path string) []string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return []string{path}
+	}
+	return []string{path, filepath.Join(filepath.Dir(path), configDirName)}
+}
+
+// ConfigFiles returns the existing config files of ConfigPaths(path) in the
+// order they are merged: the config file, then the *.yaml files of the
+// directory in lexical order.
+func ConfigFiles(path string) ([]string, error) {
+	var files []string
+	for _, p := range ConfigPaths(path) {
+		info, err := os.Stat(p)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			// Missing files and directories are fine -- env-only mode
+			// (backward compat).
+		case err != nil:
+			return nil, fmt.Errorf("reading config %s: %w", p, err)
+		case info.IsDir():
+			dirFiles, err := yamlFiles(p)
+			if err != nil {
+				return nil, fmt.Errorf("reading config directory %s: %w", p, err)
+			}
+			files = append(files, dirFiles...)
+		default:
+			files = append(files, p)
+		}
+	}
+	return files, nil
+}
+
+// yamlFiles returns the *.yaml files of dir in lexical order.
+func yamlFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		// Entries are followed, so that the symlinks of mounted
+		// ConfigMaps are read.
+		path := filepath.Join(dir, e.Name())
+		if filepath.Ext(e.Name()) != ".yaml" {
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			continue
+		}
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// decodeFile decodes the config file at path on top of c, recording the
+// fields it sets in sources and the keys that match no field.
+func (c *Config) decodeFile(path string, sources Sources) error {
+	f, err := os.Open(path) //#nosec G304 -- operator-provided config path
+	if err != nil {
+		return fmt.Errorf("reading config file %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+	data, err := io.ReadAll(io.LimitReader(f, maxConfigFileSize+1))
+	if err != nil {
+		return fmt.Errorf("reading config file %s: %w", path, err)
+	}
+	if len(data) > maxConfigFileSize {
+		return fmt.Errorf("config file %s exceeds maximum size of %d bytes", path, maxConfigFileSize)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	if err := expandEnv(&doc); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	if err := doc.Decode(c); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	for _, key := range walkYAML(&doc, func(field string) {
+		sources[field] = Source{Kind: SourceYAML, Name: path}
+	}) {
+		c.unknownKeys = append(c.unknownKeys, unknownKey{file: path, key: key})
+	}
+	return nil
+}
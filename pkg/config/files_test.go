@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad_ConfigDir(t *testing.T) {
+	t.Setenv("CLAUDE_MODEL", "")
+	t.Setenv("CLAUDE_MAX_TURNS", "")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeConfigFile(t, path, "claude:\n  model: sonnet\n  maxTurns: 5\n  allowedTools: [Read, Edit]\n")
+	writeConfigFile(t, filepath.Join(dir, "config.d", "20-tools.yaml"), "claude:\n  allowedTools: [Read]\n")
+	writeConfigFile(t, filepath.Join(dir, "config.d", "10-model.yaml"), "claude:\n  model: opus\n")
+	writeConfigFile(t, filepath.Join(dir, "config.d", "README.md"), "not: [yaml")
+
+	files, err := ConfigFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{path, filepath.Join(dir, "config.d", "10-model.yaml"), filepath.Join(dir, "config.d", "20-tools.yaml")}
+	if !slices.Equal(files, want) {
+		t.Errorf("ConfigFiles = %v, want %v", files, want)
+	}
+
+	cfg, sources, err := LoadWithSources(path)
+	if err != nil {
+		t.Fatalf("LoadWithSources: %v", err)
+	}
+	assertEqual(t, "model", "opus", cfg.Claude.Model)
+	assertEqualInt(t, "maxTurns", 5, cfg.Claude.MaxTurns)
+	// Lists are replaced, not appended to.
+	assertEqualSlice(t, "allowedTools", []string{"Read"}, cfg.Claude.AllowedTools)
+	if got := sources["claude.model"]; got != (Source{Kind: SourceYAML, Name: want[1]}) {
+		t.Errorf("unexpected source %s", got)
+	}
+	if got := sources["claude.maxTurns"]; got != (Source{Kind: SourceYAML, Name: path}) {
+		t.Errorf("unexpected source %s", got)
+	}
+
+	// The directory can be given on its own.
+	cfg, err = Load(filepath.Join(dir, "config.d"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	assertEqualInt(t, "maxTurns", 0, cfg.Claude.MaxTurns)
+	assertEqual(t, "model", "opus", cfg.Claude.Model)
+}
+
+func TestValidate_UnknownKeyNamesFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	extra := filepath.Join(dir, "config.d", "50-extra.yaml")
+	writeConfigFile(t, extra, "claude:\n  modle: opus\n")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "claude.modle: unknown field in "+extra) {
+		t.Errorf("expected the unknown key with its file, got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// profileExcluded are the claude settings a profile may not override,
+// since they apply to the server rather than to a run.
+var profileExcluded = []string{"binary", "binaryArgs", "mode", "resumeOnStart", "resourceLimits", "overridePolicy"}
+
+// Profile overrides a subset of the claude settings. Only the fields it
+// sets are applied; mappings are merged and lists replaced, like across
+// config files.
+type Profile struct {
+	node yaml.Node
+}
+
+// UnmarshalYAML keeps the profile as a YAML node, so that unset fields can
+// be told apart from zero values when it is applied.
+func (p *Profile) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: a profile must be a mapping of claude settings", value.Line)
+	}
+	p.node = *value
+	return nil
+}
+
+// MarshalJSON returns the settings of the profile, for printing. Like in
+// claude.mcpServers, the env vars and headers of MCP servers are masked.
+func (p Profile) MarshalJSON() ([]byte, error) {
+	var settings map[string]any
+	if err := p.node.Decode(&settings); err != nil {
+		return nil, err
+	}
+	if servers, ok := settings["mcpServers"].(map[string]any); ok {
+		for _, server := range servers {
+			if server, ok := server.(map[string]any); ok {
+				for _, key := range []string{"env", "headers"} {
+					if values, ok := server[key].(map[string]any); ok {
+						for k := range values {
+							values[k] = "<redacted>"
+						}
+					}
+				}
+			}
+		}
+	}
+	return json.Marshal(settings)
+}
+
+// fields returns the claude fields the profile sets, e.g. "claude.model",
+// and the keys that match no field.
+func (p Profile) fields() (fields, unknown []string) {
+	unknown = walkYAMLNode(&p.node, reflect.TypeOf(ClaudeConfig{}), "claude", func(field string) {
+		fields = append(fields, field)
+	})
+	return fields, unknown
+}
+
+// ApplyProfile overlays the profile selected by Profile onto Claude and
+// records the fields it sets in sources. Profiles apply on top of env var
+// overrides. Calling it again applies Profile to the original Claude.
+func (c *Config) ApplyProfile(sources Sources) error {
+	if c.baseClaude != nil {
+		c.Claude = *c.baseClaude
+		c.baseClaude = nil
+	}
+	if c.Profile == "" {
+		return nil
+	}
+	claude, err := c.ProfileClaude(c.Profile)
+	if err != nil {
+		return err
+	}
+	base := c.Claude
+	c.baseClaude = &base
+	c.Claude = claude
+
+	fields, _ := c.Profiles[c.Profile].fields()
+	for _, field := range fields {
+		sources[field] = Source{Kind: SourceProfile, Name: c.Profile}
+	}
+	return nil
+}
+
+// ProfileNames returns the names of the profiles in lexical order.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ProfileClaude returns the claude settings of the named profile: the
+// claude settings without the profile selected at startup, overridden by
+// the profile.
+func (c *Config) ProfileClaude(name string) (ClaudeConfig, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return ClaudeConfig{}, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+	claude := c.Claude
+	if c.baseClaude != nil {
+		claude = *c.baseClaude
+	}
+	// Decoding merges into existing maps, which the copy shares.
+	claude.MCPServers = maps.Clone(claude.MCPServers)
+	if err := profile.node.Decode(&claude); err != nil {
+		return ClaudeConfig{}, fmt.Errorf("profiles.%s: %w", name, err)
+	}
+	return claude, nil
+}
+
+// validateProfiles checks that the profiles only set claude settings they
+// may override and that the settings they result in are valid. Errors the
+// base settings have too are not repeated. baseErrs are the errors of the
+// config itself.
+func (c *Config) validateProfiles(baseErrs []error) []error {
+	var errs []error
+	if c.Profile != "" {
+		if _, ok := c.Profiles[c.Profile]; !ok {
+			errs = append(errs, fmt.Errorf("profile: unknown profile %q (available: %s)", c.Profile, strings.Join(c.ProfileNames(), ", ")))
+		}
+	}
+
+	known := make(map[string]bool, len(baseErrs))
+	for _, err := range baseErrs {
+		known[err.Error()] = true
+	}
+	for _, name := range c.ProfileNames() {
+		prefix := "profiles." + name + ": "
+		fields, unknown := c.Profiles[name].fields()
+		for _, key := range unknown {
+			errs = append(errs, fmt.Errorf("%s%s: unknown field", prefix, key))
+		}
+		for _, field := range fields {
+			for _, excluded := range profileExcluded {
+				if field == "claude."+excluded || strings.HasPrefix(field, "claude."+excluded+".") {
+					errs = append(errs, fmt.Errorf("%s%s may not be set in a profile", prefix, field))
+				}
+			}
+		}
+
+		claude, err := c.ProfileClaude(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// The claude settings are checked by Validate; the other sections
+		// are left empty.
+		profileCfg := Config{Claude: claude}
+		for _, err := range unwrapJoined(profileCfg.Validate()) {
+			if !known[err.Error()] {
+				errs = append(errs, fmt.Errorf("%s%w", prefix, err))
+			}
+		}
+	}
+	return errs
+}
+
+// unwrapJoined returns the errors joined by errors.Join.
+func unwrapJoined(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
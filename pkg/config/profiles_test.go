@@ -0,0 +1,123 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const profilesConfig = `claude:
+  model: sonnet
+  maxTurns: 20
+  allowedTools: [Read, Edit, Bash]
+profiles:
+  reviewer:
+    model: opus
+    allowedTools: [Read]
+  implementer:
+    maxTurns: 50
+`
+
+func TestLoad_Profile(t *testing.T) {
+	t.Setenv("CLAUDE_MODEL", "")
+	t.Setenv("CLAUDE_MAX_TURNS", "")
+	t.Setenv("KLAUS_PROFILE", "reviewer")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, profilesConfig)
+
+	cfg, sources, err := LoadWithSources(path)
+	if err != nil {
+		t.Fatalf("LoadWithSources: %v", err)
+	}
+	assertEqual(t, "profile", "reviewer", cfg.Profile)
+	if err := cfg.ApplyProfile(sources); err != nil {
+		t.Fatalf("ApplyProfile: %v", err)
+	}
+	assertEqual(t, "model", "opus", cfg.Claude.Model)
+	assertEqualInt(t, "maxTurns", 20, cfg.Claude.MaxTurns)
+	assertEqualSlice(t, "allowedTools", []string{"Read"}, cfg.Claude.AllowedTools)
+	if got := sources["claude.model"]; got != (Source{Kind: SourceProfile, Name: "reviewer"}) {
+		t.Errorf("unexpected source %s", got)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	// Other profiles apply to the settings without the selected profile.
+	implementer, err := cfg.ProfileClaude("implementer")
+	if err != nil {
+		t.Fatalf("ProfileClaude: %v", err)
+	}
+	assertEqual(t, "implementer model", "sonnet", implementer.Model)
+	assertEqualInt(t, "implementer maxTurns", 50, implementer.MaxTurns)
+	assertEqualSlice(t, "implementer allowedTools", []string{"Read", "Edit", "Bash"}, implementer.AllowedTools)
+
+	// Applying again starts from the original settings.
+	cfg.Profile = "implementer"
+	if err := cfg.ApplyProfile(sources); err != nil {
+		t.Fatalf("ApplyProfile: %v", err)
+	}
+	assertEqual(t, "model", "sonnet", cfg.Claude.Model)
+	assertEqualInt(t, "maxTurns", 50, cfg.Claude.MaxTurns)
+}
+
+func TestValidate_Profiles(t *testing.T) {
+	t.Setenv("KLAUS_PROFILE", "")
+	t.Setenv("CLAUDE_PERMISSION_MODE", "")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, `profile: missing
+profiles:
+  bad:
+    modle: opus
+    mode: chat
+    permissionMode: yolo
+`)
+
+	if _, err := Load(path); err == nil {
+		t.Error("expected Load to reject an unknown profile")
+	}
+	cfg, _, err := LoadWithSources(path)
+	if err != nil {
+		t.Fatalf("LoadWithSources: %v", err)
+	}
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{
+		`profile: unknown profile "missing" (available: bad)`,
+		"profiles.bad: claude.modle: unknown field",
+		"profiles.bad: claude.mode may not be set in a profile",
+		"profiles.bad: claude.permissionMode",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestProfileClaude_MCPServers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, `claude:
+  mcpServers:
+    github:
+      type: http
+      url: https://example.com/mcp
+profiles:
+  reviewer:
+    mcpServers:
+      muster:
+        command: muster
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	reviewer, err := cfg.ProfileClaude("reviewer")
+	if err != nil {
+		t.Fatalf("ProfileClaude: %v", err)
+	}
+	assertEqualInt(t, "reviewer servers", 2, len(reviewer.MCPServers))
+	// The servers of the profile are merged into a copy.
+	assertEqualInt(t, "base servers", 1, len(cfg.Claude.MCPServers))
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxSecretFileSize is the maximum allowed size for a secret file (64 KiB).
+const maxSecretFileSize = 64 * 1024
+
+// secretFile pairs a secret field with the field naming a file to read it
+// from.
+type secretFile struct {
+	field, fileField string
+	value, path      *string
+}
+
+func (c *Config) secretFiles() []secretFile {
+	return []secretFile{
+		{"oauth.google.clientSecret", "oauth.google.clientSecretFile", &c.OAuth.Google.ClientSecret, &c.OAuth.Google.ClientSecretFile},
+		{"oauth.dex.clientSecret", "oauth.dex.clientSecretFile", &c.OAuth.Dex.ClientSecret, &c.OAuth.Dex.ClientSecretFile},
+		{"oauth.security.encryptionKey", "oauth.security.encryptionKeyFile", &c.OAuth.Security.EncryptionKey, &c.OAuth.Security.EncryptionKeyFile},
+		{"oauth.security.registrationToken", "oauth.security.registrationTokenFile", &c.OAuth.Security.RegistrationToken, &c.OAuth.Security.RegistrationTokenFile},
+	}
+}
+
+// SecretFiles returns the files secrets are read from, including those of
+// the MCP servers of the claude settings and the profiles, so that changes
+// to them can be watched.
+func (c *Config) SecretFiles() []string {
+	var paths []string
+	for _, s := range c.secretFiles() {
+		if *s.path != "" {
+			paths = append(paths, *s.path)
+		}
+	}
+	claudeConfigs := []ClaudeConfig{c.Claude}
+	for _, name := range c.ProfileNames() {
+		if claude, err := c.ProfileClaude(name); err == nil {
+			claudeConfigs = append(claudeConfigs, claude)
+		}
+	}
+	for _, claude := range claudeConfigs {
+		for _, name := range slices.Sorted(maps.Keys(claude.MCPServers)) {
+			server := claude.MCPServers[name]
+			for _, files := range []map[string]string{server.EnvFiles, server.HeadersFiles} {
+				for _, key := range slices.Sorted(maps.Keys(files)) {
+					if !slices.Contains(paths, files[key]) {
+						paths = append(paths, files[key])
+					}
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// withSecretFiles returns values with the values of files read from the
+// files they name. field is the name of values in errors.
+func withSecretFiles(field string, values, files map[string]string) (map[string]string, error) {
+	if len(files) == 0 {
+		return values, nil
+	}
+	merged := maps.Clone(values)
+	if merged == nil {
+		merged = make(map[string]string, len(files))
+	}
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(files)) {
+		if _, ok := values[key]; ok {
+			errs = append(errs, fmt.Errorf("%s.%s and %sFiles.%s are mutually exclusive", field, key, field, key))
+			continue
+		}
+		value, err := readSecretFile(files[key])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%sFiles.%s: %w", field, key, err))
+			continue
+		}
+		merged[key] = value
+	}
+	return merged, errors.Join(errs...)
+}
+
+// readSecretFiles reads the secrets configured as files and records the
+// files as their source. Setting both a secret and its file is an error,
+// as it is unclear which one is meant to apply.
+func (c *Config) readSecretFiles(sources Sources) error {
+	var errs []error
+	for _, s := range c.secretFiles() {
+		if *s.path == "" {
+			continue
+		}
+		if *s.value != "" {
+			errs = append(errs, fmt.Errorf("%s and %s are mutually exclusive", s.field, s.fileField))
+			continue
+		}
+		value, err := readSecretFile(*s.path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.fileField, err))
+			continue
+		}
+		*s.value = value
+		sources[s.field] = Source{Kind: SourceFile, Name: *s.path}
+	}
+	return errors.Join(errs...)
+}
+
+// readSecretFile returns the content of a secret file without surrounding
+// whitespace, such as the trailing newline editors add.
+func readSecretFile(path string) (string, error) {
+	f, err := os.Open(path) //#nosec G304 -- operator-provided secret path
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	data, err := io.ReadAll(io.LimitReader(f, maxSecretFileSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxSecretFileSize {
+		return "", fmt.Errorf("secret file %s exceeds maximum size of %d bytes", path, maxSecretFileSize)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// envRefRE matches ${VAR} references, and $${VAR} escapes of them.
+var envRefRE = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${VAR} references in the string values of a YAML
+// document with the value of the environment variable. $${VAR} is kept as
+// a literal ${VAR}. Referencing an unset variable is an error, so that a
+// missing secret does not silently become an empty string.
+func expandEnv(node *yaml.Node) error {
+	var errs []error
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		switch n.Kind {
+		case yaml.DocumentNode, yaml.SequenceNode:
+			for _, child := range n.Content {
+				walk(child)
+			}
+		case yaml.MappingNode:
+			// Only values are expanded, keys are left alone.
+			for i := 1; i < len(n.Content); i += 2 {
+				walk(n.Content[i])
+			}
+		case yaml.ScalarNode:
+			if n.ShortTag() != "!!str" || !strings.Contains(n.Value, "${") {
+				return
+			}
+			n.Value = envRefRE.ReplaceAllStringFunc(n.Value, func(ref string) string {
+				if strings.HasPrefix(ref, "$$") {
+					return ref[1:]
+				}
+				name := envRefRE.FindStringSubmatch(ref)[1]
+				value, ok := os.LookupEnv(name)
+				if !ok {
+					errs = append(errs, fmt.Errorf("line %d: environment variable %s is not set", n.Line, name))
+				}
+				return value
+			})
+		}
+	}
+	walk(node)
+	return errors.Join(errs...)
+}
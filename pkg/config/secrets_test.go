@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoad_SecretFiles(t *testing.T) {
+	for _, key := range []string{"DEX_CLIENT_SECRET", "DEX_CLIENT_SECRET_FILE", "OAUTH_ENCRYPTION_KEY", "OAUTH_ENCRYPTION_KEY_FILE", "OAUTH_REGISTRATION_TOKEN_FILE"} {
+		t.Setenv(key, "")
+	}
+	dir := t.TempDir()
+	secretPath := filepath.Join(dir, "client-secret")
+	tokenPath := filepath.Join(dir, "token")
+	if err := os.WriteFile(secretPath, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tokenPath, []byte("tok"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.yaml")
+	content := "oauth:\n  dex:\n    clientSecretFile: " + secretPath + "\n  security:\n    registrationTokenFile: " + tokenPath + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, sources, err := LoadWithSources(path)
+	if err != nil {
+		t.Fatalf("LoadWithSources: %v", err)
+	}
+	assertEqual(t, "dex.clientSecret", "s3cret", cfg.OAuth.Dex.ClientSecret)
+	assertEqual(t, "security.registrationToken", "tok", cfg.OAuth.Security.RegistrationToken)
+	if got := sources["oauth.dex.clientSecret"]; got != (Source{Kind: SourceFile, Name: secretPath}) {
+		t.Errorf("unexpected source %s", got)
+	}
+	if got := cfg.SecretFiles(); !slices.Equal(got, []string{secretPath, tokenPath}) {
+		t.Errorf("unexpected secret files %v", got)
+	}
+
+	// A secret set both inline and as a file is ambiguous.
+	t.Setenv("DEX_CLIENT_SECRET", "inline")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Errorf("expected a conflict error, got %v", err)
+	}
+	t.Setenv("DEX_CLIENT_SECRET", "")
+
+	t.Setenv("DEX_CLIENT_SECRET_FILE", filepath.Join(dir, "missing"))
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "oauth.dex.clientSecretFile") {
+		t.Errorf("expected a missing file error, got %v", err)
+	}
+}
+
+func TestLoad_ExpandsEnv(t *testing.T) {
+	t.Setenv("CLAUDE_MODEL", "")
+	t.Setenv("CLAUDE_MAX_TURNS", "")
+	t.Setenv("TEST_KLAUS_MODEL", "opus")
+	t.Setenv("TEST_KLAUS_TOOL", "Bash")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+claude:
+  model: ${TEST_KLAUS_MODEL}
+  appendSystemPrompt: "Use $${HOME} literally with ${TEST_KLAUS_MODEL}."
+  allowedTools:
+    - ${TEST_KLAUS_TOOL}(git:*)
+  maxTurns: 3
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	assertEqual(t, "claude.model", "opus", cfg.Claude.Model)
+	assertEqual(t, "claude.appendSystemPrompt", "Use ${HOME} literally with opus.", cfg.Claude.AppendSystemPrompt)
+	assertEqualSlice(t, "claude.allowedTools", []string{"Bash(git:*)"}, cfg.Claude.AllowedTools)
+	assertEqualInt(t, "claude.maxTurns", 3, cfg.Claude.MaxTurns)
+
+	if err := os.WriteFile(path, []byte("claude:\n  model: ${TEST_KLAUS_UNSET_VARIABLE}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "TEST_KLAUS_UNSET_VARIABLE is not set") {
+		t.Errorf("expected an unset variable error, got %v", err)
+	}
+}
+
+func TestMCPServerConfig_SecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	tokenPath := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenPath, []byte("Bearer s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{Claude: ClaudeConfig{MCPServers: map[string]MCPServerConfig{
+		"gh": {Type: "http", URL: "https://example.com/mcp", Headers: map[string]string{"X-Team": "a"}, HeadersFiles: map[string]string{"Authorization": tokenPath}},
+	}}}
+
+	server, err := cfg.Claude.MCPServers["gh"].Server()
+	if err != nil {
+		t.Fatalf("Server: %v", err)
+	}
+	if server.Headers["Authorization"] != "Bearer s3cret" || server.Headers["X-Team"] != "a" {
+		t.Errorf("unexpected headers %v", server.Headers)
+	}
+	if _, ok := cfg.Claude.MCPServers["gh"].Headers["Authorization"]; ok {
+		t.Error("expected the config not to be modified")
+	}
+	if got := cfg.SecretFiles(); !slices.Equal(got, []string{tokenPath}) {
+		t.Errorf("unexpected secret files %v", got)
+	}
+
+	conflict := MCPServerConfig{Command: "srv", Env: map[string]string{"TOKEN": "x"}, EnvFiles: map[string]string{"TOKEN": tokenPath}}
+	if _, err := conflict.Server(); err == nil || !strings.Contains(err.Error(), "env.TOKEN and envFiles.TOKEN are mutually exclusive") {
+		t.Errorf("expected a conflict error, got %v", err)
+	}
+	missing := MCPServerConfig{Command: "srv", EnvFiles: map[string]string{"TOKEN": filepath.Join(dir, "missing")}}
+	if _, err := missing.Server(); err == nil || !strings.Contains(err.Error(), "envFiles.TOKEN") {
+		t.Errorf("expected a missing file error, got %v", err)
+	}
+}
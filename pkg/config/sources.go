@@ -0,0 +1,236 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of config value sources, in increasing precedence.
+const (
+	SourceDefault = "default"
+	SourceYAML    = "yaml"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+	// SourceFile is a secret read from a file, named by Source.Name.
+	SourceFile = "file"
+	// SourceProfile is the profile selected at startup, named by
+	// Source.Name.
+	SourceProfile = "profile"
+)
+
+// Source is where the value of a config field came from.
+type Source struct {
+	// Kind is one of SourceDefault, SourceYAML, SourceEnv, SourceFlag,
+	// SourceFile and SourceProfile.
+	Kind string `json:"kind"`
+	// Name is the config file, env var, flag, secret file or profile that
+	// set the field.
+	Name string `json:"name,omitempty"`
+}
+
+func (s Source) String() string {
+	if s.Name == "" {
+		return s.Kind
+	}
+	return s.Kind + " " + s.Name
+}
+
+// Sources maps config fields, e.g. "claude.model", to the source of their
+// value. Fields that are not present keep their default.
+type Sources map[string]Source
+
+// SetFlag records that a command line flag set field.
+func (s Sources) SetFlag(field, flag string) {
+	s[field] = Source{Kind: SourceFlag, Name: "--" + flag}
+}
+
+// secretFields are masked by Effective.
+var secretFields = map[string]bool{
+	"oauth.google.clientSecret":        true,
+	"oauth.dex.clientSecret":           true,
+	"oauth.security.encryptionKey":     true,
+	"oauth.security.registrationToken": true,
+}
+
+// EffectiveField is the final value of a config field.
+type EffectiveField struct {
+	Field  string `json:"field"`
+	Value  any    `json:"value"`
+	Source Source `json:"source"`
+}
+
+// Effective returns the value and source of every field of cfg, in the
+// order of the Config struct. Secrets are masked.
+func Effective(cfg Config, sources Sources) []EffectiveField {
+	fields := fieldsOf(&cfg)
+	effective := make([]EffectiveField, 0, len(fields))
+	for _, f := range fields {
+		source, ok := sources[f.Path]
+		if !ok {
+			source = Source{Kind: SourceDefault}
+		}
+		effective = append(effective, EffectiveField{Field: f.Path, Value: f.display(), Source: source})
+	}
+	return effective
+}
+
+// field is a leaf field of Config.
+type field struct {
+	// Path is the dotted YAML path, e.g. "claude.model".
+	Path  string
+	value reflect.Value
+	// ptr is the address of the field, identifying it across walks.
+	ptr any
+}
+
+// display returns the value of the field for printing.
+func (f field) display() any {
+	if f.value.IsZero() {
+		if f.value.Kind() == reflect.Pointer {
+			return nil
+		}
+	} else if secretFields[f.Path] {
+		return "<redacted>"
+	}
+	switch v := f.value.Interface().(type) {
+	case time.Duration:
+		return v.String()
+	case map[string]MCPServerConfig:
+		return redactMCPServers(v)
+	case *bool:
+		return *v
+	default:
+		return v
+	}
+}
+
+// redactMCPServers masks the values of the env vars and headers of MCP
+// servers, which commonly hold tokens.
+func redactMCPServers(servers map[string]MCPServerConfig) map[string]MCPServerConfig {
+	redacted := make(map[string]MCPServerConfig, len(servers))
+	for name, server := range servers {
+		server.Env = redactValues(server.Env)
+		server.Headers = redactValues(server.Headers)
+		redacted[name] = server
+	}
+	return redacted
+}
+
+func redactValues(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(m))
+	for k := range m {
+		redacted[k] = "<redacted>"
+	}
+	return redacted
+}
+
+// fieldsOf returns the leaf fields of cfg.
+func fieldsOf(cfg *Config) []field {
+	var fields []field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		for i, sf := range yamlFields(v.Type()) {
+			if sf == nil {
+				continue
+			}
+			fv := v.Field(i)
+			path := joinPath(prefix, yamlName(*sf))
+			if fv.Kind() == reflect.Struct {
+				walk(fv, path)
+				continue
+			}
+			fields = append(fields, field{Path: path, value: fv, ptr: fv.Addr().Interface()})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "")
+	return fields
+}
+
+// walkYAML calls seen for every config field the document sets and returns
+// the keys that match no field.
+func walkYAML(doc *yaml.Node, seen func(field string)) []string {
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		doc = doc.Content[0]
+	}
+	return walkYAMLNode(doc, reflect.TypeOf(Config{}), "", seen)
+}
+
+var yamlUnmarshaler = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+func walkYAMLNode(node *yaml.Node, t reflect.Type, prefix string, seen func(string)) []string {
+	if t.Kind() == reflect.Map && t.Elem().Kind() == reflect.Struct && node.Kind == yaml.MappingNode &&
+		!reflect.PointerTo(t.Elem()).Implements(yamlUnmarshaler) {
+		// Maps of structs, e.g. claude.mcpServers, are a single field whose
+		// entries are checked for unknown keys. Types decoding themselves,
+		// like profiles, check their own.
+		seen(prefix)
+		var unknown []string
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			path := joinPath(prefix, node.Content[i].Value)
+			unknown = append(unknown, walkYAMLNode(node.Content[i+1], t.Elem(), path, func(string) {})...)
+		}
+		return unknown
+	}
+	if t.Kind() != reflect.Struct || node.Kind != yaml.MappingNode {
+		if prefix != "" {
+			seen(prefix)
+		}
+		return nil
+	}
+	byName := make(map[string]reflect.StructField)
+	for _, sf := range yamlFields(t) {
+		if sf != nil {
+			byName[yamlName(*sf)] = *sf
+		}
+	}
+	var unknown []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		path := joinPath(prefix, key)
+		sf, ok := byName[key]
+		if !ok {
+			unknown = append(unknown, path)
+			continue
+		}
+		unknown = append(unknown, walkYAMLNode(node.Content[i+1], sf.Type, path, seen)...)
+	}
+	return unknown
+}
+
+// yamlFields returns the fields of struct type t that YAML decodes into,
+// indexed like t's fields; other entries are nil.
+func yamlFields(t reflect.Type) []*reflect.StructField {
+	fields := make([]*reflect.StructField, t.NumField())
+	for i := range fields {
+		sf := t.Field(i)
+		if !sf.IsExported() || yamlName(sf) == "-" {
+			continue
+		}
+		fields[i] = &sf
+	}
+	return fields
+}
+
+func yamlName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(sf.Name)
+	}
+	return name
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadWithSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "claude:\n  model: from-yaml\n  maxTurns: 3\noauth:\n  dex:\n    clientSecret: hunter2\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CLAUDE_MODEL", "")
+	t.Setenv("CLAUDE_MAX_TURNS", "10")
+	t.Setenv("DEX_CLIENT_SECRET", "")
+	t.Setenv("PORT", "")
+
+	cfg, sources, err := LoadWithSources(path)
+	if err != nil {
+		t.Fatalf("LoadWithSources: %v", err)
+	}
+	sources.SetFlag("server.port", "port")
+	cfg.Server.Port = "9090"
+
+	want := map[string]Source{
+		"claude.model":              {Kind: SourceYAML, Name: path},
+		"claude.maxTurns":           {Kind: SourceEnv, Name: "CLAUDE_MAX_TURNS"},
+		"oauth.dex.clientSecret":    {Kind: SourceYAML, Name: path},
+		"server.port":               {Kind: SourceFlag, Name: "--port"},
+		"claude.permissionMode":     {Kind: SourceDefault},
+		"oauth.security.enableCIMD": {Kind: SourceDefault},
+	}
+	values := map[string]any{
+		"claude.model":              "from-yaml",
+		"claude.maxTurns":           10,
+		"oauth.dex.clientSecret":    "<redacted>",
+		"server.port":               "9090",
+		"claude.permissionMode":     "",
+		"oauth.security.enableCIMD": nil,
+	}
+	found := 0
+	for _, f := range Effective(cfg, sources) {
+		source, ok := want[f.Field]
+		if !ok {
+			continue
+		}
+		found++
+		if f.Source != source {
+			t.Errorf("%s: expected source %s, got %s", f.Field, source, f.Source)
+		}
+		if f.Value != values[f.Field] {
+			t.Errorf("%s: expected value %v, got %v", f.Field, values[f.Field], f.Value)
+		}
+	}
+	if found != len(want) {
+		t.Errorf("expected %d fields, found %d", len(want), found)
+	}
+}
+
+func TestEffective_RedactsMCPServerSecrets(t *testing.T) {
+	cfg := Config{Claude: ClaudeConfig{MCPServers: map[string]MCPServerConfig{
+		"github": {Type: "http", URL: "https://example.com/mcp", Headers: map[string]string{"Authorization": "Bearer s3cret"}},
+	}}}
+	for _, f := range Effective(cfg, Sources{}) {
+		if f.Field != "claude.mcpServers" {
+			continue
+		}
+		servers := f.Value.(map[string]MCPServerConfig)
+		assertEqual(t, "header", "<redacted>", servers["github"].Headers["Authorization"])
+		assertEqual(t, "url", "https://example.com/mcp", servers["github"].URL)
+
+		// config print uses the YAML keys, not the Go field names.
+		data, err := json.Marshal(f.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, "json", `{"github":{"type":"http","url":"https://example.com/mcp","headers":{"Authorization":"\u003credacted\u003e"}}}`, string(data))
+	}
+	assertEqual(t, "original header", "Bearer s3cret", cfg.Claude.MCPServers["github"].Headers["Authorization"])
+}
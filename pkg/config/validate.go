@@ -0,0 +1,231 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/giantswarm/klaus/pkg/claude"
+)
+
+// OAuth providers accepted by oauth.provider.
+const (
+	oauthProviderDex    = "dex"
+	oauthProviderGoogle = "google"
+)
+
+// validateSchema checks the parts of the config beyond single values: keys
+// that match no field, the agent and MCP server definitions, tool patterns,
+// paths and the completeness of the OAuth settings.
+func (c *Config) validateSchema() []error {
+	var errs []error
+	for _, k := range c.unknownKeys {
+		errs = append(errs, fmt.Errorf("%s: unknown field in %s", k.key, k.file))
+	}
+	errs = append(errs, validateAgents(c.Claude.Agents)...)
+	errs = append(errs, validateMCPServers(c.Claude.MCPServers)...)
+	errs = append(errs, validateMCPConfigFile(c.Claude)...)
+	for _, list := range []struct {
+		field    string
+		patterns []string
+	}{
+		{"claude.tools", c.Claude.Tools},
+		{"claude.allowedTools", c.Claude.AllowedTools},
+		{"claude.disallowedTools", c.Claude.DisallowedTools},
+	} {
+		errs = append(errs, validateToolPatterns(list.field, list.patterns)...)
+	}
+	errs = append(errs, c.validatePaths()...)
+	errs = append(errs, c.validateOAuth()...)
+	return errs
+}
+
+// validateAgents checks that claude.agents is a JSON object of complete
+// agent definitions without unknown fields.
+func validateAgents(raw string) []error {
+	if raw == "" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	dec.DisallowUnknownFields()
+	var agents map[string]claude.AgentConfig
+	if err := dec.Decode(&agents); err != nil {
+		return []error{fmt.Errorf("claude.agents: invalid JSON: %w", err)}
+	}
+	if dec.More() {
+		return []error{errors.New("claude.agents: invalid JSON: unexpected data after the agent definitions")}
+	}
+
+	names := make([]string, 0, len(agents))
+	for name := range agents {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var errs []error
+	for _, name := range names {
+		agent := agents[name]
+		field := "claude.agents." + name
+		if agent.Description == "" {
+			errs = append(errs, fmt.Errorf("%s.description is required", field))
+		}
+		if agent.Prompt == "" {
+			errs = append(errs, fmt.Errorf("%s.prompt is required", field))
+		}
+		if agent.PermissionMode != "" {
+			if err := claude.ValidatePermissionMode(agent.PermissionMode); err != nil {
+				errs = append(errs, fmt.Errorf("%s.permissionMode: %w", field, err))
+			}
+		}
+		if agent.MaxTurns < 0 {
+			errs = append(errs, fmt.Errorf("%s.maxTurns must be >= 0, got %d", field, agent.MaxTurns))
+		}
+		errs = append(errs, validateToolPatterns(field+".tools", agent.Tools)...)
+		errs = append(errs, validateToolPatterns(field+".disallowedTools", agent.DisallowedTools)...)
+	}
+	return errs
+}
+
+// validateMCPServers checks that the inline MCP servers have names usable
+// in tool names and set the fields of their transport.
+func validateMCPServers(servers map[string]MCPServerConfig) []error {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var errs []error
+	for _, name := range names {
+		field := "claude.mcpServers." + name
+		if err := claude.ValidateMCPServerName(name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+		server, err := servers[name].Server()
+		for _, err := range append(unwrapJoined(err), unwrapJoined(server.Validate())...) {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+	}
+	return errs
+}
+
+// validateMCPConfigFile checks that claude.mcpConfigPath parses and does
+// not define the servers of claude.mcpServers again, as serve refuses to
+// start otherwise.
+func validateMCPConfigFile(c ClaudeConfig) []error {
+	if c.MCPConfigPath == "" || !filepath.IsAbs(c.MCPConfigPath) {
+		return nil
+	}
+	servers, err := claude.ReadMCPConfig(c.MCPConfigPath)
+	if err != nil {
+		return []error{fmt.Errorf("claude.mcpConfigPath: %w", err)}
+	}
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(c.MCPServers)) {
+		if _, ok := servers[name]; ok {
+			errs = append(errs, fmt.Errorf("claude.mcpServers.%s: server is also defined in %s", name, c.MCPConfigPath))
+		}
+	}
+	return errs
+}
+
+func validateToolPatterns(field string, patterns []string) []error {
+	var errs []error
+	for _, p := range patterns {
+		if err := claude.ValidateToolPattern(p); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+	}
+	return errs
+}
+
+// validatePaths checks that the configured files and directories are
+// absolute, so that they do not depend on the directory klaus runs in.
+// Whether they exist is checked by `klaus doctor`.
+func (c *Config) validatePaths() []error {
+	var errs []error
+	check := func(field, path string) {
+		if path != "" && !filepath.IsAbs(path) {
+			errs = append(errs, fmt.Errorf("%s: path %q must be absolute", field, path))
+		}
+	}
+	check("claude.mcpConfigPath", c.Claude.MCPConfigPath)
+	check("claude.workspace", c.Claude.Workspace)
+	// The settings may also be given inline as a JSON object.
+	if !strings.HasPrefix(strings.TrimSpace(c.Claude.SettingsFile), "{") {
+		check("claude.settingsFile", c.Claude.SettingsFile)
+	}
+	for _, dir := range c.Claude.PluginDirs {
+		check("claude.pluginDirs", dir)
+	}
+	for _, dir := range c.Claude.AddDirs {
+		check("claude.addDirs", dir)
+	}
+	for _, s := range c.secretFiles() {
+		check(s.fileField, *s.path)
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Claude.MCPServers)) {
+		server := c.Claude.MCPServers[name]
+		for _, key := range slices.Sorted(maps.Keys(server.EnvFiles)) {
+			check("claude.mcpServers."+name+".envFiles."+key, server.EnvFiles[key])
+		}
+		for _, key := range slices.Sorted(maps.Keys(server.HeadersFiles)) {
+			check("claude.mcpServers."+name+".headersFiles."+key, server.HeadersFiles[key])
+		}
+	}
+	check("oauth.dex.caFile", c.OAuth.Dex.CAFile)
+	check("oauth.tls.certFile", c.OAuth.TLS.CertFile)
+	check("oauth.tls.keyFile", c.OAuth.TLS.KeyFile)
+	return errs
+}
+
+// validateOAuth checks that the OAuth settings are complete. Only TLS and
+// client limits are checked when OAuth is disabled.
+func (c *Config) validateOAuth() []error {
+	var errs []error
+	o := c.OAuth
+	if (o.TLS.CertFile == "") != (o.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("oauth.tls.certFile and oauth.tls.keyFile must be set together"))
+	}
+	if o.Security.MaxClientsPerIP < 0 {
+		errs = append(errs, fmt.Errorf("oauth.security.maxClientsPerIP must be >= 0, got %d", o.Security.MaxClientsPerIP))
+	}
+	if !o.Enabled {
+		return errs
+	}
+
+	if o.BaseURL == "" {
+		errs = append(errs, errors.New("oauth.baseURL is required when OAuth is enabled"))
+	} else if u, err := url.Parse(o.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("oauth.baseURL: %q must be an absolute http or https URL", o.BaseURL))
+	}
+
+	required := func(field, value string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s is required when oauth.provider is %q", field, o.Provider))
+		}
+	}
+	switch o.Provider {
+	case oauthProviderDex:
+		required("oauth.dex.issuerURL", o.Dex.IssuerURL)
+		required("oauth.dex.clientID", o.Dex.ClientID)
+		required("oauth.dex.clientSecret", o.Dex.ClientSecret)
+	case oauthProviderGoogle:
+		required("oauth.google.clientID", o.Google.ClientID)
+		required("oauth.google.clientSecret", o.Google.ClientSecret)
+	default:
+		errs = append(errs, fmt.Errorf("oauth.provider: invalid value %q (must be %q or %q)", o.Provider, oauthProviderDex, oauthProviderGoogle))
+	}
+
+	if !o.Security.AllowPublicRegistration && o.Security.RegistrationToken == "" &&
+		len(o.Security.TrustedPublicRegistrationSchemes) == 0 && !c.EnableCIMD() {
+		errs = append(errs, errors.New("oauth.security.registrationToken is required unless allowPublicRegistration, trustedPublicRegistrationSchemes or enableCIMD is set"))
+	}
+	return errs
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate_UnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "claude:\n  model: sonnet\n  modle: typo\n  recording:\n    dir: /rec\n    bogus: 1\nextra: true\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CLAUDE_MODEL", "")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("expected unknown keys to be rejected")
+	}
+	for _, key := range []string{"claude.modle", "claude.recording.bogus", "extra"} {
+		if !strings.Contains(err.Error(), key+": unknown field") {
+			t.Errorf("expected %s to be reported, got %v", key, err)
+		}
+	}
+	if strings.Contains(err.Error(), "claude.model:") {
+		t.Errorf("known key reported as unknown: %v", err)
+	}
+}
+
+func TestValidate_Agents(t *testing.T) {
+	tests := []struct {
+		name    string
+		agents  string
+		wantErr []string
+	}{
+		{name: "valid", agents: `{"reviewer":{"description":"Reviews code","prompt":"Review it","tools":["Read","Bash(git:*)"],"permissionMode":"plan"}}`},
+		{name: "malformed", agents: `{"reviewer":`, wantErr: []string{"claude.agents: invalid JSON"}},
+		{name: "trailing data", agents: `{} {}`, wantErr: []string{"unexpected data"}},
+		{name: "unknown field", agents: `{"reviewer":{"description":"d","prompt":"p","tool":["Read"]}}`, wantErr: []string{`unknown field "tool"`}},
+		{
+			name:   "incomplete",
+			agents: `{"b":{"prompt":"p","maxTurns":-1},"a":{"description":"d","permissionMode":"yolo","disallowedTools":["Bash("]}}`,
+			wantErr: []string{
+				"claude.agents.a.prompt is required",
+				"claude.agents.a.permissionMode",
+				"claude.agents.a.disallowedTools",
+				"claude.agents.b.description is required",
+				"claude.agents.b.maxTurns must be >= 0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Claude: ClaudeConfig{Agents: tt.agents}}
+			err := cfg.Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected %q in %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate_MCPServers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `claude:
+  mcpServers:
+    github:
+      type: http
+      url: https://api.githubcopilot.com/mcp/
+      headers:
+        Authorization: Bearer token
+    muster:
+      command: muster
+      args: [serve]
+      env:
+        LOG_LEVEL: debug
+    broken:
+      type: sse
+      command: sse-server
+    "bad name":
+      type: websocket
+    typo:
+      comand: x
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	assertEqual(t, "muster command", "muster", cfg.Claude.MCPServers["muster"].Command)
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"claude.mcpServers.broken: url is required for sse servers",
+		"claude.mcpServers.broken: command, args and env are only supported by stdio servers",
+		`claude.mcpServers.bad name: invalid MCP server name "bad name"`,
+		`claude.mcpServers.bad name: invalid type "websocket"`,
+		"claude.mcpServers.typo.comand: unknown field",
+		"claude.mcpServers.typo: command is required for stdio servers",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+	for _, valid := range []string{"github", "muster:"} {
+		if strings.Contains(err.Error(), "claude.mcpServers."+valid) {
+			t.Errorf("expected %s to be valid, got %v", valid, err)
+		}
+	}
+}
+
+func TestValidate_ToolPatternsAndPaths(t *testing.T) {
+	mcpConfigPath := filepath.Join(t.TempDir(), "mcp.json")
+	if err := os.WriteFile(mcpConfigPath, []byte(`{"mcpServers":{}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{Claude: ClaudeConfig{
+		AllowedTools:  []string{"Bash(git:*)", "Edit"},
+		Tools:         []string{"Bash git"},
+		Workspace:     "work",
+		PluginDirs:    []string{"/plugins", "plugins"},
+		SettingsFile:  `{"permissions":{}}`,
+		MCPConfigPath: mcpConfigPath,
+	}}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		`claude.tools: invalid tool pattern "Bash git"`,
+		`claude.workspace: path "work" must be absolute`,
+		`claude.pluginDirs: path "plugins" must be absolute`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+	for _, unwanted := range []string{"claude.allowedTools", "claude.settingsFile", "claude.mcpConfigPath", `"/plugins"`} {
+		if strings.Contains(err.Error(), unwanted) {
+			t.Errorf("unexpected %q in %v", unwanted, err)
+		}
+	}
+}
+
+func TestValidate_MCPConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp.json")
+	if err := os.WriteFile(path, []byte(`{"mcpServers":{"github":{"type":"http","url":"https://example.com/mcp"}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{Claude: ClaudeConfig{
+		MCPConfigPath: path,
+		MCPServers:    map[string]MCPServerConfig{"github": {Command: "gh"}, "muster": {Command: "muster"}},
+	}}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "claude.mcpServers.github: server is also defined in "+path) || strings.Contains(err.Error(), "muster") {
+		t.Errorf("expected a duplicate server error for github only, got %v", err)
+	}
+
+	// serve cannot start with an MCP config it cannot read, so neither
+	// does the config validate.
+	cfg = Config{Claude: ClaudeConfig{MCPConfigPath: filepath.Join(t.TempDir(), "missing.json")}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "claude.mcpConfigPath") {
+		t.Errorf("expected an unreadable MCP config to be rejected, got %v", err)
+	}
+}
+
+func TestValidate_OAuth(t *testing.T) {
+	disabled := false
+	cfg := Config{OAuth: OAuthFileConfig{
+		Enabled:  true,
+		BaseURL:  "klaus.example.com",
+		Provider: "dex",
+		Dex:      DexConfig{IssuerURL: "https://dex.example.com", ClientID: "klaus"},
+		Security: SecurityFileConfig{EnableCIMD: &disabled},
+		TLS:      TLSFileConfig{CertFile: "/tls/cert.pem"},
+	}}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"oauth.baseURL",
+		`oauth.dex.clientSecret is required when oauth.provider is "dex"`,
+		"oauth.security.registrationToken is required",
+		"oauth.tls.certFile and oauth.tls.keyFile must be set together",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+
+	cfg.OAuth.BaseURL = "https://klaus.example.com"
+	cfg.OAuth.Dex.ClientSecret = "secret"
+	cfg.OAuth.Security.RegistrationToken = "token"
+	cfg.OAuth.TLS.KeyFile = "/tls/key.pem"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected complete OAuth settings to be valid, got %v", err)
+	}
+
+	cfg.OAuth.Provider = ""
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "oauth.provider: invalid value") {
+		t.Errorf("expected provider error, got %v", err)
+	}
+
+	// Incomplete settings are fine while OAuth is disabled.
+	if err := (&Config{OAuth: OAuthFileConfig{Provider: "google"}}).Validate(); err != nil {
+		t.Errorf("unexpected error with OAuth disabled: %v", err)
+	}
+}
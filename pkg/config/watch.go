@@ -0,0 +1,208 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ReloadEvent describes one attempt to apply changed configuration files.
+type ReloadEvent struct {
+	Time time.Time `json:"time"`
+	// Hash identifies the file contents the reload read, see HashFiles.
+	Hash string `json:"hash"`
+	// Applied is false when the reload was rejected, e.g. because the
+	// config did not validate; the last good config stays in effect.
+	Applied bool   `json:"applied"`
+	Error   string `json:"error,omitempty"`
+	// RestartRequired lists changed settings that only take effect after
+	// klaus restarts.
+	RestartRequired []string `json:"restart_required,omitempty"`
+}
+
+// ReloadStatus reports the configuration in effect and the last reload.
+type ReloadStatus struct {
+	// Hash identifies the file contents of the configuration in effect.
+	Hash string `json:"hash"`
+	// LoadedAt is when the configuration in effect was loaded.
+	LoadedAt time.Time `json:"loaded_at"`
+	// Reloads counts the applied reloads.
+	Reloads   int          `json:"reloads"`
+	LastEvent *ReloadEvent `json:"last_event,omitempty"`
+}
+
+// ApplyResult is the outcome of an applied reload.
+type ApplyResult struct {
+	// RestartRequired lists changed settings that only take effect after
+	// klaus restarts.
+	RestartRequired []string
+	// Paths are the files to watch from now on, e.g. the secret files the
+	// reloaded config names. Nil keeps the current paths.
+	Paths []string
+}
+
+// ApplyFunc loads and applies the configuration after the watched files
+// changed. It returns the result of the reload, or an error to reject it.
+type ApplyFunc func() (ApplyResult, error)
+
+// Watcher polls configuration files and applies them when their content
+// changes. Polling, rather than file system notifications, also follows
+// the symlink swaps Kubernetes uses to update mounted ConfigMaps.
+type Watcher struct {
+	// paths is only accessed by check and NewWatcher, so it needs no lock.
+	paths    []string
+	interval time.Duration
+	apply    ApplyFunc
+
+	mu     sync.Mutex
+	status ReloadStatus
+	// seen is the hash of the contents last acted on, so that a rejected
+	// config is not re-applied on every poll.
+	seen string
+}
+
+// NewWatcher returns a Watcher for paths, whose current contents are the
+// configuration in effect. Missing paths are watched for being created.
+func NewWatcher(paths []string, interval time.Duration, apply ApplyFunc) (*Watcher, error) {
+	hash, err := HashFiles(paths...)
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{
+		paths:    paths,
+		interval: interval,
+		apply:    apply,
+		status:   ReloadStatus{Hash: hash, LoadedAt: time.Now().UTC()},
+		seen:     hash,
+	}, nil
+}
+
+// Run polls the files until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// Status returns the configuration in effect and the last reload.
+func (w *Watcher) Status() ReloadStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	status := w.status
+	if status.LastEvent != nil {
+		event := *status.LastEvent
+		status.LastEvent = &event
+	}
+	return status
+}
+
+// check applies the files once if their contents changed.
+func (w *Watcher) check() {
+	hash, err := HashFiles(w.paths...)
+	if err != nil {
+		slog.Warn("config reload: cannot read watched files", "error", err)
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if hash == w.seen {
+		return
+	}
+	w.seen = hash
+	if hash == w.status.Hash {
+		// Changed back to the configuration in effect.
+		return
+	}
+
+	event := ReloadEvent{Time: time.Now().UTC(), Hash: hash}
+	result, err := w.apply()
+	if err != nil {
+		event.Error = err.Error()
+		slog.Error("config reload rejected, keeping the last good config", "hash", hash, "error", err)
+	} else {
+		event.Applied = true
+		event.RestartRequired = result.RestartRequired
+		w.status.Hash = hash
+		w.status.LoadedAt = event.Time
+		w.status.Reloads++
+		slog.Info("config reloaded", "hash", hash)
+		if len(result.RestartRequired) > 0 {
+			slog.Warn("config reload: changed settings take effect after a restart", "settings", result.RestartRequired)
+		}
+		if result.Paths != nil && !slices.Equal(result.Paths, w.paths) {
+			w.setPaths(result.Paths)
+		}
+	}
+	w.status.LastEvent = &event
+}
+
+// setPaths watches paths from now on. Their current contents are the
+// configuration in effect, so that the change of paths alone does not
+// trigger another reload. The caller must hold w.mu.
+func (w *Watcher) setPaths(paths []string) {
+	hash, err := HashFiles(paths...)
+	if err != nil {
+		slog.Warn("config reload: cannot read the new watched files, keeping the old ones", "error", err)
+		return
+	}
+	slog.Info("config reload: watched files changed", "paths", paths)
+	w.paths = paths
+	w.status.Hash = hash
+	w.seen = hash
+}
+
+// HashFiles returns a SHA-256 hash over the names and contents of paths.
+// Missing files hash differently from empty ones. A directory hashes as
+// its *.yaml files, so that adding or removing one changes the hash.
+func HashFiles(paths ...string) (string, error) {
+	h := sha256.New()
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			files, err := yamlFiles(path)
+			if err != nil {
+				return "", err
+			}
+			_, _ = fmt.Fprintf(h, "%s\x00dir\x00%d\x00", path, len(files))
+			if err := hashFiles(h, files); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if err := hashFiles(h, []string{path}); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFiles(h io.Writer, paths []string) error {
+	for _, path := range paths {
+		data, err := os.ReadFile(path) //#nosec G304 -- operator-provided config path
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			_, _ = fmt.Fprintf(h, "%s\x00missing\x00", path)
+		case err != nil:
+			return err
+		default:
+			_, _ = fmt.Fprintf(h, "%s\x00%d\x00", path, len(data))
+			_, _ = h.Write(data)
+		}
+	}
+	return nil
+}
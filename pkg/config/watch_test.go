@@ -0,0 +1,156 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHashFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	missing, err := HashFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	empty, err := HashFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing == empty {
+		t.Error("expected a missing file to hash differently from an empty one")
+	}
+	again, _ := HashFiles(path)
+	if again != empty {
+		t.Error("expected a stable hash")
+	}
+}
+
+func TestHashFiles_Directory(t *testing.T) {
+	dir := t.TempDir()
+	before, err := HashFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if ignored, _ := HashFiles(dir); ignored != before {
+		t.Error("expected files other than *.yaml to be ignored")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "10-model.yaml"), []byte("claude: {}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if added, _ := HashFiles(dir); added == before {
+		t.Error("expected an added file to change the hash")
+	}
+}
+
+func TestWatcher_AppliesAndRejects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("good: 1"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var applyErr error
+	applied := 0
+	w, err := NewWatcher([]string{path}, time.Hour, func() (ApplyResult, error) {
+		if applyErr != nil {
+			return ApplyResult{}, applyErr
+		}
+		applied++
+		return ApplyResult{RestartRequired: []string{"server.port"}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initial := w.Status()
+
+	// No change, nothing applied.
+	w.check()
+	if applied != 0 || w.Status().LastEvent != nil {
+		t.Fatalf("expected no reload without changes: %+v", w.Status())
+	}
+
+	if err := os.WriteFile(path, []byte("good: 2"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	w.check()
+	status := w.Status()
+	if applied != 1 || status.Reloads != 1 || status.Hash == initial.Hash {
+		t.Fatalf("expected the change to be applied: %+v", status)
+	}
+	if ev := status.LastEvent; ev == nil || !ev.Applied || len(ev.RestartRequired) != 1 {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+	good := status.Hash
+
+	// A rejected reload keeps the last good config and is not retried
+	// until the files change again.
+	applyErr = errors.New("invalid")
+	if err := os.WriteFile(path, []byte("bad"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	w.check()
+	w.check()
+	status = w.Status()
+	if status.Hash != good || status.Reloads != 1 {
+		t.Errorf("expected the last good config to stay in effect: %+v", status)
+	}
+	if ev := status.LastEvent; ev == nil || ev.Applied || ev.Error != "invalid" || ev.Hash == good {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+}
+
+func TestWatcher_RefreshesPaths(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	oldSecret := filepath.Join(dir, "old-secret")
+	newSecret := filepath.Join(dir, "new-secret")
+	for _, p := range []string{path, oldSecret, newSecret} {
+		if err := os.WriteFile(p, []byte("1"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	applied := 0
+	w, err := NewWatcher([]string{path, oldSecret}, time.Hour, func() (ApplyResult, error) {
+		applied++
+		return ApplyResult{Paths: []string{path, newSecret}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The config switches to the new secret file.
+	if err := os.WriteFile(path, []byte("2"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	w.check()
+	w.check()
+	if applied != 1 {
+		t.Fatalf("expected one reload, got %d", applied)
+	}
+
+	// The old secret file is no longer watched, the new one is.
+	if err := os.WriteFile(oldSecret, []byte("2"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	w.check()
+	if applied != 1 {
+		t.Errorf("expected the old secret file to be ignored, got %d reloads", applied)
+	}
+	if err := os.WriteFile(newSecret, []byte("2"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	w.check()
+	if applied != 2 {
+		t.Errorf("expected the new secret file to trigger a reload, got %d reloads", applied)
+	}
+}
@@ -0,0 +1,196 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"github.com/giantswarm/klaus/pkg/claude"
+	"github.com/giantswarm/klaus/pkg/config"
+)
+
+// credentialEnvVars are the environment variables the CLI authenticates
+// with, directly or by selecting a cloud provider.
+var credentialEnvVars = []string{
+	"ANTHROPIC_API_KEY",
+	"ANTHROPIC_AUTH_TOKEN",
+	"CLAUDE_CODE_OAUTH_TOKEN",
+	"CLAUDE_CODE_USE_BEDROCK",
+	"CLAUDE_CODE_USE_VERTEX",
+}
+
+// Checks returns the configuration checks for klaus running with cfg and
+// the claude options derived from it.
+func Checks(cfg config.Config, opts claude.Options) []Check {
+	return []Check{
+		{Name: "claude_binary", Run: func(ctx context.Context) (string, error) { return checkBinary(ctx, opts) }},
+		{Name: "workspace", Run: func(context.Context) (string, error) { return checkWorkspace(opts.WorkDir) }},
+		{Name: "mcp_config", Run: func(ctx context.Context) (string, error) { return checkMCPConfig(ctx, opts.MCPConfigPath) }},
+		{Name: "plugin_dirs", Run: func(context.Context) (string, error) { return checkDirs(opts.PluginDirs) }},
+		{Name: "add_dirs", Run: func(context.Context) (string, error) { return checkDirs(opts.AddDirs) }},
+		{Name: "agents", Run: func(context.Context) (string, error) { return checkAgents(cfg.Claude.Agents) }},
+		{Name: "credentials", Run: func(context.Context) (string, error) { return checkCredentials(os.Getenv, claude.ConfigDir()) }},
+	}
+}
+
+// checkBinary resolves the claude binary and reports its version. An
+// unknown version is only a warning, as klaus then assumes all
+// capabilities.
+func checkBinary(ctx context.Context, opts claude.Options) (string, error) {
+	path, err := exec.LookPath(opts.BinaryName())
+	if err != nil {
+		return "", err
+	}
+	if opts.CLIVersion != nil {
+		return fmt.Sprintf("%s %s", path, opts.CLIVersion), nil
+	}
+	v, err := claude.DetectCLIVersion(ctx, opts)
+	if err != nil {
+		return path, Warn(fmt.Errorf("version unknown: %w", err))
+	}
+	return fmt.Sprintf("%s %s", path, v), nil
+}
+
+// checkWorkspace verifies that the workspace is a writable directory. An
+// empty workDir means the current directory.
+func checkWorkspace(workDir string) (string, error) {
+	if workDir == "" {
+		var err error
+		if workDir, err = os.Getwd(); err != nil {
+			return "", err
+		}
+	}
+	info, err := os.Stat(workDir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", workDir)
+	}
+	f, err := os.CreateTemp(workDir, ".klaus-doctor-*")
+	if err != nil {
+		return "", fmt.Errorf("%s is not writable: %w", workDir, err)
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+	return workDir, nil
+}
+
+// checkMCPConfig parses the MCP config file and resolves every server: the
+// command of stdio servers must be on PATH and the host of HTTP servers
+// must resolve. ${VAR} references are expanded like the CLI does. Hosts
+// that do not resolve are only a warning, so that an unavailable external
+// server does not take klaus out of service.
+func checkMCPConfig(ctx context.Context, path string) (string, error) {
+	if path == "" {
+		return "not configured", nil
+	}
+	servers, err := claude.ReadMCPConfig(path)
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs, warnings []error
+	for _, name := range names {
+		err := checkMCPServer(ctx, servers[name])
+		switch {
+		case err == nil:
+		case errors.As(err, new(warning)):
+			warnings = append(warnings, fmt.Errorf("server %q: %w", name, err))
+		default:
+			errs = append(errs, fmt.Errorf("server %q: %w", name, err))
+		}
+	}
+	detail := fmt.Sprintf("%d servers", len(names))
+	if err := errors.Join(append(errs, warnings...)...); err != nil {
+		if len(errs) == 0 {
+			return detail, Warn(err)
+		}
+		return detail, err
+	}
+	return detail, nil
+}
+
+func checkMCPServer(ctx context.Context, server claude.MCPServer) error {
+	switch {
+	case server.Command != "":
+		_, err := exec.LookPath(os.ExpandEnv(server.Command))
+		return err
+	case server.URL != "":
+		u, err := url.Parse(os.ExpandEnv(server.URL))
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" || u.Hostname() == "" {
+			return fmt.Errorf("invalid url %q", server.URL)
+		}
+		if _, err := net.DefaultResolver.LookupHost(ctx, u.Hostname()); err != nil {
+			return Warn(err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("neither command nor url set (type %q)", server.Type)
+	}
+}
+
+// checkDirs verifies that every directory exists.
+func checkDirs(dirs []string) (string, error) {
+	if len(dirs) == 0 {
+		return "none configured", nil
+	}
+	var errs []error
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		switch {
+		case err != nil:
+			errs = append(errs, err)
+		case !info.IsDir():
+			errs = append(errs, fmt.Errorf("%s is not a directory", dir))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d directories", len(dirs)), nil
+}
+
+// checkAgents parses the subagent definitions and reports how many there
+// are. Their fields are checked by config.Validate.
+func checkAgents(agentsJSON string) (string, error) {
+	if agentsJSON == "" {
+		return "none configured", nil
+	}
+	var agents map[string]claude.AgentConfig
+	if err := json.Unmarshal([]byte(agentsJSON), &agents); err != nil {
+		return "", fmt.Errorf("parsing claude.agents: %w", err)
+	}
+	return fmt.Sprintf("%d agents", len(agents)), nil
+}
+
+// checkCredentials looks for credentials the CLI can authenticate with.
+// Their absence is only a warning, as settings (e.g. apiKeyHelper) can
+// provide them too.
+func checkCredentials(getenv func(string) string, configDir string) (string, error) {
+	for _, key := range credentialEnvVars {
+		if getenv(key) != "" {
+			return key, nil
+		}
+	}
+	path := filepath.Join(configDir, ".credentials.json")
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	return "", Warn(errors.New("no credentials found: set ANTHROPIC_API_KEY or log in with the claude CLI"))
+}
@@ -0,0 +1,156 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	if detail, err := checkWorkspace(dir); err != nil || detail != dir {
+		t.Errorf("writable workspace: %q, %v", detail, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("expected the test file to be removed, found %d entries", len(entries))
+	}
+
+	if _, err := checkWorkspace(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing workspace")
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkWorkspace(file); err == nil || !strings.Contains(err.Error(), "not a directory") {
+		t.Errorf("expected not a directory error, got %v", err)
+	}
+}
+
+func writeMCPConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mcp.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckMCPConfig(t *testing.T) {
+	t.Setenv("MCP_TEST_COMMAND", "go")
+
+	tests := []struct {
+		name    string
+		config  string
+		status  string
+		errPart string
+	}{
+		{
+			name:   "valid",
+			config: `{"mcpServers":{"a":{"command":"${MCP_TEST_COMMAND}"}}}`,
+			status: StatusOK,
+		},
+		{
+			name:    "missing command",
+			config:  `{"mcpServers":{"a":{"command":"klaus-does-not-exist"}}}`,
+			status:  StatusFail,
+			errPart: `server "a"`,
+		},
+		{
+			name:    "invalid url",
+			config:  `{"mcpServers":{"a":{"type":"http","url":"ftp://example"}}}`,
+			status:  StatusFail,
+			errPart: "invalid url",
+		},
+		{
+			name:    "neither command nor url",
+			config:  `{"mcpServers":{"a":{"type":"sse"}}}`,
+			status:  StatusFail,
+			errPart: "neither command nor url",
+		},
+		{
+			name:    "parse error",
+			config:  `{"mcpServers":`,
+			status:  StatusFail,
+			errPart: "parsing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := checkMCPConfig(context.Background(), writeMCPConfig(t, tt.config))
+			if got := statusOf(err); got != tt.status {
+				t.Errorf("expected status %s, got %s (%v)", tt.status, got, err)
+			}
+			if tt.errPart != "" && (err == nil || !strings.Contains(err.Error(), tt.errPart)) {
+				t.Errorf("expected error containing %q, got %v", tt.errPart, err)
+			}
+		})
+	}
+}
+
+func TestCheckMCPConfig_NotConfigured(t *testing.T) {
+	detail, err := checkMCPConfig(context.Background(), "")
+	if err != nil || detail != "not configured" {
+		t.Errorf("got %q, %v", detail, err)
+	}
+}
+
+func TestCheckDirs(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := checkDirs([]string{dir}); err != nil {
+		t.Errorf("existing directory: %v", err)
+	}
+	if _, err := checkDirs([]string{dir, filepath.Join(dir, "missing")}); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestCheckAgents(t *testing.T) {
+	if detail, err := checkAgents(`{"reviewer":{"description":"d","prompt":"p"}}`); err != nil || detail != "1 agents" {
+		t.Errorf("valid agents: %q, %v", detail, err)
+	}
+	if _, err := checkAgents(`{"reviewer":`); err == nil {
+		t.Error("expected an error for invalid agents JSON")
+	}
+}
+
+func TestCheckCredentials(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+	configDir := t.TempDir()
+
+	if _, err := checkCredentials(getenv, configDir); statusOf(err) != StatusWarn {
+		t.Errorf("expected a warning without credentials, got %v", err)
+	}
+
+	env["ANTHROPIC_API_KEY"] = "sk-test"
+	if detail, err := checkCredentials(getenv, configDir); err != nil || detail != "ANTHROPIC_API_KEY" {
+		t.Errorf("api key: %q, %v", detail, err)
+	}
+
+	delete(env, "ANTHROPIC_API_KEY")
+	path := filepath.Join(configDir, ".credentials.json")
+	if err := os.WriteFile(path, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if detail, err := checkCredentials(getenv, configDir); err != nil || detail != path {
+		t.Errorf("credentials file: %q, %v", detail, err)
+	}
+}
+
+// statusOf maps a check error to the status Run reports for it.
+func statusOf(err error) string {
+	switch {
+	case err == nil:
+		return StatusOK
+	case errors.As(err, new(warning)):
+		return StatusWarn
+	default:
+		return StatusFail
+	}
+}
@@ -0,0 +1,186 @@
+// Package doctor implements the readiness checks behind /readyz?verbose and
+// `klaus doctor`. Checks catch misconfigurations, such as a missing claude
+// binary or an unparsable MCP config, before the first prompt runs into
+// them.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Check outcomes.
+const (
+	// StatusOK means the check passed.
+	StatusOK = "ok"
+	// StatusWarn means the check found a problem that does not prevent
+	// klaus from serving prompts, e.g. because it may be solved in a way
+	// the check cannot see.
+	StatusWarn = "warn"
+	// StatusFail means the check found a problem that makes prompts fail.
+	StatusFail = "fail"
+)
+
+// DefaultCheckTimeout bounds the run time of a single check.
+const DefaultCheckTimeout = 10 * time.Second
+
+// Check is a named readiness probe. Run returns a short human-readable
+// detail on success, or an error describing the problem; wrap the error
+// with Warn for problems that should not fail readiness.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (string, error)
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Report is the outcome of a set of checks.
+type Report struct {
+	// Ready is false when at least one check failed.
+	Ready     bool      `json:"ready"`
+	Checks    []Result  `json:"checks"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// warning marks a check error as not failing readiness.
+type warning struct{ err error }
+
+func (w warning) Error() string { return w.err.Error() }
+func (w warning) Unwrap() error { return w.err }
+
+// Warn marks err as a warning: the check reports StatusWarn instead of
+// StatusFail.
+func Warn(err error) error {
+	if err == nil {
+		return nil
+	}
+	return warning{err: err}
+}
+
+// Run runs the checks concurrently, each bounded by DefaultCheckTimeout,
+// and reports their results in the order of checks.
+func Run(ctx context.Context, checks []Check) Report {
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Ready: true, Checks: results, CheckedAt: time.Now().UTC()}
+	for _, r := range results {
+		if r.Status == StatusFail {
+			report.Ready = false
+		}
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, c Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, DefaultCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := c.Run(ctx)
+	result := Result{
+		Name:       c.Name,
+		Status:     StatusOK,
+		Detail:     detail,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = StatusFail
+		if errors.As(err, new(warning)) {
+			result.Status = StatusWarn
+		}
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Runner runs a fixed set of checks and caches the report, so that frequent
+// readiness probes do not re-run expensive checks such as executing the
+// claude binary.
+type Runner struct {
+	ttl time.Duration
+
+	// mu guards the fields below. It is not held while the checks run.
+	mu     sync.Mutex
+	checks []Check
+	report *Report
+	// running is the run in progress, shared by concurrent callers; nil
+	// when none is.
+	running *runnerCall
+	// generation counts SetChecks calls, so that a run of replaced checks
+	// does not cache its report.
+	generation int
+}
+
+// runnerCall is a run of the checks; done is closed once report is set.
+type runnerCall struct {
+	done   chan struct{}
+	report Report
+}
+
+// NewRunner returns a Runner that re-runs checks at most once per ttl.
+func NewRunner(checks []Check, ttl time.Duration) *Runner {
+	return &Runner{checks: checks, ttl: ttl}
+}
+
+// Run returns the cached report, running the checks first if it is older
+// than the runner's ttl. Concurrent callers share one run. The shared run
+// is not cancelled with ctx, so that one caller giving up does not fail
+// the report of the others.
+func (r *Runner) Run(ctx context.Context) Report {
+	r.mu.Lock()
+	if r.report != nil && time.Since(r.report.CheckedAt) < r.ttl {
+		report := *r.report
+		r.mu.Unlock()
+		return report
+	}
+	if call := r.running; call != nil {
+		r.mu.Unlock()
+		<-call.done
+		return call.report
+	}
+	call := &runnerCall{done: make(chan struct{})}
+	r.running = call
+	checks, generation := r.checks, r.generation
+	r.mu.Unlock()
+
+	call.report = Run(context.WithoutCancel(ctx), checks)
+
+	r.mu.Lock()
+	if r.generation == generation {
+		r.report = &call.report
+		r.running = nil
+	}
+	r.mu.Unlock()
+	close(call.done)
+	return call.report
+}
+
+// SetChecks replaces the checks, e.g. after the configuration was
+// reloaded, and discards the cached report. A run of the old checks that
+// is in progress completes for its callers, but its report is not cached.
+func (r *Runner) SetChecks(checks []Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = checks
+	r.report = nil
+	r.running = nil
+	r.generation++
+}
@@ -0,0 +1,162 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun_Statuses(t *testing.T) {
+	report := Run(context.Background(), []Check{
+		{Name: "ok", Run: func(context.Context) (string, error) { return "fine", nil }},
+		{Name: "warn", Run: func(context.Context) (string, error) { return "", Warn(errors.New("meh")) }},
+		{Name: "fail", Run: func(context.Context) (string, error) { return "", errors.New("broken") }},
+	})
+
+	if report.Ready {
+		t.Error("expected report not to be ready with a failed check")
+	}
+	want := []struct{ name, status, detail, err string }{
+		{"ok", StatusOK, "fine", ""},
+		{"warn", StatusWarn, "", "meh"},
+		{"fail", StatusFail, "", "broken"},
+	}
+	if len(report.Checks) != len(want) {
+		t.Fatalf("expected %d results, got %d", len(want), len(report.Checks))
+	}
+	for i, w := range want {
+		r := report.Checks[i]
+		if r.Name != w.name || r.Status != w.status || r.Detail != w.detail || r.Error != w.err {
+			t.Errorf("result %d: got %+v, want %+v", i, r, w)
+		}
+	}
+}
+
+func TestRun_WarningsStayReady(t *testing.T) {
+	report := Run(context.Background(), []Check{
+		{Name: "warn", Run: func(context.Context) (string, error) { return "", Warn(errors.New("meh")) }},
+	})
+	if !report.Ready {
+		t.Error("expected warnings not to fail readiness")
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	report := Run(context.Background(), []Check{
+		{Name: "deadline", Run: func(ctx context.Context) (string, error) {
+			if _, ok := ctx.Deadline(); !ok {
+				return "", errors.New("no deadline")
+			}
+			return "", nil
+		}},
+	})
+	if report.Checks[0].Status != StatusOK {
+		t.Errorf("expected checks to run with a deadline: %+v", report.Checks[0])
+	}
+}
+
+func TestWarn_Nil(t *testing.T) {
+	if Warn(nil) != nil {
+		t.Error("Warn(nil) should be nil")
+	}
+}
+
+func TestRunner_CachesReport(t *testing.T) {
+	var runs atomic.Int32
+	checks := []Check{{Name: "count", Run: func(context.Context) (string, error) {
+		runs.Add(1)
+		return "", nil
+	}}}
+
+	r := NewRunner(checks, time.Hour)
+	r.Run(context.Background())
+	r.Run(context.Background())
+	if got := runs.Load(); got != 1 {
+		t.Errorf("expected checks to run once within the ttl, ran %d times", got)
+	}
+
+	r = NewRunner(checks, 0)
+	runs.Store(0)
+	r.Run(context.Background())
+	r.Run(context.Background())
+	if got := runs.Load(); got != 2 {
+		t.Errorf("expected checks to re-run after the ttl, ran %d times", got)
+	}
+}
+
+func TestRunner_SharesRunInProgress(t *testing.T) {
+	var runs atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	r := NewRunner([]Check{{Name: "slow", Run: func(ctx context.Context) (string, error) {
+		if runs.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return "", ctx.Err()
+	}}}, time.Hour)
+
+	reports := make(chan Report, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { reports <- r.Run(ctx) }()
+	<-started
+	// Callers arriving during the run join it, and the caller that started
+	// it giving up does not fail it.
+	go func() { reports <- r.Run(context.Background()) }()
+	go func() { reports <- r.Run(context.Background()) }()
+	cancel()
+	close(release)
+	for range 3 {
+		if report := <-reports; !report.Ready {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	}
+	if got := runs.Load(); got != 1 {
+		t.Errorf("expected concurrent callers to share one run, ran %d times", got)
+	}
+}
+
+func TestRunner_SetChecksDuringRun(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	r := NewRunner([]Check{{Name: "old", Run: func(context.Context) (string, error) {
+		close(started)
+		<-release
+		return "", nil
+	}}}, time.Hour)
+
+	old := make(chan Report, 1)
+	go func() { old <- r.Run(context.Background()) }()
+	<-started
+
+	// The lock is not held while the checks run, so replacing them does
+	// not wait for the run.
+	replaced := make(chan struct{})
+	go func() {
+		r.SetChecks([]Check{{Name: "new", Run: func(context.Context) (string, error) { return "", nil }}})
+		close(replaced)
+	}()
+	select {
+	case <-replaced:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SetChecks blocked on the running checks")
+	}
+	close(release)
+	if report := <-old; len(report.Checks) != 1 || report.Checks[0].Name != "old" {
+		t.Errorf("expected the running caller to get the old report: %+v", report)
+	}
+	if report := r.Run(context.Background()); len(report.Checks) != 1 || report.Checks[0].Name != "new" {
+		t.Errorf("expected the report of the old checks not to be cached: %+v", report)
+	}
+}
+
+func TestRunner_SetChecks(t *testing.T) {
+	r := NewRunner([]Check{{Name: "old", Run: func(context.Context) (string, error) { return "", nil }}}, time.Hour)
+	r.Run(context.Background())
+
+	r.SetChecks([]Check{{Name: "new", Run: func(context.Context) (string, error) { return "", errors.New("broken") }}})
+	report := r.Run(context.Background())
+	if report.Ready || len(report.Checks) != 1 || report.Checks[0].Name != "new" {
+		t.Errorf("expected the new checks to run despite the cached report: %+v", report)
+	}
+}
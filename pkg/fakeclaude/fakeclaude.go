@@ -0,0 +1,480 @@
+// Package fakeclaude implements a scriptable stand-in for the Claude Code
+// CLI. It accepts the flags klaus passes to the real binary and emits
+// stream-json (in the nested 2.1+ shape) from a scenario YAML, so that
+// klaus and the services built on it can be tested end to end without an
+// API key or network access.
+//
+// It is exposed as `klaus fake-claude`; point klaus at it with
+// claude.binary and claude.binaryArgs.
+package fakeclaude
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ScenarioEnv names the environment variable holding the scenario path when
+// --scenario is not given.
+const ScenarioEnv = "KLAUS_FAKE_CLAUDE_SCENARIO"
+
+const (
+	defaultSessionID = "00000000-0000-4000-8000-000000000000"
+	defaultModel     = "fake-model"
+	streamJSONFormat = "stream-json"
+)
+
+// boolFlags and valueFlags are the CLI flags the fake accepts. Unknown flags
+// are rejected like the real CLI does, so typos in klaus surface in tests.
+var (
+	boolFlags = map[string]bool{
+		"--print": true, "-p": true, "--verbose": true, "--version": true, "-v": true,
+		"--include-partial-messages": true, "--replay-user-messages": true,
+		"--dangerously-skip-permissions": true, "--strict-mcp-config": true,
+		"--no-session-persistence": true, "--continue": true, "-c": true, "--fork-session": true,
+	}
+	valueFlags = map[string]bool{
+		"--output-format": true, "--input-format": true, "--model": true, "--fallback-model": true,
+		"--system-prompt": true, "--append-system-prompt": true, "--max-turns": true,
+		"--permission-mode": true, "--mcp-config": true, "--allowedTools": true,
+		"--disallowedTools": true, "--tools": true, "--max-budget-usd": true, "--effort": true,
+		"--agents": true, "--agent": true, "--json-schema": true, "--settings": true,
+		"--setting-sources": true, "--plugin-dir": true, "--add-dir": true,
+		"--session-id": true, "--resume": true, "-r": true,
+		// --scenario is specific to the fake.
+		"--scenario": true,
+	}
+)
+
+// invocation holds the parsed command line.
+type invocation struct {
+	flags  map[string][]string
+	prompt string
+}
+
+func (inv invocation) has(name string) bool {
+	_, ok := inv.flags[name]
+	return ok
+}
+
+func (inv invocation) value(name string) string {
+	if v := inv.flags[name]; len(v) > 0 {
+		return v[len(v)-1]
+	}
+	return ""
+}
+
+func parseArgs(args []string) (invocation, error) {
+	inv := invocation{flags: make(map[string][]string)}
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			positional = append(positional, arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch {
+		case boolFlags[name] && !hasValue:
+			inv.flags[name] = append(inv.flags[name], "true")
+		case valueFlags[name]:
+			if !hasValue {
+				if i+1 >= len(args) {
+					return inv, fmt.Errorf("option '%s' argument missing", name)
+				}
+				i++
+				value = args[i]
+			}
+			inv.flags[name] = append(inv.flags[name], value)
+		default:
+			return inv, fmt.Errorf("unknown option '%s'", name)
+		}
+	}
+	inv.prompt = strings.Join(positional, " ")
+	return inv, nil
+}
+
+// exitError ends Main with a specific exit code.
+type exitError struct {
+	code int
+}
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", e.code) }
+
+// Main runs the fake CLI with the given arguments (without the program
+// name) and returns the process exit code.
+func Main(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	inv, err := parseArgs(args)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	path := inv.value("--scenario")
+	if path == "" {
+		path = os.Getenv(ScenarioEnv)
+	}
+	scenario, err := LoadScenario(path)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if inv.has("--version") || inv.has("-v") {
+		_, _ = fmt.Fprintf(stdout, "%s (Claude Code)\n", scenario.Version)
+		return 0
+	}
+
+	if err := run(scenario, inv, stdin, stdout, stderr); err != nil {
+		var exit exitError
+		if errors.As(err, &exit) {
+			return exit.code
+		}
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func run(scenario *Scenario, inv invocation, stdin io.Reader, stdout, stderr io.Writer) error {
+	outputFormat := inv.value("--output-format")
+	if outputFormat == "" {
+		outputFormat = "text"
+	}
+	if outputFormat != streamJSONFormat && outputFormat != "text" && outputFormat != "json" {
+		return fmt.Errorf("invalid --output-format %q", outputFormat)
+	}
+
+	e := &emitter{
+		out:       bufio.NewWriter(stdout),
+		stderr:    stderr,
+		format:    outputFormat,
+		partial:   inv.has("--include-partial-messages"),
+		sessionID: firstNonEmpty(scenario.SessionID, inv.value("--session-id"), inv.value("--resume"), inv.value("-r"), defaultSessionID),
+		model:     firstNonEmpty(scenario.Model, inv.value("--model"), defaultModel),
+	}
+
+	if inv.value("--input-format") != streamJSONFormat {
+		if inv.prompt == "" {
+			return errors.New("input must be provided either through stdin or as a prompt argument when using --print")
+		}
+		return e.respond(scenario.respond(inv.prompt))
+	}
+
+	// Bidirectional mode: one user message per stdin line, answered in
+	// turn until stdin is closed.
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		if handled, err := e.control(line); handled || err != nil {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		prompt, ok := parseUserMessage(line)
+		if !ok {
+			continue
+		}
+		if inv.has("--replay-user-messages") {
+			if err := e.emitRaw(line); err != nil {
+				return err
+			}
+		}
+		if strings.HasPrefix(prompt, "/compact") {
+			if err := e.compact(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := e.respond(scenario.respond(prompt)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// parseUserMessage extracts the prompt text of a stream-json user message.
+func parseUserMessage(line []byte) (string, bool) {
+	var msg struct {
+		Type    string `json:"type"`
+		Message struct {
+			Content json.RawMessage `json:"content"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(line, &msg); err != nil || msg.Type != "user" {
+		return "", false
+	}
+	var text string
+	if err := json.Unmarshal(msg.Message.Content, &text); err == nil {
+		return text, true
+	}
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(msg.Message.Content, &blocks); err != nil {
+		return "", false
+	}
+	var parts []string
+	for _, b := range blocks {
+		if b.Type == "text" {
+			parts = append(parts, b.Text)
+		}
+	}
+	return strings.Join(parts, "\n"), true
+}
+
+// control answers a stream-json control request. It reports false when the
+// line is not a control request.
+func (e *emitter) control(line []byte) (bool, error) {
+	var req struct {
+		Type      string         `json:"type"`
+		RequestID string         `json:"request_id"`
+		Request   map[string]any `json:"request"`
+	}
+	if err := json.Unmarshal(line, &req); err != nil || req.Type != "control_request" {
+		return false, nil
+	}
+	response := map[string]any{"subtype": "success", "request_id": req.RequestID}
+	switch req.Request["subtype"] {
+	case "set_model":
+		if model, ok := req.Request["model"].(string); ok && model != "" {
+			e.model = model
+		}
+	case "set_permission_mode", "interrupt":
+	case "get_context_usage":
+		response["response"] = map[string]any{"total_tokens": 10 * e.turns, "max_tokens": 200000}
+	default:
+		response = map[string]any{
+			"subtype":    "error",
+			"request_id": req.RequestID,
+			"error":      fmt.Sprintf("unsupported control request %v", req.Request["subtype"]),
+		}
+	}
+	// Control responses are part of the stream-json protocol and are sent
+	// regardless of the output format.
+	data, err := json.Marshal(map[string]any{"type": "control_response", "response": response})
+	if err != nil {
+		return true, err
+	}
+	if _, err := e.out.Write(append(data, '\n')); err != nil {
+		return true, err
+	}
+	return true, e.out.Flush()
+}
+
+// compact answers the /compact command like the CLI: a compact boundary
+// followed by an empty result.
+func (e *emitter) compact() error {
+	if err := e.emit(map[string]any{
+		"type":             "system",
+		"subtype":          "compact_boundary",
+		"session_id":       e.sessionID,
+		"compact_metadata": map[string]any{"trigger": "manual", "pre_tokens": 10 * e.turns},
+	}); err != nil {
+		return err
+	}
+	return e.emit(map[string]any{
+		"type":       "result",
+		"subtype":    "success",
+		"is_error":   false,
+		"result":     "",
+		"session_id": e.sessionID,
+		"num_turns":  e.turns,
+	})
+}
+
+// emitter writes the stream-json messages of the scripted responses.
+type emitter struct {
+	out       *bufio.Writer
+	stderr    io.Writer
+	format    string
+	partial   bool
+	sessionID string
+	model     string
+
+	initialized bool
+	nextID      int
+	turns       int
+}
+
+func (e *emitter) id(prefix string) string {
+	e.nextID++
+	return fmt.Sprintf("%s_fake%04d", prefix, e.nextID)
+}
+
+func (e *emitter) emit(msg map[string]any) error {
+	if e.format != streamJSONFormat {
+		return nil
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return e.emitRaw(data)
+}
+
+func (e *emitter) emitRaw(line []byte) error {
+	if e.format != streamJSONFormat {
+		return nil
+	}
+	if _, err := e.out.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return e.out.Flush()
+}
+
+func (e *emitter) assistant(content map[string]any) error {
+	return e.emit(map[string]any{
+		"type": "assistant",
+		"message": map[string]any{
+			"id":      e.id("msg"),
+			"type":    "message",
+			"role":    "assistant",
+			"model":   e.model,
+			"content": []any{content},
+			"usage":   map[string]any{"input_tokens": 10, "output_tokens": 5},
+		},
+		"session_id": e.sessionID,
+	})
+}
+
+// respond emits one scripted turn: init (once), the steps and the result.
+func (e *emitter) respond(r Response) error {
+	start := time.Now()
+	if !e.initialized {
+		e.initialized = true
+		if err := e.emit(map[string]any{
+			"type":       "system",
+			"subtype":    "init",
+			"session_id": e.sessionID,
+			"model":      e.model,
+			"tools":      []string{"Bash", "Read", "Write", "Edit", "Agent"},
+		}); err != nil {
+			return err
+		}
+	}
+	e.turns++
+
+	var lastText string
+	for _, step := range r.Steps {
+		switch {
+		case step.Delay > 0:
+			time.Sleep(step.Delay)
+		case step.Stderr != "":
+			_, _ = fmt.Fprintln(e.stderr, step.Stderr)
+		case step.ToolCall != nil:
+			if err := e.toolCall(*step.ToolCall); err != nil {
+				return err
+			}
+		case step.Text != "":
+			if e.partial {
+				if err := e.emit(map[string]any{
+					"type":       "stream_event",
+					"session_id": e.sessionID,
+					"event": map[string]any{
+						"type":  "content_block_delta",
+						"index": 0,
+						"delta": map[string]any{"type": "text_delta", "text": step.Text},
+					},
+				}); err != nil {
+					return err
+				}
+			}
+			if err := e.assistant(map[string]any{"type": "text", "text": step.Text}); err != nil {
+				return err
+			}
+			lastText = step.Text
+		}
+	}
+
+	if r.Crash != nil {
+		if r.Crash.Stderr != "" {
+			_, _ = fmt.Fprintln(e.stderr, r.Crash.Stderr)
+		}
+		code := r.Crash.ExitCode
+		if code == 0 {
+			code = 1
+		}
+		return exitError{code: code}
+	}
+
+	result := firstNonEmpty(r.Result, lastText)
+	msg := map[string]any{
+		"type":           "result",
+		"subtype":        "success",
+		"is_error":       false,
+		"result":         result,
+		"session_id":     e.sessionID,
+		"num_turns":      e.turns,
+		"duration_ms":    time.Since(start).Milliseconds(),
+		"total_cost_usd": r.CostUSD,
+		"usage":          map[string]any{"input_tokens": 10, "output_tokens": 5},
+	}
+	if r.Error != "" {
+		msg["subtype"] = "error_during_execution"
+		msg["is_error"] = true
+		msg["result"] = r.Error
+	}
+
+	switch e.format {
+	case "text":
+		_, _ = fmt.Fprintln(e.out, msg["result"])
+		return e.out.Flush()
+	case "json":
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		_, _ = e.out.Write(append(data, '\n'))
+		return e.out.Flush()
+	}
+	return e.emit(msg)
+}
+
+func (e *emitter) toolCall(tc ToolCall) error {
+	id := e.id("toolu")
+	input := tc.Input
+	if input == nil {
+		input = map[string]any{}
+	}
+	if err := e.assistant(map[string]any{"type": "tool_use", "id": id, "name": tc.Name, "input": input}); err != nil {
+		return err
+	}
+	return e.emit(map[string]any{
+		"type": "user",
+		"message": map[string]any{
+			"role": "user",
+			"content": []any{map[string]any{
+				"type":        "tool_result",
+				"tool_use_id": id,
+				"content":     tc.Result,
+				"is_error":    tc.IsError,
+			}},
+		},
+		"session_id": e.sessionID,
+	})
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
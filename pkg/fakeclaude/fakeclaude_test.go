@@ -0,0 +1,253 @@
+package fakeclaude
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testScenario = `
+version: 2.1.90
+sessionId: sess-1
+responses:
+  - match: "^crash"
+    steps:
+      - stderr: warming up
+    crash:
+      exitCode: 3
+      stderr: "fatal: boom"
+  - match: "fail"
+    error: "max turns reached"
+  - match: "tool"
+    steps:
+      - text: Running ls
+      - toolCall:
+          name: Bash
+          input: {command: ls}
+          result: a.txt
+      - delay: 1ms
+      - text: Found a.txt
+    costUSD: 0.02
+`
+
+func writeScenario(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// runFake runs Main and returns the exit code, decoded stdout messages and stderr.
+func runFake(t *testing.T, stdin string, args ...string) (int, []map[string]any, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Main(args, strings.NewReader(stdin), &stdout, &stderr)
+	var msgs []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("invalid stream-json line %q: %v", line, err)
+		}
+		msgs = append(msgs, m)
+	}
+	return code, msgs, stderr.String()
+}
+
+func types(msgs []map[string]any) string {
+	var out []string
+	for _, m := range msgs {
+		out = append(out, m["type"].(string))
+	}
+	return strings.Join(out, ",")
+}
+
+func TestMain_Version(t *testing.T) {
+	var stdout bytes.Buffer
+	if code := Main([]string{"--scenario", writeScenario(t, testScenario), "--version"}, nil, &stdout, &bytes.Buffer{}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if got := stdout.String(); got != "2.1.90 (Claude Code)\n" {
+		t.Errorf("--version = %q", got)
+	}
+
+	t.Setenv(ScenarioEnv, "")
+	stdout.Reset()
+	Main([]string{"--version"}, nil, &stdout, &bytes.Buffer{})
+	if !strings.HasPrefix(stdout.String(), DefaultVersion) {
+		t.Errorf("expected default version, got %q", stdout.String())
+	}
+}
+
+func TestMain_PrintModeToolCall(t *testing.T) {
+	code, msgs, _ := runFake(t, "", "--scenario", writeScenario(t, testScenario),
+		"--print", "--output-format", "stream-json", "--verbose", "--model", "sonnet",
+		"--allowedTools", "Bash", "--allowedTools", "mcp__x__*", "--", "use a tool")
+	if code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if got := types(msgs); got != "system,assistant,assistant,user,assistant,result" {
+		t.Fatalf("message types = %s", got)
+	}
+	if msgs[0]["session_id"] != "sess-1" {
+		t.Errorf("session_id = %v", msgs[0]["session_id"])
+	}
+	toolUse := msgs[2]["message"].(map[string]any)["content"].([]any)[0].(map[string]any)
+	if toolUse["name"] != "Bash" || toolUse["input"].(map[string]any)["command"] != "ls" {
+		t.Errorf("unexpected tool_use block %v", toolUse)
+	}
+	if model := msgs[1]["message"].(map[string]any)["model"]; model != "sonnet" {
+		t.Errorf("model = %v, want --model value", model)
+	}
+	result := msgs[len(msgs)-1]
+	if result["result"] != "Found a.txt" || result["total_cost_usd"] != 0.02 || result["is_error"] != false {
+		t.Errorf("unexpected result %v", result)
+	}
+}
+
+func TestMain_EchoWithoutScenario(t *testing.T) {
+	t.Setenv(ScenarioEnv, "")
+	code, msgs, _ := runFake(t, "", "-p", "--output-format", "stream-json", "--include-partial-messages", "--", "hi")
+	if code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if got := types(msgs); got != "system,stream_event,assistant,result" {
+		t.Fatalf("message types = %s", got)
+	}
+	if msgs[len(msgs)-1]["result"] != "You said: hi" {
+		t.Errorf("result = %v", msgs[len(msgs)-1]["result"])
+	}
+}
+
+func TestMain_ErrorResult(t *testing.T) {
+	_, msgs, _ := runFake(t, "", "--scenario", writeScenario(t, testScenario), "-p", "--output-format", "stream-json", "--", "please fail")
+	result := msgs[len(msgs)-1]
+	if result["is_error"] != true || result["subtype"] != "error_during_execution" || result["result"] != "max turns reached" {
+		t.Errorf("unexpected error result %v", result)
+	}
+}
+
+func TestMain_Crash(t *testing.T) {
+	code, msgs, stderr := runFake(t, "", "--scenario", writeScenario(t, testScenario), "-p", "--output-format", "stream-json", "--", "crash now")
+	if code != 3 {
+		t.Errorf("exit code = %d, want 3", code)
+	}
+	if got := types(msgs); got != "system" {
+		t.Errorf("expected no result after crash, got %s", got)
+	}
+	if stderr != "warming up\nfatal: boom\n" {
+		t.Errorf("stderr = %q", stderr)
+	}
+}
+
+func TestMain_StreamJSONInput(t *testing.T) {
+	t.Setenv(ScenarioEnv, writeScenario(t, testScenario))
+	stdin := `{"type":"user","message":{"role":"user","content":"hello"}}` + "\n" +
+		`{"type":"user","message":{"role":"user","content":[{"type":"text","text":"use tool"},{"type":"image","source":{}}]}}` + "\n"
+	code, msgs, _ := runFake(t, stdin, "--print", "--input-format", "stream-json", "--output-format", "stream-json",
+		"--replay-user-messages", "--verbose")
+	if code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if got := types(msgs); got != "user,system,assistant,result,user,assistant,assistant,user,assistant,result" {
+		t.Fatalf("message types = %s", got)
+	}
+	if msgs[3]["result"] != "You said: hello" || msgs[len(msgs)-1]["result"] != "Found a.txt" {
+		t.Errorf("unexpected results %v / %v", msgs[3]["result"], msgs[len(msgs)-1]["result"])
+	}
+	if msgs[len(msgs)-1]["num_turns"] != float64(2) {
+		t.Errorf("num_turns = %v", msgs[len(msgs)-1]["num_turns"])
+	}
+}
+
+func TestMain_ControlRequests(t *testing.T) {
+	t.Setenv(ScenarioEnv, "")
+	stdin := `{"type":"control_request","request_id":"r1","request":{"subtype":"set_model","model":"opus"}}` + "\n" +
+		`{"type":"user","message":{"role":"user","content":"hello"}}` + "\n" +
+		`{"type":"control_request","request_id":"r2","request":{"subtype":"get_context_usage"}}` + "\n" +
+		`{"type":"control_request","request_id":"r3","request":{"subtype":"rewind"}}` + "\n"
+	code, msgs, _ := runFake(t, stdin, "--print", "--input-format", "stream-json", "--output-format", "stream-json", "--verbose")
+	if code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if got := types(msgs); got != "control_response,system,assistant,result,control_response,control_response" {
+		t.Fatalf("message types = %s", got)
+	}
+	if msgs[1]["model"] != "opus" {
+		t.Errorf("set_model not applied: %v", msgs[1]["model"])
+	}
+	usage := msgs[4]["response"].(map[string]any)
+	if usage["request_id"] != "r2" || usage["response"] == nil {
+		t.Errorf("unexpected context usage response: %v", usage)
+	}
+	if errResp := msgs[5]["response"].(map[string]any); errResp["subtype"] != "error" {
+		t.Errorf("expected error for unsupported request, got %v", errResp)
+	}
+}
+
+func TestMain_Compact(t *testing.T) {
+	t.Setenv(ScenarioEnv, "")
+	stdin := `{"type":"user","message":{"role":"user","content":"hello"}}` + "\n" +
+		`{"type":"user","message":{"role":"user","content":"/compact keep the plan"}}` + "\n"
+	code, msgs, _ := runFake(t, stdin, "--print", "--input-format", "stream-json", "--output-format", "stream-json", "--verbose")
+	if code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if got := types(msgs); got != "system,assistant,result,system,result" {
+		t.Fatalf("message types = %s", got)
+	}
+	if msgs[3]["subtype"] != "compact_boundary" || msgs[4]["result"] != "" {
+		t.Errorf("unexpected compaction messages: %v %v", msgs[3], msgs[4])
+	}
+}
+
+func TestMain_TextOutput(t *testing.T) {
+	t.Setenv(ScenarioEnv, "")
+	var stdout bytes.Buffer
+	if code := Main([]string{"-p", "hello", "world"}, nil, &stdout, &bytes.Buffer{}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if stdout.String() != "You said: hello world\n" {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+}
+
+func TestMain_RejectsBadInvocations(t *testing.T) {
+	t.Setenv(ScenarioEnv, "")
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "unknown flag", args: []string{"--bogus"}, wantErr: "unknown option '--bogus'"},
+		{name: "missing value", args: []string{"--model"}, wantErr: "argument missing"},
+		{name: "no prompt", args: []string{"-p"}, wantErr: "input must be provided"},
+		{name: "bad format", args: []string{"-p", "--output-format", "xml", "x"}, wantErr: "invalid --output-format"},
+		{name: "missing scenario", args: []string{"--scenario", "/nonexistent.yaml", "-p", "x"}, wantErr: "reading scenario"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			if code := Main(tt.args, nil, &bytes.Buffer{}, &stderr); code != 1 {
+				t.Errorf("exit code = %d, want 1", code)
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadScenario_InvalidMatch(t *testing.T) {
+	_, err := LoadScenario(writeScenario(t, "responses:\n  - match: \"(\"\n"))
+	if err == nil || !strings.Contains(err.Error(), "responses[0].match") {
+		t.Errorf("expected match error, got %v", err)
+	}
+}
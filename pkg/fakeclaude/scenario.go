@@ -0,0 +1,125 @@
+package fakeclaude
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultVersion is reported by --version when the scenario sets none.
+const DefaultVersion = "2.1.81"
+
+// Scenario scripts the behaviour of the fake CLI.
+type Scenario struct {
+	// Version is printed by --version, e.g. "2.1.81".
+	Version string `yaml:"version"`
+	// SessionID is reported in every message. Defaults to --session-id or
+	// --resume when given, otherwise a fixed ID.
+	SessionID string `yaml:"sessionId"`
+	// Model is reported in the init and assistant messages. Defaults to
+	// --model, otherwise "fake-model".
+	Model string `yaml:"model"`
+	// Responses are matched against each prompt in order; the first match
+	// answers it. Prompts without a match are echoed back.
+	Responses []Response `yaml:"responses"`
+}
+
+// Response is the scripted answer to one prompt.
+type Response struct {
+	// Match is a regular expression matched against the prompt text. Empty
+	// matches every prompt.
+	Match string `yaml:"match"`
+	// Steps are emitted in order before the result message.
+	Steps []Step `yaml:"steps"`
+	// Result is the text of the result message. Defaults to the text of the
+	// last text step.
+	Result string `yaml:"result"`
+	// Error turns the result message into an error result with this text.
+	Error string `yaml:"error"`
+	// CostUSD is reported as total_cost_usd on the result message.
+	CostUSD float64 `yaml:"costUSD"`
+	// Crash makes the CLI exit after the steps without a result message.
+	Crash *Crash `yaml:"crash"`
+
+	match *regexp.Regexp
+}
+
+// Step is a single action in a response. Exactly one field should be set.
+type Step struct {
+	// Text emits an assistant text message.
+	Text string `yaml:"text"`
+	// ToolCall emits an assistant tool_use message followed by the user
+	// tool_result message.
+	ToolCall *ToolCall `yaml:"toolCall"`
+	// Delay pauses before the next step, e.g. "500ms".
+	Delay time.Duration `yaml:"delay"`
+	// Stderr writes a line to stderr.
+	Stderr string `yaml:"stderr"`
+}
+
+// ToolCall describes a tool invocation and its result.
+type ToolCall struct {
+	Name    string         `yaml:"name"`
+	Input   map[string]any `yaml:"input"`
+	Result  string         `yaml:"result"`
+	IsError bool           `yaml:"isError"`
+}
+
+// Crash ends the process abnormally.
+type Crash struct {
+	// ExitCode is the process exit code; defaults to 1.
+	ExitCode int `yaml:"exitCode"`
+	// Stderr is written to stderr before exiting.
+	Stderr string `yaml:"stderr"`
+}
+
+// LoadScenario reads a scenario YAML file. An empty path returns the
+// default scenario, which echoes every prompt.
+func LoadScenario(path string) (*Scenario, error) {
+	var s Scenario
+	if path != "" {
+		data, err := os.ReadFile(path) //nolint:gosec // operator-supplied scenario path
+		if err != nil {
+			return nil, fmt.Errorf("reading scenario: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &s); err != nil {
+			return nil, fmt.Errorf("parsing scenario %s: %w", path, err)
+		}
+	}
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// compile validates the scenario and compiles the match patterns.
+func (s *Scenario) compile() error {
+	if s.Version == "" {
+		s.Version = DefaultVersion
+	}
+	for i := range s.Responses {
+		r := &s.Responses[i]
+		if r.Match == "" {
+			continue
+		}
+		re, err := regexp.Compile(r.Match)
+		if err != nil {
+			return fmt.Errorf("responses[%d].match: %w", i, err)
+		}
+		r.match = re
+	}
+	return nil
+}
+
+// respond returns the response for a prompt.
+func (s *Scenario) respond(prompt string) Response {
+	for _, r := range s.Responses {
+		if r.match == nil || r.match.MatchString(prompt) {
+			return r
+		}
+	}
+	return Response{Steps: []Step{{Text: "You said: " + prompt}}}
+}
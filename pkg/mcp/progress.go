@@ -0,0 +1,133 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	claudepkg "github.com/giantswarm/klaus/pkg/claude"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// progressNotifier sends notifications/progress for the messages of a run to
+// the client that started it. It is not safe for concurrent use; the
+// messages of a run are observed from a single goroutine.
+type progressNotifier struct {
+	token mcp.ProgressToken
+	// send delivers a notification with the given params to the client.
+	send func(params map[string]any) error
+
+	count float64
+	// gone is set once the client's session has gone away, after which
+	// nothing is sent.
+	gone bool
+}
+
+// newRequestProgress returns a notifier sending to the client of the request
+// in ctx, or nil if the client did not ask for progress notifications.
+// Notifications are only delivered while the request is in flight.
+func newRequestProgress(ctx context.Context, request mcp.CallToolRequest) *progressNotifier {
+	token := progressToken(request)
+	mcpServer := server.ServerFromContext(ctx)
+	if token == nil || mcpServer == nil {
+		return nil
+	}
+	return &progressNotifier{
+		token: token,
+		send: func(params map[string]any) error {
+			return mcpServer.SendNotificationToClient(ctx, string(mcp.MethodNotificationProgress), params)
+		},
+	}
+}
+
+// newSessionProgress returns a notifier sending to the MCP session of the
+// request in ctx, or nil if the client did not ask for progress
+// notifications. Unlike newRequestProgress, notifications keep being
+// delivered after the request has finished, for as long as the session
+// exists.
+func newSessionProgress(ctx context.Context, request mcp.CallToolRequest) *progressNotifier {
+	token := progressToken(request)
+	mcpServer := server.ServerFromContext(ctx)
+	session := server.ClientSessionFromContext(ctx)
+	if token == nil || mcpServer == nil || session == nil {
+		return nil
+	}
+	sessionID := session.SessionID()
+	return &progressNotifier{
+		token: token,
+		send: func(params map[string]any) error {
+			return mcpServer.SendNotificationToSpecificClient(sessionID, string(mcp.MethodNotificationProgress), params)
+		},
+	}
+}
+
+func progressToken(request mcp.CallToolRequest) mcp.ProgressToken {
+	if request.Params.Meta == nil {
+		return nil
+	}
+	return request.Params.Meta.ProgressToken
+}
+
+// observe sends a notification for msg if it is worth reporting: text deltas
+// of stream events and the messages described by progressMessage. It is safe
+// to call on a nil notifier.
+func (n *progressNotifier) observe(msg claudepkg.StreamMessage) {
+	if n == nil {
+		return
+	}
+	if msg.Type == claudepkg.MessageTypeStreamEvent {
+		if msg.EventType == "content_block_delta" && msg.DeltaText != "" {
+			n.notify(msg.DeltaText, false)
+		}
+		return
+	}
+	if progressMsg := progressMessage(msg); progressMsg != "" {
+		n.notify(progressMsg, false)
+	}
+}
+
+// finish sends the final notification of a run that has ended with the given
+// status. Its total equals its progress, marking the run as done. It is safe
+// to call on a nil notifier.
+func (n *progressNotifier) finish(status claudepkg.StatusInfo) {
+	if n == nil {
+		return
+	}
+	switch status.Status {
+	case claudepkg.ProcessStatusError:
+		n.notify(fmt.Sprintf("Task failed: %s", status.ErrorMessage), true)
+	case claudepkg.ProcessStatusStopped:
+		n.notify("Task stopped", true)
+	default:
+		n.notify("Task completed", true)
+	}
+}
+
+func (n *progressNotifier) notify(message string, final bool) {
+	if n.gone {
+		return
+	}
+	n.count++
+	params := map[string]any{
+		"progressToken": n.token,
+		"progress":      n.count,
+		argMessage:      message,
+	}
+	if final {
+		params["total"] = n.count
+	}
+	err := n.send(params)
+	switch {
+	case err == nil:
+	case errors.Is(err, server.ErrSessionNotFound), errors.Is(err, server.ErrSessionNotInitialized):
+		n.gone = true
+		slog.Debug("MCP session has gone away, no longer sending progress notifications", "error", err)
+	default:
+		// The notification is dropped, e.g. because the client is not
+		// reading them; later ones may still get through.
+		slog.Debug("failed to send progress notification", "error", err)
+	}
+}
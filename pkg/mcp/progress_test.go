@@ -0,0 +1,164 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	claudepkg "github.com/giantswarm/klaus/pkg/claude"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// testSession is a server.ClientSession whose notifications are buffered
+// for inspection.
+type testSession struct {
+	id            string
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *testSession) SessionID() string { return s.id }
+func (s *testSession) Initialize()       {}
+func (s *testSession) Initialized() bool { return true }
+func (s *testSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+// drainProgress returns the messages and params of the progress
+// notifications received by the session so far.
+func drainProgress(session *testSession) []map[string]any {
+	var params []map[string]any
+	for {
+		select {
+		case n := <-session.notifications:
+			if n.Method == string(mcp.MethodNotificationProgress) {
+				params = append(params, n.Params.AdditionalFields)
+			}
+		default:
+			return params
+		}
+	}
+}
+
+func TestPromptTool_NonBlockingSendsProgressToSession(t *testing.T) {
+	mock := &mockPrompter{status: claudepkg.StatusInfo{Status: claudepkg.ProcessStatusCompleted}}
+	serverCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewMCPServer(serverCtx, mock)
+
+	session := &testSession{id: "session-1", notifications: make(chan mcp.JSONRPCNotification, 10)}
+	if err := s.RegisterSession(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0", "id": 1, "method": "tools/call",
+		"params": map[string]any{
+			"name":      "prompt",
+			"arguments": map[string]any{"message": "hello"},
+			"_meta":     map[string]any{"progressToken": "tok"},
+		},
+	})
+	// The request context ends with the request, as it does over HTTP.
+	reqCtx, reqCancel := context.WithCancel(s.WithContext(context.Background(), session))
+	s.HandleMessage(reqCtx, req)
+	reqCancel()
+
+	opts := mock.lastRunOpts
+	if !mock.submitCalled || opts == nil || opts.OnMessage == nil || opts.OnDone == nil {
+		t.Fatalf("expected the run to be submitted with observers, got %+v", opts)
+	}
+
+	opts.OnMessage(claudepkg.StreamMessage{Type: claudepkg.MessageTypeAssistant, Subtype: claudepkg.SubtypeToolUse, ToolName: "Bash"})
+	opts.OnMessage(claudepkg.StreamMessage{Type: claudepkg.MessageTypeStreamEvent, EventType: "content_block_delta", DeltaText: "Hel"})
+	opts.OnMessage(claudepkg.StreamMessage{Type: claudepkg.MessageTypeResult, Result: "done"})
+	opts.OnDone()
+
+	got := drainProgress(session)
+	want := []string{"Using tool: Bash", "Hel", "Task completed"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d progress notifications, got %v", len(want), got)
+	}
+	for i, params := range got {
+		if params[argMessage] != want[i] || params["progressToken"] != "tok" || params["progress"] != float64(i+1) {
+			t.Errorf("unexpected notification %d: %v", i, params)
+		}
+	}
+	if _, ok := got[1]["total"]; ok {
+		t.Error("expected only the final notification to have a total")
+	}
+	if got[2]["total"] != float64(3) {
+		t.Errorf("expected the final notification to have total 3, got %v", got[2]["total"])
+	}
+
+	// Once the session has gone away, nothing is sent anymore.
+	s.UnregisterSession(context.Background(), session.id)
+	opts.OnMessage(claudepkg.StreamMessage{Type: claudepkg.MessageTypeAssistant, Subtype: claudepkg.SubtypeToolUse, ToolName: "Read"})
+	if got := drainProgress(session); len(got) != 0 {
+		t.Errorf("expected no notifications after the session went away, got %v", got)
+	}
+}
+
+func TestPromptTool_NonBlockingWithoutProgressToken(t *testing.T) {
+	mock := &mockPrompter{}
+	tools := buildToolMap(mock)
+
+	result, err := tools["prompt"](context.Background(), newCallToolRequest("prompt", map[string]any{"message": "hello"}))
+	if err != nil || result.IsError {
+		t.Fatalf("unexpected result %v, %v", result, err)
+	}
+	if mock.lastRunOpts.OnMessage != nil || mock.lastRunOpts.OnDone != nil {
+		t.Error("expected no observers without a progress token")
+	}
+}
+
+func TestProgressNotifier_Finish(t *testing.T) {
+	tests := []struct {
+		status claudepkg.StatusInfo
+		want   string
+	}{
+		{claudepkg.StatusInfo{Status: claudepkg.ProcessStatusCompleted}, "Task completed"},
+		{claudepkg.StatusInfo{Status: claudepkg.ProcessStatusStopped}, "Task stopped"},
+		{claudepkg.StatusInfo{Status: claudepkg.ProcessStatusError, ErrorMessage: "boom"}, "Task failed: boom"},
+	}
+	for _, tt := range tests {
+		var sent map[string]any
+		n := &progressNotifier{token: "tok", send: func(params map[string]any) error {
+			sent = params
+			return nil
+		}}
+		n.finish(tt.status)
+		if sent[argMessage] != tt.want || sent["total"] != sent["progress"] {
+			t.Errorf("finish(%s) sent %v, want message %q", tt.status.Status, sent, tt.want)
+		}
+	}
+
+	var nilNotifier *progressNotifier
+	nilNotifier.observe(claudepkg.StreamMessage{Type: claudepkg.MessageTypeResult})
+	nilNotifier.finish(claudepkg.StatusInfo{})
+}
+
+func TestProgressNotifier_StopsWhenSessionIsGone(t *testing.T) {
+	sends := 0
+	err := errors.New("blocked")
+	n := &progressNotifier{token: "tok", send: func(map[string]any) error {
+		sends++
+		return err
+	}}
+	msg := claudepkg.StreamMessage{Type: claudepkg.MessageTypeAssistant, Subtype: claudepkg.SubtypeText, Text: "hi"}
+
+	n.observe(msg)
+	n.observe(msg)
+	if sends != 2 || n.gone {
+		t.Fatalf("expected other errors not to stop notifications, got %d sends", sends)
+	}
+
+	err = server.ErrSessionNotFound
+	n.observe(msg)
+	n.observe(msg)
+	n.finish(claudepkg.StatusInfo{})
+	if sends != 3 || !n.gone {
+		t.Errorf("expected notifications to stop once the session is gone, got %d sends", sends)
+	}
+}
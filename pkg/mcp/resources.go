@@ -0,0 +1,311 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+
+	claudepkg "github.com/giantswarm/klaus/pkg/claude"
+	"github.com/giantswarm/klaus/pkg/transcript"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// URIs of the klaus resources.
+const (
+	uriStatus     = "klaus://status"
+	uriTranscript = "klaus://transcript"
+	uriRuns       = "klaus://runs"
+	// uriRunPrefix is followed by a run ID or claudepkg.CurrentRunID,
+	// optionally followed by uriSummarySuffix.
+	uriRunPrefix       = "klaus://runs/"
+	uriSummarySuffix   = "/summary"
+	uriWorkspacePrefix = "klaus://workspace/"
+
+	mimeJSON = "application/json"
+)
+
+// RegisterResources registers the klaus resources on the given server and
+// sends notifications/resources/updated to the sessions subscribed to them.
+// hooks must be the hooks s was created with (see server.WithHooks), and s
+// must have the resources capability with subscriptions enabled. The
+// serverCtx controls the lifetime of the goroutine watching for changes.
+func RegisterResources(serverCtx context.Context, s *server.MCPServer, hooks *server.Hooks, process claudepkg.Prompter) {
+	s.AddResources(
+		server.ServerResource{
+			Resource: mcp.NewResource(uriStatus, "status",
+				mcp.WithResourceDescription("Current status of the agent, as returned by the status tool. "+
+					"Subscribe to be notified when the status of a run or its message count changes."),
+				mcp.WithMIMEType(mimeJSON)),
+			Handler: func(_ context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+				return jsonResource(request.Params.URI, process.Status())
+			},
+		},
+		server.ServerResource{
+			Resource: mcp.NewResource(uriTranscript, "transcript",
+				mcp.WithResourceDescription("Live transcript of the conversation in OpenAI Chat Completions format, "+
+					"as returned by the messages tool. Subscribe to be notified of new messages."),
+				mcp.WithMIMEType(mimeJSON)),
+			Handler: func(_ context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+				return jsonResource(request.Params.URI, process.OpenAIMessages(0))
+			},
+		},
+		server.ServerResource{
+			Resource: mcp.NewResource(uriRuns, "runs",
+				mcp.WithResourceDescription("Past non-blocking runs whose results are kept, newest first. "+
+					"Read klaus://runs/{id} for the result of one."),
+				mcp.WithMIMEType(mimeJSON)),
+			Handler: func(_ context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+				runs, err := process.Results().ListRuns()
+				if err != nil {
+					return nil, err
+				}
+				return jsonResource(request.Params.URI, map[string]any{"runs": runs})
+			},
+		},
+	)
+
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate(uriRunPrefix+"{id}", "run",
+			mcp.WithTemplateDescription("Result of a run: the full result text, messages, tool calls, cost and status. "+
+				"id is a run_id reported by status, or \"current\" for the current or last run."),
+			mcp.WithTemplateMIMEType(mimeJSON)),
+		func(_ context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			detail, err := runDetail(process, request.Params.URI)
+			if err != nil {
+				return nil, err
+			}
+			return jsonResource(request.Params.URI, detail)
+		},
+	)
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate(uriRunPrefix+"{id}"+uriSummarySuffix, "run-summary",
+			mcp.WithTemplateDescription("Transcript summary of a run: message distribution, tool, model and token usage, "+
+				"PR URLs, subagents, errors and plugin compliance."),
+			mcp.WithTemplateMIMEType(mimeJSON)),
+		func(_ context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			detail, err := runDetail(process, strings.TrimSuffix(request.Params.URI, uriSummarySuffix))
+			if err != nil {
+				return nil, err
+			}
+			return jsonResource(request.Params.URI, transcript.Summarize(detail))
+		},
+	)
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate(uriWorkspacePrefix+"{+path}", "workspace-file",
+			mcp.WithTemplateDescription(fmt.Sprintf("A file of the agent's workspace, e.g. klaus://workspace/README.md. "+
+				"path is relative to the workspace; files larger than %d bytes cannot be read.", claudepkg.MaxAttachmentBytes))),
+		func(_ context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			path, err := url.PathUnescape(strings.TrimPrefix(request.Params.URI, uriWorkspacePrefix))
+			if err != nil {
+				return nil, fmt.Errorf("invalid path: %w", err)
+			}
+			data, mediaType, err := claudepkg.ReadWorkspaceFile(process.WorkDir(), path)
+			if err != nil {
+				return nil, err
+			}
+			if isTextMediaType(mediaType) {
+				return []mcp.ResourceContents{mcp.TextResourceContents{
+					URI: request.Params.URI, MIMEType: mediaType, Text: string(data),
+				}}, nil
+			}
+			return []mcp.ResourceContents{mcp.BlobResourceContents{
+				URI: request.Params.URI, MIMEType: mediaType, Blob: base64.StdEncoding.EncodeToString(data),
+			}}, nil
+		},
+	)
+
+	subs := newResourceSubscriptions(func(sessionID, uri string) error {
+		return s.SendNotificationToSpecificClient(sessionID, mcp.MethodNotificationResourceUpdated, map[string]any{"uri": uri})
+	})
+	hooks.AddAfterSubscribe(func(ctx context.Context, _ any, request *mcp.SubscribeRequest, _ *mcp.EmptyResult) {
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			subs.subscribe(session.SessionID(), request.Params.URI)
+		}
+	})
+	hooks.AddAfterUnsubscribe(func(ctx context.Context, _ any, request *mcp.UnsubscribeRequest, _ *mcp.EmptyResult) {
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			subs.unsubscribe(session.SessionID(), request.Params.URI)
+		}
+	})
+	hooks.AddOnUnregisterSession(func(_ context.Context, session server.ClientSession) {
+		subs.drop(session.SessionID())
+	})
+
+	go subs.watch(serverCtx, process)
+}
+
+// runDetail returns the detail of the run named by a run URI.
+func runDetail(process claudepkg.Prompter, uri string) (claudepkg.ResultDetailInfo, error) {
+	return claudepkg.RunDetail(process, strings.TrimPrefix(uri, uriRunPrefix))
+}
+
+func jsonResource(uri string, v any) ([]mcp.ResourceContents, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s: %w", uri, err)
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{URI: uri, MIMEType: mimeJSON, Text: string(data)}}, nil
+}
+
+// isTextMediaType reports whether files of the media type are returned as
+// text rather than base64 blobs.
+func isTextMediaType(mediaType string) bool {
+	mediaType, _, _ = strings.Cut(mediaType, ";")
+	return strings.HasPrefix(mediaType, "text/") || mediaType == mimeJSON ||
+		strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "xml") ||
+		mediaType == "application/yaml" || mediaType == "application/javascript"
+}
+
+// resourceSubscriptions tracks the resources each MCP session subscribed to
+// and notifies the sessions when they change.
+type resourceSubscriptions struct {
+	// notify sends notifications/resources/updated for uri to a session.
+	notify func(sessionID, uri string) error
+
+	// subscribed wakes the watcher when a resource is subscribed to, so
+	// that it records the state that later changes are compared with.
+	subscribed chan struct{}
+
+	mu        sync.Mutex
+	bySession map[string]map[string]bool
+}
+
+func newResourceSubscriptions(notify func(sessionID, uri string) error) *resourceSubscriptions {
+	return &resourceSubscriptions{
+		notify:     notify,
+		subscribed: make(chan struct{}, 1),
+		bySession:  make(map[string]map[string]bool),
+	}
+}
+
+func (r *resourceSubscriptions) subscribe(sessionID, uri string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.bySession[sessionID] == nil {
+		r.bySession[sessionID] = make(map[string]bool)
+	}
+	r.bySession[sessionID][uri] = true
+	select {
+	case r.subscribed <- struct{}{}:
+	default:
+	}
+}
+
+func (r *resourceSubscriptions) unsubscribe(sessionID, uri string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.bySession[sessionID], uri)
+	if len(r.bySession[sessionID]) == 0 {
+		delete(r.bySession, sessionID)
+	}
+}
+
+// drop removes the subscriptions of a session that has gone away.
+func (r *resourceSubscriptions) drop(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.bySession, sessionID)
+}
+
+func (r *resourceSubscriptions) empty() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.bySession) == 0
+}
+
+// publish notifies the sessions subscribed to any of uris. Sessions that
+// no longer exist are dropped.
+func (r *resourceSubscriptions) publish(uris []string) {
+	type target struct{ sessionID, uri string }
+	var targets []target
+	r.mu.Lock()
+	for sessionID, subscribed := range r.bySession {
+		for _, uri := range uris {
+			if subscribed[uri] {
+				targets = append(targets, target{sessionID, uri})
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	for _, t := range targets {
+		if err := r.notify(t.sessionID, t.uri); err != nil {
+			if errors.Is(err, server.ErrSessionNotFound) {
+				r.drop(t.sessionID)
+				continue
+			}
+			slog.Debug("failed to send resource update", "session", t.sessionID, "uri", t.uri, "error", err)
+		}
+	}
+}
+
+// resourceState is the part of the agent status whose changes are
+// published to subscribers.
+type resourceState struct {
+	runID        string
+	status       claudepkg.ProcessStatus
+	messageCount int
+}
+
+// watch publishes the resources affected by the state changes process
+// signals while there are subscriptions, until ctx is cancelled.
+func (r *resourceSubscriptions) watch(ctx context.Context, process claudepkg.Prompter) {
+	var last *resourceState
+	for {
+		// Take the change channel before reading the status so that no
+		// change between the two is missed.
+		changed := process.Changes()
+		if r.empty() {
+			// Status may read the result store, so it is not read for
+			// nobody.
+			last = nil
+		} else {
+			info := process.Status()
+			state := resourceState{runID: info.RunID, status: info.Status, messageCount: info.MessageCount}
+			if last != nil {
+				r.publish(changedResources(*last, state))
+			}
+			last = &state
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case <-r.subscribed:
+		}
+	}
+}
+
+// changedResources returns the URIs of the resources affected by a change
+// of the agent state from prev to cur.
+func changedResources(prev, cur resourceState) []string {
+	var uris []string
+	if prev.messageCount != cur.messageCount {
+		uris = append(uris, uriTranscript)
+	}
+	if prev == cur {
+		return uris
+	}
+	uris = append(uris, uriStatus,
+		uriRunPrefix+claudepkg.CurrentRunID, uriRunPrefix+claudepkg.CurrentRunID+uriSummarySuffix)
+	if cur.runID != "" {
+		uris = append(uris, uriRunPrefix+cur.runID, uriRunPrefix+cur.runID+uriSummarySuffix)
+	}
+	if isActive(prev.status) && !isActive(cur.status) {
+		uris = append(uris, uriRuns)
+	}
+	return uris
+}
+
+// isActive reports whether a run is in progress in the status.
+func isActive(status claudepkg.ProcessStatus) bool {
+	return status == claudepkg.ProcessStatusBusy || status == claudepkg.ProcessStatusStarting
+}
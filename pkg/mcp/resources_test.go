@@ -0,0 +1,302 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	claudepkg "github.com/giantswarm/klaus/pkg/claude"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	testCurrentRun = "20260101T110000-00000002"
+	testPastRun    = "20260101T100000-00000001"
+)
+
+// readResource reads uri from s and returns its single content, failing
+// the test on errors.
+func readResource(t *testing.T, s *server.MCPServer, uri string) (text, blob, mimeType string) {
+	t.Helper()
+	contents, err := readResourceErr(s, uri)
+	if err != nil {
+		t.Fatalf("reading %s: %v", uri, err)
+	}
+	if len(contents) != 1 {
+		t.Fatalf("expected one content for %s, got %d", uri, len(contents))
+	}
+	return contents[0].Text, contents[0].Blob, contents[0].MIMEType
+}
+
+type resourceContent struct {
+	URI      string `json:"uri"`
+	MIMEType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Blob     string `json:"blob"`
+}
+
+func readResourceErr(s *server.MCPServer, uri string) ([]resourceContent, error) {
+	req, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0", "id": 1, "method": "resources/read",
+		"params": map[string]any{"uri": uri},
+	})
+	data, err := json.Marshal(s.HandleMessage(context.Background(), req))
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Result *struct {
+			Contents []resourceContent `json:"contents"`
+		} `json:"result"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, errors.New(resp.Error.Message)
+	}
+	return resp.Result.Contents, nil
+}
+
+func newResourceTestServer(t *testing.T) (*server.MCPServer, *mockPrompter) {
+	t.Helper()
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "config.json"), []byte(`{"a":1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, "image.png"), []byte("\x89PNG\r\n\x1a\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	results := claudepkg.NewResultStore(t.TempDir())
+	if err := results.Save(claudepkg.PersistedResult{RunID: testPastRun, ResultText: "past result", Status: claudepkg.ProcessStatusCompleted}); err != nil {
+		t.Fatal(err)
+	}
+
+	mock := &mockPrompter{
+		status:       claudepkg.StatusInfo{Status: claudepkg.ProcessStatusCompleted, RunID: testCurrentRun, MessageCount: 3},
+		resultDetail: claudepkg.ResultDetailInfo{RunID: testCurrentRun, ResultText: "current result", Status: claudepkg.ProcessStatusCompleted},
+		results:      results,
+		workDir:      workDir,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return NewMCPServer(ctx, mock), mock
+}
+
+func TestResources_Read(t *testing.T) {
+	s, _ := newResourceTestServer(t)
+
+	text, _, mimeType := readResource(t, s, "klaus://status")
+	if mimeType != "application/json" || !strings.Contains(text, `"run_id":"`+testCurrentRun+`"`) {
+		t.Errorf("unexpected status resource %q (%s)", text, mimeType)
+	}
+
+	tests := map[string]string{
+		"klaus://runs/current":                      `"result_text":"current result"`,
+		"klaus://runs/" + testCurrentRun:            `"result_text":"current result"`,
+		"klaus://runs/" + testPastRun:               `"result_text":"past result"`,
+		"klaus://runs/" + testPastRun + "/summary":  `"plugin_compliance"`,
+		"klaus://runs/current/summary":              `"result_text":"current result"`,
+		"klaus://runs":                              `"run_id":"` + testPastRun + `"`,
+		"klaus://transcript":                        `"messages"`,
+		"klaus://workspace/config.json":             `{"a":1}`,
+		"klaus://workspace/" + "config%2Ejson":      `{"a":1}`,
+		"klaus://runs/" + testCurrentRun + "/other": "",
+	}
+	for uri, want := range tests {
+		contents, err := readResourceErr(s, uri)
+		if want == "" {
+			if err == nil {
+				t.Errorf("expected an error reading %s", uri)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("reading %s: %v", uri, err)
+			continue
+		}
+		if len(contents) != 1 || !strings.Contains(contents[0].Text, want) {
+			t.Errorf("expected %s to contain %s, got %+v", uri, want, contents)
+		}
+	}
+
+	_, blob, mimeType := readResource(t, s, "klaus://workspace/image.png")
+	if mimeType != "image/png" || blob == "" {
+		t.Errorf("expected the image as a blob, got %q (%s)", blob, mimeType)
+	}
+
+	for _, uri := range []string{
+		"klaus://runs/20260101T120000-00000003",
+		"klaus://runs/not-a-run",
+		"klaus://workspace/../outside",
+		"klaus://workspace/missing.txt",
+	} {
+		if _, err := readResourceErr(s, uri); err == nil {
+			t.Errorf("expected an error reading %s", uri)
+		}
+	}
+}
+
+func TestChangedResources(t *testing.T) {
+	busy := resourceState{runID: testCurrentRun, status: claudepkg.ProcessStatusBusy, messageCount: 2}
+
+	if uris := changedResources(busy, busy); len(uris) != 0 {
+		t.Errorf("expected no changes, got %v", uris)
+	}
+
+	more := busy
+	more.messageCount = 4
+	uris := changedResources(busy, more)
+	for _, want := range []string{"klaus://transcript", "klaus://status", "klaus://runs/current", "klaus://runs/" + testCurrentRun + "/summary"} {
+		if !slices.Contains(uris, want) {
+			t.Errorf("expected %s in %v", want, uris)
+		}
+	}
+	if slices.Contains(uris, "klaus://runs") {
+		t.Errorf("expected the run list to be unchanged while the run is busy, got %v", uris)
+	}
+
+	done := more
+	done.status = claudepkg.ProcessStatusCompleted
+	uris = changedResources(more, done)
+	if !slices.Contains(uris, "klaus://runs") || slices.Contains(uris, "klaus://transcript") {
+		t.Errorf("unexpected changes when the run completed: %v", uris)
+	}
+}
+
+func TestResourceSubscriptions_Publish(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	subs := newResourceSubscriptions(func(sessionID, uri string) error {
+		if sessionID == "gone" {
+			return server.ErrSessionNotFound
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, sessionID+" "+uri)
+		return nil
+	})
+	subs.subscribe("a", "klaus://status")
+	subs.subscribe("a", "klaus://transcript")
+	subs.subscribe("b", "klaus://transcript")
+	subs.subscribe("gone", "klaus://status")
+	subs.unsubscribe("a", "klaus://transcript")
+
+	subs.publish([]string{"klaus://status", "klaus://transcript"})
+	slices.Sort(sent)
+	if want := []string{"a klaus://status", "b klaus://transcript"}; !slices.Equal(sent, want) {
+		t.Errorf("expected notifications %v, got %v", want, sent)
+	}
+	if _, ok := subs.bySession["gone"]; ok {
+		t.Error("expected the subscriptions of a missing session to be dropped")
+	}
+
+	subs.drop("a")
+	subs.drop("b")
+	if !subs.empty() {
+		t.Error("expected no subscriptions left")
+	}
+}
+
+// statusPrompter is a mockPrompter whose status may change concurrently.
+// read receives a value whenever the status is read.
+type statusPrompter struct {
+	*mockPrompter
+	mu      sync.Mutex
+	changed chan struct{}
+	read    chan struct{}
+}
+
+func newStatusPrompter(info claudepkg.StatusInfo) *statusPrompter {
+	return &statusPrompter{
+		mockPrompter: &mockPrompter{status: info},
+		changed:      make(chan struct{}),
+		read:         make(chan struct{}, 1),
+	}
+}
+
+func (p *statusPrompter) Status() claudepkg.StatusInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case p.read <- struct{}{}:
+	default:
+	}
+	return p.status
+}
+
+func (p *statusPrompter) Changes() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.changed
+}
+
+func (p *statusPrompter) setStatus(info claudepkg.StatusInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status = info
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+func TestResources_SubscribeNotifiesOnStatusChange(t *testing.T) {
+	process := newStatusPrompter(claudepkg.StatusInfo{Status: claudepkg.ProcessStatusBusy, RunID: testCurrentRun})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ts := httptest.NewServer(NewServer(ctx, process))
+	defer ts.Close()
+
+	c, err := client.NewStreamableHttpClient(ts.URL+"/mcp", transport.WithContinuousListening())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = c.Close() }()
+	updated := make(chan string, 10)
+	c.OnNotification(func(n mcp.JSONRPCNotification) {
+		if n.Method == mcp.MethodNotificationResourceUpdated {
+			uri, _ := n.Params.AdditionalFields["uri"].(string)
+			updated <- uri
+		}
+	})
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Subscribe(ctx, mcp.SubscribeRequest{Params: mcp.SubscribeParams{URI: "klaus://status"}}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	// Let the watcher see the busy status before it changes.
+	select {
+	case <-process.read:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the watcher did not read the status after the subscription")
+	}
+	process.setStatus(claudepkg.StatusInfo{Status: claudepkg.ProcessStatusCompleted, RunID: testCurrentRun})
+
+	select {
+	case uri := <-updated:
+		if uri != "klaus://status" {
+			t.Errorf("expected an update of klaus://status, got %s", uri)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no resource update received")
+	}
+}
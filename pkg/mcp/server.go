@@ -22,20 +22,25 @@ func NewServer(serverCtx context.Context, process claudepkg.Prompter) *server.St
 	return httpServer
 }
 
-// NewMCPServer returns the raw MCPServer with tools registered, for use
+// NewMCPServer returns the raw MCPServer with tools and resources registered, for use
 // when wrapping with custom middleware (e.g. OAuth). The serverCtx controls
 // the lifetime of background goroutines; it should be cancelled during
 // server shutdown.
 func NewMCPServer(serverCtx context.Context, process claudepkg.Prompter) *server.MCPServer {
+	hooks := &server.Hooks{}
 	mcpServer := server.NewMCPServer(
 		project.Name,
 		project.Version(),
 		server.WithToolCapabilities(false),
+		server.WithResourceCapabilities(true, false),
+		server.WithHooks(hooks),
 		server.WithRecovery(),
-		server.WithInstructions("Klaus wraps a Claude Code agent. Use the 'prompt' tool to send tasks."),
+		server.WithInstructions("Klaus wraps a Claude Code agent. Use the 'prompt' tool to send tasks. "+
+			"Subscribe to the klaus://status resource to follow a run instead of polling the status tool."),
 	)
 
 	RegisterTools(serverCtx, mcpServer, process)
+	RegisterResources(serverCtx, mcpServer, hooks, process)
 
 	return mcpServer
 }
@@ -1,7 +1,9 @@
 package mcp
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -9,6 +11,7 @@ import (
 
 	claudepkg "github.com/giantswarm/klaus/pkg/claude"
 	"github.com/giantswarm/klaus/pkg/metrics"
+	"github.com/giantswarm/klaus/pkg/transcript"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -26,16 +29,27 @@ func RegisterTools(serverCtx context.Context, s *server.MCPServer, process claud
 	s.AddTools(
 		promptTool(serverCtx, process),
 		statusTool(process),
+		waitTool(process),
 		stopTool(process),
 		resultTool(process),
+		transcriptTool(process),
 		messagesTool(process),
+		logsTool(process),
+		configureSessionTool(process),
+		compactTool(process),
+		listSessionsTool(process),
+		inspectSessionTool(process),
+		exportSessionTool(process),
+		deleteSessionTool(process),
+		exportSessionBundleTool(process),
+		importSessionBundleTool(process),
 	)
 }
 
 func promptTool(serverCtx context.Context, process claudepkg.Prompter) server.ServerTool {
 	tool := mcp.NewTool("prompt",
 		mcp.WithDescription("Send a prompt to the Claude Code agent. "+
-			"By default, the task runs asynchronously -- use the status tool to check progress and get the result. "+
+			"By default, the task runs asynchronously -- use the wait or status tool to check progress and get the result. "+
 			"Set blocking=true to wait for the task to complete and return the full result inline."),
 		mcp.WithString(argMessage,
 			mcp.Required(),
@@ -72,6 +86,44 @@ func promptTool(serverCtx context.Context, process claudepkg.Prompter) server.Se
 		mcp.WithBoolean("fork_session",
 			mcp.Description("Optional: fork the session when resuming, creating a new session ID"),
 		),
+		mcp.WithString("profile",
+			mcp.Description("Optional named profile of claude settings to run with, e.g. reviewer "+
+				"(agent mode only; subject to the server's override policy). Other overrides apply on top of it."),
+		),
+		mcp.WithString("model",
+			mcp.Description("Optional model override for this run (agent mode only; subject to the server's override policy)"),
+		),
+		mcp.WithNumber("max_turns",
+			mcp.Description("Optional limit on agentic turns for this run (agent mode only; subject to the server's override policy)"),
+		),
+		mcp.WithArray("allowed_tools",
+			mcp.Description("Optional tool allowlist for this run, replacing the configured list "+
+				"(agent mode only; subject to the server's override policy, which may only permit narrowing)"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithArray("disallowed_tools",
+			mcp.Description("Optional tools to block for this run, in addition to the configured ones "+
+				"(agent mode only; subject to the server's override policy)"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithString("append_system_prompt",
+			mcp.Description("Optional text appended to the system prompt for this run "+
+				"(agent mode only; subject to the server's override policy)"),
+		),
+		mcp.WithArray("attachments",
+			mcp.Description("Optional images or files to send with the prompt (e.g. screenshots). "+
+				"Each item sets either data (base64 with media_type) or path (relative to the workspace). "+
+				"Supported types: PNG, JPEG, GIF, WebP images, PDF and text files."),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"data":       map[string]any{"type": "string", "description": "Base64-encoded content"},
+					"media_type": map[string]any{"type": "string", "description": "MIME type, e.g. image/png (required with data)"},
+					"path":       map[string]any{"type": "string", "description": "File path relative to the workspace"},
+					"name":       map[string]any{"type": "string", "description": "Optional display name"},
+				},
+			}),
+		),
 	)
 
 	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -144,8 +196,71 @@ func promptTool(serverCtx context.Context, process claudepkg.Prompter) server.Se
 			runOpts.ForkSession = true
 		}
 
+		if v, err := optionalString(request, claudepkg.OverrideProfile); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		} else if v != "" {
+			runOpts.Profile = v
+		}
+
+		if v, err := optionalString(request, claudepkg.OverrideModel); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		} else if v != "" {
+			runOpts.Model = v
+		}
+
+		if v, err := optionalFloat(request, claudepkg.OverrideMaxTurns); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		} else if v != 0 {
+			if v < 0 || v != float64(int(v)) {
+				return mcp.NewToolResultError(fmt.Sprintf("parameter %q must be a positive integer", claudepkg.OverrideMaxTurns)), nil
+			}
+			runOpts.MaxTurns = int(v)
+		}
+
+		if v, err := optionalStringSlice(request, claudepkg.OverrideAllowedTools); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		} else if len(v) > 0 {
+			runOpts.AllowedTools = v
+		}
+
+		if v, err := optionalStringSlice(request, claudepkg.OverrideDisallowedTools); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		} else if len(v) > 0 {
+			runOpts.DisallowedTools = v
+		}
+
+		if v, err := optionalString(request, claudepkg.OverrideAppendSystemPrompt); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		} else if v != "" {
+			runOpts.AppendSystemPrompt = v
+		}
+
+		if v, err := optionalAttachments(request, "attachments"); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		} else if len(v) > 0 {
+			if err := claudepkg.ValidateAttachments(v); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			runOpts.Attachments = v
+		}
+
 		// Non-blocking (default): start the task and return immediately.
 		if !blocking {
+			// Keep sending progress notifications to the client's session
+			// after this request has returned.
+			if progress := newSessionProgress(ctx, request); progress != nil {
+				runOpts.OnMessage = func(msg claudepkg.StreamMessage) {
+					// The final notification is sent once the result is
+					// stored, see OnDone.
+					if msg.Type != claudepkg.MessageTypeResult {
+						progress.observe(msg)
+					}
+				}
+				runOpts.OnDone = func() {
+					progress.finish(process.Status())
+				}
+			}
+
 			// Use the server-scoped context so the drain goroutine
 			// outlives the MCP request but is cancelled on shutdown.
 			if err := process.Submit(serverCtx, message, &runOpts); err != nil {
@@ -159,9 +274,11 @@ func promptTool(serverCtx context.Context, process claudepkg.Prompter) server.Se
 			response := struct {
 				Status    string `json:"status"`
 				SessionID string `json:"session_id,omitempty"`
+				RunID     string `json:"run_id,omitempty"`
 			}{
 				Status:    "started",
 				SessionID: status.SessionID,
+				RunID:     status.RunID,
 			}
 
 			data, err := json.Marshal(response)
@@ -174,12 +291,6 @@ func promptTool(serverCtx context.Context, process claudepkg.Prompter) server.Se
 		// Blocking: wait for completion and return the full result.
 		promptStart := time.Now()
 
-		// Extract progress token for streaming progress notifications.
-		var progressToken mcp.ProgressToken
-		if request.Params.Meta != nil {
-			progressToken = request.Params.Meta.ProgressToken
-		}
-
 		// Use the streaming Run method so we can send progress notifications.
 		ch, err := process.RunWithOptions(ctx, message, &runOpts)
 		if err != nil {
@@ -187,16 +298,15 @@ func promptTool(serverCtx context.Context, process claudepkg.Prompter) server.Se
 			return mcp.NewToolResultError(fmt.Sprintf("claude execution failed: %v", err)), nil
 		}
 
-		mcpServer := server.ServerFromContext(ctx)
+		progress := newRequestProgress(ctx, request)
 
 		var messages []claudepkg.StreamMessage
-		var progressCount float64
 
 	loop:
 		for {
 			select {
 			case <-ctx.Done():
-				_ = process.Stop()
+				_ = claudepkg.StopRun(context.WithoutCancel(ctx), process, ch)
 				metrics.PromptsTotal.WithLabelValues("error", "blocking").Inc()
 				metrics.PromptDurationSeconds.WithLabelValues("error", "blocking").Observe(time.Since(promptStart).Seconds())
 				return mcp.NewToolResultError(fmt.Sprintf("cancelled: %v", ctx.Err())), nil
@@ -205,39 +315,10 @@ func promptTool(serverCtx context.Context, process claudepkg.Prompter) server.Se
 					break loop
 				}
 
-				if msg.Type == claudepkg.MessageTypeStreamEvent {
-					if progressToken != nil && mcpServer != nil && msg.EventType == "content_block_delta" && msg.DeltaText != "" {
-						progressCount++
-						_ = mcpServer.SendNotificationToClient(
-							ctx,
-							"notifications/progress",
-							map[string]any{
-								"progressToken": progressToken,
-								"progress":      progressCount,
-								argMessage:      msg.DeltaText,
-							},
-						)
-					}
-					continue
-				}
-
-				messages = append(messages, msg)
-
-				// Send progress notification if client requested it.
-				if progressToken != nil && mcpServer != nil {
-					progressMsg := progressMessage(msg)
-					if progressMsg != "" {
-						progressCount++
-						_ = mcpServer.SendNotificationToClient(
-							ctx,
-							"notifications/progress",
-							map[string]any{
-								"progressToken": progressToken,
-								"progress":      progressCount,
-								argMessage:      progressMsg,
-							},
-						)
-					}
+				// Send progress notifications if the client requested them.
+				progress.observe(msg)
+				if msg.Type != claudepkg.MessageTypeStreamEvent {
+					messages = append(messages, msg)
 				}
 			}
 		}
@@ -340,6 +421,132 @@ func statusTool(process claudepkg.Prompter) server.ServerTool {
 	return server.ServerTool{Tool: tool, Handler: handler}
 }
 
+// Bounds of the timeout of the wait tool. The maximum stays below the
+// write timeout of the HTTP server.
+const (
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = 100 * time.Second
+)
+
+func waitTool(process claudepkg.Prompter) server.ServerTool {
+	tool := mcp.NewTool("wait",
+		mcp.WithDescription("Wait until the current run is no longer busy, then return the same payload as the status tool. "+
+			"Use it instead of polling status after a non-blocking prompt. "+
+			"Returns the current status when the timeout expires; check the status field to see whether the run has finished."),
+		mcp.WithString("run_id",
+			mcp.Description("ID of the run to wait for, as returned by prompt or status. "+
+				"Returns immediately if that run has already finished. Default: the current run."),
+		),
+		mcp.WithNumber("since_message_count",
+			mcp.Description("Also return as soon as the message count differs from this value, "+
+				"e.g. the message_count of the previous status, to follow a run as it progresses."),
+		),
+		mcp.WithNumber("timeout_seconds",
+			mcp.Description(fmt.Sprintf("Maximum time to wait in seconds (default: %d, maximum: %d).",
+				int(defaultWaitTimeout.Seconds()), int(maxWaitTimeout.Seconds()))),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		runID, err := optionalString(request, "run_id")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		var since *int
+		if _, ok := request.GetArguments()["since_message_count"]; ok {
+			v, err := optionalFloat(request, "since_message_count")
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			if v < 0 || v != float64(int(v)) {
+				return mcp.NewToolResultError("parameter \"since_message_count\" must be a non-negative integer"), nil
+			}
+			n := int(v)
+			since = &n
+		}
+		timeout := defaultWaitTimeout
+		if v, err := optionalFloat(request, "timeout_seconds"); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		} else if v < 0 || v > maxWaitTimeout.Seconds() {
+			return mcp.NewToolResultError(fmt.Sprintf("parameter \"timeout_seconds\" must be between 0 and %d", int(maxWaitTimeout.Seconds()))), nil
+		} else if v > 0 {
+			timeout = time.Duration(v * float64(time.Second))
+		}
+
+		info, err := waitForRun(ctx, process, runID, since, timeout)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to wait: %v", err)), nil
+		}
+		data, err := json.Marshal(info)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal status: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+// waitForRun waits until the run with the given ID is no longer active, the
+// message count differs from since (when not nil), the timeout expires or
+// ctx is cancelled, and returns the status at that point. An empty runID,
+// claudepkg.CurrentRunID or claudepkg.LastRunID waits for the current run.
+func waitForRun(ctx context.Context, process claudepkg.Prompter, runID string, since *int, timeout time.Duration) (claudepkg.StatusInfo, error) {
+	if runID == claudepkg.CurrentRunID || runID == claudepkg.LastRunID {
+		runID = ""
+	}
+	// Take the channels before reading the status so that no change
+	// between the two is missed.
+	done, changed := process.Done(), process.Changes()
+	info := process.Status()
+	if runID != "" && runID != info.RunID {
+		// Not the current run: it has finished if it is stored at all.
+		if _, err := process.Results().LoadRun(runID); err != nil {
+			return claudepkg.StatusInfo{}, err
+		}
+		return info, nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		switch {
+		case runID != "" && info.RunID != runID:
+			// Another run has started since, so the awaited one has ended.
+			return info, nil
+		case since != nil && info.MessageCount != *since:
+			return info, nil
+		case !isActive(info.Status):
+			return info, nil
+		}
+
+		// Done signals the end of the run. Other changes only matter for
+		// new messages, and while Done is already closed because the agent
+		// is starting and no run is in progress yet.
+		var changes <-chan struct{}
+		select {
+		case <-done:
+			done, changes = nil, changed
+		default:
+			if since != nil {
+				changes = changed
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return claudepkg.StatusInfo{}, ctx.Err()
+		case <-timer.C:
+			return process.Status(), nil
+		case <-done:
+		case <-changes:
+		}
+		done, changed = process.Done(), process.Changes()
+		info = process.Status()
+	}
+}
+
 func stopTool(process claudepkg.Prompter) server.ServerTool {
 	tool := mcp.NewTool("stop",
 		mcp.WithDescription("Stop the currently running Claude Code agent task"),
@@ -374,6 +581,36 @@ func resultTool(process claudepkg.Prompter) server.ServerTool {
 	return server.ServerTool{Tool: tool, Handler: handler}
 }
 
+func transcriptTool(process claudepkg.Prompter) server.ServerTool {
+	tool := mcp.NewTool("transcript",
+		mcp.WithDescription("Summarize the transcript of a run: message type distribution, tool, model and token usage, "+
+			"PR URLs, subagent dispatches, error texts and plugin compliance. "+
+			"Summarizes the current run, or the last one when none is in progress, unless run_id is given."),
+		mcp.WithString("run_id",
+			mcp.Description("ID of the run, as reported by status or listed by the klaus://runs resource. "+
+				"Past runs are only kept for non-blocking prompts. Default: the current or last run."),
+		),
+	)
+
+	handler := func(_ context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := optionalString(request, "run_id")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		detail, err := claudepkg.RunDetail(process, id)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to load run: %v", err)), nil
+		}
+		data, err := json.Marshal(transcript.Summarize(detail))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal transcript summary: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
 func messagesTool(process claudepkg.Prompter) server.ServerTool {
 	tool := mcp.NewTool("messages",
 		mcp.WithDescription("Get conversation messages in OpenAI Chat Completions compatible format. "+
@@ -406,6 +643,298 @@ func messagesTool(process claudepkg.Prompter) server.ServerTool {
 	return server.ServerTool{Tool: tool, Handler: handler}
 }
 
+func logsTool(process claudepkg.Prompter) server.ServerTool {
+	tool := mcp.NewTool("logs",
+		mcp.WithDescription("Get the most recent stderr output of the Claude Code subprocess. "+
+			"Use this to diagnose runs that failed to start or crashed. "+
+			"Returns {status, total, lines} where total is the number of lines retained."),
+		mcp.WithNumber("lines",
+			mcp.Description("Return at most this many of the most recent lines. Default: all retained lines."),
+		),
+	)
+
+	handler := func(_ context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		limit, err := optionalFloat(request, "lines")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if limit < 0 || limit != float64(int(limit)) {
+			return mcp.NewToolResultError("parameter \"lines\" must be a non-negative integer"), nil
+		}
+
+		data, err := json.Marshal(process.Logs(int(limit)))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal logs: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func configureSessionTool(process claudepkg.Prompter) server.ServerTool {
+	tool := mcp.NewTool("configure_session",
+		mcp.WithDescription("Change the model or permission mode of the running chat session without restarting it, "+
+			"e.g. switch from plan to acceptEdits, and optionally query its context usage. "+
+			"Changes apply from the next turn and survive subprocess restarts. Only available in chat mode. "+
+			"Returns {model, permission_mode, context_usage}."),
+		mcp.WithString("model",
+			mcp.Description("Model to use for subsequent turns. Subject to the override policy."),
+		),
+		mcp.WithString("permission_mode",
+			mcp.Description("Permission mode to switch to: "+strings.Join(claudepkg.ValidPermissionModes, ", ")+
+				". Must be one of the override policy's permission modes."),
+		),
+		mcp.WithBoolean("context_usage",
+			mcp.Description("Also return the context window usage of the session."),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var cfg claudepkg.SessionConfig
+		var err error
+		if cfg.Model, err = optionalString(request, "model"); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if cfg.PermissionMode, err = optionalString(request, "permission_mode"); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if cfg.ContextUsage, err = optionalBool(request, "context_usage"); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		settings, err := process.ConfigureSession(ctx, cfg)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to configure session: %v", err)), nil
+		}
+		data, err := json.Marshal(settings)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal session settings: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func compactTool(process claudepkg.Prompter) server.ServerTool {
+	tool := mcp.NewTool("compact",
+		mcp.WithDescription("Compact the running chat session: the conversation is replaced by a summary to free context window space. "+
+			"Blocks until compaction completes; the session must not be busy. Only available in chat mode. "+
+			"Returns {trigger, time, pre_tokens, context_tokens}."),
+		mcp.WithString("instructions",
+			mcp.Description("Optional instructions for the summary, e.g. which decisions and open tasks to keep."),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		instructions, err := optionalString(request, "instructions")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		event, err := process.Compact(ctx, instructions)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to compact session: %v", err)), nil
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal compaction: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func listSessionsTool(process claudepkg.Prompter) server.ServerTool {
+	tool := mcp.NewTool("list_sessions",
+		mcp.WithDescription("List the Claude sessions stored for the workspace, most recently modified first. "+
+			"Use the IDs with the prompt tool's session_id/resume parameters. "+
+			"Returns [{session_id, created_at, modified_at, first_prompt, size_bytes, message_count}]."),
+	)
+
+	handler := func(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		sessions, err := process.Sessions().List()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to list sessions: %v", err)), nil
+		}
+		data, err := json.Marshal(sessions)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal sessions: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func inspectSessionTool(process claudepkg.Prompter) server.ServerTool {
+	tool := mcp.NewTool("inspect_session",
+		mcp.WithDescription("Show the details of a stored Claude session: timestamps, first and last prompt, size, "+
+			"message count, working directory, git branch and CLI version."),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description("ID of the session, as returned by list_sessions"),
+		),
+	)
+
+	handler := func(_ context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := request.RequireString("session_id")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		detail, err := process.Sessions().Inspect(id)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to inspect session: %v", err)), nil
+		}
+		data, err := json.Marshal(detail)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal session: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func exportSessionTool(process claudepkg.Prompter) server.ServerTool {
+	tool := mcp.NewTool("export_session",
+		mcp.WithDescription("Export the transcript of a stored Claude session as JSONL, one CLI transcript entry per line. "+
+			"Secrets are masked when redaction is enabled."),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description("ID of the session, as returned by list_sessions"),
+		),
+	)
+
+	handler := func(_ context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := request.RequireString("session_id")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		data, err := process.Sessions().Export(id)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to export session: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func deleteSessionTool(process claudepkg.Prompter) server.ServerTool {
+	tool := mcp.NewTool("delete_session",
+		mcp.WithDescription("Delete a stored Claude session. The session the running agent uses cannot be deleted."),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description("ID of the session, as returned by list_sessions"),
+		),
+	)
+
+	handler := func(_ context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := request.RequireString("session_id")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if err := process.Sessions().Delete(id); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to delete session: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("session %s deleted", id)), nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func exportSessionBundleTool(process claudepkg.Prompter) server.ServerTool {
+	tool := mcp.NewTool("export_session_bundle",
+		mcp.WithDescription("Package a stored Claude session into a portable bundle (gzip-compressed tar, base64-encoded): "+
+			"its transcript and tool output files, klaus' last result if it belongs to the session, and optionally the "+
+			"uncommitted workspace changes. Import it on another instance with import_session_bundle to resume the session there. "+
+			"Returns {manifest, bundle}."),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description("ID of the session, as returned by list_sessions"),
+		),
+		mcp.WithBoolean("include_diff",
+			mcp.Description("Include the uncommitted changes of the workspace (git diff HEAD); untracked files are not included"),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := request.RequireString("session_id")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		includeDiff, err := optionalBool(request, "include_diff")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		var buf bytes.Buffer
+		manifest, err := process.Sessions().ExportBundle(ctx, &buf, id, claudepkg.BundleExportOptions{IncludeDiff: includeDiff})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to export session bundle: %v", err)), nil
+		}
+		data, err := json.Marshal(struct {
+			Manifest claudepkg.BundleManifest `json:"manifest"`
+			Bundle   string                   `json:"bundle"`
+		}{manifest, base64.StdEncoding.EncodeToString(buf.Bytes())})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal session bundle: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func importSessionBundleTool(process claudepkg.Prompter) server.ServerTool {
+	tool := mcp.NewTool("import_session_bundle",
+		mcp.WithDescription("Import a session bundle created by export_session_bundle or `klaus session export`, so that "+
+			"the session can be resumed with the prompt tool's session_id and resume parameters. "+
+			"Returns {manifest, result_restored, runs_restored, diff_applied}."),
+		mcp.WithString("bundle",
+			mcp.Required(),
+			mcp.Description("Base64-encoded bundle"),
+		),
+		mcp.WithBoolean("force",
+			mcp.Description("Overwrite a stored session with the same ID and the last result"),
+		),
+		mcp.WithBoolean("apply_diff",
+			mcp.Description("Apply the bundled workspace changes with git apply"),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		encoded, err := request.RequireString("bundle")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		bundle, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid bundle encoding: %v", err)), nil
+		}
+		var opts claudepkg.BundleImportOptions
+		if opts.Force, err = optionalBool(request, "force"); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if opts.ApplyDiff, err = optionalBool(request, "apply_diff"); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		imported, err := process.Sessions().ImportBundle(ctx, bytes.NewReader(bundle), opts)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to import session bundle: %v", err)), nil
+		}
+		data, err := json.Marshal(imported)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal import: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
 // optionalString extracts an optional string parameter from the request.
 func optionalString(request mcp.CallToolRequest, key string) (string, error) {
 	args := request.GetArguments()
@@ -447,3 +976,65 @@ func optionalFloat(request mcp.CallToolRequest, key string) (float64, error) {
 	}
 	return f, nil
 }
+
+// optionalStringSlice extracts an optional array of strings from the request.
+func optionalStringSlice(request mcp.CallToolRequest, key string) ([]string, error) {
+	args := request.GetArguments()
+	v, ok := args[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	items, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("parameter %q must be an array of strings", key)
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("parameter %q must be an array of strings", key)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// optionalAttachments extracts an optional array of attachment objects from
+// the request. Each item may set data, media_type, path and name as strings.
+func optionalAttachments(request mcp.CallToolRequest, key string) ([]claudepkg.Attachment, error) {
+	args := request.GetArguments()
+	v, ok := args[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	items, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("parameter %q must be an array", key)
+	}
+	attachments := make([]claudepkg.Attachment, 0, len(items))
+	for i, item := range items {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("parameter %q: item %d must be an object", key, i)
+		}
+		var a claudepkg.Attachment
+		for field, dst := range map[string]*string{
+			"data":       &a.Data,
+			"media_type": &a.MediaType,
+			"path":       &a.Path,
+			"name":       &a.Name,
+		} {
+			fv, ok := obj[field]
+			if !ok || fv == nil {
+				continue
+			}
+			s, ok := fv.(string)
+			if !ok {
+				return nil, fmt.Errorf("parameter %q: item %d field %q must be a string", key, i, field)
+			}
+			*dst = s
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, nil
+}
@@ -4,9 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	claudepkg "github.com/giantswarm/klaus/pkg/claude"
+	"github.com/giantswarm/klaus/pkg/transcript"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -40,6 +46,22 @@ type mockPrompter struct {
 	// lastRawOffset and lastRawTypes track the last RawMessages call args.
 	lastRawOffset int
 	lastRawTypes  []string
+	// logsInfo is returned by Logs; lastLogsLimit tracks the last limit.
+	logsInfo      claudepkg.LogsInfo
+	lastLogsLimit int
+	// sessionCfg records the last ConfigureSession call; configureErr is
+	// returned by it.
+	sessionCfg   claudepkg.SessionConfig
+	configureErr error
+	// compactInstructions records the last Compact call; compactErr is
+	// returned by it.
+	compactInstructions string
+	compactErr          error
+	// sessions is returned by Sessions.
+	sessions *claudepkg.SessionStore
+	// results is returned by Results; workDir by WorkDir.
+	results *claudepkg.ResultStore
+	workDir string
 }
 
 func (m *mockPrompter) Run(ctx context.Context, prompt string) (<-chan claudepkg.StreamMessage, error) {
@@ -107,12 +129,20 @@ func (m *mockPrompter) Stop() error {
 	return nil
 }
 
+func (m *mockPrompter) Interrupt(context.Context) error {
+	return claudepkg.ErrInterruptUnsupported
+}
+
 func (m *mockPrompter) Done() <-chan struct{} {
 	ch := make(chan struct{})
 	close(ch)
 	return ch
 }
 
+func (m *mockPrompter) Changes() <-chan struct{} {
+	return nil
+}
+
 func (m *mockPrompter) Messages() claudepkg.MessagesInfo { return m.messagesInfo }
 
 func (m *mockPrompter) RawMessages(offset int, types []string) claudepkg.RawMessagesInfo {
@@ -126,6 +156,41 @@ func (m *mockPrompter) OpenAIMessages(offset int) claudepkg.OpenAIMessagesInfo {
 	return m.openAIMessagesInfo
 }
 
+func (m *mockPrompter) Logs(limit int) claudepkg.LogsInfo {
+	m.lastLogsLimit = limit
+	return m.logsInfo
+}
+
+func (m *mockPrompter) ConfigureSession(_ context.Context, cfg claudepkg.SessionConfig) (claudepkg.SessionSettings, error) {
+	m.sessionCfg = cfg
+	if m.configureErr != nil {
+		return claudepkg.SessionSettings{}, m.configureErr
+	}
+	return claudepkg.SessionSettings{Model: cfg.Model, PermissionMode: cfg.PermissionMode}, nil
+}
+
+func (m *mockPrompter) Compact(_ context.Context, instructions string) (claudepkg.CompactionEvent, error) {
+	m.compactInstructions = instructions
+	if m.compactErr != nil {
+		return claudepkg.CompactionEvent{}, m.compactErr
+	}
+	return claudepkg.CompactionEvent{Trigger: claudepkg.CompactTriggerManual, PreTokens: 150000}, nil
+}
+
+func (m *mockPrompter) UpdateOptions(claudepkg.Options) {}
+
+func (m *mockPrompter) Sessions() *claudepkg.SessionStore {
+	return m.sessions
+}
+
+func (m *mockPrompter) Results() *claudepkg.ResultStore {
+	return m.results
+}
+
+func (m *mockPrompter) WorkDir() string {
+	return m.workDir
+}
+
 func (m *mockPrompter) MarshalStatus() ([]byte, error) {
 	return json.Marshal(m.status)
 }
@@ -523,7 +588,7 @@ func TestPromptTool_NonBlockingDefault(t *testing.T) {
 		result:    "Hello, world!",
 		sessionID: "sess-123",
 	}
-	mock.status = claudepkg.StatusInfo{SessionID: "sess-123"}
+	mock.status = claudepkg.StatusInfo{SessionID: "sess-123", RunID: testCurrentRun}
 
 	tools := buildToolMap(mock)
 	handler := tools["prompt"]
@@ -548,11 +613,12 @@ func TestPromptTool_NonBlockingDefault(t *testing.T) {
 		t.Errorf("expected prompt %q, got %q", "Do something", mock.lastPrompt)
 	}
 
-	// Parse response -- should be {status: "started", session_id: "..."}.
+	// Parse response -- should be {status: "started", session_id: "...", run_id: "..."}.
 	text := extractText(t, result)
 	var resp struct {
 		Status    string `json:"status"`
 		SessionID string `json:"session_id"`
+		RunID     string `json:"run_id"`
 	}
 	if err := json.Unmarshal([]byte(text), &resp); err != nil {
 		t.Fatalf("failed to parse response: %v (text: %s)", err, text)
@@ -563,6 +629,9 @@ func TestPromptTool_NonBlockingDefault(t *testing.T) {
 	if resp.SessionID != "sess-123" {
 		t.Errorf("expected session_id %q, got %q", "sess-123", resp.SessionID)
 	}
+	if resp.RunID != testCurrentRun {
+		t.Errorf("expected run_id %q, got %q", testCurrentRun, resp.RunID)
+	}
 }
 
 func TestPromptTool_NonBlockingExplicit(t *testing.T) {
@@ -824,6 +893,229 @@ func TestResultTool_EmptyResult(t *testing.T) {
 	}
 }
 
+// --- Transcript tool tests ---
+
+func TestTranscriptTool(t *testing.T) {
+	results := claudepkg.NewResultStore(t.TempDir())
+	if err := results.Save(claudepkg.PersistedResult{RunID: testPastRun, ResultText: "past", ToolCalls: map[string]int{"Bash": 2}}); err != nil {
+		t.Fatal(err)
+	}
+	mock := &mockPrompter{
+		status:       claudepkg.StatusInfo{Status: claudepkg.ProcessStatusCompleted, RunID: testCurrentRun},
+		resultDetail: claudepkg.ResultDetailInfo{RunID: testCurrentRun, ResultText: "current", Status: claudepkg.ProcessStatusCompleted},
+		results:      results,
+	}
+	handler := buildToolMap(mock)["transcript"]
+
+	for _, tc := range []struct {
+		args map[string]any
+		want string
+	}{
+		{nil, "current"},
+		{map[string]any{"run_id": "last"}, "current"},
+		{map[string]any{"run_id": testCurrentRun}, "current"},
+		{map[string]any{"run_id": testPastRun}, "past"},
+	} {
+		result, err := handler(context.Background(), newCallToolRequest("transcript", tc.args))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("unexpected tool error for %v: %v", tc.args, result.Content)
+		}
+		var summary transcript.TranscriptSummary
+		if err := json.Unmarshal([]byte(extractText(t, result)), &summary); err != nil {
+			t.Fatalf("failed to parse transcript summary: %v", err)
+		}
+		if summary.ResultText != tc.want {
+			t.Errorf("expected the %s run for %v, got %+v", tc.want, tc.args, summary)
+		}
+		if tc.want == "past" && (summary.RunID != testPastRun || summary.ToolCalls["Bash"] != 2) {
+			t.Errorf("unexpected summary of a past run: %+v", summary)
+		}
+	}
+
+	for _, id := range []any{"20260101T120000-00000003", "../secret", 42} {
+		result, err := handler(context.Background(), newCallToolRequest("transcript", map[string]any{"run_id": id}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.IsError {
+			t.Errorf("expected a tool error for run_id %v", id)
+		}
+	}
+}
+
+// --- Wait tool tests ---
+
+// runPrompter is a statusPrompter whose current run can be finished.
+type runPrompter struct {
+	*statusPrompter
+	done chan struct{}
+}
+
+func newRunPrompter(info claudepkg.StatusInfo, results *claudepkg.ResultStore) *runPrompter {
+	sp := newStatusPrompter(info)
+	sp.results = results
+	return &runPrompter{
+		statusPrompter: sp,
+		done:           make(chan struct{}),
+	}
+}
+
+func (p *runPrompter) Done() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.done
+}
+
+// finish ends the run like the processes do: Done is closed once the
+// result is stored and the agent reports completed. Changes is not
+// signalled, so that waiting is shown to end on Done.
+func (p *runPrompter) finish() {
+	p.mu.Lock()
+	p.status.Status = claudepkg.ProcessStatusCompleted
+	close(p.done)
+	p.mu.Unlock()
+}
+
+func callWait(t *testing.T, process claudepkg.Prompter, args map[string]any) (claudepkg.StatusInfo, time.Duration) {
+	t.Helper()
+	start := time.Now()
+	result, err := waitTool(process).Handler(context.Background(), newCallToolRequest("wait", args))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %v", result.Content)
+	}
+	var info claudepkg.StatusInfo
+	if err := json.Unmarshal([]byte(extractText(t, result)), &info); err != nil {
+		t.Fatalf("failed to parse status: %v", err)
+	}
+	return info, time.Since(start)
+}
+
+func TestWaitTool_ReturnsWhenRunFinishes(t *testing.T) {
+	process := newRunPrompter(claudepkg.StatusInfo{Status: claudepkg.ProcessStatusBusy, RunID: testCurrentRun}, nil)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		process.finish()
+	}()
+
+	info, elapsed := callWait(t, process, map[string]any{"run_id": testCurrentRun})
+	if info.Status != claudepkg.ProcessStatusCompleted {
+		t.Errorf("expected the completed status, got %s", info.Status)
+	}
+	// Done wakes the wait up.
+	if elapsed > time.Second {
+		t.Errorf("expected wait to return when the run finished, took %s", elapsed)
+	}
+}
+
+func TestWaitTool_ReturnsWhenStartFinishes(t *testing.T) {
+	// While the agent starts, no run is in progress and Done is closed.
+	process := newStatusPrompter(claudepkg.StatusInfo{Status: claudepkg.ProcessStatusStarting})
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		process.setStatus(claudepkg.StatusInfo{Status: claudepkg.ProcessStatusIdle})
+	}()
+
+	info, elapsed := callWait(t, process, nil)
+	if info.Status != claudepkg.ProcessStatusIdle {
+		t.Errorf("expected the idle status, got %s", info.Status)
+	}
+	if elapsed > time.Second {
+		t.Errorf("expected wait to return when the start finished, took %s", elapsed)
+	}
+}
+
+func TestWaitTool_ReturnsImmediately(t *testing.T) {
+	results := claudepkg.NewResultStore(t.TempDir())
+	if err := results.Save(claudepkg.PersistedResult{RunID: testPastRun}); err != nil {
+		t.Fatal(err)
+	}
+	busy := newRunPrompter(claudepkg.StatusInfo{Status: claudepkg.ProcessStatusBusy, RunID: testCurrentRun, MessageCount: 3}, results)
+	idle := newRunPrompter(claudepkg.StatusInfo{Status: claudepkg.ProcessStatusIdle}, nil)
+
+	tests := []struct {
+		name    string
+		process claudepkg.Prompter
+		args    map[string]any
+	}{
+		{"no run in progress", idle, nil},
+		{"finished run", busy, map[string]any{"run_id": testPastRun}},
+		{"new messages", busy, map[string]any{"since_message_count": float64(1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, elapsed := callWait(t, tt.process, tt.args); elapsed > time.Second {
+				t.Errorf("expected wait to return immediately, took %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestWaitTool_SinceMessageCount(t *testing.T) {
+	process := newRunPrompter(claudepkg.StatusInfo{Status: claudepkg.ProcessStatusBusy, RunID: testCurrentRun, MessageCount: 2}, nil)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		process.setStatus(claudepkg.StatusInfo{Status: claudepkg.ProcessStatusBusy, RunID: testCurrentRun, MessageCount: 4})
+	}()
+
+	info, _ := callWait(t, process, map[string]any{"since_message_count": float64(2)})
+	if info.Status != claudepkg.ProcessStatusBusy || info.MessageCount != 4 {
+		t.Errorf("expected the busy status with the new messages, got %s with %d messages", info.Status, info.MessageCount)
+	}
+}
+
+func TestWaitTool_Timeout(t *testing.T) {
+	process := newRunPrompter(claudepkg.StatusInfo{Status: claudepkg.ProcessStatusBusy, RunID: testCurrentRun}, nil)
+
+	info, elapsed := callWait(t, process, map[string]any{"timeout_seconds": 0.3})
+	if info.Status != claudepkg.ProcessStatusBusy {
+		t.Errorf("expected the busy status, got %s", info.Status)
+	}
+	if elapsed < 300*time.Millisecond {
+		t.Errorf("expected wait to last until the timeout, took %s", elapsed)
+	}
+}
+
+func TestWaitTool_Cancelled(t *testing.T) {
+	process := newRunPrompter(claudepkg.StatusInfo{Status: claudepkg.ProcessStatusBusy, RunID: testCurrentRun}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	result, err := waitTool(process).Handler(ctx, newCallToolRequest("wait", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Error("expected a tool error when the request is cancelled")
+	}
+}
+
+func TestWaitTool_InvalidParams(t *testing.T) {
+	process := newRunPrompter(claudepkg.StatusInfo{Status: claudepkg.ProcessStatusIdle}, nil)
+	for _, args := range []map[string]any{
+		{"run_id": "20260101T120000-00000003"},
+		{"run_id": "../secret"},
+		{"since_message_count": float64(-1)},
+		{"since_message_count": 1.5},
+		{"timeout_seconds": float64(-1)},
+		{"timeout_seconds": float64(1000)},
+		{"timeout_seconds": "10"},
+	} {
+		result, err := waitTool(process).Handler(context.Background(), newCallToolRequest("wait", args))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.IsError {
+			t.Errorf("expected a tool error for %v", args)
+		}
+	}
+}
+
 // --- Stop tool tests ---
 
 func TestStopTool(t *testing.T) {
@@ -1167,6 +1459,363 @@ func TestOptionalFloat(t *testing.T) {
 	})
 }
 
+func TestLogsTool(t *testing.T) {
+	mock := &mockPrompter{logsInfo: claudepkg.LogsInfo{
+		Status: claudepkg.ProcessStatusError,
+		Total:  3,
+		Lines:  []string{"warn: retrying", "error: API key invalid"},
+	}}
+	tools := buildToolMap(mock)
+
+	result, err := tools["logs"](context.Background(), newCallToolRequest("logs", map[string]any{"lines": float64(2)}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %s", extractText(t, result))
+	}
+	if mock.lastLogsLimit != 2 {
+		t.Errorf("expected limit 2, got %d", mock.lastLogsLimit)
+	}
+
+	var info claudepkg.LogsInfo
+	if err := json.Unmarshal([]byte(extractText(t, result)), &info); err != nil {
+		t.Fatalf("failed to parse logs: %v", err)
+	}
+	if info.Total != 3 || len(info.Lines) != 2 || info.Lines[1] != "error: API key invalid" {
+		t.Errorf("unexpected logs: %+v", info)
+	}
+}
+
+func TestLogsTool_InvalidLines(t *testing.T) {
+	tools := buildToolMap(&mockPrompter{})
+	for _, v := range []any{float64(-1), 1.5, "ten"} {
+		result, err := tools["logs"](context.Background(), newCallToolRequest("logs", map[string]any{"lines": v}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.IsError {
+			t.Errorf("expected error for lines=%v", v)
+		}
+	}
+}
+
+func TestConfigureSessionTool(t *testing.T) {
+	mock := &mockPrompter{}
+	tools := buildToolMap(mock)
+
+	result, err := tools["configure_session"](context.Background(), newCallToolRequest("configure_session", map[string]any{
+		"permission_mode": "acceptEdits",
+		"context_usage":   true,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %s", extractText(t, result))
+	}
+	if mock.sessionCfg.PermissionMode != "acceptEdits" || !mock.sessionCfg.ContextUsage || mock.sessionCfg.Model != "" {
+		t.Errorf("unexpected session config: %+v", mock.sessionCfg)
+	}
+	var settings claudepkg.SessionSettings
+	if err := json.Unmarshal([]byte(extractText(t, result)), &settings); err != nil {
+		t.Fatalf("failed to parse settings: %v", err)
+	}
+	if settings.PermissionMode != "acceptEdits" {
+		t.Errorf("unexpected settings: %+v", settings)
+	}
+}
+
+func TestConfigureSessionTool_Errors(t *testing.T) {
+	tools := buildToolMap(&mockPrompter{configureErr: claudepkg.ErrSessionControlUnsupported})
+	result, err := tools["configure_session"](context.Background(), newCallToolRequest("configure_session", map[string]any{"model": "opus"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(extractText(t, result), "requires chat mode") {
+		t.Errorf("expected unsupported error, got %+v", result)
+	}
+
+	result, err = tools["configure_session"](context.Background(), newCallToolRequest("configure_session", map[string]any{"context_usage": "yes"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Error("expected error for wrong type")
+	}
+}
+
+func TestCompactTool(t *testing.T) {
+	mock := &mockPrompter{}
+	tools := buildToolMap(mock)
+
+	result, err := tools["compact"](context.Background(), newCallToolRequest("compact", map[string]any{
+		"instructions": "keep the open TODOs",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %s", extractText(t, result))
+	}
+	if mock.compactInstructions != "keep the open TODOs" {
+		t.Errorf("instructions = %q", mock.compactInstructions)
+	}
+	var event claudepkg.CompactionEvent
+	if err := json.Unmarshal([]byte(extractText(t, result)), &event); err != nil {
+		t.Fatalf("failed to parse compaction: %v", err)
+	}
+	if event.Trigger != claudepkg.CompactTriggerManual || event.PreTokens != 150000 {
+		t.Errorf("unexpected compaction: %+v", event)
+	}
+}
+
+func TestCompactTool_Errors(t *testing.T) {
+	tools := buildToolMap(&mockPrompter{compactErr: claudepkg.ErrCompactUnsupported})
+	result, err := tools["compact"](context.Background(), newCallToolRequest("compact", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(extractText(t, result), "requires chat mode") {
+		t.Errorf("expected unsupported error, got %+v", result)
+	}
+
+	result, err = tools["compact"](context.Background(), newCallToolRequest("compact", map[string]any{"instructions": 3}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Error("expected error for wrong type")
+	}
+}
+
+func TestSessionTools(t *testing.T) {
+	dir := t.TempDir()
+	transcript := `{"type":"user","timestamp":"2026-01-02T09:00:00Z","message":{"role":"user","content":"hello"}}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "abc-123.jsonl"), []byte(transcript), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tools := buildToolMap(&mockPrompter{sessions: claudepkg.NewSessionStore(dir, nil)})
+
+	result, err := tools["list_sessions"](context.Background(), newCallToolRequest("list_sessions", nil))
+	if err != nil || result.IsError {
+		t.Fatalf("list_sessions: %v %+v", err, result)
+	}
+	var sessions []claudepkg.SessionInfo
+	if err := json.Unmarshal([]byte(extractText(t, result)), &sessions); err != nil {
+		t.Fatalf("failed to parse sessions: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != "abc-123" || sessions[0].FirstPrompt != "hello" {
+		t.Errorf("unexpected sessions: %+v", sessions)
+	}
+
+	result, err = tools["inspect_session"](context.Background(), newCallToolRequest("inspect_session", map[string]any{"session_id": "abc-123"}))
+	if err != nil || result.IsError || !strings.Contains(extractText(t, result), `"message_count":1`) {
+		t.Errorf("inspect_session: %v %+v", err, result)
+	}
+
+	result, err = tools["export_session"](context.Background(), newCallToolRequest("export_session", map[string]any{"session_id": "abc-123"}))
+	if err != nil || result.IsError || extractText(t, result) != transcript {
+		t.Errorf("export_session: %v %+v", err, result)
+	}
+
+	result, err = tools["delete_session"](context.Background(), newCallToolRequest("delete_session", map[string]any{"session_id": "abc-123"}))
+	if err != nil || result.IsError {
+		t.Fatalf("delete_session: %v %+v", err, result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "abc-123.jsonl")); !os.IsNotExist(err) {
+		t.Error("session file not deleted")
+	}
+
+	result, err = tools["inspect_session"](context.Background(), newCallToolRequest("inspect_session", map[string]any{"session_id": "abc-123"}))
+	if err != nil || !result.IsError || !strings.Contains(extractText(t, result), "session not found") {
+		t.Errorf("expected not found error, got %v %+v", err, result)
+	}
+	result, err = tools["export_session"](context.Background(), newCallToolRequest("export_session", nil))
+	if err != nil || !result.IsError {
+		t.Errorf("expected error for missing session_id, got %v %+v", err, result)
+	}
+}
+
+func TestSessionBundleTools(t *testing.T) {
+	src := t.TempDir()
+	transcript := `{"type":"user","message":{"role":"user","content":"hello"}}` + "\n"
+	if err := os.WriteFile(filepath.Join(src, "abc-123.jsonl"), []byte(transcript), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tools := buildToolMap(&mockPrompter{sessions: claudepkg.NewSessionStore(src, nil)})
+
+	result, err := tools["export_session_bundle"](context.Background(), newCallToolRequest("export_session_bundle", map[string]any{"session_id": "abc-123"}))
+	if err != nil || result.IsError {
+		t.Fatalf("export_session_bundle: %v %+v", err, result)
+	}
+	var exported struct {
+		Manifest claudepkg.BundleManifest `json:"manifest"`
+		Bundle   string                   `json:"bundle"`
+	}
+	if err := json.Unmarshal([]byte(extractText(t, result)), &exported); err != nil {
+		t.Fatalf("failed to parse export: %v", err)
+	}
+	if exported.Manifest.SessionID != "abc-123" || exported.Bundle == "" {
+		t.Fatalf("unexpected export: %+v", exported)
+	}
+
+	dst := t.TempDir()
+	tools = buildToolMap(&mockPrompter{sessions: claudepkg.NewSessionStore(dst, nil)})
+	result, err = tools["import_session_bundle"](context.Background(), newCallToolRequest("import_session_bundle", map[string]any{"bundle": exported.Bundle}))
+	if err != nil || result.IsError {
+		t.Fatalf("import_session_bundle: %v %+v", err, result)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "abc-123.jsonl")); err != nil || string(data) != transcript {
+		t.Errorf("imported transcript: %q, %v", data, err)
+	}
+
+	result, err = tools["import_session_bundle"](context.Background(), newCallToolRequest("import_session_bundle", map[string]any{"bundle": exported.Bundle}))
+	if err != nil || !result.IsError || !strings.Contains(extractText(t, result), "session already exists") {
+		t.Errorf("expected exists error, got %v %+v", err, result)
+	}
+	result, err = tools["import_session_bundle"](context.Background(), newCallToolRequest("import_session_bundle", map[string]any{"bundle": "%%%"}))
+	if err != nil || !result.IsError || !strings.Contains(extractText(t, result), "invalid bundle encoding") {
+		t.Errorf("expected encoding error, got %v %+v", err, result)
+	}
+}
+
+func TestPromptTool_Attachments(t *testing.T) {
+	mock := &mockPrompter{result: "ok"}
+	tools := buildToolMap(mock)
+	handler := tools["prompt"]
+
+	request := newCallToolRequest("prompt", map[string]any{
+		"message":  "What is wrong with this page?",
+		"blocking": true,
+		"attachments": []any{
+			map[string]any{"data": "iVBORw0KGgo=", "media_type": "image/png"},
+			map[string]any{"path": "screenshots/login.png", "name": "login"},
+		},
+	})
+
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %v", result.Content)
+	}
+
+	want := []claudepkg.Attachment{
+		{Data: "iVBORw0KGgo=", MediaType: "image/png"},
+		{Path: "screenshots/login.png", Name: "login"},
+	}
+	if mock.lastRunOpts == nil || !reflect.DeepEqual(mock.lastRunOpts.Attachments, want) {
+		t.Errorf("expected attachments %+v, got %+v", want, mock.lastRunOpts)
+	}
+}
+
+func TestPromptTool_PolicyOverrides(t *testing.T) {
+	mock := &mockPrompter{result: "ok"}
+	handler := buildToolMap(mock)["prompt"]
+
+	result, err := handler(context.Background(), newCallToolRequest("prompt", map[string]any{
+		"message":              "Review only",
+		"blocking":             true,
+		"profile":              "reviewer",
+		"model":                "haiku",
+		"max_turns":            5.0,
+		"allowed_tools":        []any{"Read", "Grep"},
+		"disallowed_tools":     []any{"Bash"},
+		"append_system_prompt": "Do not edit files.",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %v", result.Content)
+	}
+
+	opts := mock.lastRunOpts
+	if opts == nil {
+		t.Fatal("expected RunOptions to be set")
+	}
+	if opts.Profile != "reviewer" || opts.Model != "haiku" || opts.MaxTurns != 5 || opts.AppendSystemPrompt != "Do not edit files." {
+		t.Errorf("unexpected overrides: %+v", opts)
+	}
+	if !reflect.DeepEqual(opts.AllowedTools, []string{"Read", "Grep"}) {
+		t.Errorf("unexpected allowed tools: %v", opts.AllowedTools)
+	}
+	if !reflect.DeepEqual(opts.DisallowedTools, []string{"Bash"}) {
+		t.Errorf("unexpected disallowed tools: %v", opts.DisallowedTools)
+	}
+}
+
+func TestPromptTool_InvalidPolicyOverrideParams(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]any
+	}{
+		{"fractional max_turns", map[string]any{"max_turns": 2.5}},
+		{"negative max_turns", map[string]any{"max_turns": -3.0}},
+		{"profile not a string", map[string]any{"profile": 1.0}},
+		{"allowed_tools not an array", map[string]any{"allowed_tools": "Read"}},
+		{"disallowed_tools with non-string", map[string]any{"disallowed_tools": []any{"Bash", 1.0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockPrompter{result: "ok"}
+			args := map[string]any{"message": "hi"}
+			for k, v := range tt.args {
+				args[k] = v
+			}
+			result, err := buildToolMap(mock)["prompt"](context.Background(), newCallToolRequest("prompt", args))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.IsError {
+				t.Fatal("expected tool error")
+			}
+		})
+	}
+}
+
+func TestPromptTool_InvalidAttachments(t *testing.T) {
+	tests := []struct {
+		name        string
+		attachments any
+		wantErr     string
+	}{
+		{"not an array", "file.png", "must be an array"},
+		{"item not an object", []any{"file.png"}, "must be an object"},
+		{"field not a string", []any{map[string]any{"path": 42}}, `field "path" must be a string`},
+		{"neither data nor path", []any{map[string]any{"name": "x"}}, "one of data or path is required"},
+		{"data without media type", []any{map[string]any{"data": "aGk="}}, "media_type is required"},
+		{"unsupported media type", []any{map[string]any{"data": "aGk=", "media_type": "application/zip"}}, "unsupported media type"},
+		{"absolute path", []any{map[string]any{"path": "/etc/passwd"}}, "must be relative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockPrompter{result: "ok"}
+			handler := buildToolMap(mock)["prompt"]
+
+			result, err := handler(context.Background(), newCallToolRequest("prompt", map[string]any{
+				"message":     "hi",
+				"attachments": tt.attachments,
+			}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.IsError {
+				t.Fatal("expected tool error")
+			}
+			if text := extractText(t, result); !strings.Contains(text, tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, text)
+			}
+			if mock.submitCalled {
+				t.Error("expected Submit not to be called")
+			}
+		})
+	}
+}
+
 // --- Test helpers ---
 
 // buildToolMap registers tools and returns a name->handler map.
@@ -1179,15 +1828,48 @@ func buildToolMap(process claudepkg.Prompter) map[string]func(context.Context, m
 	st := statusTool(process)
 	tools[st.Tool.Name] = st.Handler
 
+	wt := waitTool(process)
+	tools[wt.Tool.Name] = wt.Handler
+
 	stp := stopTool(process)
 	tools[stp.Tool.Name] = stp.Handler
 
 	rt := resultTool(process)
 	tools[rt.Tool.Name] = rt.Handler
 
+	tt := transcriptTool(process)
+	tools[tt.Tool.Name] = tt.Handler
+
 	mt := messagesTool(process)
 	tools[mt.Tool.Name] = mt.Handler
 
+	lt := logsTool(process)
+	tools[lt.Tool.Name] = lt.Handler
+
+	cst := configureSessionTool(process)
+	tools[cst.Tool.Name] = cst.Handler
+
+	ct := compactTool(process)
+	tools[ct.Tool.Name] = ct.Handler
+
+	lst := listSessionsTool(process)
+	tools[lst.Tool.Name] = lst.Handler
+
+	ist := inspectSessionTool(process)
+	tools[ist.Tool.Name] = ist.Handler
+
+	est := exportSessionTool(process)
+	tools[est.Tool.Name] = est.Handler
+
+	dst := deleteSessionTool(process)
+	tools[dst.Tool.Name] = dst.Handler
+
+	ebt := exportSessionBundleTool(process)
+	tools[ebt.Tool.Name] = ebt.Handler
+
+	ibt := importSessionBundleTool(process)
+	tools[ibt.Tool.Name] = ibt.Handler
+
 	return tools
 }
 
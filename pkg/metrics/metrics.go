@@ -98,6 +98,38 @@ var ProcessRestartsTotal = promauto.NewCounter(prometheus.CounterOpts{
 	Help:      "Total number of automatic persistent process restarts.",
 })
 
+// RedactionsTotal counts secrets masked in Claude subprocess output.
+var RedactionsTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "redactions_total",
+	Help:      "Total number of secrets redacted from agent output.",
+})
+
+// CompactionsTotal counts context compactions of chat-mode sessions by
+// trigger: "manual" (compact tool), "auto" (klaus threshold policy) or "cli"
+// (compacted by the CLI on its own).
+var CompactionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "compactions_total",
+	Help:      "Total number of context compactions of the agent session.",
+}, []string{"trigger"})
+
+// MCPServerUp reports whether the last probe of a configured MCP server
+// succeeded (1) or failed (0), by server name.
+var MCPServerUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "mcp_server_up",
+	Help:      "Whether the last probe of the MCP server succeeded (1) or failed (0).",
+}, []string{"server"})
+
+// MCPServerTools reports the number of tools a configured MCP server listed
+// in its last successful probe, by server name.
+var MCPServerTools = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "mcp_server_tools",
+	Help:      "Number of tools the MCP server listed in its last successful probe.",
+}, []string{"server"})
+
 // AllStatuses is the complete list of process status labels used by the
 // ProcessStatusGauge. It must match claude.AllProcessStatuses -- a cross-
 // package test in sync_test.go enforces this at test time.
@@ -162,3 +194,33 @@ func RecordCost(delta float64) {
 		SessionCostUSDTotal.Add(delta)
 	}
 }
+
+// RecordRedactions adds n to the redaction counter.
+func RecordRedactions(n int) {
+	if n > 0 {
+		RedactionsTotal.Add(float64(n))
+	}
+}
+
+// RecordCompaction increments the compaction counter for trigger.
+func RecordCompaction(trigger string) {
+	CompactionsTotal.WithLabelValues(trigger).Inc()
+}
+
+// SetMCPServerHealth records the outcome of a probe of the MCP server
+// name. The tool count is kept from the last successful probe.
+func SetMCPServerHealth(name string, up bool, tools int) {
+	if !up {
+		MCPServerUp.WithLabelValues(name).Set(0)
+		return
+	}
+	MCPServerUp.WithLabelValues(name).Set(1)
+	MCPServerTools.WithLabelValues(name).Set(float64(tools))
+}
+
+// DeleteMCPServer removes the series of an MCP server that is no longer
+// configured.
+func DeleteMCPServer(name string) {
+	MCPServerUp.DeleteLabelValues(name)
+	MCPServerTools.DeleteLabelValues(name)
+}
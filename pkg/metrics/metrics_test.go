@@ -126,6 +126,54 @@ func TestRecordCost(t *testing.T) {
 	}
 }
 
+func TestRecordRedactions(t *testing.T) {
+	before := readCounter(t, RedactionsTotal)
+
+	RecordRedactions(2)
+	RecordRedactions(0)
+	RecordRedactions(-1)
+
+	if got := readCounter(t, RedactionsTotal) - before; got != 2 {
+		t.Errorf("expected redaction delta 2, got %f", got)
+	}
+}
+
+func TestRecordCompaction(t *testing.T) {
+	before := readCounter(t, CompactionsTotal.WithLabelValues("auto"))
+
+	RecordCompaction("auto")
+	RecordCompaction("manual")
+
+	if got := readCounter(t, CompactionsTotal.WithLabelValues("auto")) - before; got != 1 {
+		t.Errorf("expected auto compaction delta 1, got %f", got)
+	}
+}
+
+func TestSetMCPServerHealth(t *testing.T) {
+	read := func(g prometheus.Gauge) float64 {
+		t.Helper()
+		var m dto.Metric
+		if err := g.Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetGauge().GetValue()
+	}
+
+	SetMCPServerHealth("github", true, 12)
+	if up, tools := read(MCPServerUp.WithLabelValues("github")), read(MCPServerTools.WithLabelValues("github")); up != 1 || tools != 12 {
+		t.Errorf("expected up 1 with 12 tools, got %f, %f", up, tools)
+	}
+	SetMCPServerHealth("github", false, 0)
+	if up, tools := read(MCPServerUp.WithLabelValues("github")), read(MCPServerTools.WithLabelValues("github")); up != 0 || tools != 12 {
+		t.Errorf("expected up 0 keeping 12 tools, got %f, %f", up, tools)
+	}
+
+	DeleteMCPServer("github")
+	if MCPServerUp.DeleteLabelValues("github") {
+		t.Error("expected the series to be deleted")
+	}
+}
+
 func TestPromptDurationSeconds(t *testing.T) {
 	PromptDurationSeconds.WithLabelValues("completed", "blocking").Observe(5.0)
 	PromptDurationSeconds.WithLabelValues("error", "blocking").Observe(1.0)
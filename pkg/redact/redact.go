@@ -0,0 +1,226 @@
+// Package redact masks secrets such as cloud credentials, API tokens and
+// private keys in agent output before it is stored, logged or returned.
+//
+// A Redactor combines a set of built-in detectors with operator-supplied
+// regular expressions. Matches are replaced with a "[REDACTED:<detector>]"
+// marker. Redaction operates on raw stream-json lines so that the parsed
+// message text, tool arguments and the raw JSON all see the same masked
+// content.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// secretGroup is the name of the optional capture group that limits
+// redaction to part of a match. Patterns that need surrounding context to
+// identify a secret (e.g. a key name) capture only the value in this group,
+// so the context stays readable.
+const secretGroup = "secret"
+
+// CustomDetector is the detector name reported for operator-supplied patterns.
+const CustomDetector = "custom"
+
+// builtinDetectors are always active. Order matters: multi-line private keys
+// are masked first so that their base64 body is not partially matched by the
+// narrower detectors.
+var builtinDetectors = []struct {
+	name    string
+	pattern string
+}{
+	{"private_key", `-----BEGIN[A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END[A-Z0-9 ]*PRIVATE KEY-----`},
+	{"aws_access_key_id", `\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`},
+	{"aws_secret_access_key", `(?i)aws_?secret_?access_?key[\s"'\\:=]{1,8}(?P<secret>[A-Za-z0-9/+=]{40})`},
+	{"github_token", `\b(?:gh[pousr]_[A-Za-z0-9]{36,251}|github_pat_[A-Za-z0-9_]{22,255})\b`},
+	{"jwt", `\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`},
+	{"kubeconfig_credential", `\b(?:client-key-data|token)\\?["']?\s*:\s*\\?["']?(?P<secret>[A-Za-z0-9+/=._-]{20,})`},
+}
+
+// BuiltinDetectors returns the names of the built-in detectors.
+func BuiltinDetectors() []string {
+	names := make([]string, len(builtinDetectors))
+	for i, d := range builtinDetectors {
+		names[i] = d.name
+	}
+	return names
+}
+
+type detector struct {
+	name string
+	re   *regexp.Regexp
+	// group is the index of the secret capture group, or 0 to redact the
+	// whole match.
+	group       int
+	replacement []byte
+}
+
+func newDetector(name string, re *regexp.Regexp) detector {
+	return detector{
+		name:        name,
+		re:          re,
+		group:       re.SubexpIndex(secretGroup),
+		replacement: []byte("[REDACTED:" + name + "]"),
+	}
+}
+
+// replace masks all matches in data and returns the result with the number
+// of replacements. data is never modified in place.
+func (d detector) replace(data []byte) ([]byte, int) {
+	matches := d.re.FindAllSubmatchIndex(data, -1)
+	if len(matches) == 0 {
+		return data, 0
+	}
+	out := make([]byte, 0, len(data))
+	last := 0
+	n := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if d.group > 0 {
+			if m[2*d.group] < 0 {
+				continue
+			}
+			start, end = m[2*d.group], m[2*d.group+1]
+		}
+		out = append(out, data[last:start]...)
+		out = append(out, d.replacement...)
+		last = end
+		n++
+	}
+	out = append(out, data[last:]...)
+	return out, n
+}
+
+// Redactor masks secrets in byte slices and strings. It is safe for
+// concurrent use. A nil *Redactor is valid and redacts nothing.
+type Redactor struct {
+	detectors []detector
+}
+
+// New returns a Redactor with the built-in detectors plus the given regular
+// expressions. A pattern may contain a capture group named "secret" to
+// redact only that part of each match.
+func New(patterns []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, d := range builtinDetectors {
+		r.detectors = append(r.detectors, newDetector(d.name, regexp.MustCompile(d.pattern)))
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		r.detectors = append(r.detectors, newDetector(CustomDetector, re))
+	}
+	return r, nil
+}
+
+// Equal reports whether r and other mask the same patterns. Nil equals only
+// nil.
+func (r *Redactor) Equal(other *Redactor) bool {
+	if r == nil || other == nil {
+		return r == other
+	}
+	if len(r.detectors) != len(other.detectors) {
+		return false
+	}
+	for i, d := range r.detectors {
+		o := other.detectors[i]
+		if d.name != o.name || d.re.String() != o.re.String() {
+			return false
+		}
+	}
+	return true
+}
+
+// Redact masks secrets in data and returns the result together with the
+// number of redactions. When nothing matches, data is returned unchanged.
+//
+// If data is valid JSON and a match would break its structure (e.g. a
+// custom pattern spanning a closing quote), the JSON is decoded and each
+// string value is redacted individually instead, so the output always
+// remains valid JSON.
+func (r *Redactor) Redact(data []byte) ([]byte, int) {
+	if r == nil || len(data) == 0 {
+		return data, 0
+	}
+	out, n := r.redact(data)
+	if n == 0 {
+		return data, 0
+	}
+	if !json.Valid(out) && json.Valid(data) {
+		if redacted, count, ok := r.redactJSON(data); ok {
+			return redacted, count
+		}
+	}
+	return out, n
+}
+
+// RedactString is the string variant of Redact.
+func (r *Redactor) RedactString(s string) (string, int) {
+	if r == nil || s == "" {
+		return s, 0
+	}
+	out, n := r.redact([]byte(s))
+	if n == 0 {
+		return s, 0
+	}
+	return string(out), n
+}
+
+func (r *Redactor) redact(data []byte) ([]byte, int) {
+	total := 0
+	for _, d := range r.detectors {
+		var n int
+		data, n = d.replace(data)
+		total += n
+	}
+	return data, total
+}
+
+// redactJSON decodes data, redacts every string value and object key, and
+// re-encodes it. Numbers are preserved verbatim.
+func (r *Redactor) redactJSON(data []byte) ([]byte, int, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, 0, false
+	}
+	total := 0
+	v = r.walk(v, &total)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, 0, false
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), total, true
+}
+
+func (r *Redactor) walk(v any, total *int) any {
+	switch val := v.(type) {
+	case string:
+		s, n := r.RedactString(val)
+		*total += n
+		return s
+	case []any:
+		for i := range val {
+			val[i] = r.walk(val[i], total)
+		}
+		return val
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			key, n := r.RedactString(k)
+			*total += n
+			out[key] = r.walk(item, total)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -23,9 +24,17 @@ const (
 // OpenAI-compatible request/response types for the chat completions endpoint.
 
 type chatCompletionRequest struct {
-	Messages []chatMessage `json:"messages"`
-	Stream   bool          `json:"stream"`
-	Model    string        `json:"model,omitempty"`
+	Messages []chatRequestMessage `json:"messages"`
+	Stream   bool                 `json:"stream"`
+	Model    string               `json:"model,omitempty"`
+}
+
+// chatRequestMessage is a message in the request history. Unlike chatMessage
+// (used for responses), its content may be a string or an array of content
+// parts.
+type chatRequestMessage struct {
+	Role    string      `json:"role"`
+	Content chatContent `json:"content"`
 }
 
 type chatMessage struct {
@@ -101,15 +110,27 @@ func handleChatCompletions(process claudepkg.Prompter) http.HandlerFunc {
 			return
 		}
 
-		prompt := extractLastUserMessage(req.Messages)
-		if prompt == "" {
+		content := extractLastUserMessage(req.Messages)
+		if content.err != nil {
+			http.Error(w, "invalid user message: "+content.err.Error(), http.StatusBadRequest)
+			return
+		}
+		if content.Text == "" && len(content.Attachments) == 0 {
 			http.Error(w, "no user message found", http.StatusBadRequest)
 			return
 		}
+		if err := claudepkg.ValidateAttachments(content.Attachments); err != nil {
+			http.Error(w, "invalid attachments: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		prompt := content.Text
 
 		var runOpts *claudepkg.RunOptions
-		if callCount > 0 {
-			runOpts = &claudepkg.RunOptions{ContinueSession: true}
+		if callCount > 0 || len(content.Attachments) > 0 {
+			runOpts = &claudepkg.RunOptions{
+				ContinueSession: callCount > 0,
+				Attachments:     content.Attachments,
+			}
 		}
 
 		ch, err := process.RunWithOptions(r.Context(), prompt, runOpts)
@@ -167,7 +188,7 @@ func streamResponse(w http.ResponseWriter, r *http.Request, process claudepkg.Pr
 	for {
 		select {
 		case <-r.Context().Done():
-			if err := process.Stop(); err != nil {
+			if err := claudepkg.StopRun(context.WithoutCancel(r.Context()), process, ch); err != nil {
 				slog.Error("chat: failed to stop process on client disconnect", "error", err)
 			}
 			return
@@ -375,11 +396,11 @@ func streamMessageToDelta(msg claudepkg.StreamMessage, toolIndex *int) *chatMess
 }
 
 // extractLastUserMessage returns the content of the last message with role "user".
-func extractLastUserMessage(messages []chatMessage) string {
+func extractLastUserMessage(messages []chatRequestMessage) chatContent {
 	for i := len(messages) - 1; i >= 0; i-- {
 		if messages[i].Role == "user" {
 			return messages[i].Content
 		}
 	}
-	return ""
+	return chatContent{}
 }
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	claudepkg "github.com/giantswarm/klaus/pkg/claude"
+)
+
+// chatContent is the content of a chat request message. It accepts both the
+// plain string form and the OpenAI multimodal array form:
+//
+//	[{"type":"text","text":"What is wrong here?"},
+//	 {"type":"image_url","image_url":{"url":"data:image/png;base64,..."}},
+//	 {"type":"file","file":{"filename":"report.pdf","file_data":"data:application/pdf;base64,..."}}]
+//
+// As a klaus extension, a file part may set "path" instead of "file_data" to
+// attach a file from the workspace. Text parts are joined with newlines;
+// image and file parts become attachments.
+type chatContent struct {
+	Text        string
+	Attachments []claudepkg.Attachment
+	// err reports the first part that cannot be passed to the agent, e.g.
+	// of an unsupported type. It does not fail decoding, so that history
+	// messages with such parts (refusals, audio) do not fail the request;
+	// only the new user message must be free of them.
+	err error
+}
+
+// chatContentPart is a single element of a multimodal content array.
+type chatContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+	File *struct {
+		FileData string `json:"file_data,omitempty"`
+		Filename string `json:"filename,omitempty"`
+		Path     string `json:"path,omitempty"`
+	} `json:"file,omitempty"`
+}
+
+// UnmarshalJSON decodes either a JSON string, null, or an array of content parts.
+func (c *chatContent) UnmarshalJSON(data []byte) error {
+	*c = chatContent{}
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &c.Text)
+	}
+
+	var parts []chatContentPart
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return fmt.Errorf("content must be a string or an array of content parts: %w", err)
+	}
+
+	var texts []string
+	for i, part := range parts {
+		if err := c.addPart(part, &texts); err != nil && c.err == nil {
+			c.err = fmt.Errorf("content part %d: %w", i, err)
+		}
+	}
+	c.Text = strings.Join(texts, "\n")
+	return nil
+}
+
+// addPart adds a content part to c, or its text to texts. Parts that fail
+// are skipped.
+func (c *chatContent) addPart(part chatContentPart, texts *[]string) error {
+	switch part.Type {
+	case "text":
+		*texts = append(*texts, part.Text)
+	case "image_url":
+		if part.ImageURL == nil {
+			return fmt.Errorf("missing image_url")
+		}
+		mediaType, b64, err := parseDataURL(part.ImageURL.URL)
+		if err != nil {
+			return err
+		}
+		c.Attachments = append(c.Attachments, claudepkg.Attachment{MediaType: mediaType, Data: b64})
+	case "file":
+		if part.File == nil {
+			return fmt.Errorf("missing file")
+		}
+		a, err := fileAttachment(part.File.FileData, part.File.Filename, part.File.Path)
+		if err != nil {
+			return err
+		}
+		c.Attachments = append(c.Attachments, a)
+	default:
+		return fmt.Errorf("unsupported type %q", part.Type)
+	}
+	return nil
+}
+
+// fileAttachment builds an attachment from an OpenAI file content part.
+// file_data may be a data URL or bare base64, in which case the media type is
+// derived from the filename.
+func fileAttachment(fileData, filename, path string) (claudepkg.Attachment, error) {
+	if path != "" {
+		return claudepkg.Attachment{Path: path, Name: filename}, nil
+	}
+	if fileData == "" {
+		return claudepkg.Attachment{}, fmt.Errorf("file part requires file_data or path (file_id is not supported)")
+	}
+	if strings.HasPrefix(fileData, "data:") {
+		mediaType, b64, err := parseDataURL(fileData)
+		if err != nil {
+			return claudepkg.Attachment{}, err
+		}
+		return claudepkg.Attachment{MediaType: mediaType, Data: b64, Name: filename}, nil
+	}
+	mediaType := mime.TypeByExtension(strings.ToLower(filepath.Ext(filename)))
+	if mediaType == "" {
+		return claudepkg.Attachment{}, fmt.Errorf("cannot determine media type of file %q; use a data URL", filename)
+	}
+	return claudepkg.Attachment{MediaType: mediaType, Data: fileData, Name: filename}, nil
+}
+
+// parseDataURL splits a base64 data URL ("data:image/png;base64,...") into
+// its media type and payload. Remote URLs are rejected since the agent
+// cannot be relied upon to fetch them.
+func parseDataURL(url string) (mediaType, data string, err error) {
+	rest, ok := strings.CutPrefix(url, "data:")
+	if !ok {
+		return "", "", fmt.Errorf("only base64 data URLs are supported")
+	}
+	header, payload, ok := strings.Cut(rest, ",")
+	if !ok {
+		return "", "", fmt.Errorf("malformed data URL")
+	}
+	mediaType, ok = strings.CutSuffix(header, ";base64")
+	if !ok {
+		return "", "", fmt.Errorf("data URL must be base64-encoded")
+	}
+	return mediaType, payload, nil
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/klaus/pkg/claude"
+)
+
+func TestChatContent_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    chatContent
+		wantErr string
+	}{
+		{
+			name:  "plain string",
+			input: `"hello"`,
+			want:  chatContent{Text: "hello"},
+		},
+		{
+			name:  "null",
+			input: `null`,
+			want:  chatContent{},
+		},
+		{
+			name:  "text parts are joined",
+			input: `[{"type":"text","text":"one"},{"type":"text","text":"two"}]`,
+			want:  chatContent{Text: "one\ntwo"},
+		},
+		{
+			name:  "image data URL",
+			input: `[{"type":"text","text":"look"},{"type":"image_url","image_url":{"url":"data:image/png;base64,aGk="}}]`,
+			want: chatContent{
+				Text:        "look",
+				Attachments: []claude.Attachment{{MediaType: "image/png", Data: "aGk="}},
+			},
+		},
+		{
+			name:  "file data URL",
+			input: `[{"type":"file","file":{"filename":"spec.pdf","file_data":"data:application/pdf;base64,JVBERi0="}}]`,
+			want: chatContent{
+				Attachments: []claude.Attachment{{MediaType: "application/pdf", Data: "JVBERi0=", Name: "spec.pdf"}},
+			},
+		},
+		{
+			name:  "file bare base64 uses filename extension",
+			input: `[{"type":"file","file":{"filename":"spec.pdf","file_data":"JVBERi0="}}]`,
+			want: chatContent{
+				Attachments: []claude.Attachment{{MediaType: "application/pdf", Data: "JVBERi0=", Name: "spec.pdf"}},
+			},
+		},
+		{
+			name:  "workspace path extension",
+			input: `[{"type":"file","file":{"path":"shots/ui.png"}}]`,
+			want: chatContent{
+				Attachments: []claude.Attachment{{Path: "shots/ui.png"}},
+			},
+		},
+		{
+			name:    "remote image URL",
+			input:   `[{"type":"image_url","image_url":{"url":"https://example.com/x.png"}}]`,
+			wantErr: "only base64 data URLs",
+		},
+		{
+			name:    "non-base64 data URL",
+			input:   `[{"type":"image_url","image_url":{"url":"data:text/plain,hi"}}]`,
+			wantErr: "must be base64",
+		},
+		{
+			name:    "file_id",
+			input:   `[{"type":"file","file":{"file_id":"file-123"}}]`,
+			wantErr: "file_id is not supported",
+		},
+		{
+			name:    "unknown part type",
+			input:   `[{"type":"input_audio"}]`,
+			wantErr: `unsupported type "input_audio"`,
+		},
+		{
+			name:    "wrong JSON type",
+			input:   `42`,
+			wantErr: "must be a string or an array",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got chatContent
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if err == nil {
+				err = got.err
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleChatCompletions_MultimodalContent(t *testing.T) {
+	var gotPrompt string
+	var gotOpts *claude.RunOptions
+	prompter := &chatTestPrompter{
+		status: claude.StatusInfo{Status: claude.ProcessStatusIdle},
+		runFn: func(_ context.Context, prompt string, opts *claude.RunOptions) (<-chan claude.StreamMessage, error) {
+			gotPrompt = prompt
+			gotOpts = opts
+			ch := make(chan claude.StreamMessage, 1)
+			ch <- claude.StreamMessage{Type: claude.MessageTypeResult, Result: "a button is missing"}
+			close(ch)
+			return ch, nil
+		},
+	}
+
+	body := `{"messages":[{"role":"user","content":[` +
+		`{"type":"text","text":"What is wrong?"},` +
+		`{"type":"image_url","image_url":{"url":"data:image/png;base64,aGk="}}]}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handleChatCompletions(prompter)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if gotPrompt != "What is wrong?" {
+		t.Errorf("expected prompt %q, got %q", "What is wrong?", gotPrompt)
+	}
+	want := []claude.Attachment{{MediaType: "image/png", Data: "aGk="}}
+	if gotOpts == nil || !reflect.DeepEqual(gotOpts.Attachments, want) {
+		t.Errorf("expected attachments %+v, got %+v", want, gotOpts)
+	}
+	if gotOpts != nil && gotOpts.ContinueSession {
+		t.Error("expected first call not to continue the session")
+	}
+}
+
+func TestHandleChatCompletions_InvalidAttachment(t *testing.T) {
+	prompter := &chatTestPrompter{status: claude.StatusInfo{Status: claude.ProcessStatusIdle}}
+
+	body := `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:application/zip;base64,aGk="}}]}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handleChatCompletions(prompter)(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "unsupported media type") {
+		t.Errorf("unexpected body: %s", w.Body.String())
+	}
+}
+
+func TestHandleChatCompletions_UnsupportedParts(t *testing.T) {
+	var gotPrompt string
+	prompter := &chatTestPrompter{
+		status: claude.StatusInfo{Status: claude.ProcessStatusIdle},
+		runFn: func(_ context.Context, prompt string, _ *claude.RunOptions) (<-chan claude.StreamMessage, error) {
+			gotPrompt = prompt
+			ch := make(chan claude.StreamMessage, 1)
+			ch <- claude.StreamMessage{Type: claude.MessageTypeResult, Result: "ok"}
+			close(ch)
+			return ch, nil
+		},
+	}
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handleChatCompletions(prompter)(w, req)
+		return w
+	}
+
+	// Parts klaus does not support in the history are skipped.
+	w := post(`{"messages":[` +
+		`{"role":"user","content":[{"type":"input_audio","input_audio":{"data":"aGk=","format":"wav"}}]},` +
+		`{"role":"assistant","content":[{"type":"refusal","refusal":"I cannot listen to audio."}]},` +
+		`{"role":"user","content":[{"type":"text","text":"Read this instead."}]}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if gotPrompt != "Read this instead." {
+		t.Errorf("expected prompt %q, got %q", "Read this instead.", gotPrompt)
+	}
+
+	// The new user message must not have any.
+	w = post(`{"messages":[{"role":"user","content":[{"type":"text","text":"Listen."},{"type":"input_audio"}]}]}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `content part 1: unsupported type "input_audio"`) {
+		t.Errorf("unexpected body: %s", w.Body.String())
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 
@@ -55,12 +56,20 @@ func (p *chatTestPrompter) Stop() error {
 	return nil
 }
 
+func (p *chatTestPrompter) Interrupt(context.Context) error {
+	return claude.ErrInterruptUnsupported
+}
+
 func (p *chatTestPrompter) Done() <-chan struct{} {
 	ch := make(chan struct{})
 	close(ch)
 	return ch
 }
 
+func (p *chatTestPrompter) Changes() <-chan struct{} {
+	return nil
+}
+
 func (p *chatTestPrompter) ResultDetail() claude.ResultDetailInfo {
 	return claude.ResultDetailInfo{}
 }
@@ -73,6 +82,32 @@ func (p *chatTestPrompter) RawMessages(_ int, _ []string) claude.RawMessagesInfo
 	return claude.RawMessagesInfo{Status: p.status.Status}
 }
 
+func (p *chatTestPrompter) Logs(_ int) claude.LogsInfo {
+	return claude.LogsInfo{Lines: []string{}}
+}
+
+func (p *chatTestPrompter) ConfigureSession(context.Context, claude.SessionConfig) (claude.SessionSettings, error) {
+	return claude.SessionSettings{}, claude.ErrSessionControlUnsupported
+}
+
+func (p *chatTestPrompter) Compact(context.Context, string) (claude.CompactionEvent, error) {
+	return claude.CompactionEvent{}, claude.ErrCompactUnsupported
+}
+
+func (p *chatTestPrompter) UpdateOptions(claude.Options) {}
+
+func (p *chatTestPrompter) Sessions() *claude.SessionStore {
+	return claude.NewSessionStore(os.TempDir(), nil)
+}
+
+func (p *chatTestPrompter) Results() *claude.ResultStore {
+	return nil
+}
+
+func (p *chatTestPrompter) WorkDir() string {
+	return ""
+}
+
 func (p *chatTestPrompter) OpenAIMessages(_ int) claude.OpenAIMessagesInfo {
 	return claude.OpenAIMessagesInfo{Messages: []claude.OpenAIMessage{}, Metadata: claude.OpenAIMetadata{}}
 }
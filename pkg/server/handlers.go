@@ -1,14 +1,18 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	claudepkg "github.com/giantswarm/klaus/pkg/claude"
+	configpkg "github.com/giantswarm/klaus/pkg/config"
+	"github.com/giantswarm/klaus/pkg/doctor"
 	"github.com/giantswarm/klaus/pkg/project"
 )
 
@@ -22,6 +26,9 @@ type statusResponse struct {
 	// Owner is intentionally exposed on the unauthenticated /status endpoint
 	// for observability (e.g. confirming which identity owns this instance).
 	Owner string `json:"owner,omitempty"`
+	// Config reports the configuration in effect and the last hot reload;
+	// omitted when reloading is disabled.
+	Config *configpkg.ReloadStatus `json:"config,omitempty"`
 }
 
 func handleHealthz(w http.ResponseWriter, _ *http.Request) {
@@ -30,22 +37,62 @@ func handleHealthz(w http.ResponseWriter, _ *http.Request) {
 }
 
 // handleReadyz reports whether the Claude process is ready to accept traffic.
-// It returns 503 when the process is starting, stopped, or in an error state.
-func handleReadyz(process claudepkg.Prompter) http.HandlerFunc {
-	return func(w http.ResponseWriter, _ *http.Request) {
+// It returns 503 when the process is starting, stopped, or in an error state,
+// or when one of the readiness checks fails. With ?verbose, the status of
+// every check is returned as JSON. The endpoint is unauthenticated, so the
+// details and errors of the checks, which name paths, env vars and MCP
+// servers, are left out; `klaus doctor` reports them.
+func handleReadyz(process claudepkg.Prompter, readiness *doctor.Runner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		status := process.Status().Status
+		processCheck := doctor.Result{Name: "process", Status: doctor.StatusOK, Detail: string(status)}
 		switch status {
 		case claudepkg.ProcessStatusStarting, claudepkg.ProcessStatusError, claudepkg.ProcessStatusStopped:
-			w.WriteHeader(http.StatusServiceUnavailable)
-			_, _ = fmt.Fprintln(w, "not ready")
+			processCheck.Status = doctor.StatusFail
+		}
+
+		report := doctor.Report{Ready: true, CheckedAt: time.Now().UTC()}
+		if readiness != nil {
+			// Probes time out quickly; let the checks finish and cache
+			// their results for the next probe.
+			report = readiness.Run(context.WithoutCancel(r.Context()))
+			report.Checks = redactCheckDetails(report.Checks)
+		}
+		report.Checks = append([]doctor.Result{processCheck}, report.Checks...)
+		report.Ready = report.Ready && processCheck.Status != doctor.StatusFail
+
+		code := http.StatusOK
+		if !report.Ready {
+			code = http.StatusServiceUnavailable
+		}
+		if r.URL.Query().Has("verbose") {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(code)
+			if err := json.NewEncoder(w).Encode(report); err != nil {
+				slog.Error("failed to encode readiness report", "error", err)
+			}
 			return
-		default:
-			w.WriteHeader(http.StatusOK)
+		}
+		w.WriteHeader(code)
+		if report.Ready {
 			_, _ = fmt.Fprintln(w, "ok")
+		} else {
+			_, _ = fmt.Fprintln(w, "not ready")
 		}
 	}
 }
 
+// redactCheckDetails returns a copy of checks without their details and
+// errors.
+func redactCheckDetails(checks []doctor.Result) []doctor.Result {
+	redacted := make([]doctor.Result, len(checks))
+	for i, c := range checks {
+		c.Detail, c.Error = "", ""
+		redacted[i] = c
+	}
+	return redacted
+}
+
 func handleRoot(w http.ResponseWriter, r *http.Request) {
 	// Go's ServeMux uses "/" as a catch-all. Return 404 for unmatched paths
 	// to avoid masking routing issues and confusing monitoring.
@@ -56,7 +103,7 @@ func handleRoot(w http.ResponseWriter, r *http.Request) {
 	_, _ = fmt.Fprintf(w, "%s %s\n", project.Name, project.Version())
 }
 
-func handleStatus(process claudepkg.Prompter, mode string, ownerSubject string) http.HandlerFunc {
+func handleStatus(process claudepkg.Prompter, mode string, ownerSubject string, reload *configpkg.Watcher) http.HandlerFunc {
 	return func(w http.ResponseWriter, _ *http.Request) {
 		resp := statusResponse{
 			Name:    project.Name,
@@ -67,6 +114,10 @@ func handleStatus(process claudepkg.Prompter, mode string, ownerSubject string)
 			Mode:    mode,
 			Owner:   ownerSubject,
 		}
+		if reload != nil {
+			status := reload.Status()
+			resp.Config = &status
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
@@ -75,10 +126,10 @@ func handleStatus(process claudepkg.Prompter, mode string, ownerSubject string)
 	}
 }
 
-func registerOperationalRoutes(mux *http.ServeMux, process claudepkg.Prompter, mode string, ownerSubject string) {
+func registerOperationalRoutes(mux *http.ServeMux, process claudepkg.Prompter, mode string, ownerSubject string, readiness *doctor.Runner, reload *configpkg.Watcher) {
 	mux.HandleFunc("/healthz", handleHealthz)
-	mux.HandleFunc("/readyz", handleReadyz(process))
-	mux.HandleFunc("/status", handleStatus(process, mode, ownerSubject))
+	mux.HandleFunc("/readyz", handleReadyz(process, readiness))
+	mux.HandleFunc("/status", handleStatus(process, mode, ownerSubject, reload))
 	mux.Handle("/metrics", promhttp.Handler())
 	mux.HandleFunc("/", handleRoot)
 }
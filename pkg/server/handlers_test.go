@@ -3,16 +3,25 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/giantswarm/klaus/pkg/claude"
+	configpkg "github.com/giantswarm/klaus/pkg/config"
+	"github.com/giantswarm/klaus/pkg/doctor"
 )
 
 type mockPrompter struct {
-	status claude.StatusInfo
+	status       claude.StatusInfo
+	resultDetail claude.ResultDetailInfo
+	results      *claude.ResultStore
+	sessions     *claude.SessionStore
 }
 
 func (m *mockPrompter) Run(_ context.Context, _ string) (<-chan claude.StreamMessage, error) {
@@ -43,14 +52,22 @@ func (m *mockPrompter) Stop() error {
 	return nil
 }
 
+func (m *mockPrompter) Interrupt(context.Context) error {
+	return claude.ErrInterruptUnsupported
+}
+
 func (m *mockPrompter) Done() <-chan struct{} {
 	ch := make(chan struct{})
 	close(ch)
 	return ch
 }
 
+func (m *mockPrompter) Changes() <-chan struct{} {
+	return nil
+}
+
 func (m *mockPrompter) ResultDetail() claude.ResultDetailInfo {
-	return claude.ResultDetailInfo{}
+	return m.resultDetail
 }
 
 func (m *mockPrompter) Messages() claude.MessagesInfo {
@@ -61,6 +78,35 @@ func (m *mockPrompter) RawMessages(_ int, _ []string) claude.RawMessagesInfo {
 	return claude.RawMessagesInfo{Status: m.status.Status}
 }
 
+func (m *mockPrompter) Logs(_ int) claude.LogsInfo {
+	return claude.LogsInfo{Lines: []string{}}
+}
+
+func (m *mockPrompter) ConfigureSession(context.Context, claude.SessionConfig) (claude.SessionSettings, error) {
+	return claude.SessionSettings{}, claude.ErrSessionControlUnsupported
+}
+
+func (m *mockPrompter) Compact(context.Context, string) (claude.CompactionEvent, error) {
+	return claude.CompactionEvent{}, claude.ErrCompactUnsupported
+}
+
+func (m *mockPrompter) UpdateOptions(claude.Options) {}
+
+func (m *mockPrompter) Sessions() *claude.SessionStore {
+	if m.sessions != nil {
+		return m.sessions
+	}
+	return claude.NewSessionStore(os.TempDir(), nil)
+}
+
+func (m *mockPrompter) Results() *claude.ResultStore {
+	return m.results
+}
+
+func (m *mockPrompter) WorkDir() string {
+	return ""
+}
+
 func (m *mockPrompter) OpenAIMessages(_ int) claude.OpenAIMessagesInfo {
 	return claude.OpenAIMessagesInfo{Messages: []claude.OpenAIMessage{}, Metadata: claude.OpenAIMetadata{}}
 }
@@ -130,7 +176,7 @@ func TestHandleReadyz(t *testing.T) {
 			w := httptest.NewRecorder()
 			process := &mockPrompter{status: claude.StatusInfo{Status: tc.status}}
 
-			handleReadyz(process)(w, req)
+			handleReadyz(process, nil)(w, req)
 
 			resp := w.Result()
 			if resp.StatusCode != tc.wantStatus {
@@ -140,6 +186,38 @@ func TestHandleReadyz(t *testing.T) {
 	}
 }
 
+func TestHandleReadyz_Checks(t *testing.T) {
+	checks := []doctor.Check{
+		{Name: "binary", Run: func(context.Context) (string, error) { return "/usr/bin/claude 2.1.81", nil }},
+		{Name: "credentials", Run: func(context.Context) (string, error) { return "", doctor.Warn(errors.New("none")) }},
+	}
+	process := &mockPrompter{status: claude.StatusInfo{Status: claude.ProcessStatusIdle}}
+
+	w := httptest.NewRecorder()
+	handleReadyz(process, doctor.NewRunner(checks, time.Minute))(w, httptest.NewRequest(http.MethodGet, "/readyz?verbose", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 with only warnings, got %d", w.Code)
+	}
+	var report doctor.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !report.Ready || len(report.Checks) != 3 || report.Checks[0].Name != "process" || report.Checks[2].Status != doctor.StatusWarn {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	// The endpoint is unauthenticated: paths and errors are left out.
+	if strings.Contains(w.Body.String(), "/usr/bin/claude") || strings.Contains(w.Body.String(), "none") {
+		t.Errorf("expected no check details in the verbose report, got %s", w.Body.String())
+	}
+
+	checks = append(checks, doctor.Check{Name: "workspace", Run: func(context.Context) (string, error) { return "", errors.New("not writable") }})
+	w = httptest.NewRecorder()
+	handleReadyz(process, doctor.NewRunner(checks, time.Minute))(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable || strings.TrimSpace(w.Body.String()) != "not ready" {
+		t.Errorf("expected 503 for a failed check, got %d %q", w.Code, w.Body.String())
+	}
+}
+
 func TestHandleRoot(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	w := httptest.NewRecorder()
@@ -172,7 +250,7 @@ func TestHandleRoot_UnknownPath(t *testing.T) {
 func TestHandleStatus(t *testing.T) {
 	process := claude.NewProcess(claude.DefaultOptions())
 
-	handler := handleStatus(process, ModeAgent, "")
+	handler := handleStatus(process, ModeAgent, "", nil)
 	req := httptest.NewRequest(http.MethodGet, "/status", nil)
 	w := httptest.NewRecorder()
 
@@ -207,7 +285,7 @@ func TestHandleStatus(t *testing.T) {
 func TestHandleStatus_ChatMode(t *testing.T) {
 	process := claude.NewProcess(claude.DefaultOptions())
 
-	handler := handleStatus(process, ModeChat, "")
+	handler := handleStatus(process, ModeChat, "", nil)
 	req := httptest.NewRequest(http.MethodGet, "/status", nil)
 	w := httptest.NewRecorder()
 
@@ -226,7 +304,7 @@ func TestHandleStatus_ChatMode(t *testing.T) {
 func TestHandleStatus_WithOwner(t *testing.T) {
 	process := claude.NewProcess(claude.DefaultOptions())
 
-	handler := handleStatus(process, ModeAgent, "owner@example.com")
+	handler := handleStatus(process, ModeAgent, "owner@example.com", nil)
 	req := httptest.NewRequest(http.MethodGet, "/status", nil)
 	w := httptest.NewRecorder()
 
@@ -242,11 +320,35 @@ func TestHandleStatus_WithOwner(t *testing.T) {
 	}
 }
 
+func TestHandleStatus_WithConfigReload(t *testing.T) {
+	process := claude.NewProcess(claude.DefaultOptions())
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	reload, err := configpkg.NewWatcher([]string{path}, time.Hour, func() (configpkg.ApplyResult, error) { return configpkg.ApplyResult{}, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := handleStatus(process, ModeAgent, "", reload)
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	var status statusResponse
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if status.Config == nil || status.Config.Hash != reload.Status().Hash {
+		t.Errorf("expected config hash %q, got %+v", reload.Status().Hash, status.Config)
+	}
+}
+
 func TestRegisterOperationalRoutes(t *testing.T) {
 	process := claude.NewProcess(claude.DefaultOptions())
 	mux := http.NewServeMux()
 
-	registerOperationalRoutes(mux, process, ModeAgent, "")
+	registerOperationalRoutes(mux, process, ModeAgent, "", nil, nil)
 
 	paths := []string{"/healthz", "/readyz", "/status", "/", "/metrics"}
 	for _, path := range paths {
@@ -265,7 +367,7 @@ func TestHandleMetrics(t *testing.T) {
 	process := claude.NewProcess(claude.DefaultOptions())
 	mux := http.NewServeMux()
 
-	registerOperationalRoutes(mux, process, ModeAgent, "")
+	registerOperationalRoutes(mux, process, ModeAgent, "", nil, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
 	w := httptest.NewRecorder()
@@ -288,7 +390,7 @@ func TestRegisterOperationalRoutes_UnknownPath(t *testing.T) {
 	process := claude.NewProcess(claude.DefaultOptions())
 	mux := http.NewServeMux()
 
-	registerOperationalRoutes(mux, process, ModeAgent, "")
+	registerOperationalRoutes(mux, process, ModeAgent, "", nil, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/nonexistent", nil)
 	w := httptest.NewRecorder()
@@ -25,6 +25,8 @@ import (
 	mcpserver "github.com/mark3labs/mcp-go/server"
 
 	claudepkg "github.com/giantswarm/klaus/pkg/claude"
+	configpkg "github.com/giantswarm/klaus/pkg/config"
+	"github.com/giantswarm/klaus/pkg/doctor"
 	mcppkg "github.com/giantswarm/klaus/pkg/mcp"
 	"github.com/giantswarm/klaus/pkg/project"
 )
@@ -206,13 +208,17 @@ type OAuthServer struct {
 	oauthHandler *handler.Handler
 	httpServer   *http.Server
 	ownerSubject string
+	readiness    *doctor.Runner
+	reload       *configpkg.Watcher
 }
 
 // NewOAuthServer creates an OAuth-protected MCP server. The serverCtx
 // controls the lifetime of background goroutines; it should be cancelled
 // during server shutdown. ownerSubject restricts MCP access to the configured
-// owner identity; when empty, no owner validation is performed.
-func NewOAuthServer(serverCtx context.Context, process claudepkg.Prompter, config OAuthConfig, ownerSubject string) (*OAuthServer, error) {
+// owner identity; when empty, no owner validation is performed. readiness
+// runs the checks /readyz reports on; nil checks only the process status.
+// reload is reported on /status; nil when hot reloading is disabled.
+func NewOAuthServer(serverCtx context.Context, process claudepkg.Prompter, config OAuthConfig, ownerSubject string, readiness *doctor.Runner, reload *configpkg.Watcher) (*OAuthServer, error) {
 	oauthSrv, err := createOAuthServer(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OAuth server: %w", err)
@@ -226,6 +232,8 @@ func NewOAuthServer(serverCtx context.Context, process claudepkg.Prompter, confi
 		oauthServer:  oauthSrv,
 		oauthHandler: oauthHandler,
 		ownerSubject: ownerSubject,
+		readiness:    readiness,
+		reload:       reload,
 	}, nil
 }
 
@@ -244,7 +252,7 @@ func (s *OAuthServer) Start(addr string, mode string, config OAuthConfig) error
 	s.setupMCPRoutes(mux, config)
 
 	// Health and status endpoints (unprotected, bypass owner validation).
-	registerOperationalRoutes(mux, s.process, mode, s.ownerSubject)
+	registerOperationalRoutes(mux, s.process, mode, s.ownerSubject, s.readiness, s.reload)
 
 	s.httpServer = &http.Server{
 		Addr:              addr,
@@ -321,6 +329,13 @@ func (s *OAuthServer) setupMCPRoutes(mux *http.ServeMux, config OAuthConfig) {
 	// validation verifies the token cryptographically. This order is safe:
 	// a forged JWT with matching claims will still be rejected by ValidateToken.
 	mux.Handle("/mcp", OwnerMiddleware(s.ownerSubject, slog.Default())(s.oauthHandler.ValidateToken(httpServer)))
+
+	// Session and run endpoints share the protection of /mcp.
+	protect := func(h http.Handler) http.Handler {
+		return OwnerMiddleware(s.ownerSubject, slog.Default())(s.oauthHandler.ValidateToken(h))
+	}
+	registerSessionRoutes(mux, s.process, protect)
+	registerRunRoutes(mux, s.process, protect)
 }
 
 func createOAuthServer(config OAuthConfig) (*oauth.Server, error) {
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	claudepkg "github.com/giantswarm/klaus/pkg/claude"
+	"github.com/giantswarm/klaus/pkg/transcript"
+)
+
+// registerRunRoutes registers the endpoints for the runs of the agent. wrap
+// applies the authentication of /mcp.
+func registerRunRoutes(mux *http.ServeMux, process claudepkg.Prompter, wrap func(http.Handler) http.Handler) {
+	mux.Handle("GET /v1/runs/{id}/summary", wrap(handleRunSummary(process)))
+}
+
+// handleRunSummary returns the transcript summary of a run. The id is a run
+// ID, or "current" or "last" for the current or last run.
+func handleRunSummary(process claudepkg.Prompter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		detail, err := claudepkg.RunDetail(process, r.PathValue("id"))
+		if err != nil {
+			writeRunError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(transcript.Summarize(detail)); err != nil {
+			slog.Error("failed to encode run summary", "error", err)
+		}
+	}
+}
+
+// writeRunError maps result store errors to HTTP status codes.
+func writeRunError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, claudepkg.ErrInvalidRunID):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, claudepkg.ErrRunNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/giantswarm/klaus/pkg/claude"
+	"github.com/giantswarm/klaus/pkg/transcript"
+)
+
+func TestRunRoutes_Summary(t *testing.T) {
+	const current, past = "20260101T110000-00000002", "20260101T100000-00000001"
+	results := claude.NewResultStore(t.TempDir())
+	if err := results.Save(claude.PersistedResult{RunID: past, ResultText: "past", ToolCalls: map[string]int{"Bash": 2}}); err != nil {
+		t.Fatal(err)
+	}
+	process := &mockPrompter{
+		status:       claude.StatusInfo{Status: claude.ProcessStatusCompleted, RunID: current},
+		resultDetail: claude.ResultDetailInfo{RunID: current, ResultText: "current", Status: claude.ProcessStatusCompleted},
+		results:      results,
+	}
+	mux := http.NewServeMux()
+	registerRunRoutes(mux, process, func(h http.Handler) http.Handler { return h })
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/v1/runs/current/summary", "current"},
+		{"/v1/runs/last/summary", "current"},
+		{"/v1/runs/" + current + "/summary", "current"},
+		{"/v1/runs/" + past + "/summary", "past"},
+	}
+	for _, tt := range tests {
+		w := serveSession(mux, http.MethodGet, tt.path)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status %d: %s", tt.path, w.Code, w.Body)
+			continue
+		}
+		var summary transcript.TranscriptSummary
+		if err := json.Unmarshal(w.Body.Bytes(), &summary); err != nil {
+			t.Fatalf("%s: failed to parse summary: %v", tt.path, err)
+		}
+		if summary.ResultText != tt.want {
+			t.Errorf("%s: expected the %s run, got %+v", tt.path, tt.want, summary)
+		}
+	}
+
+	w := serveSession(mux, http.MethodGet, "/v1/runs/"+past+"/summary")
+	var summary transcript.TranscriptSummary
+	_ = json.Unmarshal(w.Body.Bytes(), &summary)
+	if summary.RunID != past || summary.ToolCalls["Bash"] != 2 {
+		t.Errorf("unexpected summary of a past run: %+v", summary)
+	}
+
+	for path, want := range map[string]int{
+		"/v1/runs/20260101T120000-00000003/summary": http.StatusNotFound,
+		"/v1/runs/not-a-run/summary":                http.StatusBadRequest,
+		"/v1/runs/..%2Fsecret/summary":              http.StatusBadRequest,
+	} {
+		if w := serveSession(mux, http.MethodGet, path); w.Code != want {
+			t.Errorf("%s: expected status %d, got %d: %s", path, want, w.Code, w.Body)
+		}
+	}
+}
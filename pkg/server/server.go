@@ -6,6 +6,8 @@ import (
 	"net/http"
 
 	claudepkg "github.com/giantswarm/klaus/pkg/claude"
+	configpkg "github.com/giantswarm/klaus/pkg/config"
+	"github.com/giantswarm/klaus/pkg/doctor"
 	mcppkg "github.com/giantswarm/klaus/pkg/mcp"
 	"github.com/giantswarm/klaus/pkg/project"
 
@@ -34,6 +36,12 @@ type Config struct {
 	// by matching the JWT sub or email claim. When empty, no owner
 	// validation is performed (backward-compatible).
 	OwnerSubject string
+	// Readiness runs the checks /readyz reports on in addition to the
+	// process status. Nil checks only the process status.
+	Readiness *doctor.Runner
+	// ConfigReload watches the configuration; /status reports its hash and
+	// the last reload. Nil when hot reloading is disabled.
+	ConfigReload *configpkg.Watcher
 }
 
 // NewServer creates a Server that serves MCP and operational endpoints.
@@ -56,8 +64,14 @@ func NewServer(serverCtx context.Context, process claudepkg.Prompter, cfg Config
 	// Chat endpoint -- owner-authenticated, OpenAI-compatible.
 	mux.Handle("/v1/chat/completions", ownerMW(handleChatCompletions(process)))
 
+	// Session endpoints -- owner-authenticated.
+	registerSessionRoutes(mux, process, ownerMW)
+
+	// Run endpoints -- owner-authenticated.
+	registerRunRoutes(mux, process, ownerMW)
+
 	// Operational endpoints (bypass owner validation).
-	registerOperationalRoutes(mux, process, cfg.Mode, cfg.OwnerSubject)
+	registerOperationalRoutes(mux, process, cfg.Mode, cfg.OwnerSubject, cfg.Readiness, cfg.ConfigReload)
 
 	s.httpServer = &http.Server{
 		Addr:              ":" + cfg.Port,
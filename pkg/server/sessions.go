@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	claudepkg "github.com/giantswarm/klaus/pkg/claude"
+)
+
+// registerSessionRoutes registers the endpoints for the sessions the CLI
+// stored for the workspace. wrap applies the authentication of /mcp.
+func registerSessionRoutes(mux *http.ServeMux, process claudepkg.Prompter, wrap func(http.Handler) http.Handler) {
+	mux.Handle("GET /v1/sessions", wrap(handleListSessions(process)))
+	mux.Handle("GET /v1/sessions/{id}", wrap(handleInspectSession(process)))
+	mux.Handle("GET /v1/sessions/{id}/export", wrap(handleExportSession(process)))
+	mux.Handle("DELETE /v1/sessions/{id}", wrap(handleDeleteSession(process)))
+}
+
+func handleListSessions(process claudepkg.Prompter) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		sessions, err := process.Sessions().List()
+		if err != nil {
+			writeSessionError(w, err)
+			return
+		}
+		writeSessionJSON(w, map[string]any{"sessions": sessions})
+	}
+}
+
+func handleInspectSession(process claudepkg.Prompter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		detail, err := process.Sessions().Inspect(r.PathValue("id"))
+		if err != nil {
+			writeSessionError(w, err)
+			return
+		}
+		writeSessionJSON(w, detail)
+	}
+}
+
+func handleExportSession(process claudepkg.Prompter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		data, err := process.Sessions().Export(id)
+		if err != nil {
+			writeSessionError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+id+`.jsonl"`)
+		_, _ = w.Write(data)
+	}
+}
+
+func handleDeleteSession(process claudepkg.Prompter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := process.Sessions().Delete(r.PathValue("id")); err != nil {
+			writeSessionError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeSessionJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to encode session response", "error", err)
+	}
+}
+
+// writeSessionError maps session store errors to HTTP status codes.
+func writeSessionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, claudepkg.ErrInvalidSessionID):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, claudepkg.ErrSessionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, claudepkg.ErrSessionActive):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/klaus/pkg/claude"
+)
+
+func newSessionMux(t *testing.T) (*http.ServeMux, string) {
+	t.Helper()
+	dir := t.TempDir()
+	transcript := `{"type":"user","timestamp":"2026-01-02T09:00:00Z","message":{"role":"user","content":"hello"}}` + "\n"
+	for _, id := range []string{"s1", "s2"} {
+		if err := os.WriteFile(filepath.Join(dir, id+".jsonl"), []byte(transcript), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mux := http.NewServeMux()
+	process := &mockPrompter{sessions: claude.NewSessionStore(dir, nil)}
+	registerSessionRoutes(mux, process, func(h http.Handler) http.Handler { return h })
+	return mux, dir
+}
+
+func serveSession(mux *http.ServeMux, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestSessionRoutes(t *testing.T) {
+	mux, dir := newSessionMux(t)
+
+	w := serveSession(mux, http.MethodGet, "/v1/sessions")
+	if w.Code != http.StatusOK {
+		t.Fatalf("list: status %d: %s", w.Code, w.Body)
+	}
+	var list struct {
+		Sessions []claude.SessionInfo `json:"sessions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("failed to parse list: %v", err)
+	}
+	if len(list.Sessions) != 2 {
+		t.Errorf("expected 2 sessions, got %+v", list.Sessions)
+	}
+
+	w = serveSession(mux, http.MethodGet, "/v1/sessions/s1")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"first_prompt":"hello"`) {
+		t.Errorf("inspect: status %d: %s", w.Code, w.Body)
+	}
+
+	w = serveSession(mux, http.MethodGet, "/v1/sessions/s1/export")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" || !strings.Contains(w.Body.String(), `"hello"`) {
+		t.Errorf("export: status %d, content type %q: %s", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+
+	w = serveSession(mux, http.MethodDelete, "/v1/sessions/s1")
+	if w.Code != http.StatusNoContent {
+		t.Errorf("delete: status %d: %s", w.Code, w.Body)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "s1.jsonl")); !os.IsNotExist(err) {
+		t.Error("session not deleted")
+	}
+}
+
+func TestSessionRoutes_Errors(t *testing.T) {
+	mux, _ := newSessionMux(t)
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/v1/sessions/missing", http.StatusNotFound},
+		{http.MethodDelete, "/v1/sessions/missing", http.StatusNotFound},
+		{http.MethodGet, "/v1/sessions/..%2Fsecret/export", http.StatusBadRequest},
+		{http.MethodPost, "/v1/sessions", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		if w := serveSession(mux, tt.method, tt.path); w.Code != tt.want {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, w.Code, tt.want)
+		}
+	}
+}
@@ -21,6 +21,7 @@ type PluginCompliance struct {
 type TranscriptSummary struct {
 	// Session metadata.
 	SessionID    string               `json:"session_id,omitempty"`
+	RunID        string               `json:"run_id,omitempty"`
 	Status       claude.ProcessStatus `json:"status"`
 	MessageCount int                  `json:"message_count"`
 	TotalCost    *float64             `json:"total_cost_usd"`
@@ -51,6 +52,9 @@ type TranscriptSummary struct {
 	ErrorCount int      `json:"error_count,omitempty"`
 	ErrorTexts []string `json:"error_texts,omitempty"`
 
+	// Context compactions of a chat-mode session.
+	Compactions []claude.CompactionEvent `json:"compactions,omitempty"`
+
 	// Plugin compliance check.
 	PluginCompliance PluginCompliance `json:"plugin_compliance"`
 }
@@ -60,6 +64,7 @@ type TranscriptSummary struct {
 func Summarize(detail claude.ResultDetailInfo) TranscriptSummary {
 	summary := TranscriptSummary{
 		SessionID:    detail.SessionID,
+		RunID:        detail.RunID,
 		Status:       detail.Status,
 		MessageCount: detail.MessageCount,
 		TotalCost:    detail.TotalCost,
@@ -82,6 +87,11 @@ func Summarize(detail claude.ResultDetailInfo) TranscriptSummary {
 		copy(summary.SubagentCalls, detail.SubagentCalls)
 	}
 
+	if len(detail.Compactions) > 0 {
+		summary.Compactions = make([]claude.CompactionEvent, len(detail.Compactions))
+		copy(summary.Compactions, detail.Compactions)
+	}
+
 	// Compute message type distribution from raw messages.
 	summary.MessageTypes = collectMessageTypes(detail.Messages)
 
@@ -18,6 +18,7 @@ func TestSummarize(t *testing.T) {
 		ResultText:   "All done",
 		MessageCount: 5,
 		SessionID:    "sess-123",
+		RunID:        "20260101T100000-00000001",
 		Status:       claude.ProcessStatusCompleted,
 		TotalCost:    &cost,
 		TokenUsage:   tu,
@@ -29,6 +30,9 @@ func TestSummarize(t *testing.T) {
 			{Type: "base:code-reviewer", Status: "completed"},
 			{Type: "code-quality:security-auditor", Status: "completed"},
 		},
+		Compactions: []claude.CompactionEvent{
+			{Trigger: claude.CompactTriggerAuto, PreTokens: 160000},
+		},
 		Messages: []claude.StreamMessage{
 			{Type: claude.MessageTypeSystem},
 			{Type: claude.MessageTypeAssistant, Subtype: claude.SubtypeText},
@@ -46,6 +50,9 @@ func TestSummarize(t *testing.T) {
 	if summary.SessionID != "sess-123" {
 		t.Errorf("SessionID = %q, want %q", summary.SessionID, "sess-123")
 	}
+	if summary.RunID != "20260101T100000-00000001" {
+		t.Errorf("RunID = %q, want %q", summary.RunID, "20260101T100000-00000001")
+	}
 	if summary.Status != claude.ProcessStatusCompleted {
 		t.Errorf("Status = %q", summary.Status)
 	}
@@ -73,6 +80,9 @@ func TestSummarize(t *testing.T) {
 	if summary.ErrorCount != 1 {
 		t.Errorf("ErrorCount = %d", summary.ErrorCount)
 	}
+	if len(summary.Compactions) != 1 || summary.Compactions[0].Trigger != claude.CompactTriggerAuto {
+		t.Errorf("Compactions = %v", summary.Compactions)
+	}
 
 	// Message type distribution.
 	if summary.MessageTypes["system"] != 1 {